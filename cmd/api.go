@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/afterdarksys/apiproxyd/pkg/cache"
 	"github.com/afterdarksys/apiproxyd/pkg/client"
@@ -29,16 +31,19 @@ The daemon will:
 Examples:
   apiproxy api GET /v1/darkapi/ip/8.8.8.8
   apiproxy api POST /v1/nerdapi/hash --data '{"value":"test","algorithm":"sha256"}'
-  apiproxy api GET /v1/status --no-cache`,
+  apiproxy api GET /v1/status --no-cache
+  apiproxy api GET /v1/status --timeout 5s --retry-timeout 30s`,
 	Args: cobra.MinimumNArgs(2),
 	RunE: runAPI,
 }
 
 var (
-	apiData    string
-	apiHeaders []string
-	noCache    bool
-	cacheOnly  bool
+	apiData         string
+	apiHeaders      []string
+	noCache         bool
+	cacheOnly       bool
+	apiTimeout      time.Duration
+	apiRetryTimeout time.Duration
 )
 
 func init() {
@@ -47,6 +52,8 @@ func init() {
 	apiCmd.Flags().StringArrayVarP(&apiHeaders, "header", "H", []string{}, "custom headers (key:value)")
 	apiCmd.Flags().BoolVar(&noCache, "no-cache", false, "bypass cache and force fresh request")
 	apiCmd.Flags().BoolVar(&cacheOnly, "cache-only", false, "only return cached response, don't make request")
+	apiCmd.Flags().DurationVar(&apiTimeout, "timeout", 0, "per-attempt request timeout, overriding config (e.g. 5s)")
+	apiCmd.Flags().DurationVar(&apiRetryTimeout, "retry-timeout", 0, "overall deadline for retries, overriding config (e.g. 30s)")
 }
 
 func runAPI(cmd *cobra.Command, args []string) error {
@@ -99,9 +106,31 @@ func runAPI(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not found in cache (use --no-cache to fetch)")
 	}
 
-	// Make request
-	c := client.New(cfg.APIKey)
-	resp, err := c.Request(method, path, body, headers)
+	// Make request. --timeout overrides the per-attempt request timeout
+	// for this invocation only; --retry-timeout bounds the whole retry
+	// loop (including backoff waits) via ctx, on top of whatever
+	// client.RetryPolicy and retry budget cfg.Client configures.
+	clientCfg := client.DefaultClientConfig()
+	clientCfg.RetryBudgetRatio = cfg.Client.RetryBudgetRatio
+	clientCfg.RetryBudgetMinTokens = cfg.Client.RetryBudgetMinTokens
+	clientCfg.Retry = retryPolicyFromConfig(cfg.Client.Retry)
+	if apiTimeout > 0 {
+		clientCfg.RequestTimeout = apiTimeout
+	} else {
+		clientCfg.RequestTimeout = time.Duration(cfg.Client.RequestTimeout) * time.Second
+	}
+	c := client.NewWithConfig(cfg.APIKey, clientCfg)
+	if cfg.EntryPoint != "" {
+		c.BaseURL = cfg.EntryPoint
+	}
+
+	ctx := context.Background()
+	if apiRetryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, apiRetryTimeout)
+		defer cancel()
+	}
+	resp, err := c.RequestContext(ctx, method, path, body, headers)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -118,6 +147,32 @@ func runAPI(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// retryPolicyFromConfig translates cfg into a client.RetryPolicy, or
+// client.DefaultRetryPolicy() if cfg isn't enabled.
+func retryPolicyFromConfig(cfg config.RetryConfig) *client.RetryPolicy {
+	if !cfg.Enabled {
+		return client.DefaultRetryPolicy()
+	}
+
+	statuses := make(map[int]bool, len(cfg.RetryOn))
+	for _, code := range cfg.RetryOn {
+		statuses[code] = true
+	}
+	methods := make(map[string]bool, len(cfg.RetryOnMethods))
+	for _, m := range cfg.RetryOnMethods {
+		methods[strings.ToUpper(m)] = true
+	}
+
+	return &client.RetryPolicy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoff) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.MaxBackoff) * time.Millisecond,
+		Multiplier:     cfg.Multiplier,
+		RetryOnStatus:  statuses,
+		RetryOnMethods: methods,
+	}
+}
+
 func printResponse(data []byte) {
 	format := viper.GetString("format")
 
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/afterdarksys/apiproxyd/pkg/client"
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var circuitCmd = &cobra.Command{
+	Use:   "circuit",
+	Short: "Inspect and override persisted circuit breaker state",
+	Long: `Inspect and override the circuit breaker state the daemon
+persists to client.circuit_breaker_state_path, for operational visibility
+and manual recovery during an incident.
+
+This reads and writes the on-disk state store directly rather than
+talking to a running daemon, so it works whether or not the daemon is up.`,
+}
+
+var circuitRestoreState string
+
+var circuitDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Print every persisted circuit breaker snapshot",
+	RunE:  runCircuitDump,
+}
+
+var circuitRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Force a breaker's persisted state to open or closed",
+	Long: `Overwrite the persisted snapshot for the named breaker (the
+registry key, typically "method host") with --state, e.g. to force-close
+a breaker an operator has confirmed is healthy again without waiting out
+its timeout, or to force-open one ahead of a known-bad deploy.
+
+The daemon only rehydrates a breaker from its persisted snapshot when
+that breaker is first created, so a restore takes effect on the daemon's
+next restart, not against a breaker already running in memory.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCircuitRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(circuitCmd)
+	circuitCmd.AddCommand(circuitDumpCmd, circuitRestoreCmd)
+
+	circuitRestoreCmd.Flags().StringVar(&circuitRestoreState, "state", "closed", "state to force: open or closed")
+}
+
+// openCircuitStateStore opens the BadgerDB at cfg.Client.CircuitBreakerStatePath.
+func openCircuitStateStore(cfg *config.Config) (*client.BadgerStateStore, error) {
+	if cfg.Client.CircuitBreakerStatePath == "" {
+		return nil, fmt.Errorf("client.circuit_breaker_state_path is not configured")
+	}
+	return client.NewBadgerStateStore(cfg.Client.CircuitBreakerStatePath)
+}
+
+func runCircuitDump(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store, err := openCircuitStateStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshots, err := store.Dump()
+	if err != nil {
+		return fmt.Errorf("failed to read circuit breaker state: %w", err)
+	}
+
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	format := viper.GetString("format")
+	switch format {
+	case "yaml", "yml":
+		data, err := yaml.Marshal(snapshots)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "json":
+		data, err := json.MarshalIndent(snapshots, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		if len(names) == 0 {
+			fmt.Println("No persisted circuit breaker state.")
+			return nil
+		}
+		for _, name := range names {
+			snap := snapshots[name]
+			fmt.Printf("%s: state=%s failures=%d successes=%d last_failure=%s\n",
+				name, snap.State, snap.FailureCount, snap.SuccessCount, snap.LastFailureTime)
+		}
+	}
+
+	return nil
+}
+
+func runCircuitRestore(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	var state client.CircuitState
+	switch circuitRestoreState {
+	case "open":
+		state = client.StateOpen
+	case "closed":
+		state = client.StateClosed
+	default:
+		return fmt.Errorf("invalid --state %q (use open or closed)", circuitRestoreState)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store, err := openCircuitStateStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snap, err := store.Load(name)
+	if err != nil && err != client.ErrSnapshotNotFound {
+		return fmt.Errorf("failed to read existing state for %s: %w", name, err)
+	}
+
+	snap.State = state
+	if err := store.Save(name, snap); err != nil {
+		return fmt.Errorf("failed to save state for %s: %w", name, err)
+	}
+
+	fmt.Printf("✅ %s forced to %s (takes effect on the daemon's next restart)\n", name, state)
+	return nil
+}
@@ -20,7 +20,8 @@ Examples:
   apiproxy config show                          # Display current config
   apiproxy config set cache.backend sqlite      # Set cache backend
   apiproxy config set cache.ttl 3600            # Set cache TTL (seconds)
-  apiproxy config init                          # Initialize default config`,
+  apiproxy config init                          # Initialize default config
+  apiproxy config validate                      # Check for config problems`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runConfig,
 }
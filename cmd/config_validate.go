@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configValidateFile         string
+	configValidateRetryTimeout time.Duration
+	configValidateSleep        time.Duration
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file",
+	Long: `Load apiproxyd's configuration and report every problem found - not just
+the first - with each field's path, what's wrong with it, and how to fix
+it.
+
+With --retry-timeout, validate keeps reloading and re-checking on
+--sleep intervals until it passes or the timeout elapses, so a CI
+pipeline can wait for a freshly deployed daemon's config to settle
+before promoting a release:
+
+  apiproxy config validate --retry-timeout 60s --sleep 2s`,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	configValidateCmd.Flags().StringVar(&configValidateFile, "file", "", "config file to validate (defaults to the usual config.json/config.yml search path)")
+	configValidateCmd.Flags().DurationVar(&configValidateRetryTimeout, "retry-timeout", 0, "keep retrying until the config passes validation or this much time elapses")
+	configValidateCmd.Flags().DurationVar(&configValidateSleep, "sleep", 2*time.Second, "how long to wait between retries")
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	deadline := time.Now().Add(configValidateRetryTimeout)
+
+	for {
+		errs, loadErr := loadConfigForValidation()
+		if loadErr == nil && len(errs) == 0 {
+			fmt.Println("✅ Configuration is valid")
+			return nil
+		}
+
+		if configValidateRetryTimeout <= 0 || time.Now().After(deadline) {
+			if loadErr != nil {
+				return fmt.Errorf("failed to load config: %w", loadErr)
+			}
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "  ✗ %s\n", e.Error())
+			}
+			return fmt.Errorf("configuration is invalid (%d problem(s))", len(errs))
+		}
+
+		time.Sleep(configValidateSleep)
+	}
+}
+
+func loadConfigForValidation() (config.ConfigErrors, error) {
+	var cfg *config.Config
+	var err error
+	if configValidateFile != "" {
+		cfg, err = config.LoadFile(configValidateFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Validate(), nil
+}
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 
 	"github.com/afterdarktech/apiproxyd/pkg/daemon"
 	"github.com/spf13/cobra"
 )
 
 var daemonCmd = &cobra.Command{
-	Use:   "daemon [start|stop|status|restart]",
+	Use:   "daemon [start|stop|status|restart|run-job <name>]",
 	Short: "Manage the background daemon service",
 	Long: `Control the apiproxyd background service.
 
@@ -19,8 +23,9 @@ Examples:
   apiproxy daemon start            # Start daemon in background
   apiproxy daemon stop             # Stop daemon
   apiproxy daemon status           # Check daemon status
-  apiproxy daemon restart          # Restart daemon`,
-	Args: cobra.ExactArgs(1),
+  apiproxy daemon restart          # Restart daemon
+  apiproxy daemon run-job cleanup  # Trigger a scheduler job immediately`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runDaemon,
 }
 
@@ -55,7 +60,37 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 			fmt.Printf("Warning: failed to stop daemon: %v\n", err)
 		}
 		return d.Start()
+	case "run-job":
+		if len(args) < 2 {
+			return fmt.Errorf("run-job requires a job name, e.g. apiproxy daemon run-job cleanup")
+		}
+		return runSchedulerJob(args[1])
 	default:
-		return fmt.Errorf("unknown action: %s (use: start, stop, status, restart)", action)
+		return fmt.Errorf("unknown action: %s (use: start, stop, status, restart, run-job)", action)
+	}
+}
+
+// runSchedulerJob triggers job on the already-running daemon via
+// POST /admin/scheduler/run, the same way POST /admin/reload is used
+// to trigger a reload from outside the daemon process.
+func runSchedulerJob(job string) error {
+	endpoint := fmt.Sprintf("http://%s:%d/admin/scheduler/run?job=%s", daemonHost, daemonPort, url.QueryEscape(job))
+	resp, err := http.Post(endpoint, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("failed to reach daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("run-job %s failed: %s", job, string(body))
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(body, &result); err != nil {
+		fmt.Println(string(body))
+		return nil
 	}
+	fmt.Printf("✅ Ran job %q\n", result["job"])
+	return nil
 }
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Scrape and print the running daemon's Prometheus metrics",
+	Long: `Fetch GET /metrics from the running daemon and print the current
+values in the CLI's --format (json/yaml/table), instead of the raw
+Prometheus text exposition format.
+
+Example:
+  apiproxy metrics --format table   # table output
+  apiproxy metrics --format json    # machine-readable output`,
+	RunE: runMetrics,
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+	metricsCmd.Flags().IntVarP(&daemonPort, "port", "p", 9002, "daemon listen port")
+	metricsCmd.Flags().StringVar(&daemonHost, "host", "127.0.0.1", "daemon listen host")
+}
+
+// metricSample is one parsed line of the Prometheus text exposition
+// format: a metric name, its label set rendered verbatim, and its value.
+type metricSample struct {
+	Name   string `json:"name" yaml:"name"`
+	Labels string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Value  string `json:"value" yaml:"value"`
+}
+
+func runMetrics(cmd *cobra.Command, args []string) error {
+	endpoint := fmt.Sprintf("http://%s:%d/metrics", daemonHost, daemonPort)
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to reach daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("scrape failed: %s: %s", resp.Status, string(body))
+	}
+
+	samples, err := parseMetricsExposition(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	format := viper.GetString("format")
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(samples, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "yaml", "yml":
+		data, err := yaml.Marshal(samples)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		for _, s := range samples {
+			if s.Labels != "" {
+				fmt.Printf("%s%s %s\n", s.Name, s.Labels, s.Value)
+			} else {
+				fmt.Printf("%s %s\n", s.Name, s.Value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseMetricsExposition reads Prometheus text exposition format, skipping
+// # HELP/# TYPE comment lines and blank lines, and returns one
+// metricSample per remaining sample line, sorted by name then labels for
+// stable output.
+func parseMetricsExposition(r io.Reader) ([]metricSample, error) {
+	var samples []metricSample
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		value := fields[len(fields)-1]
+		nameAndLabels := strings.Join(fields[:len(fields)-1], " ")
+
+		name := nameAndLabels
+		labels := ""
+		if idx := strings.Index(nameAndLabels, "{"); idx != -1 {
+			name = nameAndLabels[:idx]
+			labels = nameAndLabels[idx:]
+		}
+
+		samples = append(samples, metricSample{Name: name, Labels: labels, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Name != samples[j].Name {
+			return samples[i].Name < samples[j].Name
+		}
+		return samples[i].Labels < samples[j].Labels
+	})
+
+	return samples, nil
+}
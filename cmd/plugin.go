@@ -0,0 +1,439 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+	"github.com/afterdarksys/apiproxyd/pkg/plugin"
+	"github.com/spf13/cobra"
+)
+
+// fetchTimeout bounds how long a plugin artifact download may take.
+const fetchTimeout = 30 * time.Second
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Manage apiproxyd plugins",
+	Long: `Install, list, update, and remove apiproxyd plugins.
+
+Plugin artifacts (Go .so, Python .py, or WASM .wasm files) are downloaded
+into ~/.apiproxy/plugins/ and registered in the config file so the daemon
+loads them on the next start or SIGHUP reload.`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured plugins",
+	RunE:  runPluginList,
+}
+
+var pluginInstallCmd = &cobra.Command{
+	Use:   "install <url|path>",
+	Short: "Install a plugin from a URL or local path",
+	Long: `Download (or copy) a plugin artifact into ~/.apiproxy/plugins/ and
+register it in the config file.
+
+Examples:
+  apiproxy plugin install https://example.com/plugins/router.wasm --checksum sha256:abc123...
+  apiproxy plugin install ./my_plugin.py --type python`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPluginInstall,
+}
+
+var pluginUpdateCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Re-download an installed plugin's artifact",
+	Long: `Re-fetch a plugin's artifact from the URL it was originally
+installed from. With no name, updates every plugin that has one.
+Plugins installed from a local path have no upstream to re-fetch from.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPluginUpdate,
+}
+
+var pluginRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an installed plugin",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPluginRemove,
+}
+
+var pluginInfoCmd = &cobra.Command{
+	Use:   "info <name>",
+	Short: "Show details about a configured plugin",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPluginInfo,
+}
+
+var pluginSchemaCmd = &cobra.Command{
+	Use:   "schema <name>",
+	Short: "Print a plugin's JSON Schema for its config",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPluginSchema,
+}
+
+var (
+	pluginType     string
+	pluginChecksum string
+)
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginListCmd, pluginInstallCmd, pluginUpdateCmd, pluginRemoveCmd, pluginInfoCmd, pluginSchemaCmd)
+
+	pluginInstallCmd.Flags().StringVar(&pluginType, "type", "", "plugin type: go, python, wasm, or rpc (default: inferred from file extension)")
+	pluginInstallCmd.Flags().StringVar(&pluginChecksum, "checksum", "", "expected sha256 of the downloaded artifact (optionally prefixed sha256:)")
+}
+
+// pluginsDir returns ~/.apiproxy/plugins, creating it if necessary.
+func pluginsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	dir := filepath.Join(home, ".apiproxy", "plugins")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	return dir, nil
+}
+
+// inferPluginType guesses a plugin's Type from its artifact's extension,
+// falling back to "rpc" for anything else (a standalone executable).
+func inferPluginType(path string) string {
+	switch filepath.Ext(path) {
+	case ".so":
+		return "go"
+	case ".py":
+		return "python"
+	case ".wasm":
+		return "wasm"
+	default:
+		return "rpc"
+	}
+}
+
+// isRemoteSource reports whether source should be downloaded over HTTP(S)
+// rather than read from the local filesystem.
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// fetchArtifact reads a plugin artifact's bytes from a URL or a local path.
+func fetchArtifact(source string) ([]byte, error) {
+	if !isRemoteSource(source) {
+		return os.ReadFile(source)
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", source, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// verifyArtifactChecksum compares data's sha256 against want (a hex
+// digest, optionally prefixed "sha256:").
+func verifyArtifactChecksum(data []byte, want string) error {
+	want = strings.TrimPrefix(strings.ToLower(want), "sha256:")
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	if got != want {
+		return fmt.Errorf("checksum mismatch: expected sha256:%s, got sha256:%s", want, got)
+	}
+	return nil
+}
+
+func runPluginInstall(cmd *cobra.Command, args []string) error {
+	source := args[0]
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	for _, pe := range cfg.Plugins.Plugins {
+		if pe.Name == name {
+			return fmt.Errorf("plugin %q is already installed (use 'apiproxy plugin update %s' to refresh it)", name, name)
+		}
+	}
+
+	data, err := fetchArtifact(source)
+	if err != nil {
+		return fmt.Errorf("failed to fetch plugin artifact: %w", err)
+	}
+
+	if pluginChecksum != "" {
+		if err := verifyArtifactChecksum(data, pluginChecksum); err != nil {
+			return err
+		}
+	}
+
+	dir, err := pluginsDir()
+	if err != nil {
+		return err
+	}
+	destPath := filepath.Join(dir, filepath.Base(source))
+	if err := os.WriteFile(destPath, data, 0o755); err != nil {
+		return fmt.Errorf("failed to write plugin artifact: %w", err)
+	}
+
+	typ := pluginType
+	if typ == "" {
+		typ = inferPluginType(destPath)
+	}
+
+	entry := config.PluginEntry{
+		Name:    name,
+		Type:    typ,
+		Path:    destPath,
+		Enabled: true,
+		Config:  map[string]interface{}{},
+	}
+	if isRemoteSource(source) {
+		entry.Source = source
+		entry.Checksum = pluginChecksum
+	}
+
+	cfg.Plugins.Enabled = true
+	cfg.Plugins.Plugins = append(cfg.Plugins.Plugins, entry)
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("✅ Installed plugin %q (%s) at %s\n", name, typ, destPath)
+	fmt.Println("   Restart the daemon (or send it SIGHUP) to load it.")
+	return nil
+}
+
+func runPluginUpdate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	names := args
+	if len(names) == 0 {
+		for _, pe := range cfg.Plugins.Plugins {
+			names = append(names, pe.Name)
+		}
+	}
+
+	for _, name := range names {
+		idx, err := findPluginEntry(cfg, name)
+		if err != nil {
+			return err
+		}
+		pe := &cfg.Plugins.Plugins[idx]
+
+		if pe.Source == "" {
+			fmt.Printf("- %s: no upstream source recorded; reinstall from a URL to enable updates\n", pe.Name)
+			continue
+		}
+
+		data, err := fetchArtifact(pe.Source)
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", pe.Name, err)
+		}
+		if pe.Checksum != "" {
+			if err := verifyArtifactChecksum(data, pe.Checksum); err != nil {
+				return fmt.Errorf("failed to update %s: %w", pe.Name, err)
+			}
+		}
+		if err := os.WriteFile(pe.Path, data, 0o755); err != nil {
+			return fmt.Errorf("failed to write updated artifact for %s: %w", pe.Name, err)
+		}
+
+		fmt.Printf("✅ Updated %s from %s\n", pe.Name, pe.Source)
+	}
+
+	return config.Save(cfg)
+}
+
+func runPluginRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	idx, err := findPluginEntry(cfg, name)
+	if err != nil {
+		return err
+	}
+	pe := cfg.Plugins.Plugins[idx]
+
+	cfg.Plugins.Plugins = append(cfg.Plugins.Plugins[:idx], cfg.Plugins.Plugins[idx+1:]...)
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if dir, err := pluginsDir(); err == nil && filepath.Dir(pe.Path) == dir {
+		if err := os.Remove(pe.Path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove artifact %s: %v\n", pe.Path, err)
+		}
+	}
+
+	fmt.Printf("✅ Removed plugin %q\n", name)
+	return nil
+}
+
+func runPluginInfo(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	idx, err := findPluginEntry(cfg, name)
+	if err != nil {
+		return err
+	}
+	pe := cfg.Plugins.Plugins[idx]
+
+	fmt.Printf("Name:     %s\n", pe.Name)
+	fmt.Printf("Type:     %s\n", pe.Type)
+	fmt.Printf("Path:     %s\n", pe.Path)
+	fmt.Printf("Enabled:  %t\n", pe.Enabled)
+	if pe.Source != "" {
+		fmt.Printf("Source:   %s\n", pe.Source)
+	}
+	if pe.Checksum != "" {
+		fmt.Printf("Checksum: %s\n", pe.Checksum)
+	}
+
+	if !pe.Enabled {
+		return nil
+	}
+
+	pm, loaded := loadConfiguredPlugins(cfg)
+	defer pm.Shutdown()
+	if p, ok := loaded[pe.Name]; ok {
+		fmt.Printf("Version:  %s\n", p.Version())
+	}
+
+	return nil
+}
+
+func runPluginSchema(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if _, err := findPluginEntry(cfg, name); err != nil {
+		return err
+	}
+
+	pm, loaded := loadConfiguredPlugins(cfg)
+	defer pm.Shutdown()
+
+	p, ok := loaded[name]
+	if !ok {
+		return fmt.Errorf("plugin %q did not load; see warnings above", name)
+	}
+
+	schema := p.ConfigSchema()
+	if len(schema) == 0 {
+		fmt.Printf("%s declares no config schema\n", name)
+		return nil
+	}
+
+	fmt.Println(string(schema))
+	return nil
+}
+
+func runPluginList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(cfg.Plugins.Plugins) == 0 {
+		fmt.Println("No plugins configured.")
+		return nil
+	}
+
+	pm, loaded := loadConfiguredPlugins(cfg)
+	defer pm.Shutdown()
+
+	fmt.Printf("%-20s %-8s %-8s %-10s %s\n", "NAME", "TYPE", "ENABLED", "VERSION", "CHECKSUM")
+	for _, pe := range cfg.Plugins.Plugins {
+		version := "-"
+		if p, ok := loaded[pe.Name]; ok {
+			version = p.Version()
+		}
+		checksum := pe.Checksum
+		if checksum == "" {
+			checksum = "-"
+		}
+		fmt.Printf("%-20s %-8s %-8t %-10s %s\n", pe.Name, pe.Type, pe.Enabled, version, checksum)
+	}
+
+	return nil
+}
+
+// findPluginEntry returns the index of the PluginEntry named name, or an
+// error if none is configured under that name.
+func findPluginEntry(cfg *config.Config, name string) (int, error) {
+	for i, pe := range cfg.Plugins.Plugins {
+		if pe.Name == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no configured plugin named %q", name)
+}
+
+// loadConfiguredPlugins loads every enabled plugin in cfg so list/info can
+// read its live Name()/Version(), returning the manager (the caller must
+// Shutdown it) and a lookup of loaded plugins by the name they were
+// configured under. A load error is reported to stderr rather than
+// aborting the command, since an operator still wants to see whichever
+// plugins did load.
+func loadConfiguredPlugins(cfg *config.Config) (*plugin.Manager, map[string]plugin.Plugin) {
+	pluginCfg := &plugin.Config{Enabled: true}
+	for _, pe := range cfg.Plugins.Plugins {
+		if !pe.Enabled {
+			continue
+		}
+		pluginCfg.Plugins = append(pluginCfg.Plugins, plugin.PluginConfig{
+			Name:    pe.Name,
+			Type:    pe.Type,
+			Path:    pe.Path,
+			Enabled: pe.Enabled,
+			Config:  pe.Config,
+		})
+	}
+
+	pm := plugin.NewManager(pluginCfg)
+	if err := pm.LoadPlugins(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+
+	loaded := make(map[string]plugin.Plugin)
+	for _, p := range pm.Plugins() {
+		loaded[p.Name()] = p
+	}
+
+	return pm, loaded
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/afterdarksys/apiproxyd/pkg/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -74,4 +75,18 @@ func initConfig() {
 			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 		}
 	}
+
+	logCfg := &logger.Config{
+		Level:      viper.GetString("log.level"),
+		Format:     viper.GetString("log.format"),
+		File:       viper.GetString("log.file"),
+		Components: viper.GetStringMapString("log.components"),
+	}
+	if viper.GetBool("debug") {
+		logCfg.Level = "debug"
+	}
+	if err := logger.Setup(logCfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring logger: %v\n", err)
+		os.Exit(1)
+	}
 }
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/afterdarksys/apiproxyd/pkg/cache"
-	"github.com/afterdarksys/apiproxyd/pkg/client"
 	"github.com/afterdarksys/apiproxyd/pkg/config"
+	"github.com/afterdarksys/apiproxyd/pkg/diagnostics"
+	"github.com/afterdarksys/apiproxyd/pkg/logger"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
 var testCmd = &cobra.Command{
@@ -16,149 +20,149 @@ var testCmd = &cobra.Command{
 	Short: "Run diagnostic tests",
 	Long: `Run diagnostic tests to verify apiproxyd setup.
 
-Tests:
-  - Authentication with api.apiproxy.app
-  - Cache read/write operations
-  - Daemon connectivity
-  - Configuration validity
+Checks:
+  - config           configuration file loads and is populated
+  - auth             API key validates against the upstream
+  - cache            read/write round trip against the configured backend
+  - daemon           the local daemon's /health endpoint responds
+  - upstream         TLS reachability and certificate expiry of the entry point
+  - disk_space       free space on the filesystem backing the cache path
+  - circuit_breaker  no host's circuit breaker is open or half-open
+
+With --format=json|yaml, output is structured for CI pipelines and the
+command exits non-zero on any failing check. The default TTY output is
+a short emoji summary.
 
 Example:
-  apiproxy test                # Run all tests
-  apiproxy test --verbose      # Show detailed output`,
+  apiproxy test                        # Run all checks
+  apiproxy test --verbose              # Show detailed output
+  apiproxy test --only=auth,cache      # Run a subset
+  apiproxy test --skip=daemon          # Skip a check
+  apiproxy test --format=json          # CI-friendly output`,
 	RunE: runTest,
 }
 
-var testVerbose bool
+var (
+	testVerbose bool
+	testOnly    []string
+	testSkip    []string
+	testTimeout time.Duration
+)
 
 func init() {
 	rootCmd.AddCommand(testCmd)
 	testCmd.Flags().BoolVarP(&testVerbose, "verbose", "v", false, "verbose output")
+	testCmd.Flags().StringSliceVar(&testOnly, "only", nil, "run only these checks (comma-separated)")
+	testCmd.Flags().StringSliceVar(&testSkip, "skip", nil, "skip these checks (comma-separated)")
+	testCmd.Flags().DurationVar(&testTimeout, "timeout", 10*time.Second, "per-check timeout")
 }
 
 func runTest(cmd *cobra.Command, args []string) error {
-	fmt.Println("Running apiproxyd diagnostic tests...\n")
+	log := logger.Component("test")
+	cfg, cfgErr := config.Load()
+	if cfgErr != nil {
+		log.Warn("proceeding without a loaded config", zap.Error(cfgErr))
+	}
 
-	results := make(map[string]bool)
+	checks := diagnostics.DefaultRegistry.Checks(testOnly, testSkip)
+	results := diagnostics.Run(cmd.Context(), cfg, checks, testTimeout)
 
-	// Test 1: Configuration
-	fmt.Print("1. Testing configuration... ")
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Println("❌ FAILED")
-		if testVerbose {
-			fmt.Printf("   Error: %v\n", err)
+	failed := 0
+	for _, r := range results {
+		if r.Status == diagnostics.StatusFail {
+			failed++
 		}
-		results["config"] = false
-	} else {
-		fmt.Println("✅ PASSED")
-		if testVerbose {
-			fmt.Printf("   Endpoint: %s\n", cfg.Endpoint)
-			fmt.Printf("   Cache: %s (%s)\n", cfg.CacheBackend, cfg.CachePath)
+	}
+	log.Info("diagnostic test run complete",
+		zap.Int("total", len(results)),
+		zap.Int("failed", failed))
+
+	format := viper.GetString("format")
+	if !viper.IsSet("format") {
+		// No explicit --format (flag, env or config): keep the emoji summary.
+		format = "table"
+	}
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "yaml", "yml":
+		data, err := yaml.Marshal(results)
+		if err != nil {
+			return err
 		}
-		results["config"] = true
+		fmt.Println(string(data))
+	default:
+		printTestSummary(results)
 	}
 
-	// Test 2: Authentication
-	fmt.Print("2. Testing authentication... ")
-	if cfg != nil && cfg.APIKey != "" {
-		c := client.New(cfg.APIKey)
-		info, err := c.ValidateKey()
-		if err != nil {
-			fmt.Println("❌ FAILED")
-			if testVerbose {
-				fmt.Printf("   Error: %v\n", err)
-			}
-			results["auth"] = false
-		} else {
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// printTestSummary renders results as the interactive emoji output,
+// numbering checks in registration order and printing the verbose
+// details/error for each when --verbose is set.
+func printTestSummary(results []diagnostics.Result) {
+	fmt.Println("Running apiproxyd diagnostic tests...")
+
+	for i, r := range results {
+		fmt.Printf("%d. %s (%s)... ", i+1, r.Name, r.Category)
+		switch r.Status {
+		case diagnostics.StatusPass:
 			fmt.Println("✅ PASSED")
-			if testVerbose {
-				fmt.Printf("   Email: %s\n", info.Email)
-				fmt.Printf("   Tier: %s\n", info.Tier)
+		case diagnostics.StatusWarn:
+			fmt.Println("⚠️  WARN")
+		case diagnostics.StatusSkip:
+			fmt.Print("⏭️  SKIPPED")
+			if reason, ok := r.Details["reason"]; ok {
+				fmt.Printf(" (%v)", reason)
 			}
-			results["auth"] = true
+			fmt.Println()
+		case diagnostics.StatusFail:
+			fmt.Println("❌ FAILED")
 		}
-	} else {
-		fmt.Println("⏭️  SKIPPED (not authenticated)")
-		results["auth"] = false
-	}
 
-	// Test 3: Cache operations
-	fmt.Print("3. Testing cache... ")
-	if cfg != nil {
-		cacheStore, err := cache.New(cfg.CacheBackend, cfg.CachePath)
-		if err != nil {
-			fmt.Println("❌ FAILED")
-			if testVerbose {
-				fmt.Printf("   Error: %v\n", err)
+		if testVerbose {
+			for k, v := range r.Details {
+				fmt.Printf("   %s: %v\n", k, v)
 			}
-			results["cache"] = false
-		} else {
-			defer cacheStore.Close()
-
-			// Test write
-			testKey := fmt.Sprintf("test:%d", time.Now().Unix())
-			testData := []byte(`{"test": "data"}`)
-
-			if err := cacheStore.Set(testKey, testData); err != nil {
-				fmt.Println("❌ FAILED (write)")
-				if testVerbose {
-					fmt.Printf("   Error: %v\n", err)
-				}
-				results["cache"] = false
-			} else {
-				// Test read
-				retrieved, err := cacheStore.Get(testKey)
-				if err != nil || string(retrieved) != string(testData) {
-					fmt.Println("❌ FAILED (read)")
-					if testVerbose {
-						fmt.Printf("   Error: %v\n", err)
-					}
-					results["cache"] = false
-				} else {
-					fmt.Println("✅ PASSED")
-					if testVerbose {
-						stats, _ := cacheStore.Stats()
-						fmt.Printf("   Backend: %s\n", cfg.CacheBackend)
-						fmt.Printf("   Entries: %d\n", stats.Entries)
-					}
-					results["cache"] = true
-				}
-
-				// Cleanup
-				cacheStore.Delete(testKey)
+			if r.ErrMessage != "" {
+				fmt.Printf("   Error: %s\n", r.ErrMessage)
 			}
 		}
-	} else {
-		fmt.Println("⏭️  SKIPPED (no config)")
-		results["cache"] = false
 	}
 
-	// Test 4: Daemon connectivity (optional)
-	fmt.Print("4. Testing daemon... ")
-	// TODO: Implement daemon connectivity test
-	fmt.Println("⏭️  SKIPPED (not implemented)")
-	results["daemon"] = false
-
-	// Summary
 	fmt.Println("\n" + strings.Repeat("-", 40))
-	passed := 0
-	total := 0
-	for _, result := range results {
-		total++
-		if result {
+
+	var passed, warned, failed, skipped int
+	for _, r := range results {
+		switch r.Status {
+		case diagnostics.StatusPass:
 			passed++
+		case diagnostics.StatusWarn:
+			warned++
+		case diagnostics.StatusFail:
+			failed++
+		case diagnostics.StatusSkip:
+			skipped++
 		}
 	}
 
-	fmt.Printf("Tests: %d passed, %d failed, %d total\n", passed, total-passed, total)
+	fmt.Printf("Tests: %d passed, %d warned, %d failed, %d skipped, %d total\n",
+		passed, warned, failed, skipped, len(results))
 
-	if passed == total {
+	switch {
+	case failed > 0:
+		fmt.Println("❌ Some tests failed")
+	case warned > 0:
+		fmt.Println("⚠️  All tests passed, with warnings")
+	default:
 		fmt.Println("✅ All tests passed!")
-		return nil
-	} else if passed > 0 {
-		fmt.Println("⚠️  Some tests failed")
-		return nil
-	} else {
-		return fmt.Errorf("all tests failed")
 	}
 }
@@ -6,23 +6,95 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/afterdarksys/apiproxyd/pkg/plugin"
+	"github.com/afterdarksys/apiproxyd/pkg/plugin/auth"
 )
 
+// routeEntry is the configuration for one routed pattern: its target URL
+// and, optionally, how outbound calls to that URL should authenticate
+// themselves to the upstream.
+type routeEntry struct {
+	URL  string      `json:"url"`
+	Auth auth.Config `json:"auth,omitempty"`
+}
+
+// parseRouteEntry accepts either a bare URL string (the legacy config
+// shape) or a {"url": ..., "auth": {...}} object, so existing configs
+// keep working unchanged.
+func parseRouteEntry(raw interface{}) (routeEntry, bool) {
+	switch v := raw.(type) {
+	case string:
+		return routeEntry{URL: v}, true
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return routeEntry{}, false
+		}
+		var entry routeEntry
+		if err := json.Unmarshal(data, &entry); err != nil || entry.URL == "" {
+			return routeEntry{}, false
+		}
+		return entry, true
+	default:
+		return routeEntry{}, false
+	}
+}
+
+// routeStat tracks per-pattern usage counters surfaced by the admin API.
+// It is kept separately from the routes map itself so read-heavy lookups
+// on the OnRequest hot path never have to touch a mutex.
+type routeStat struct {
+	mu           sync.Mutex
+	hits         int64
+	lastError    string
+	totalLatency time.Duration
+}
+
+func (s *routeStat) record(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits++
+	s.totalLatency += d
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+func (s *routeStat) snapshot() (hits int64, lastError string, avg time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hits > 0 {
+		avg = s.totalLatency / time.Duration(s.hits)
+	}
+	return s.hits, s.lastError, avg
+}
+
 // CustomRouterPlugin routes requests to custom APIs based on endpoint patterns
 type CustomRouterPlugin struct {
-	routes map[string]string // endpoint pattern -> custom API URL
-	client *http.Client
+	routes   atomic.Pointer[map[string]routeEntry] // endpoint pattern -> route config, swapped atomically
+	client   *http.Client
+	adminTok string
+	statsMu  sync.Mutex
+	stats    map[string]*routeStat // pattern -> usage stats, keyed independently of routes
+	admin    *http.Server
 }
 
 // NewPlugin is the required factory function for Go plugins
 func NewPlugin() plugin.Plugin {
-	return &CustomRouterPlugin{
-		routes: make(map[string]string),
+	c := &CustomRouterPlugin{
 		client: &http.Client{},
+		stats:  make(map[string]*routeStat),
 	}
+	empty := make(map[string]routeEntry)
+	c.routes.Store(&empty)
+	return c
 }
 
 func (c *CustomRouterPlugin) Name() string {
@@ -30,7 +102,22 @@ func (c *CustomRouterPlugin) Name() string {
 }
 
 func (c *CustomRouterPlugin) Version() string {
-	return "1.0.0"
+	return "1.1.0"
+}
+
+// configSchema is the JSON Schema for CustomRouterPlugin's config, as
+// returned by ConfigSchema. It's validated by the manager before Init runs.
+const configSchema = `{
+	"type": "object",
+	"properties": {
+		"routes": {"type": "object"},
+		"admin_port": {"type": "number"},
+		"admin_token": {"type": "string"}
+	}
+}`
+
+func (c *CustomRouterPlugin) ConfigSchema() []byte {
+	return []byte(configSchema)
 }
 
 func (c *CustomRouterPlugin) Init(config map[string]interface{}) error {
@@ -39,34 +126,78 @@ func (c *CustomRouterPlugin) Init(config map[string]interface{}) error {
 	// {
 	//   "routes": {
 	//     "/v1/custom/*": "https://my-api.example.com",
-	//     "/v1/external/weather": "https://api.weather.com"
-	//   }
+	//     "/v1/external/weather": {
+	//       "url": "https://api.weather.com",
+	//       "auth": {"type": "hmac", "secret": "shh"}
+	//     }
+	//   },
+	//   "admin_port": 9004,
+	//   "admin_token": "apx_live_xxxxx"
 	// }
+	routes := make(map[string]routeEntry)
 	if routesRaw, ok := config["routes"].(map[string]interface{}); ok {
-		for pattern, url := range routesRaw {
-			if urlStr, ok := url.(string); ok {
-				c.routes[pattern] = urlStr
-				fmt.Printf("[CustomRouter] Registered route: %s -> %s\n", pattern, urlStr)
+		for pattern, raw := range routesRaw {
+			entry, ok := parseRouteEntry(raw)
+			if !ok {
+				fmt.Printf("[CustomRouter] Skipping invalid route config for %s\n", pattern)
+				continue
 			}
+			routes[pattern] = entry
+			fmt.Printf("[CustomRouter] Registered route: %s -> %s\n", pattern, entry.URL)
 		}
 	}
+	c.routes.Store(&routes)
+
+	if tok, ok := config["admin_token"].(string); ok {
+		c.adminTok = tok
+	}
+
+	port := 9004
+	if p, ok := config["admin_port"].(float64); ok {
+		port = int(p)
+	} else if p, ok := config["admin_port"].(int); ok {
+		port = p
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/routes", c.handleRoutes)
+	mux.HandleFunc("/api/v1/routes/reload", c.handleReload)
+	mux.HandleFunc("/api/v1/routes/", c.handleRouteByPattern)
+
+	c.admin = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+
+	go func() {
+		fmt.Printf("[CustomRouter] Starting admin API on http://localhost:%d\n", port)
+		if err := c.admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("[CustomRouter] Admin server error: %v\n", err)
+		}
+	}()
+
 	return nil
 }
 
 func (c *CustomRouterPlugin) OnRequest(ctx context.Context, req *plugin.Request) (*plugin.Request, bool, error) {
+	routes := *c.routes.Load()
+
 	// Check if this endpoint matches any custom routes
-	for pattern, baseURL := range c.routes {
+	for pattern, route := range routes {
 		if c.matchPattern(pattern, req.Endpoint) {
+			start := time.Now()
+
 			// Route to custom API
-			fmt.Printf("[CustomRouter] Routing %s to custom API: %s\n", req.Endpoint, baseURL)
+			fmt.Printf("[CustomRouter] Routing %s to custom API: %s\n", req.Endpoint, route.URL)
 
 			// Build the full URL
 			endpoint := strings.TrimPrefix(req.Endpoint, strings.TrimSuffix(pattern, "*"))
-			fullURL := baseURL + endpoint
+			fullURL := route.URL + endpoint
 
 			// Make request to custom API
 			httpReq, err := http.NewRequestWithContext(ctx, req.Method, fullURL, strings.NewReader(string(req.Body)))
 			if err != nil {
+				c.recordStat(pattern, start, err)
 				return req, false, fmt.Errorf("failed to create request: %w", err)
 			}
 
@@ -75,17 +206,32 @@ func (c *CustomRouterPlugin) OnRequest(ctx context.Context, req *plugin.Request)
 				httpReq.Header.Set(k, v)
 			}
 
+			// A route with its own auth speaks for itself to the
+			// upstream; don't let the caller's own credentials leak
+			// through to a third-party API.
+			if route.Auth.Type != "" {
+				httpReq.Header.Del("Authorization")
+			}
+			if err := auth.Apply(httpReq, req.Body, route.Auth); err != nil {
+				c.recordStat(pattern, start, err)
+				return req, false, fmt.Errorf("failed to sign request: %w", err)
+			}
+
 			resp, err := c.client.Do(httpReq)
 			if err != nil {
+				c.recordStat(pattern, start, err)
 				return req, false, fmt.Errorf("failed to call custom API: %w", err)
 			}
 			defer resp.Body.Close()
 
 			body, err := io.ReadAll(resp.Body)
 			if err != nil {
+				c.recordStat(pattern, start, err)
 				return req, false, fmt.Errorf("failed to read response: %w", err)
 			}
 
+			c.recordStat(pattern, start, nil)
+
 			// Store the custom response in metadata so we can return it
 			if req.Metadata == nil {
 				req.Metadata = make(map[string]string)
@@ -93,6 +239,8 @@ func (c *CustomRouterPlugin) OnRequest(ctx context.Context, req *plugin.Request)
 			req.Metadata["custom_response"] = string(body)
 			req.Metadata["custom_status"] = fmt.Sprintf("%d", resp.StatusCode)
 			req.Metadata["routed"] = "true"
+			req.Metadata["routed_pattern"] = pattern
+			req.Metadata["routed_latency_seconds"] = strconv.FormatFloat(time.Since(start).Seconds(), 'f', -1, 64)
 
 			// Stop further processing - we handled this request
 			return req, false, nil
@@ -103,6 +251,17 @@ func (c *CustomRouterPlugin) OnRequest(ctx context.Context, req *plugin.Request)
 	return req, true, nil
 }
 
+func (c *CustomRouterPlugin) recordStat(pattern string, start time.Time, err error) {
+	c.statsMu.Lock()
+	s, ok := c.stats[pattern]
+	if !ok {
+		s = &routeStat{}
+		c.stats[pattern] = s
+	}
+	c.statsMu.Unlock()
+	s.record(time.Since(start), err)
+}
+
 func (c *CustomRouterPlugin) OnResponse(ctx context.Context, req *plugin.Request, resp *plugin.Response) (*plugin.Response, error) {
 	// If we routed this request to a custom API, use that response instead
 	if req.Metadata != nil && req.Metadata["routed"] == "true" {
@@ -130,6 +289,11 @@ func (c *CustomRouterPlugin) OnCacheHit(ctx context.Context, req *plugin.Request
 func (c *CustomRouterPlugin) Shutdown() error {
 	fmt.Println("[CustomRouter] Shutting down")
 	c.client.CloseIdleConnections()
+	if c.admin != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return c.admin.Shutdown(ctx)
+	}
 	return nil
 }
 
@@ -148,6 +312,185 @@ func (c *CustomRouterPlugin) matchPattern(pattern, path string) bool {
 	return false
 }
 
+// Admin API
+//
+// GET    /api/v1/routes              list every pattern, its target URL, and usage stats
+// POST   /api/v1/routes              add or replace a pattern -> URL mapping (admin token required)
+// DELETE /api/v1/routes/{pattern}    remove a pattern (admin token required)
+// POST   /api/v1/routes/reload       atomically swap the routes map from a JSON file on disk (admin token required)
+
+// routeInfo is the JSON shape returned by GET /api/v1/routes. AuthType is
+// surfaced so operators can see what's configured without ever exposing
+// the actual secret/token.
+type routeInfo struct {
+	Pattern      string    `json:"pattern"`
+	URL          string    `json:"url"`
+	AuthType     auth.Type `json:"auth_type,omitempty"`
+	Hits         int64     `json:"hits"`
+	LastError    string    `json:"last_error,omitempty"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+}
+
+// checkAdminToken validates the Authorization header against the configured
+// admin token, reusing the apx_live_/apx_test_ key format from cmd/login.
+func (c *CustomRouterPlugin) checkAdminToken(r *http.Request) bool {
+	if c.adminTok == "" {
+		return false
+	}
+	tok := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if tok == "" || tok != c.adminTok {
+		return false
+	}
+	return strings.HasPrefix(tok, "apx_live_") || strings.HasPrefix(tok, "apx_test_")
+}
+
+func (c *CustomRouterPlugin) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		routes := *c.routes.Load()
+		infos := make([]routeInfo, 0, len(routes))
+		for pattern, route := range routes {
+			hits, lastErr, avg := c.routeStats(pattern)
+			infos = append(infos, routeInfo{
+				Pattern:      pattern,
+				URL:          route.URL,
+				AuthType:     route.Auth.Type,
+				Hits:         hits,
+				LastError:    lastErr,
+				AvgLatencyMs: float64(avg) / float64(time.Millisecond),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(infos)
+
+	case http.MethodPost:
+		if !c.checkAdminToken(r) {
+			http.Error(w, "invalid or missing admin token", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Pattern string      `json:"pattern"`
+			URL     string      `json:"url"`
+			Auth    auth.Config `json:"auth,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if body.Pattern == "" || body.URL == "" {
+			http.Error(w, "pattern and url are required", http.StatusBadRequest)
+			return
+		}
+		c.setRoute(body.Pattern, routeEntry{URL: body.URL, Auth: body.Auth})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *CustomRouterPlugin) handleRouteByPattern(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.checkAdminToken(r) {
+		http.Error(w, "invalid or missing admin token", http.StatusUnauthorized)
+		return
+	}
+	pattern := strings.TrimPrefix(r.URL.Path, "/api/v1/routes/")
+	if pattern == "" {
+		http.Error(w, "pattern is required", http.StatusBadRequest)
+		return
+	}
+	if !c.deleteRoute(pattern) {
+		http.Error(w, "pattern not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+func (c *CustomRouterPlugin) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.checkAdminToken(r) {
+		http.Error(w, "invalid or missing admin token", http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		File string `json:"file"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.File == "" {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	data, err := os.ReadFile(body.File)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read %s: %v", body.File, err), http.StatusBadRequest)
+		return
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		http.Error(w, fmt.Sprintf("invalid routes file: %v", err), http.StatusBadRequest)
+		return
+	}
+	routes := make(map[string]routeEntry, len(raw))
+	for pattern, v := range raw {
+		entry, ok := parseRouteEntry(v)
+		if !ok {
+			http.Error(w, fmt.Sprintf("invalid route config for %s", pattern), http.StatusBadRequest)
+			return
+		}
+		routes[pattern] = entry
+	}
+	c.routes.Store(&routes)
+	fmt.Printf("[CustomRouter] Reloaded %d routes from %s\n", len(routes), body.File)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded", "count": strconv.Itoa(len(routes))})
+}
+
+// setRoute adds or replaces a single pattern's route config by copying the
+// current map, mutating the copy, and swapping the pointer atomically.
+func (c *CustomRouterPlugin) setRoute(pattern string, route routeEntry) {
+	current := *c.routes.Load()
+	updated := make(map[string]routeEntry, len(current)+1)
+	for k, v := range current {
+		updated[k] = v
+	}
+	updated[pattern] = route
+	c.routes.Store(&updated)
+}
+
+// deleteRoute removes a pattern, returning false if it did not exist.
+func (c *CustomRouterPlugin) deleteRoute(pattern string) bool {
+	current := *c.routes.Load()
+	if _, ok := current[pattern]; !ok {
+		return false
+	}
+	updated := make(map[string]routeEntry, len(current)-1)
+	for k, v := range current {
+		if k != pattern {
+			updated[k] = v
+		}
+	}
+	c.routes.Store(&updated)
+	return true
+}
+
+func (c *CustomRouterPlugin) routeStats(pattern string) (hits int64, lastError string, avg time.Duration) {
+	c.statsMu.Lock()
+	s, ok := c.stats[pattern]
+	c.statsMu.Unlock()
+	if !ok {
+		return 0, "", 0
+	}
+	return s.snapshot()
+}
+
 func main() {
 	// Required for Go plugins
 	plugin := NewPlugin()
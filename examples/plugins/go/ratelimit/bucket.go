@@ -0,0 +1,88 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// bucketShards is the number of independent mutex-guarded map shards
+// memoryBuckets splits keys across, so concurrent OnRequest calls for
+// different keys don't contend on a single map's lock.
+const bucketShards = 32
+
+// tokenBucket is one key's lazily-refilled bucket: tokens accrue at
+// rate/sec up to capacity, computed from the elapsed time since
+// updatedAt whenever the bucket is next touched, rather than on a
+// per-bucket timer goroutine.
+type tokenBucket struct {
+	mu        sync.Mutex
+	tokens    float64
+	updatedAt time.Time
+}
+
+// bucketShard is one shard of memoryBuckets: its own lock and its own
+// slice of the overall key space.
+type bucketShard struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// memoryBuckets is the in-process limiter backend: a sharded map of
+// tokenBuckets, one entry per key, refilled lazily on access. It never
+// spawns a goroutine per bucket or per key; the only state is the maps
+// themselves.
+type memoryBuckets struct {
+	rate   float64
+	burst  int
+	shards [bucketShards]bucketShard
+}
+
+func newMemoryBuckets(rate float64, burst int) *memoryBuckets {
+	b := &memoryBuckets{rate: rate, burst: burst}
+	for i := range b.shards {
+		b.shards[i].buckets = make(map[string]*tokenBucket)
+	}
+	return b
+}
+
+func (b *memoryBuckets) shardFor(key string) *bucketShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &b.shards[h.Sum32()%bucketShards]
+}
+
+func (b *memoryBuckets) allow(key string) (bool, int, time.Duration) {
+	shard := b.shardFor(key)
+
+	shard.mu.Lock()
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(b.burst), updatedAt: time.Now()}
+		shard.buckets[key] = bucket
+	}
+	shard.mu.Unlock()
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens += elapsed * b.rate
+	if bucket.tokens > float64(b.burst) {
+		bucket.tokens = float64(b.burst)
+	}
+	bucket.updatedAt = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, int(bucket.tokens), 0
+	}
+
+	retryAfter := time.Duration((1 - bucket.tokens) / b.rate * float64(time.Second))
+	return false, 0, retryAfter
+}
+
+func (b *memoryBuckets) close() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/afterdarksys/apiproxyd/pkg/plugin"
+)
+
+// keyFunc derives the rate-limit bucket key for a request.
+type keyFunc func(req *plugin.Request) string
+
+// parseKeyFunc builds a keyFunc from a small expression language over
+// plugin.Request: "ip", "header:<Name>", "method", "endpoint", or any
+// "+"-joined combination of those (e.g. "ip+header:X-API-Key" buckets by
+// IP and API key together). cel-go isn't a dependency this module
+// otherwise pulls in, so a full CEL expression isn't available here
+// without adding one without network access to vendor it; this covers
+// the same per-request fields a CEL expression over plugin.Request would
+// reach for, without the grammar.
+func parseKeyFunc(expr string) (keyFunc, error) {
+	parts := strings.Split(expr, "+")
+	fns := make([]keyFunc, 0, len(parts))
+	for _, part := range parts {
+		fn, err := parseKeyTerm(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, fn)
+	}
+	if len(fns) == 1 {
+		return fns[0], nil
+	}
+	return func(req *plugin.Request) string {
+		vals := make([]string, len(fns))
+		for i, fn := range fns {
+			vals[i] = fn(req)
+		}
+		return strings.Join(vals, "|")
+	}, nil
+}
+
+func parseKeyTerm(term string) (keyFunc, error) {
+	switch {
+	case term == "ip":
+		return func(req *plugin.Request) string {
+			return clientIP(req)
+		}, nil
+	case term == "method":
+		return func(req *plugin.Request) string { return req.Method }, nil
+	case term == "endpoint":
+		return func(req *plugin.Request) string { return req.Endpoint }, nil
+	case strings.HasPrefix(term, "header:"):
+		name := strings.TrimPrefix(term, "header:")
+		if name == "" {
+			return nil, fmt.Errorf("empty header name in %q", term)
+		}
+		return func(req *plugin.Request) string { return req.Headers[name] }, nil
+	default:
+		return nil, fmt.Errorf("unrecognized key term %q (want ip, method, endpoint, or header:<Name>)", term)
+	}
+}
+
+// clientIP returns the request's client IP from X-Forwarded-For or
+// X-Real-Ip. plugin.Request has no field for the raw TCP remote address
+// (FromHTTPRequest never copies r.RemoteAddr into it), so a direct
+// client with neither header set buckets under the empty-string key,
+// i.e. shares one global bucket; a deployment behind any reverse proxy
+// that sets one of these headers isn't affected.
+func clientIP(req *plugin.Request) string {
+	if fwd := req.Headers["X-Forwarded-For"]; fwd != "" {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	return req.Headers["X-Real-Ip"]
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/plugin"
+)
+
+// limiter is the token-bucket backend RateLimitPlugin enforces against.
+// memoryBuckets and redisBuckets both implement it; which one Init picks
+// is the only thing that differs between a single-instance and a
+// cluster-coordinated deployment.
+type limiter interface {
+	// allow consumes one token for key if available, reporting whether the
+	// request is allowed, how many tokens remain afterward (clamped to
+	// >=0), and, when rejected, how long the caller should wait before
+	// retrying.
+	allow(key string) (ok bool, remaining int, retryAfter time.Duration)
+	close() error
+}
+
+// keyStat is the per-key counters handleStats surfaces as this plugin's
+// admin stats grid — see the RateLimitPlugin doc comment below for why
+// that's this plugin's own admin endpoint rather than something pushed
+// into WebAdminPlugin.
+type keyStat struct {
+	mu      sync.Mutex
+	allowed int64
+	denied  int64
+}
+
+// RateLimitPlugin enforces token-bucket limits on the OnRequest hot path,
+// keyed per request by keyFunc, using backend to track and refill tokens.
+//
+// There's no channel for one plugin process to push data into another's
+// admin UI (each examples/plugins/go/* binary is loaded and run
+// independently by pkg/plugin/go_plugin.go), so per-key counters and
+// drops are surfaced the same way CustomRouterPlugin surfaces its own
+// route stats: a small admin HTTP API on this plugin's own port.
+type RateLimitPlugin struct {
+	backend limiter
+	keyFunc keyFunc
+	admin   *http.Server
+	statsMu sync.Mutex
+	stats   map[string]*keyStat // key -> counters, created lazily on first sight
+	rate    float64
+	burst   int
+}
+
+// NewPlugin is the required factory function for Go plugins.
+func NewPlugin() plugin.Plugin {
+	return &RateLimitPlugin{
+		stats: make(map[string]*keyStat),
+	}
+}
+
+func (p *RateLimitPlugin) Name() string {
+	return "ratelimit"
+}
+
+func (p *RateLimitPlugin) Version() string {
+	return "1.0.0"
+}
+
+// configSchema is the JSON Schema for RateLimitPlugin's config, validated
+// by the manager before Init runs.
+const configSchema = `{
+	"type": "object",
+	"properties": {
+		"key": {"type": "string"},
+		"rate": {"type": "number"},
+		"burst": {"type": "number"},
+		"backend": {"type": "string", "enum": ["memory", "redis"]},
+		"redis_addr": {"type": "string"},
+		"admin_port": {"type": "number"}
+	}
+}`
+
+func (p *RateLimitPlugin) ConfigSchema() []byte {
+	return []byte(configSchema)
+}
+
+// Init reads:
+//
+//	{
+//	  "key": "ip" | "header:X-API-Key" | "ip+header:X-API-Key",
+//	  "rate": 5,            // tokens refilled per second
+//	  "burst": 20,          // bucket capacity
+//	  "backend": "memory",  // or "redis" for cluster-wide coordination
+//	  "redis_addr": "localhost:6379",
+//	  "admin_port": 9005
+//	}
+func (p *RateLimitPlugin) Init(config map[string]interface{}) error {
+	p.rate = 5
+	if v, ok := config["rate"].(float64); ok && v > 0 {
+		p.rate = v
+	}
+	p.burst = 20
+	if v, ok := config["burst"].(float64); ok && v > 0 {
+		p.burst = int(v)
+	}
+
+	keyExpr, _ := config["key"].(string)
+	if keyExpr == "" {
+		keyExpr = "ip"
+	}
+	kf, err := parseKeyFunc(keyExpr)
+	if err != nil {
+		return fmt.Errorf("ratelimit: key: %w", err)
+	}
+	p.keyFunc = kf
+
+	backend, _ := config["backend"].(string)
+	switch backend {
+	case "redis":
+		addr, _ := config["redis_addr"].(string)
+		if addr == "" {
+			return fmt.Errorf("ratelimit: redis_addr is required when backend is \"redis\"")
+		}
+		b, err := newRedisBuckets(addr, p.rate, p.burst)
+		if err != nil {
+			return fmt.Errorf("ratelimit: %w", err)
+		}
+		p.backend = b
+	default:
+		p.backend = newMemoryBuckets(p.rate, p.burst)
+	}
+
+	port := 9005
+	if v, ok := config["admin_port"].(float64); ok {
+		port = int(v)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/stats", p.handleStats)
+	p.admin = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+	go func() {
+		fmt.Printf("[RateLimit] Starting admin API on http://localhost:%d\n", port)
+		if err := p.admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("[RateLimit] Admin server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// recordStat updates the allowed/denied counters for key, creating its
+// entry on first sight.
+func (p *RateLimitPlugin) recordStat(key string, allowed bool) {
+	p.statsMu.Lock()
+	s, ok := p.stats[key]
+	if !ok {
+		s = &keyStat{}
+		p.stats[key] = s
+	}
+	p.statsMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if allowed {
+		s.allowed++
+	} else {
+		s.denied++
+	}
+}
+
+func (p *RateLimitPlugin) OnRequest(ctx context.Context, req *plugin.Request) (*plugin.Request, bool, error) {
+	key := p.keyFunc(req)
+	ok, remaining, retryAfter := p.backend.allow(key)
+	p.recordStat(key, ok)
+
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+	req.Metadata["ratelimit_remaining"] = strconv.Itoa(remaining)
+
+	if ok {
+		return req, true, nil
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"error":       "rate limit exceeded",
+		"retry_after": retryAfter.Seconds(),
+	})
+	req.Metadata["custom_status"] = strconv.Itoa(http.StatusTooManyRequests)
+	req.Metadata["custom_response"] = string(body)
+	req.Metadata["custom_header_Retry-After"] = strconv.Itoa(int(retryAfter.Seconds() + 0.999))
+	req.Metadata["custom_header_X-RateLimit-Remaining"] = "0"
+	return req, false, nil
+}
+
+// OnResponse annotates successful responses with the remaining quota
+// OnRequest computed, so a caller that stays under the limit can still
+// see how close it's getting.
+func (p *RateLimitPlugin) OnResponse(ctx context.Context, req *plugin.Request, resp *plugin.Response) (*plugin.Response, error) {
+	if req.Metadata == nil {
+		return resp, nil
+	}
+	remaining, ok := req.Metadata["ratelimit_remaining"]
+	if !ok {
+		return resp, nil
+	}
+	if resp.Headers == nil {
+		resp.Headers = make(map[string]string)
+	}
+	resp.Headers["X-RateLimit-Remaining"] = remaining
+	return resp, nil
+}
+
+func (p *RateLimitPlugin) OnCacheHit(ctx context.Context, req *plugin.Request, resp *plugin.Response) (*plugin.Response, error) {
+	return p.OnResponse(ctx, req, resp)
+}
+
+// handleStats is the "admin UI stats grid" this plugin exposes: per-key
+// allow/deny counters, the same shape CustomRouterPlugin.handleRoutes
+// uses for its own per-pattern stats.
+func (p *RateLimitPlugin) handleStats(w http.ResponseWriter, r *http.Request) {
+	type keyStatView struct {
+		Key     string `json:"key"`
+		Allowed int64  `json:"allowed"`
+		Denied  int64  `json:"denied"`
+	}
+
+	p.statsMu.Lock()
+	views := make([]keyStatView, 0, len(p.stats))
+	for key, s := range p.stats {
+		s.mu.Lock()
+		views = append(views, keyStatView{Key: key, Allowed: s.allowed, Denied: s.denied})
+		s.mu.Unlock()
+	}
+	p.statsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+func (p *RateLimitPlugin) Shutdown() error {
+	if p.admin != nil {
+		p.admin.Close()
+	}
+	if p.backend != nil {
+		return p.backend.close()
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisBuckets is the cluster-coordinated limiter backend: every allow
+// call runs tokenBucketScript atomically on the Redis server via EVAL, so
+// every apiproxyd instance sharing the same Redis sees the same bucket
+// for a key. Like pkg/cache/redis.go, it speaks RESP directly over one
+// net.Conn instead of pulling in a client library — a rate limiter needs
+// exactly one command round-trip per request, which doesn't justify the
+// dependency any more than the cache backend did.
+type redisBuckets struct {
+	mu    sync.Mutex
+	conn  net.Conn
+	rw    *bufio.ReadWriter
+	rate  float64
+	burst int
+}
+
+func newRedisBuckets(addr string, rate float64, burst int) (*redisBuckets, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("redis buckets: dial %s: %w", addr, err)
+	}
+	return &redisBuckets{
+		conn:  conn,
+		rw:    bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
+		rate:  rate,
+		burst: burst,
+	}, nil
+}
+
+// tokenBucketScript is the standard atomic token-bucket check-and-decrement:
+// it reads the last known token count and refill timestamp, tops up the
+// count for elapsed time, and either takes one token or reports how long
+// until one is free, storing the new state back with a TTL that expires
+// keys nobody has touched in a while rather than growing forever.
+const tokenBucketScript = `
+local tokens_key = KEYS[1] .. ":tokens"
+local ts_key = KEYS[1] .. ":ts"
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local tokens = tonumber(redis.call('GET', tokens_key))
+if tokens == nil then
+  tokens = capacity
+end
+local last = tonumber(redis.call('GET', ts_key))
+if last == nil then
+  last = now
+end
+
+local elapsed = math.max(0, now - last)
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+  allowed = 1
+  tokens = tokens - 1
+else
+  retry_after = (1 - tokens) / rate
+end
+
+local ttl = math.ceil((capacity / rate) * 2)
+redis.call('SETEX', tokens_key, ttl, tokens)
+redis.call('SETEX', ts_key, ttl, now)
+
+return {allowed, tostring(tokens), tostring(retry_after)}
+`
+
+func (b *redisBuckets) allow(key string) (bool, int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := strconv.FormatFloat(float64(time.Now().UnixNano())/1e9, 'f', 6, 64)
+	reply, err := b.command("EVAL", tokenBucketScript, "1", key,
+		strconv.FormatFloat(b.rate, 'f', -1, 64),
+		strconv.Itoa(b.burst),
+		now,
+	)
+	if err != nil {
+		// A Redis hiccup fails open: better to let a request through than
+		// to reject every caller because the limiter backend is down.
+		return true, b.burst, 0
+	}
+
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 3 {
+		return true, b.burst, 0
+	}
+	allowed, _ := items[0].(int64)
+	tokensStr, _ := items[1].([]byte)
+	retryStr, _ := items[2].([]byte)
+
+	tokens, _ := strconv.ParseFloat(string(tokensStr), 64)
+	retrySeconds, _ := strconv.ParseFloat(string(retryStr), 64)
+
+	return allowed == 1, int(tokens), time.Duration(retrySeconds * float64(time.Second))
+}
+
+func (b *redisBuckets) close() error {
+	return b.conn.Close()
+}
+
+// command sends args as a RESP array and returns the decoded reply. Must
+// be called with mu held.
+func (b *redisBuckets) command(args ...string) (interface{}, error) {
+	var req []byte
+	req = append(req, []byte(fmt.Sprintf("*%d\r\n", len(args)))...)
+	for _, a := range args {
+		req = append(req, []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(a), a))...)
+	}
+	if _, err := b.rw.Write(req); err != nil {
+		return nil, err
+	}
+	if err := b.rw.Flush(); err != nil {
+		return nil, err
+	}
+	return readRESP(b.rw.Reader)
+}
+
+// readRESP decodes one RESP value: simple string (+), error (-), integer
+// (:), bulk string ($, nil on length -1), or array (*). Mirrors
+// pkg/cache/redis.go's decoder; duplicated rather than imported since
+// that decoder is unexported.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = line[:len(line)-2] // strip \r\n
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return n, err
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil // nil bulk string
+		}
+		buf := make([]byte, n+2) // value + trailing \r\n
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = readRESP(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply prefix %q", line[0])
+	}
+}
@@ -26,19 +26,22 @@ type WebAdminPlugin struct {
 	maxRequests int
 	startTime   time.Time
 	stats       *Stats
+	metrics     *adminMetrics
+	broadcaster *requestBroadcaster
+	auth        *adminAuth
 }
 
 // RequestLog stores information about a proxied request
 type RequestLog struct {
-	Timestamp    time.Time `json:"timestamp"`
-	Method       string    `json:"method"`
-	Endpoint     string    `json:"endpoint"`
-	StatusCode   int       `json:"status_code"`
-	Duration     int64     `json:"duration_ms"`
-	Cached       bool      `json:"cached"`
-	BodySize     int       `json:"body_size"`
+	Timestamp    time.Time         `json:"timestamp"`
+	Method       string            `json:"method"`
+	Endpoint     string            `json:"endpoint"`
+	StatusCode   int               `json:"status_code"`
+	Duration     int64             `json:"duration_ms"`
+	Cached       bool              `json:"cached"`
+	BodySize     int               `json:"body_size"`
 	Headers      map[string]string `json:"headers,omitempty"`
-	ResponseBody string    `json:"response_body,omitempty"`
+	ResponseBody string            `json:"response_body,omitempty"`
 }
 
 // Stats tracks overall statistics
@@ -58,6 +61,9 @@ func NewPlugin() plugin.Plugin {
 		maxRequests: 1000, // Keep last 1000 requests
 		startTime:   time.Now(),
 		stats:       &Stats{},
+		metrics:     newAdminMetrics(),
+		broadcaster: newRequestBroadcaster(),
+		auth:        newAdminAuth(),
 	}
 }
 
@@ -87,12 +93,25 @@ func (w *WebAdminPlugin) Init(config map[string]interface{}) error {
 		w.maxRequests = maxReq
 	}
 
+	w.metrics.configure(config)
+	w.auth.configure(config)
+
+	var tlsCertFile, tlsKeyFile string
+	if v, ok := config["tls.cert_file"].(string); ok {
+		tlsCertFile = v
+	}
+	if v, ok := config["tls.key_file"].(string); ok {
+		tlsKeyFile = v
+	}
+
 	// Start the web server
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", w.handleDashboard)
-	mux.HandleFunc("/api/stats", w.handleAPIStats)
-	mux.HandleFunc("/api/requests", w.handleAPIRequests)
-	mux.HandleFunc("/api/requests/clear", w.handleAPIClearRequests)
+	mux.HandleFunc("/", w.auth.middleware(w.handleDashboard))
+	mux.HandleFunc("/api/stats", w.auth.middleware(w.handleAPIStats))
+	mux.HandleFunc("/api/requests", w.auth.middleware(w.handleAPIRequests))
+	mux.HandleFunc("/api/requests/stream", w.auth.middleware(w.handleRequestsStream))
+	mux.HandleFunc("/api/requests/clear", w.auth.middleware(w.handleAPIClearRequests))
+	mux.HandleFunc(w.metrics.metricsPath, w.auth.middleware(w.handleMetrics))
 
 	w.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", w.port),
@@ -102,7 +121,13 @@ func (w *WebAdminPlugin) Init(config map[string]interface{}) error {
 	// Start server in background
 	go func() {
 		fmt.Printf("[Web Admin] Starting admin interface on http://localhost:%d\n", w.port)
-		if err := w.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsCertFile != "" && tlsKeyFile != "" {
+			err = w.server.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+		} else {
+			err = w.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			fmt.Printf("[Web Admin] Server error: %v\n", err)
 		}
 	}()
@@ -166,6 +191,9 @@ func (w *WebAdminPlugin) logRequest(req *plugin.Request, resp *plugin.Response,
 		w.requests = w.requests[len(w.requests)-w.maxRequests:]
 	}
 
+	w.metrics.record(req.Method, req.Endpoint, resp.StatusCode, cached, time.Duration(duration)*time.Millisecond, len(resp.Body))
+	w.broadcaster.publish(log)
+
 	// Update stats
 	w.stats.TotalRequests++
 	w.stats.TotalBytes += int64(len(resp.Body))
@@ -389,7 +417,7 @@ func (w *WebAdminPlugin) handleDashboard(wr http.ResponseWriter, r *http.Request
             <div class="request-log" id="request-log">
                 <p style="color: #888;">No requests yet. Make some API calls to see them here.</p>
             </div>
-            <p class="refresh-info">Auto-refreshing every 2 seconds</p>
+            <p class="refresh-info" id="refresh-info">Auto-refreshing every 2 seconds</p>
         </div>
     </div>
 
@@ -412,18 +440,55 @@ func (w *WebAdminPlugin) handleDashboard(wr http.ResponseWriter, r *http.Request
             element.classList.toggle('expanded');
         }
 
+        let allRequests = [];
+        let eventSource = null;
+
         async function clearRequests() {
             try {
                 await fetch('/api/requests/clear', { method: 'POST' });
-                updateData();
+                allRequests = [];
+                renderRequestLog();
             } catch (e) {
                 console.error('Failed to clear requests:', e);
             }
         }
 
-        async function updateData() {
+        function renderRequestItem(req) {
+            const timestamp = new Date(req.timestamp).toLocaleTimeString();
+            const statusClass = req.status_code < 400 ? 'status-200' :
+                              req.status_code < 500 ? 'status-400' : 'status-500';
+            return '<div class="request-item" onclick="toggleRequestDetails(this)">' +
+                '<div class="request-header">' +
+                    '<span class="method ' + req.method + '">' + req.method + '</span>' +
+                    '<span class="endpoint">' + req.endpoint + '</span>' +
+                    '<span class="badge ' + (req.cached ? 'cached' : 'miss') + '">' +
+                        (req.cached ? '⚡ CACHED' : 'MISS') +
+                    '</span>' +
+                '</div>' +
+                '<div class="request-meta">' +
+                    '<span>' + timestamp + '</span>' +
+                    '<span class="' + statusClass + '">Status: ' + req.status_code + '</span>' +
+                    '<span class="duration">⏱ ' + req.duration_ms + 'ms</span>' +
+                    '<span>📦 ' + formatBytes(req.body_size) + '</span>' +
+                '</div>' +
+                '<div class="request-details">' +
+                    '<h3 style="color: #00ff88; margin-bottom: 10px;">Response Body</h3>' +
+                    '<pre>' + (req.response_body || 'No body') + '</pre>' +
+                '</div>' +
+            '</div>';
+        }
+
+        function renderRequestLog() {
+            const logEl = document.getElementById('request-log');
+            if (allRequests.length === 0) {
+                logEl.innerHTML = '<p style="color: #888;">No requests yet. Make some API calls to see them here.</p>';
+                return;
+            }
+            logEl.innerHTML = allRequests.slice().reverse().map(renderRequestItem).join('');
+        }
+
+        async function fetchStats() {
             try {
-                // Fetch stats
                 const statsRes = await fetch('/api/stats');
                 const stats = await statsRes.json();
 
@@ -437,50 +502,59 @@ func (w *WebAdminPlugin) handleDashboard(wr http.ResponseWriter, r *http.Request
                 document.getElementById('hit-rate').textContent = hitRate + '%';
                 document.getElementById('avg-time').textContent = Math.round(stats.avg_response_time_ms) + 'ms';
                 document.getElementById('uptime').textContent = formatDuration(stats.uptime_seconds);
+            } catch (e) {
+                console.error('Failed to update stats:', e);
+            }
+        }
 
-                // Fetch requests
+        async function pollRequests() {
+            try {
                 const reqRes = await fetch('/api/requests');
-                const requests = await reqRes.json();
-
-                const logEl = document.getElementById('request-log');
-                if (requests.length === 0) {
-                    logEl.innerHTML = '<p style="color: #888;">No requests yet. Make some API calls to see them here.</p>';
-                } else {
-                    logEl.innerHTML = requests.reverse().map(req => {
-                        const timestamp = new Date(req.timestamp).toLocaleTimeString();
-                        const statusClass = req.status_code < 400 ? 'status-200' :
-                                          req.status_code < 500 ? 'status-400' : 'status-500';
-                        return \`
-                            <div class="request-item" onclick="toggleRequestDetails(this)">
-                                <div class="request-header">
-                                    <span class="method \${req.method}">\${req.method}</span>
-                                    <span class="endpoint">\${req.endpoint}</span>
-                                    <span class="badge \${req.cached ? 'cached' : 'miss'}">
-                                        \${req.cached ? '⚡ CACHED' : 'MISS'}
-                                    </span>
-                                </div>
-                                <div class="request-meta">
-                                    <span>\${timestamp}</span>
-                                    <span class="\${statusClass}">Status: \${req.status_code}</span>
-                                    <span class="duration">⏱ \${req.duration_ms}ms</span>
-                                    <span>📦 \${formatBytes(req.body_size)}</span>
-                                </div>
-                                <div class="request-details">
-                                    <h3 style="color: #00ff88; margin-bottom: 10px;">Response Body</h3>
-                                    <pre>\${req.response_body || 'No body'}</pre>
-                                </div>
-                            </div>
-                        \`;
-                    }).join('');
-                }
+                allRequests = await reqRes.json();
+                renderRequestLog();
             } catch (e) {
-                console.error('Failed to update data:', e);
+                console.error('Failed to update requests:', e);
             }
         }
 
-        // Initial load and auto-refresh
-        updateData();
-        setInterval(updateData, 2000);
+        function startPolling() {
+            document.getElementById('refresh-info').textContent = 'Auto-refreshing every 2 seconds (polling)';
+            pollRequests();
+            setInterval(pollRequests, 2000);
+        }
+
+        function startStream() {
+            document.getElementById('refresh-info').textContent = 'Live via Server-Sent Events';
+            pollRequests().then(() => {
+                eventSource = new EventSource('/api/requests/stream');
+                eventSource.onmessage = function(e) {
+                    try {
+                        const req = JSON.parse(e.data);
+                        allRequests.push(req);
+                        if (allRequests.length > 1000) {
+                            allRequests = allRequests.slice(allRequests.length - 1000);
+                        }
+                        renderRequestLog();
+                    } catch (err) {
+                        console.error('Failed to parse stream event:', err);
+                    }
+                };
+                eventSource.onerror = function() {
+                    console.error('Event stream lost, falling back to polling');
+                    eventSource.close();
+                    startPolling();
+                };
+            });
+        }
+
+        // Initial load and auto-refresh/stream
+        setInterval(fetchStats, 2000);
+        fetchStats();
+        if (typeof EventSource !== 'undefined') {
+            startStream();
+        } else {
+            startPolling();
+        }
     </script>
 </body>
 </html>`
@@ -500,15 +574,71 @@ func (w *WebAdminPlugin) handleAPIStats(wr http.ResponseWriter, r *http.Request)
 }
 
 func (w *WebAdminPlugin) handleAPIRequests(wr http.ResponseWriter, r *http.Request) {
+	filter := parseRequestFilter(r)
+
 	w.mu.RLock()
-	requests := make([]RequestLog, len(w.requests))
-	copy(requests, w.requests)
+	requests := make([]RequestLog, 0, len(w.requests))
+	for _, log := range w.requests {
+		if filter.matches(log) {
+			requests = append(requests, log)
+		}
+	}
 	w.mu.RUnlock()
 
 	wr.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(wr).Encode(requests)
 }
 
+// handleRequestsStream upgrades to Server-Sent Events and pushes each new
+// RequestLog recorded by logRequest as it happens, instead of requiring
+// clients to poll /api/requests. Query-string filters (method,
+// endpoint_prefix, min_status, cached) are applied the same way as
+// handleAPIRequests, so a client can tail just the traffic it cares about.
+func (w *WebAdminPlugin) handleRequestsStream(wr http.ResponseWriter, r *http.Request) {
+	flusher, ok := wr.(http.Flusher)
+	if !ok {
+		http.Error(wr, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	filter := parseRequestFilter(r)
+	ch := w.broadcaster.subscribe(filter)
+	defer w.broadcaster.unsubscribe(ch)
+
+	wr.Header().Set("Content-Type", "text/event-stream")
+	wr.Header().Set("Cache-Control", "no-cache")
+	wr.Header().Set("Connection", "keep-alive")
+	wr.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case log, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(log)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(wr, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleMetrics exposes the same OnRequest/OnResponse/OnCacheHit-derived
+// counters as /api/stats, but in Prometheus text exposition format, so ops
+// teams can scrape apiproxyd's admin plugin like any other service instead
+// of polling the JSON API.
+func (w *WebAdminPlugin) handleMetrics(wr http.ResponseWriter, r *http.Request) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	w.metrics.ServeHTTP(wr, r)
+}
+
 func (w *WebAdminPlugin) handleAPIClearRequests(wr http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(wr, "Method not allowed", http.StatusMethodNotAllowed)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// adminAuthMode selects how requests to the admin interface are
+// authenticated, configured via the auth.mode key in Init's config map.
+type adminAuthMode string
+
+const (
+	authModeNone   adminAuthMode = "none"
+	authModeBasic  adminAuthMode = "basic"
+	authModeBearer adminAuthMode = "bearer"
+)
+
+// adminAuth enforces auth.mode/basic.users/bearer.tokens/auth.allow_cidrs
+// around every handler registered on the admin mux. Credentials are stored
+// and compared as sha256 hashes (hex-encoded) rather than bcrypt, matching
+// how this repo already hashes credentials elsewhere (pkg/plugin/auth's
+// HMAC signing, pkg/daemon's MetricsAuthToken comparison) instead of
+// pulling in a dependency this module doesn't otherwise use.
+type adminAuth struct {
+	mode       adminAuthMode
+	users      map[string]string // username -> sha256 hex hash of password
+	tokens     map[string]bool   // sha256 hex hash of bearer token -> allowed
+	allowCIDRs []*net.IPNet
+}
+
+func newAdminAuth() *adminAuth {
+	return &adminAuth{
+		mode:   authModeNone,
+		users:  make(map[string]string),
+		tokens: make(map[string]bool),
+	}
+}
+
+// configure reads auth.mode, basic.users, bearer.tokens, and
+// auth.allow_cidrs from the plugin config, leaving auth disabled
+// (authModeNone) if auth.mode is unset or unrecognized.
+func (a *adminAuth) configure(config map[string]interface{}) {
+	if mode, ok := config["auth.mode"].(string); ok {
+		switch adminAuthMode(mode) {
+		case authModeBasic:
+			a.mode = authModeBasic
+		case authModeBearer:
+			a.mode = authModeBearer
+		default:
+			a.mode = authModeNone
+		}
+	}
+
+	if users, ok := config["basic.users"].(map[string]interface{}); ok {
+		for user, hash := range users {
+			if h, ok := hash.(string); ok {
+				a.users[user] = strings.ToLower(h)
+			}
+		}
+	}
+
+	if tokens, ok := config["bearer.tokens"].([]interface{}); ok {
+		for _, t := range tokens {
+			if h, ok := t.(string); ok {
+				a.tokens[strings.ToLower(h)] = true
+			}
+		}
+	}
+
+	if cidrs, ok := config["auth.allow_cidrs"].([]interface{}); ok {
+		for _, c := range cidrs {
+			s, ok := c.(string)
+			if !ok {
+				continue
+			}
+			if _, ipnet, err := net.ParseCIDR(s); err == nil {
+				a.allowCIDRs = append(a.allowCIDRs, ipnet)
+			}
+		}
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// ipAllowed reports whether r's remote address is within allow_cidrs, or
+// true if no allowlist was configured.
+func (a *adminAuth) ipAllowed(r *http.Request) bool {
+	if len(a.allowCIDRs) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipnet := range a.allowCIDRs {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate checks r's credentials against the configured mode using
+// constant-time comparisons, so response timing can't be used to brute
+// force a valid username/token.
+func (a *adminAuth) authenticate(r *http.Request) bool {
+	switch a.mode {
+	case authModeBasic:
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			return false
+		}
+		want, exists := a.users[user]
+		if !exists {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(sha256Hex(pass)), []byte(want)) == 1
+
+	case authModeBearer:
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header {
+			return false
+		}
+		hashed := sha256Hex(token)
+		for known := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(hashed), []byte(known)) == 1 {
+				return true
+			}
+		}
+		return false
+
+	default:
+		return true
+	}
+}
+
+// middleware wraps next with IP allowlisting and auth.mode enforcement,
+// challenging with the appropriate WWW-Authenticate header on failure and
+// logging rejected attempts so an admin port left exposed doesn't fail
+// silently.
+func (a *adminAuth) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.ipAllowed(r) {
+			fmt.Printf("[Web Admin] rejected %s %s from disallowed address %s\n", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if a.authenticate(r) {
+			next(w, r)
+			return
+		}
+
+		fmt.Printf("[Web Admin] rejected %s %s from %s: authentication failed\n", r.Method, r.URL.Path, r.RemoteAddr)
+
+		switch a.mode {
+		case authModeBasic:
+			w.Header().Set("WWW-Authenticate", `Basic realm="apiproxyd admin"`)
+		case authModeBearer:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="apiproxyd admin"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+}
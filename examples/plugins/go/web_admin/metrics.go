@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHistogramBuckets mirrors Prometheus's own standard latency
+// boundaries (seconds), used unless the plugin config overrides them via
+// histogram_buckets.
+var defaultHistogramBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// defaultCardinalityLimit caps how many distinct normalized endpoints
+// metrics are tracked for before new ones collapse into "other", so a
+// misbehaving upstream that mints one path per request (unnormalized IDs,
+// query-string-as-path, etc.) can't blow up /metrics scrape size.
+const defaultCardinalityLimit = 200
+
+var (
+	numericSegmentRe = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegmentRe    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// normalizeEndpoint collapses path segments that look like numeric IDs or
+// UUIDs into ":id", so e.g. /v1/users/1042 and /v1/users/1043 share one
+// apiproxyd_requests_total series instead of each minting their own.
+func normalizeEndpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		if numericSegmentRe.MatchString(seg) || uuidSegmentRe.MatchString(seg) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// metricLabels identifies the dimensions a single proxied request is
+// recorded under for /metrics.
+type metricLabels struct {
+	Method   string
+	Endpoint string
+	Status   int
+	Cached   bool
+}
+
+func (l metricLabels) key() string {
+	return formatPromLabels([]promLabel{
+		{"method", l.Method},
+		{"endpoint", l.Endpoint},
+		{"status", strconv.Itoa(l.Status)},
+		{"cached", strconv.FormatBool(l.Cached)},
+	})
+}
+
+type promLabel struct {
+	name  string
+	value string
+}
+
+func formatPromLabels(labels []promLabel) string {
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, l.name, escapePromLabelValue(l.value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapePromLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return v
+}
+
+// promHistogram is a Prometheus-style cumulative histogram: counts[i] holds
+// the number of observations <= buckets[i].
+type promHistogram struct {
+	buckets []float64
+	counts  []int64
+	sum     float64
+	count   int64
+}
+
+func newPromHistogram(buckets []float64) *promHistogram {
+	return &promHistogram{buckets: buckets, counts: make([]int64, len(buckets))}
+}
+
+func (h *promHistogram) observe(seconds float64) {
+	h.sum += seconds
+	h.count++
+	for i, le := range h.buckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+}
+
+// adminMetrics is the Prometheus-exposition counterpart to Stats: it's fed
+// from the same OnRequest/OnResponse/OnCacheHit hooks that populate
+// RequestLog, so the two never drift apart. Guarded by WebAdminPlugin.mu,
+// since record is always called from logRequest while that lock is
+// held.
+type adminMetrics struct {
+	metricsPath      string
+	histogramBuckets []float64
+	cardinalityLimit int
+
+	requestsTotal    map[string]int64
+	requestDurations map[string]*promHistogram
+	cacheHitsTotal   int64
+	cacheMissesTotal int64
+	bytesTotal       int64
+
+	// seenEndpoints tracks distinct normalized endpoints recorded so far;
+	// once it reaches cardinalityLimit, any endpoint not already in it is
+	// recorded under "other" instead of minting a new label combination.
+	seenEndpoints map[string]bool
+}
+
+func newAdminMetrics() *adminMetrics {
+	return &adminMetrics{
+		metricsPath:      "/metrics",
+		histogramBuckets: defaultHistogramBuckets,
+		cardinalityLimit: defaultCardinalityLimit,
+		requestsTotal:    make(map[string]int64),
+		requestDurations: make(map[string]*promHistogram),
+		seenEndpoints:    make(map[string]bool),
+	}
+}
+
+// configure applies metrics_path, histogram_buckets, and cardinality_limit
+// from the plugin config, leaving defaults in place for anything unset.
+func (m *adminMetrics) configure(config map[string]interface{}) {
+	if path, ok := config["metrics_path"].(string); ok && path != "" {
+		m.metricsPath = path
+	}
+
+	if raw, ok := config["histogram_buckets"].([]interface{}); ok && len(raw) > 0 {
+		buckets := make([]float64, 0, len(raw))
+		for _, v := range raw {
+			switch n := v.(type) {
+			case float64:
+				buckets = append(buckets, n)
+			case int:
+				buckets = append(buckets, float64(n))
+			}
+		}
+		if len(buckets) > 0 {
+			m.histogramBuckets = buckets
+		}
+	}
+
+	if limit, ok := config["cardinality_limit"].(float64); ok && limit > 0 {
+		m.cardinalityLimit = int(limit)
+	} else if limit, ok := config["cardinality_limit"].(int); ok && limit > 0 {
+		m.cardinalityLimit = limit
+	}
+}
+
+// record folds one proxied request into the Prometheus series, normalizing
+// endpoint and collapsing it to "other" once cardinalityLimit distinct
+// endpoints have already been seen.
+func (m *adminMetrics) record(method, endpoint string, status int, cached bool, duration time.Duration, bytes int) {
+	endpoint = normalizeEndpoint(endpoint)
+	if !m.seenEndpoints[endpoint] {
+		if len(m.seenEndpoints) >= m.cardinalityLimit {
+			endpoint = "other"
+		} else {
+			m.seenEndpoints[endpoint] = true
+		}
+	}
+
+	labels := metricLabels{Method: method, Endpoint: endpoint, Status: status, Cached: cached}
+	key := labels.key()
+
+	m.requestsTotal[key]++
+
+	hist, ok := m.requestDurations[key]
+	if !ok {
+		hist = newPromHistogram(m.histogramBuckets)
+		m.requestDurations[key] = hist
+	}
+	hist.observe(duration.Seconds())
+
+	m.bytesTotal += int64(bytes)
+	if cached {
+		m.cacheHitsTotal++
+	} else {
+		m.cacheMissesTotal++
+	}
+}
+
+// ServeHTTP exports the collected metrics in Prometheus text exposition
+// format. Callers must hold WebAdminPlugin.mu (at least for reading).
+func (m *adminMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writePromCounterFamily(w, "apiproxyd_requests_total", "Total number of proxied requests, by method/endpoint/status/cached", m.requestsTotal)
+	writePromHistogramFamily(w, "apiproxyd_request_duration_seconds", "Duration of proxied requests, by method/endpoint/status/cached", m.histogramBuckets, m.requestDurations)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_hits_total Total number of cache hits\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_hits_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_cache_hits_total %d\n\n", m.cacheHitsTotal)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_misses_total Total number of cache misses\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_misses_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_cache_misses_total %d\n\n", m.cacheMissesTotal)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_bytes_total Total response bytes observed by the admin plugin\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_bytes_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_bytes_total %d\n", m.bytesTotal)
+}
+
+func writePromCounterFamily(w io.Writer, name, help string, series map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, key := range sortedPromKeys(series) {
+		fmt.Fprintf(w, "%s%s %d\n", name, key, series[key])
+	}
+	fmt.Fprintln(w)
+}
+
+func writePromHistogramFamily(w io.Writer, name, help string, buckets []float64, series map[string]*promHistogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, key := range sortedPromHistKeys(series) {
+		hist := series[key]
+		prefix := strings.TrimSuffix(key, "}") + ","
+		for i, le := range buckets {
+			fmt.Fprintf(w, "%s_bucket%sle=\"%s\"} %d\n", name, prefix, formatPromBucketBound(le), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%sle=\"+Inf\"} %d\n", name, prefix, hist.count)
+		fmt.Fprintf(w, "%s_sum%s %.6f\n", name, key, hist.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", name, key, hist.count)
+	}
+	fmt.Fprintln(w)
+}
+
+func formatPromBucketBound(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedPromKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPromHistKeys(m map[string]*promHistogram) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
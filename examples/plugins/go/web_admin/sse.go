@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// requestFilter holds the server-side query-string filters accepted by
+// /api/requests and /api/requests/stream (method, endpoint_prefix,
+// min_status, cached), so a client debugging one failing endpoint doesn't
+// have to pull the full log and filter client-side.
+type requestFilter struct {
+	method         string
+	endpointPrefix string
+	minStatus      int
+	cached         *bool
+}
+
+func parseRequestFilter(r *http.Request) requestFilter {
+	q := r.URL.Query()
+
+	f := requestFilter{
+		method:         q.Get("method"),
+		endpointPrefix: q.Get("endpoint_prefix"),
+	}
+
+	if v := q.Get("min_status"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			f.minStatus = n
+		}
+	}
+
+	if v := q.Get("cached"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			f.cached = &b
+		}
+	}
+
+	return f
+}
+
+func (f requestFilter) matches(log RequestLog) bool {
+	if f.method != "" && !strings.EqualFold(f.method, log.Method) {
+		return false
+	}
+	if f.endpointPrefix != "" && !strings.HasPrefix(log.Endpoint, f.endpointPrefix) {
+		return false
+	}
+	if f.minStatus != 0 && log.StatusCode < f.minStatus {
+		return false
+	}
+	if f.cached != nil && *f.cached != log.Cached {
+		return false
+	}
+	return true
+}
+
+// requestSubscriberBuffer is how many pending RequestLogs a single SSE
+// client may be behind before new events are dropped for it rather than
+// blocking logRequest.
+const requestSubscriberBuffer = 16
+
+// requestBroadcaster fans out each RequestLog recorded by logRequest to the
+// subscribed /api/requests/stream clients, applying each subscriber's own
+// filter so a client only receives the events it asked for.
+type requestBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan RequestLog]requestFilter
+}
+
+func newRequestBroadcaster() *requestBroadcaster {
+	return &requestBroadcaster{
+		subscribers: make(map[chan RequestLog]requestFilter),
+	}
+}
+
+func (b *requestBroadcaster) subscribe(filter requestFilter) chan RequestLog {
+	ch := make(chan RequestLog, requestSubscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = filter
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *requestBroadcaster) unsubscribe(ch chan RequestLog) {
+	b.mu.Lock()
+	delete(b.subscribers, ch)
+	b.mu.Unlock()
+	close(ch)
+}
+
+// publish fans log out to every subscriber whose filter matches it. A
+// subscriber whose buffer is full (a slow consumer) has this event dropped
+// rather than blocking the caller, which holds WebAdminPlugin.mu.
+func (b *requestBroadcaster) publish(log RequestLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch, filter := range b.subscribers {
+		if !filter.matches(log) {
+			continue
+		}
+		select {
+		case ch <- log:
+		default:
+		}
+	}
+}
@@ -10,17 +10,18 @@ import (
 
 // Analytics tracks cache usage and provides insights
 type Analytics struct {
-	mu              sync.RWMutex
-	requests        int64
-	cacheHits       int64
-	cacheMisses     int64
-	totalLatency    time.Duration
-	totalBytes      int64
-	savedBytes      int64 // bytes saved by serving from cache
-	endpoints       map[string]*EndpointStats
-	hourlyStats     map[int64]*HourlyStats
-	costSavings     float64
-	startTime       time.Time
+	mu           sync.RWMutex
+	requests     int64
+	cacheHits    int64
+	cacheMisses  int64
+	totalLatency time.Duration
+	totalBytes   int64
+	savedBytes   int64 // bytes saved by serving from cache
+	endpoints    map[string]*EndpointStats
+	hourlyStats  map[int64]*HourlyStats
+	costSavings  float64
+	startTime    time.Time
+	queryLog     *QueryLog
 }
 
 // EndpointStats tracks statistics for a specific endpoint
@@ -46,17 +47,17 @@ type HourlyStats struct {
 
 // Summary provides a snapshot of analytics
 type Summary struct {
-	TotalRequests   int64         `json:"total_requests"`
-	CacheHits       int64         `json:"cache_hits"`
-	CacheMisses     int64         `json:"cache_misses"`
-	HitRate         float64       `json:"hit_rate"`
-	AvgLatency      string        `json:"avg_latency"`
-	TotalBytes      int64         `json:"total_bytes"`
-	SavedBytes      int64         `json:"saved_bytes"`
-	CostSavings     float64       `json:"cost_savings"`
+	TotalRequests   int64           `json:"total_requests"`
+	CacheHits       int64           `json:"cache_hits"`
+	CacheMisses     int64           `json:"cache_misses"`
+	HitRate         float64         `json:"hit_rate"`
+	AvgLatency      string          `json:"avg_latency"`
+	TotalBytes      int64           `json:"total_bytes"`
+	SavedBytes      int64           `json:"saved_bytes"`
+	CostSavings     float64         `json:"cost_savings"`
 	TopEndpoints    []EndpointStats `json:"top_endpoints"`
 	HourlyBreakdown []HourlyStats   `json:"hourly_breakdown"`
-	Uptime          string        `json:"uptime"`
+	Uptime          string          `json:"uptime"`
 }
 
 // NewAnalytics creates a new analytics tracker
@@ -68,11 +69,29 @@ func NewAnalytics() *Analytics {
 	}
 }
 
+// SetQueryLog installs a QueryLog to receive a line for every request
+// recorded via RecordRequest. Pass nil to disable.
+func (a *Analytics) SetQueryLog(q *QueryLog) {
+	a.mu.Lock()
+	a.queryLog = q
+	a.mu.Unlock()
+}
+
 // RecordRequest records a request event
 func (a *Analytics) RecordRequest(path string, cached bool, latency time.Duration, bytes int64) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if a.queryLog != nil {
+		a.queryLog.Write(QueryLogEntry{
+			Timestamp: time.Now(),
+			Path:      path,
+			Cached:    cached,
+			Latency:   latency,
+			Bytes:     bytes,
+		})
+	}
+
 	a.requests++
 	a.totalLatency += latency
 	a.totalBytes += bytes
@@ -204,6 +223,14 @@ func (a *Analytics) GetSummary(topN int) *Summary {
 	return summary
 }
 
+// TotalLatencySeconds returns the sum of every recorded request's latency,
+// in seconds, for the apiproxyd_request_latency_seconds summary metric.
+func (a *Analytics) TotalLatencySeconds() float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.totalLatency.Seconds()
+}
+
 // GetEndpointStats returns statistics for a specific endpoint
 func (a *Analytics) GetEndpointStats(path string) (*EndpointStats, error) {
 	a.mu.RLock()
@@ -339,11 +366,11 @@ func (a *Analytics) PerformanceMetrics() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"total_requests":  a.requests,
-		"cache_hit_rate":  hitRate,
-		"avg_latency_ms":  avgLatency.Milliseconds(),
-		"total_bytes":     a.totalBytes,
-		"saved_bytes":     a.savedBytes,
-		"uptime_seconds":  time.Since(a.startTime).Seconds(),
+		"total_requests": a.requests,
+		"cache_hit_rate": hitRate,
+		"avg_latency_ms": avgLatency.Milliseconds(),
+		"total_bytes":    a.totalBytes,
+		"saved_bytes":    a.savedBytes,
+		"uptime_seconds": time.Since(a.startTime).Seconds(),
 	}
 }
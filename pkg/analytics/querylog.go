@@ -0,0 +1,300 @@
+package analytics
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryLogConfig configures the optional on-disk query log, which records
+// one JSON line per RecordRequest call independent of the in-memory rollups
+// above. It exists for operators who want a durable, greppable record of
+// every request the cache saw (e.g. for offline analysis or compliance)
+// without standing up an external sink.
+type QueryLogConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	// MaxSizeMB is the active file size before rotation.
+	MaxSizeMB int `json:"max_size_mb"`
+	// MaxBackups caps the number of rotated files retained (compressed or
+	// not); 0 keeps them all.
+	MaxBackups int `json:"max_backups"`
+	// Compress gzip-compacts each rotated file once it's no longer active.
+	Compress      bool `json:"compress"`
+	BufferSize    int  `json:"buffer_size"`    // entries to buffer before flush
+	FlushInterval int  `json:"flush_interval"` // seconds between flushes
+}
+
+// QueryLogEntry is a single line of the query log.
+type QueryLogEntry struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Path      string        `json:"path"`
+	Cached    bool          `json:"cached"`
+	Latency   time.Duration `json:"latency"`
+	Bytes     int64         `json:"bytes"`
+}
+
+// QueryLog appends a QueryLogEntry per recorded request to a size-rotated
+// file, gzip-compacting each rotated file in the background so a
+// long-running daemon doesn't accumulate uncompressed history. It rotates
+// by rename the same way audit.Logger does, adding the gzip step that
+// audit.Logger doesn't have.
+type QueryLog struct {
+	mu            sync.Mutex
+	file          *os.File
+	path          string
+	maxSize       int64
+	maxBackups    int
+	compress      bool
+	buffer        []QueryLogEntry
+	bufferSize    int
+	flushInterval time.Duration
+	done          chan struct{}
+}
+
+// NewQueryLog creates a query log writer per config. A nil or disabled
+// config returns a no-op QueryLog whose Write calls are silently ignored,
+// mirroring audit.NewLogger.
+func NewQueryLog(config *QueryLogConfig) (*QueryLog, error) {
+	if config == nil || !config.Enabled {
+		return &QueryLog{done: make(chan struct{})}, nil
+	}
+
+	logPath := config.Path
+	if strings.HasPrefix(logPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		logPath = filepath.Join(home, logPath[2:])
+	}
+
+	dir := filepath.Dir(logPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create query log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open query log file: %w", err)
+	}
+
+	maxSize := int64(config.MaxSizeMB) * 1024 * 1024
+	if maxSize == 0 {
+		maxSize = 50 * 1024 * 1024 // 50MB default
+	}
+
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 100
+	}
+
+	flushInterval := time.Duration(config.FlushInterval) * time.Second
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+
+	q := &QueryLog{
+		file:          file,
+		path:          logPath,
+		maxSize:       maxSize,
+		maxBackups:    config.MaxBackups,
+		compress:      config.Compress,
+		buffer:        make([]QueryLogEntry, 0, bufferSize),
+		bufferSize:    bufferSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+
+	go q.flusher()
+	go q.rotationChecker()
+
+	return q, nil
+}
+
+// Write buffers entry, flushing immediately once the buffer fills.
+func (q *QueryLog) Write(entry QueryLogEntry) {
+	if q.file == nil {
+		return // logging disabled
+	}
+
+	q.mu.Lock()
+	q.buffer = append(q.buffer, entry)
+	shouldFlush := len(q.buffer) >= q.bufferSize
+	q.mu.Unlock()
+
+	if shouldFlush {
+		q.Flush()
+	}
+}
+
+// Flush writes buffered entries to disk.
+func (q *QueryLog) Flush() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.flushLocked()
+}
+
+func (q *QueryLog) flushLocked() error {
+	if len(q.buffer) == 0 || q.file == nil {
+		return nil
+	}
+
+	for _, entry := range q.buffer {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := q.file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	q.buffer = q.buffer[:0]
+	return q.file.Sync()
+}
+
+// flusher periodically flushes the buffer.
+func (q *QueryLog) flusher() {
+	ticker := time.NewTicker(q.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.Flush()
+		case <-q.done:
+			q.Flush() // final flush
+			return
+		}
+	}
+}
+
+// rotationChecker periodically checks whether the active file needs
+// rotating.
+func (q *QueryLog) rotationChecker() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.checkRotation()
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (q *QueryLog) checkRotation() {
+	if q.file == nil {
+		return
+	}
+
+	info, err := q.file.Stat()
+	if err != nil {
+		return
+	}
+
+	if info.Size() >= q.maxSize {
+		q.rotate()
+	}
+}
+
+// rotate closes the active file, renames it aside with a timestamp suffix,
+// gzip-compacts it if configured, opens a fresh active file, and prunes old
+// backups beyond maxBackups.
+func (q *QueryLog) rotate() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.flushLocked()
+	q.file.Close()
+
+	timestamp := time.Now().Format("20060102-150405")
+	rotatedPath := fmt.Sprintf("%s.%s", q.path, timestamp)
+	if err := os.Rename(q.path, rotatedPath); err == nil && q.compress {
+		if err := compressFile(rotatedPath); err == nil {
+			os.Remove(rotatedPath)
+		}
+	}
+
+	file, err := os.OpenFile(q.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	q.file = file
+
+	q.pruneBackupsLocked()
+}
+
+// compressFile gzip-compresses src into src+".gz".
+func compressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(src+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// pruneBackupsLocked removes the oldest rotated files beyond maxBackups,
+// counting compressed and uncompressed backups together.
+func (q *QueryLog) pruneBackupsLocked() {
+	if q.maxBackups <= 0 {
+		return
+	}
+
+	dir := filepath.Dir(q.path)
+	pattern := filepath.Base(q.path) + ".*"
+
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return
+	}
+
+	sort.Strings(matches) // timestamp suffix sorts chronologically
+	if len(matches) <= q.maxBackups {
+		return
+	}
+
+	for _, path := range matches[:len(matches)-q.maxBackups] {
+		os.Remove(path)
+	}
+}
+
+// Close stops the background goroutines and flushes/closes the active file.
+func (q *QueryLog) Close() error {
+	select {
+	case <-q.done:
+		return nil
+	default:
+		close(q.done)
+	}
+
+	if q.file != nil {
+		q.Flush()
+		return q.file.Close()
+	}
+
+	return nil
+}
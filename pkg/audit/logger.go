@@ -1,15 +1,41 @@
 package audit
 
 import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Integrity modes for Config.IntegrityMode. IntegritySHA256 chains each
+// record with a plain SHA-256 digest; IntegrityHMACSHA256 additionally
+// keys the digest with Config.IntegrityKey so a log can't be extended or
+// fabricated without that key. The zero value disables chaining.
+const (
+	IntegritySHA256     = "sha256"
+	IntegrityHMACSHA256 = "hmac-sha256"
+)
+
+// chainGenesisSeed returns the expected tip hash the chain should descend
+// from in HMAC mode when no existing log history was found. Plain SHA-256
+// mode has no key to bind to, so it starts from "" instead.
+func chainGenesisSeed(mode string, key []byte) string {
+	if mode != IntegrityHMACSHA256 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("apiproxyd-audit-chain-genesis"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // LogLevel defines the severity of an audit event
 type LogLevel int
 
@@ -53,6 +79,15 @@ type Event struct {
 	Cached     bool              `json:"cached,omitempty"`
 	Message    string            `json:"message"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
+
+	// PrevHash and Hash form a tamper-evident chain across every record in
+	// the log when Config.IntegrityMode is set: PrevHash is the previous
+	// record's Hash (or the chain's genesis value for the first record),
+	// and Hash is SHA256/HMAC-SHA256 of PrevHash plus this record's
+	// canonical JSON with Hash itself zeroed. Both are empty when
+	// integrity checking is disabled.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 // Logger handles audit logging with rotation and structured output
@@ -69,19 +104,48 @@ type Logger struct {
 	bufferSize    int
 	flushInterval time.Duration
 	done          chan struct{}
+
+	// sinks delivers every flushed event to additional backends beyond the
+	// file above (syslog, Splunk HEC, webhook), each with its own bounded
+	// queue and retry loop. See Config.Sinks.
+	sinks []*sinkRunner
+
+	// integrityMode, integrityKey and prevHash implement the hash chain
+	// described on Event.PrevHash/Hash. integrityMode is "" when
+	// Config.IntegrityMode is unset, in which case writeEvent leaves both
+	// fields blank. prevHash is mutated under mu by writeEvent and rotate.
+	integrityMode string
+	integrityKey  []byte
+	prevHash      string
 }
 
 // Config defines audit logger configuration
 type Config struct {
-	Enabled       bool          `json:"enabled"`
-	Path          string        `json:"path"`
-	MaxSizeMB     int           `json:"max_size_mb"`     // file size before rotation
-	MaxAgeDays    int           `json:"max_age_days"`    // days to keep logs
-	Level         string        `json:"level"`           // minimum log level
-	JSONFormat    bool          `json:"json_format"`     // JSON vs plain text
-	Console       bool          `json:"console"`         // also log to console
-	BufferSize    int           `json:"buffer_size"`     // number of events to buffer
-	FlushInterval int           `json:"flush_interval"`  // seconds between flushes
+	Enabled       bool   `json:"enabled"`
+	Path          string `json:"path"`
+	MaxSizeMB     int    `json:"max_size_mb"`    // file size before rotation
+	MaxAgeDays    int    `json:"max_age_days"`   // days to keep logs
+	Level         string `json:"level"`          // minimum log level
+	JSONFormat    bool   `json:"json_format"`    // JSON vs plain text
+	Console       bool   `json:"console"`        // also log to console
+	BufferSize    int    `json:"buffer_size"`    // number of events to buffer
+	FlushInterval int    `json:"flush_interval"` // seconds between flushes
+
+	// Sinks configures additional delivery backends beyond the on-disk file
+	// above (syslog, Splunk HEC, webhook). Each shares this logger's ring
+	// buffer and FlushInterval but independently reports delivery failures
+	// through its own bounded queue, so a slow or down sink can't block
+	// Log/LogRequest or starve the others.
+	Sinks []SinkConfig `json:"sinks,omitempty"`
+
+	// IntegrityMode turns on the tamper-evident hash chain over Event's
+	// PrevHash/Hash fields: "" (default) disables it, IntegritySHA256
+	// chains with a plain digest, IntegrityHMACSHA256 additionally keys it
+	// with IntegrityKey.
+	IntegrityMode string `json:"integrity_mode,omitempty"`
+	// IntegrityKey is the HMAC key used when IntegrityMode is
+	// IntegrityHMACSHA256. Ignored otherwise.
+	IntegrityKey string `json:"integrity_key,omitempty"`
 }
 
 // NewLogger creates a new audit logger
@@ -134,6 +198,33 @@ func NewLogger(config *Config) (*Logger, error) {
 		flushInterval = 5 * time.Second
 	}
 
+	sinks := make([]*sinkRunner, 0, len(config.Sinks))
+	for _, sinkCfg := range config.Sinks {
+		sink, err := newSink(sinkCfg)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, newSinkRunner(sink, sinkCfg.QueueSize))
+	}
+
+	var integrityKey []byte
+	var prevHash string
+	if config.IntegrityMode != "" {
+		if !config.JSONFormat {
+			return nil, fmt.Errorf("audit: integrity_mode requires json_format, since the hash chain is only persisted in JSON records")
+		}
+		integrityKey = []byte(config.IntegrityKey)
+
+		tip, err := lastChainHash(logPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing audit log chain: %w", err)
+		}
+		if tip == "" {
+			tip = chainGenesisSeed(config.IntegrityMode, integrityKey)
+		}
+		prevHash = tip
+	}
+
 	logger := &Logger{
 		file:          file,
 		path:          logPath,
@@ -146,6 +237,10 @@ func NewLogger(config *Config) (*Logger, error) {
 		bufferSize:    bufferSize,
 		flushInterval: flushInterval,
 		done:          make(chan struct{}),
+		sinks:         sinks,
+		integrityMode: config.IntegrityMode,
+		integrityKey:  integrityKey,
+		prevHash:      prevHash,
 	}
 
 	// Start background flusher
@@ -263,7 +358,8 @@ func (l *Logger) LogError(context, message string, err error) {
 	l.Log(LevelError, "error", message, metadata)
 }
 
-// Flush writes buffered events to disk
+// Flush writes buffered events to disk and enqueues them on every
+// configured Sink.
 func (l *Logger) Flush() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -273,9 +369,13 @@ func (l *Logger) Flush() error {
 	}
 
 	for _, event := range l.buffer {
-		if err := l.writeEvent(event); err != nil {
+		written, err := l.writeEvent(event)
+		if err != nil {
 			return err
 		}
+		for _, r := range l.sinks {
+			r.enqueue(written)
+		}
 	}
 
 	// Clear buffer
@@ -285,15 +385,38 @@ func (l *Logger) Flush() error {
 	return l.file.Sync()
 }
 
-// writeEvent writes a single event to the log file
-func (l *Logger) writeEvent(event Event) error {
+// SinkDropCounts returns, for each configured Sink, the number of events it
+// has dropped so far — either from a full queue or exhausted delivery
+// retries — for the apiproxyd_audit_sink_drops_total metric.
+func (l *Logger) SinkDropCounts() map[string]int64 {
+	counts := make(map[string]int64, len(l.sinks))
+	for _, r := range l.sinks {
+		counts[r.sink.Name()] = r.drops()
+	}
+	return counts
+}
+
+// writeEvent writes a single event to the log file, returning the event as
+// written - with PrevHash/Hash populated, when integrity checking is
+// enabled - so the caller can forward the same record to its sinks.
+func (l *Logger) writeEvent(event Event) (Event, error) {
 	var output []byte
 	var err error
 
+	if l.integrityMode != "" {
+		event.PrevHash = l.prevHash
+		hash, err := l.chainHash(event)
+		if err != nil {
+			return event, fmt.Errorf("failed to compute audit chain hash: %w", err)
+		}
+		event.Hash = hash
+		l.prevHash = hash
+	}
+
 	if l.jsonFormat {
 		output, err = json.Marshal(event)
 		if err != nil {
-			return err
+			return event, err
 		}
 		output = append(output, '\n')
 	} else {
@@ -308,7 +431,7 @@ func (l *Logger) writeEvent(event Event) error {
 
 	// Write to file
 	if _, err := l.file.Write(output); err != nil {
-		return err
+		return event, err
 	}
 
 	// Also write to console if enabled
@@ -316,6 +439,128 @@ func (l *Logger) writeEvent(event Event) error {
 		os.Stdout.Write(output)
 	}
 
+	return event, nil
+}
+
+// chainHash computes event's chained hash: SHA-256 (or, in
+// IntegrityHMACSHA256 mode, HMAC-SHA256 keyed with integrityKey) of
+// event.PrevHash followed by event's canonical JSON with Hash itself
+// zeroed, so the digest can't trivially include itself.
+func (l *Logger) chainHash(event Event) (string, error) {
+	event.Hash = ""
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	data := append([]byte(event.PrevHash), payload...)
+
+	if l.integrityMode == IntegrityHMACSHA256 {
+		mac := hmac.New(sha256.New, l.integrityKey)
+		mac.Write(data)
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastChainHash returns the Hash of the last record in the existing log at
+// path, or "" if the file doesn't exist, is empty, or its last line has no
+// recorded hash (e.g. integrity checking was just turned on for a log that
+// predates it).
+func lastChainHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lastLine string
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lastLine = line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if lastLine == "" {
+		return "", nil
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(lastLine), &event); err != nil {
+		// Pre-existing plain-text or otherwise non-JSON log: nothing to
+		// chain from, start fresh.
+		return "", nil
+	}
+	return event.Hash, nil
+}
+
+// Verify re-hashes every record in the audit log at path using this
+// Logger's configured integrity mode and key, and returns an error
+// identifying the first line whose hash doesn't match what writeEvent
+// would have produced, or whose prev_hash doesn't match the previous
+// line's hash - either is evidence the log was edited, reordered, or
+// truncated after the fact. It only checks links within path itself; a
+// log that was rotated should also have its .chain sidecar compared
+// against the next segment's first record to verify continuity across
+// the rotation boundary.
+func (l *Logger) Verify(path string) error {
+	if l.integrityMode == "" {
+		return fmt.Errorf("audit log integrity checking is not enabled")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var prevHash string
+	haveFirst := false
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return fmt.Errorf("audit log %s line %d: failed to parse event: %w", path, lineNo, err)
+		}
+
+		if haveFirst && event.PrevHash != prevHash {
+			return fmt.Errorf("audit log %s line %d: broken chain link (expected prev_hash %q, found %q)", path, lineNo, prevHash, event.PrevHash)
+		}
+
+		want, err := l.chainHash(event)
+		if err != nil {
+			return fmt.Errorf("audit log %s line %d: failed to hash event: %w", path, lineNo, err)
+		}
+		if want != event.Hash {
+			return fmt.Errorf("audit log %s line %d: hash mismatch, record was likely tampered with", path, lineNo)
+		}
+
+		prevHash = event.Hash
+		haveFirst = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+
 	return nil
 }
 
@@ -377,7 +622,13 @@ func (l *Logger) rotate() {
 
 	// Flush any buffered events
 	for _, event := range l.buffer {
-		l.writeEvent(event)
+		written, err := l.writeEvent(event)
+		if err != nil {
+			continue
+		}
+		for _, r := range l.sinks {
+			r.enqueue(written)
+		}
 	}
 	l.buffer = l.buffer[:0]
 
@@ -389,12 +640,39 @@ func (l *Logger) rotate() {
 	rotatedPath := fmt.Sprintf("%s.%s", l.path, timestamp)
 	os.Rename(l.path, rotatedPath)
 
+	// Record the chain tip in a sidecar file so the full history across
+	// rotations can be verified as one linked list even though each
+	// segment is checked independently by Verify.
+	if l.integrityMode != "" {
+		l.writeChainSidecar(rotatedPath)
+	}
+
 	// Open new file
 	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
 	if err != nil {
 		return
 	}
 	l.file = file
+
+	// Start the new segment with a genesis entry referencing the previous
+	// chain tip, so the link across the rotation boundary is recorded in
+	// the log itself and not just the sidecar.
+	if l.integrityMode != "" {
+		l.writeEvent(Event{
+			Timestamp: time.Now(),
+			Level:     LevelInfo.String(),
+			EventType: "chain_genesis",
+			Message:   fmt.Sprintf("chain continued from %s", filepath.Base(rotatedPath)),
+		})
+	}
+}
+
+// writeChainSidecar records prevHash - the final hash of the segment just
+// rotated out - in a "<rotatedPath>.chain" file. Best-effort: a failure to
+// write it doesn't undo the rotation that already happened.
+func (l *Logger) writeChainSidecar(rotatedPath string) {
+	sidecarPath := rotatedPath + ".chain"
+	os.WriteFile(sidecarPath, []byte(l.prevHash+"\n"), 0600)
 }
 
 // cleanupOldLogs removes log files older than maxAge
@@ -424,6 +702,10 @@ func (l *Logger) cleanupOldLogs() {
 func (l *Logger) Close() error {
 	close(l.done)
 
+	for _, r := range l.sinks {
+		r.close()
+	}
+
 	if l.file != nil {
 		l.Flush()
 		return l.file.Close()
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newChainedLogger(t *testing.T, mode string) (*Logger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := NewLogger(&Config{
+		Enabled:       true,
+		Path:          path,
+		JSONFormat:    true,
+		IntegrityMode: mode,
+		IntegrityKey:  "test-key",
+	})
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, path
+}
+
+func TestLoggerVerifyDetectsTamperedRecord(t *testing.T) {
+	l, path := newChainedLogger(t, IntegrityHMACSHA256)
+
+	l.Log(LevelInfo, "test", "first event", nil)
+	l.Log(LevelInfo, "test", "second event", nil)
+	if err := l.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if err := l.Verify(path); err != nil {
+		t.Fatalf("Verify on untampered log: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	tampered := strings.Replace(string(data), "first event", "tampered event", 1)
+	if tampered == string(data) {
+		t.Fatal("replacement did not change file contents")
+	}
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := l.Verify(path); err == nil {
+		t.Fatal("expected Verify to detect the tampered record")
+	}
+}
+
+func TestLoggerVerifyDetectsReorderedRecord(t *testing.T) {
+	l, path := newChainedLogger(t, IntegritySHA256)
+
+	l.Log(LevelInfo, "test", "first event", nil)
+	l.Log(LevelInfo, "test", "second event", nil)
+	if err := l.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+	reordered := strings.Join([]string{lines[1], lines[0]}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(reordered), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := l.Verify(path); err == nil {
+		t.Fatal("expected Verify to detect the broken chain link from reordering")
+	}
+}
+
+func TestNewLoggerRejectsIntegrityModeWithoutJSONFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	_, err := NewLogger(&Config{
+		Enabled:       true,
+		Path:          path,
+		JSONFormat:    false,
+		IntegrityMode: IntegritySHA256,
+	})
+	if err == nil {
+		t.Fatal("expected NewLogger to reject IntegrityMode without JSONFormat")
+	}
+}
@@ -0,0 +1,384 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink delivers a batch of audit events to an external backend. Send should
+// treat events as at-least-once delivery: sinkRunner retries the whole
+// batch with exponential backoff on error, and Send implementations should
+// be safe to call again with the same events.
+type Sink interface {
+	Name() string
+	Send(events []Event) error
+}
+
+// SinkConfig configures one additional delivery backend beyond the on-disk
+// file Logger already writes to. Type selects which fields apply:
+// "syslog" (RFC 5424), "splunk" (HTTP Event Collector), or "webhook"
+// (newline-delimited JSON over HTTPS).
+type SinkConfig struct {
+	Type string `json:"type"`
+	// Name distinguishes multiple sinks of the same Type in metrics;
+	// defaults to Type.
+	Name string `json:"name,omitempty"`
+
+	// Syslog
+	Network string `json:"network,omitempty"` // "tcp" or "udp"; default "udp"
+	Address string `json:"address,omitempty"`
+	AppName string `json:"app_name,omitempty"`
+
+	// Splunk HTTP Event Collector
+	HECURL     string `json:"hec_url,omitempty"`
+	HECToken   string `json:"hec_token,omitempty"`
+	SourceType string `json:"sourcetype,omitempty"`
+
+	// Webhook
+	WebhookURL string            `json:"webhook_url,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+
+	// QueueSize bounds how many events may be buffered for this sink before
+	// new ones are dropped (and counted) rather than blocking Log/LogRequest.
+	QueueSize int `json:"queue_size,omitempty"`
+}
+
+// newSink builds the Sink implementation named by cfg.Type.
+func newSink(cfg SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case "syslog":
+		return newSyslogSink(cfg), nil
+	case "splunk":
+		return newSplunkSink(cfg), nil
+	case "webhook":
+		return newWebhookSink(cfg), nil
+	default:
+		return nil, fmt.Errorf("audit: unknown sink type %q", cfg.Type)
+	}
+}
+
+// sinkRunner owns one Sink's bounded in-memory queue and background
+// delivery loop, so a slow or down sink can't block the hot Log/LogRequest
+// paths or starve the other configured sinks.
+type sinkRunner struct {
+	sink      Sink
+	queue     chan Event
+	dropCount int64
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func newSinkRunner(sink Sink, queueSize int) *sinkRunner {
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+
+	r := &sinkRunner{
+		sink:  sink,
+		queue: make(chan Event, queueSize),
+		done:  make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+// enqueue buffers event for delivery. If the sink's queue is full, the event
+// is dropped and counted rather than blocking the caller.
+func (r *sinkRunner) enqueue(event Event) {
+	select {
+	case r.queue <- event:
+	default:
+		atomic.AddInt64(&r.dropCount, 1)
+	}
+}
+
+func (r *sinkRunner) run() {
+	defer r.wg.Done()
+
+	const maxBatch = 50
+	batch := make([]Event, 0, maxBatch)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		r.sendWithRetry(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case e := <-r.queue:
+			batch = append(batch, e)
+			if len(batch) >= maxBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			for {
+				select {
+				case e := <-r.queue:
+					batch = append(batch, e)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendWithRetry retries Send with exponential backoff, dropping (and
+// counting) the whole batch only once every attempt has failed.
+func (r *sinkRunner) sendWithRetry(events []Event) {
+	delay := 500 * time.Millisecond
+	const maxDelay = 30 * time.Second
+	const maxAttempts = 5
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err := r.sink.Send(events); err == nil {
+			return
+		}
+		if attempt == maxAttempts-1 {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+
+	atomic.AddInt64(&r.dropCount, int64(len(events)))
+}
+
+// drops returns the number of events this sink has dropped, either from
+// queue overflow or exhausted delivery retries.
+func (r *sinkRunner) drops() int64 {
+	return atomic.LoadInt64(&r.dropCount)
+}
+
+func (r *sinkRunner) close() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+// syslogSink delivers events as RFC 5424 messages over a syslog network
+// connection.
+type syslogSink struct {
+	name     string
+	network  string
+	address  string
+	appName  string
+	hostname string
+}
+
+func newSyslogSink(cfg SinkConfig) *syslogSink {
+	name := cfg.Name
+	if name == "" {
+		name = "syslog"
+	}
+	network := cfg.Network
+	if network == "" {
+		network = "udp"
+	}
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "apiproxyd"
+	}
+	hostname, _ := os.Hostname()
+
+	return &syslogSink{name: name, network: network, address: cfg.Address, appName: appName, hostname: hostname}
+}
+
+func (s *syslogSink) Name() string { return s.name }
+
+// Send dials a fresh connection per batch: simpler than keeping one alive
+// across calls, and dial failures are already covered by sinkRunner's retry.
+func (s *syslogSink) Send(events []Event) error {
+	conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("syslog sink %s: dial: %w", s.name, err)
+	}
+	defer conn.Close()
+
+	for _, e := range events {
+		if _, err := conn.Write([]byte(s.formatRFC5424(e) + "\n")); err != nil {
+			return fmt.Errorf("syslog sink %s: write: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// formatRFC5424 renders e as an RFC 5424 syslog message, with the event
+// itself (as JSON) carried in MSG.
+func (s *syslogSink) formatRFC5424(e Event) string {
+	const facilityUser = 1
+	pri := facilityUser*8 + rfc5424Severity(e.Level)
+
+	msgID := e.EventType
+	if msgID == "" {
+		msgID = "-"
+	}
+
+	msg, _ := json.Marshal(e)
+	return fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		pri, e.Timestamp.UTC().Format(time.RFC3339Nano), s.hostname, s.appName, msgID, msg)
+}
+
+// rfc5424Severity maps a LogLevel.String() value to its RFC 5424 severity
+// number.
+func rfc5424Severity(level string) int {
+	switch level {
+	case "DEBUG":
+		return 7
+	case "INFO":
+		return 6
+	case "WARN":
+		return 4
+	case "ERROR":
+		return 3
+	case "CRITICAL":
+		return 2
+	default:
+		return 6
+	}
+}
+
+// splunkSink delivers events to a Splunk HTTP Event Collector endpoint,
+// batching them as back-to-back JSON objects in a single POST body (the
+// shape HEC's /services/collector/event expects for multi-event batches).
+type splunkSink struct {
+	name       string
+	url        string
+	token      string
+	sourcetype string
+	client     *http.Client
+}
+
+func newSplunkSink(cfg SinkConfig) *splunkSink {
+	name := cfg.Name
+	if name == "" {
+		name = "splunk"
+	}
+	return &splunkSink{
+		name:       name,
+		url:        cfg.HECURL,
+		token:      cfg.HECToken,
+		sourcetype: cfg.SourceType,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *splunkSink) Name() string { return s.name }
+
+// splunkHECEvent is the HEC event envelope: the audit Event goes verbatim
+// into the "event" field.
+type splunkHECEvent struct {
+	Time       float64 `json:"time"`
+	Sourcetype string  `json:"sourcetype,omitempty"`
+	Event      Event   `json:"event"`
+}
+
+func (s *splunkSink) Send(events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		envelope := splunkHECEvent{
+			Time:       float64(e.Timestamp.UnixNano()) / 1e9,
+			Sourcetype: s.sourcetype,
+			Event:      e,
+		}
+		if err := enc.Encode(envelope); err != nil {
+			return fmt.Errorf("splunk sink %s: encode: %w", s.name, err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &buf)
+	if err != nil {
+		return fmt.Errorf("splunk sink %s: request: %w", s.name, err)
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("splunk sink %s: post: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("splunk sink %s: unexpected status %d", s.name, resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookSink posts events as newline-delimited JSON to a generic HTTPS
+// endpoint.
+type webhookSink struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newWebhookSink(cfg SinkConfig) *webhookSink {
+	name := cfg.Name
+	if name == "" {
+		name = "webhook"
+	}
+	return &webhookSink{
+		name:    name,
+		url:     cfg.WebhookURL,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *webhookSink) Name() string { return s.name }
+
+func (s *webhookSink) Send(events []Event) error {
+	var buf bytes.Buffer
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("webhook sink %s: encode: %w", s.name, err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &buf)
+	if err != nil {
+		return fmt.Errorf("webhook sink %s: request: %w", s.name, err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook sink %s: post: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook sink %s: unexpected status %d", s.name, resp.StatusCode)
+	}
+	return nil
+}
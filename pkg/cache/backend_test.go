@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// backendCase names a Cache implementation under test along with a
+// constructor, used to run the same behavioral assertions (TTL semantics,
+// expired-key deletion, stats accuracy) across every backend so they stay
+// consistent with each other. A constructor calls t.Skip if its backend
+// needs an external service that isn't configured in this environment.
+type backendCase struct {
+	name string
+	new  func(t *testing.T) Cache
+}
+
+func backendCases() []backendCase {
+	return []backendCase{
+		{"sqlite", func(t *testing.T) Cache {
+			c, err := NewSQLite(filepath.Join(t.TempDir(), "cache.db"))
+			if err != nil {
+				t.Fatalf("NewSQLite: %v", err)
+			}
+			t.Cleanup(func() { c.Close() })
+			return c
+		}},
+		{"badger", func(t *testing.T) Cache {
+			c, err := NewBadgerCache(BadgerOptions{InMemory: true})
+			if err != nil {
+				t.Fatalf("NewBadgerCache: %v", err)
+			}
+			t.Cleanup(func() { c.Close() })
+			return c
+		}},
+		{"redis", func(t *testing.T) Cache {
+			addr := os.Getenv("TEST_REDIS_ADDR")
+			if addr == "" {
+				t.Skip("TEST_REDIS_ADDR not set; skipping Redis backend test")
+			}
+			c, err := NewRedisCache(RedisOptions{Addr: addr})
+			if err != nil {
+				t.Fatalf("NewRedisCache: %v", err)
+			}
+			t.Cleanup(func() { c.Close() })
+			return c
+		}},
+	}
+}
+
+// backendTestTTL and backendTestSleep are long enough that Badger's second-granularity TTL
+// rounding can't make a "not yet expired" check flaky, but the expiry
+// check still only costs ~2s per backend.
+const (
+	backendTestTTL   = 1200 * time.Millisecond
+	backendTestSleep = 2 * time.Second
+)
+
+func TestBackendsTTLSemantics(t *testing.T) {
+	for _, tc := range backendCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.new(t)
+			setter, ok := c.(TTLSetter)
+			if !ok {
+				t.Skipf("%s does not implement TTLSetter", tc.name)
+			}
+
+			if err := setter.SetTTL("ttl-key", []byte("value"), backendTestTTL); err != nil {
+				t.Fatalf("SetTTL: %v", err)
+			}
+
+			val, err := c.Get("ttl-key")
+			if err != nil {
+				t.Fatalf("Get immediately after SetTTL: %v", err)
+			}
+			if string(val) != "value" {
+				t.Errorf("Get = %q, want %q", val, "value")
+			}
+
+			time.Sleep(backendTestSleep)
+
+			if _, err := c.Get("ttl-key"); err == nil {
+				t.Error("Get after TTL expiry: expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestBackendsExpiredKeyDeletion(t *testing.T) {
+	for _, tc := range backendCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.new(t)
+			setter, ok := c.(TTLSetter)
+			if !ok {
+				t.Skipf("%s does not implement TTLSetter", tc.name)
+			}
+
+			if err := setter.SetTTL("expired-key", []byte("value"), backendTestTTL); err != nil {
+				t.Fatalf("SetTTL: %v", err)
+			}
+			time.Sleep(backendTestSleep)
+
+			if err := c.Delete("expired-key"); err != nil {
+				t.Fatalf("Delete on already-expired key: %v", err)
+			}
+			if _, err := c.Get("expired-key"); err == nil {
+				t.Error("Get after Delete: expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestBackendsStatsAccuracy(t *testing.T) {
+	for _, tc := range backendCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.new(t)
+
+			before, err := c.Stats()
+			if err != nil {
+				t.Fatalf("Stats: %v", err)
+			}
+
+			if err := c.Set("stats-key", []byte("value")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+
+			after, err := c.Stats()
+			if err != nil {
+				t.Fatalf("Stats: %v", err)
+			}
+			if after.Entries != before.Entries+1 {
+				t.Errorf("Stats().Entries = %d, want %d", after.Entries, before.Entries+1)
+			}
+
+			if _, err := c.Get("stats-key"); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if _, err := c.Get("nonexistent-key"); err == nil {
+				t.Fatal("Get(nonexistent-key) = nil error, want a miss")
+			}
+
+			finalStats, err := c.Stats()
+			if err != nil {
+				t.Fatalf("Stats: %v", err)
+			}
+			if finalStats.Hits == 0 && finalStats.Misses == 0 {
+				t.Skipf("%s does not track hit/miss counters", tc.name)
+			}
+			if finalStats.Hits < before.Hits+1 {
+				t.Errorf("Stats().Hits = %d, want at least %d", finalStats.Hits, before.Hits+1)
+			}
+			if finalStats.Misses < before.Misses+1 {
+				t.Errorf("Stats().Misses = %d, want at least %d", finalStats.Misses, before.Misses+1)
+			}
+		})
+	}
+}
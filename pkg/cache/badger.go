@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// BadgerOptions configures the badger cache backend, the typed options
+// block behind `cache.backend: badger` in config.CacheConfig.Badger.
+// BadgerDB is an embedded LSM-tree store, so (unlike redis/s3) this
+// backend needs no network address - just a directory it owns.
+type BadgerOptions struct {
+	// Dir is where BadgerDB stores its SST files and value log. Required.
+	Dir string
+	// TTL is the default expiry for Set, used when the caller doesn't go
+	// through TTLSetter.SetTTL; zero means 24 hours, matching the other
+	// backends' default.
+	TTL time.Duration
+	// InMemory runs Badger with no on-disk files at all, for tests.
+	InMemory bool
+}
+
+// BadgerCache is a Cache backed by an embedded BadgerDB instance, useful
+// for a single-instance deployment that wants Postgres-cache durability
+// without running a separate database process.
+type BadgerCache struct {
+	db  *badger.DB
+	ttl time.Duration
+}
+
+// NewBadgerCache opens (creating if necessary) the BadgerDB at opts.Dir.
+func NewBadgerCache(opts BadgerOptions) (*BadgerCache, error) {
+	if opts.Dir == "" && !opts.InMemory {
+		return nil, fmt.Errorf("badger cache: dir is required")
+	}
+
+	badgerOpts := badger.DefaultOptions(opts.Dir).WithLogger(nil)
+	if opts.InMemory {
+		badgerOpts = badgerOpts.WithInMemory(true)
+	}
+
+	db, err := badger.Open(badgerOpts)
+	if err != nil {
+		return nil, fmt.Errorf("badger cache: open %s: %w", opts.Dir, err)
+	}
+
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	return &BadgerCache{db: db, ttl: ttl}, nil
+}
+
+func (c *BadgerCache) Get(key string) ([]byte, error) {
+	var value []byte
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, fmt.Errorf("cache miss")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("badger cache: get: %w", err)
+	}
+	return value, nil
+}
+
+func (c *BadgerCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return c.Get(key)
+}
+
+func (c *BadgerCache) Set(key string, value []byte) error {
+	return c.SetTTL(key, value, c.ttl)
+}
+
+func (c *BadgerCache) SetContext(ctx context.Context, key string, value []byte) error {
+	return c.Set(key, value)
+}
+
+// SetTTL is like Set but stores the entry under ttl instead of the
+// cache's configured default, implementing cache.TTLSetter.
+func (c *BadgerCache) SetTTL(key string, value []byte, ttl time.Duration) error {
+	err := c.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(key), value).WithTTL(ttl)
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return fmt.Errorf("badger cache: set: %w", err)
+	}
+	return nil
+}
+
+func (c *BadgerCache) Delete(key string) error {
+	err := c.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("badger cache: delete: %w", err)
+	}
+	return nil
+}
+
+func (c *BadgerCache) DeleteContext(ctx context.Context, key string) error {
+	return c.Delete(key)
+}
+
+// Stats reports Entries by iterating every live key and SizeBytes from
+// Badger's LSM tree plus value log size estimate; HitRate,
+// Hits, and Misses are left at zero since Badger doesn't track them
+// per-cache the way PostgresCache's stats table does.
+func (c *BadgerCache) Stats() (*Stats, error) {
+	lsm, vlog := c.db.Size()
+
+	entries := int64(0)
+	err := c.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			entries++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("badger cache: stats: %w", err)
+	}
+
+	return &Stats{
+		Entries:   entries,
+		SizeBytes: lsm + vlog,
+	}, nil
+}
+
+func (c *BadgerCache) Close() error {
+	return c.db.Close()
+}
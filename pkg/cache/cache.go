@@ -1,21 +1,93 @@
 package cache
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"time"
 )
 
-// Cache defines the interface for cache backends
+// Cache defines the interface for cache backends. The *Context variants
+// take a context.Context so a caller can cancel a slow backend query (e.g.
+// Postgres) or abandon it once the request that triggered it is gone,
+// instead of leaking the goroutine and connection until it finishes on its
+// own. The non-context methods behave like their *Context counterpart
+// called with context.Background(), kept for backward compatibility. Some
+// backends (redis, badger, memcached) implement the *Context variants by
+// calling the non-context method and ignore ctx.
 type Cache interface {
 	Get(key string) ([]byte, error)
+	GetContext(ctx context.Context, key string) ([]byte, error)
 	Set(key string, value []byte) error
+	SetContext(ctx context.Context, key string, value []byte) error
 	Delete(key string) error
+	DeleteContext(ctx context.Context, key string) error
 	Stats() (*Stats, error)
 	Close() error
 }
 
+// TTLSetter is implemented by cache backends that can store an entry under
+// an explicit TTL instead of whatever default the backend was constructed
+// with, used for per-endpoint overrides (see config.EndpointEntry.TTL and
+// config.Policy.CacheTTLOverride). Not every Cache implementation supports
+// an explicit per-key TTL, so a caller that wants one must type-assert for
+// it rather than relying on it being part of Cache itself.
+type TTLSetter interface {
+	SetTTL(key string, value []byte, ttl time.Duration) error
+}
+
+// ResponseCacheMode controls whether a cache backend stores non-2xx
+// ("negative") responses in addition to successful ones. It's distinct
+// from CacheMode (see mode.go), which governs how strictly Cache-Control
+// directives are honored; ResponseCacheMode instead governs which status
+// codes are eligible for caching at all. See NegativeCacheConfig.
+type ResponseCacheMode string
+
+const (
+	// ResponseCacheOff disables negative caching outright: SetEntry stores
+	// whatever expiry the caller already put on entry.ExpiresAt,
+	// regardless of status code, matching pre-negative-cache behavior.
+	ResponseCacheOff ResponseCacheMode = "off"
+	// ResponseCachePositive, which an empty Mode also selects, caches only
+	// 2xx responses; a non-2xx entry passed to SetEntry is not stored.
+	ResponseCachePositive ResponseCacheMode = "positive"
+	// ResponseCacheBoth caches both 2xx responses and whichever non-2xx
+	// status codes are configured in NegativeCacheConfig.StatusTTL.
+	ResponseCacheBoth ResponseCacheMode = "both"
+)
+
+// NegativeCacheConfig controls whether and how long a non-2xx upstream
+// response is cached. Unlike a normal cache hit, a negative entry usually
+// needs to expire quickly (a 404 may start existing moments later) and
+// some status codes shouldn't be cached at all (a 5xx is often transient
+// and caching it risks serving a stale failure to every subsequent
+// request). See PostgresCache.SetNegativeCacheConfig.
+type NegativeCacheConfig struct {
+	// Mode selects whether non-2xx responses are cached at all. The zero
+	// value behaves as ResponseCachePositive; ResponseCacheOff is the mode
+	// that preserves pre-negative-cache behavior.
+	Mode ResponseCacheMode
+	// StatusTTL maps a status code to how long a response with that code
+	// is cached for when Mode is ResponseCacheBoth. A status code with no
+	// entry here is not cached - in particular, 5xx responses are skipped
+	// by default unless explicitly added.
+	StatusTTL map[int]time.Duration
+	// MaxSize caps how many negative-cache entries a backend keeps at
+	// once; the oldest are evicted once the cap is reached. Zero means
+	// unlimited.
+	MaxSize int
+	// ServeStaleOnRefresh, when true, makes Get return an expired negative
+	// entry's value instead of a miss, while calling OnStaleNegative (if
+	// set) so the caller can kick off a background refresh instead of
+	// making the next request wait on one.
+	ServeStaleOnRefresh bool
+	// OnStaleNegative, if set, is called with a key whenever Get serves a
+	// stale negative entry for it under ServeStaleOnRefresh, so the caller
+	// can trigger a refresh of that key against the origin. Called in its
+	// own goroutine; it must not block on anything Get itself is holding.
+	OnStaleNegative func(key string)
+}
+
 // Stats represents cache statistics
 type Stats struct {
 	Entries   int64
@@ -23,6 +95,10 @@ type Stats struct {
 	HitRate   float64
 	Hits      int64
 	Misses    int64
+	// PendingWrites and FailedWrites are only meaningful for a LayeredCache
+	// running in write-back mode; they are always zero otherwise.
+	PendingWrites int64
+	FailedWrites  int64
 }
 
 // Entry represents a cached item
@@ -38,19 +114,14 @@ type Entry struct {
 	Metadata   map[string]string
 }
 
-// New creates a new cache backend
+// New creates a new cache backend, looking up backend in DefaultRegistry.
 func New(backend, path string) (Cache, error) {
-	switch backend {
-	case "sqlite", "":
-		return NewSQLite(path)
-	case "postgres", "postgresql":
-		return NewPostgres(path)
-	default:
-		return nil, fmt.Errorf("unsupported cache backend: %s", backend)
-	}
+	return DefaultRegistry.New(backend, &CacheOptions{Backend: backend, Path: path})
 }
 
-// CacheOptions holds configuration for creating a cache
+// CacheOptions holds configuration for creating a cache. Redis, Badger,
+// and S3 are only consulted by their respective backend's Factory; every
+// other backend ignores them.
 type CacheOptions struct {
 	Backend            string
 	Path               string
@@ -63,43 +134,17 @@ type CacheOptions struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
+	// Backend-specific option blocks
+	Redis     RedisOptions
+	Badger    BadgerOptions
+	Memcached MemcachedOptions
+	S3        S3Options
 }
 
-// NewWithOptions creates a cache with advanced options
+// NewWithOptions creates a cache with advanced options, looking up
+// opts.Backend in DefaultRegistry rather than a hardcoded switch.
 func NewWithOptions(opts *CacheOptions) (Cache, error) {
-	var dbCache Cache
-	var err error
-
-	// Create database cache with connection pooling
-	switch opts.Backend {
-	case "sqlite", "":
-		if opts.MaxOpenConns > 0 {
-			dbCache, err = NewSQLiteWithConfig(
-				opts.Path,
-				opts.MaxOpenConns,
-				opts.MaxIdleConns,
-				opts.ConnMaxLifetime,
-				opts.ConnMaxIdleTime,
-			)
-		} else {
-			dbCache, err = NewSQLite(opts.Path)
-		}
-	case "postgres", "postgresql":
-		if opts.MaxOpenConns > 0 {
-			dbCache, err = NewPostgresWithConfig(
-				opts.Path,
-				opts.MaxOpenConns,
-				opts.MaxIdleConns,
-				opts.ConnMaxLifetime,
-				opts.ConnMaxIdleTime,
-			)
-		} else {
-			dbCache, err = NewPostgres(opts.Path)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported cache backend: %s", opts.Backend)
-	}
-
+	dbCache, err := DefaultRegistry.New(opts.Backend, opts)
 	if err != nil {
 		return nil, err
 	}
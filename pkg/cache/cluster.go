@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Locker acquires a short-lived distributed lock for a cache key so that
+// when several nodes miss the same key at once, only one of them fills it
+// while the rest wait. AcquireLock blocks until the lock is held or ttl
+// elapses without acquiring it, in which case it returns an error.
+//
+// The returned cancel func must always be invoked exactly once when the
+// caller is done with the lock, whether the fill succeeded or failed, to
+// stop ClusterCache's background lease-renewal goroutine and release the
+// underlying lock. Forgetting to call it leaks both.
+type Locker interface {
+	AcquireLock(key string, ttl time.Duration) (cancel func(), err error)
+}
+
+// Invalidator broadcasts and receives cache invalidation events across the
+// cluster. Publish is called by the node that deleted or overwrote a key;
+// Subscribe is called once by ClusterCache at construction time to learn
+// about invalidations made by peers.
+type Invalidator interface {
+	Publish(key string) error
+	Subscribe(handler func(key string)) (unsubscribe func())
+}
+
+// ClusterStats extends Stats with counters specific to a clustered cache:
+// time spent waiting on distributed locks, requests coalesced by the
+// singleflight lease instead of hitting the fill path, and invalidations
+// applied to L1 on behalf of a peer node.
+type ClusterStats struct {
+	*Stats
+	LockWait            time.Duration
+	SingleflightHits    int64
+	RemoteInvalidations int64
+}
+
+// FillFunc fills a cache miss, typically by calling through to the origin
+// or an L2 backend. It is only invoked by the node that wins the
+// singleflight lease for a given key.
+type FillFunc func() ([]byte, error)
+
+// leaseWaiter is a pending local singleflight call for a key: the first
+// goroutine to miss on a key creates one and starts the fill, and later
+// goroutines that miss on the same key while it is in flight just wait on
+// done instead of acquiring their own distributed lock.
+type leaseWaiter struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// ClusterCache wraps a MemoryCache (L1) and an L2 backend with the two
+// pieces a single-process LayeredCache is missing in a multi-instance
+// deployment: a singleflight lease backed by a distributed Locker so only
+// one node fills a given key at a time, and an Invalidator so a Delete or
+// Set on one node evicts the stale L1 entry on every other node instead of
+// leaving it to expire on its own TTL.
+type ClusterCache struct {
+	l1     *MemoryCache
+	l2     Cache
+	locker Locker
+	pubsub Invalidator
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*leaseWaiter
+
+	statsMu             sync.Mutex
+	lockWait            time.Duration
+	singleflightHits    int64
+	remoteInvalidations int64
+
+	unsubscribe func()
+}
+
+// NewClusterCache wraps dbCache with an L1 memory cache of the given size
+// and the distributed coordination primitives needed to run it safely
+// across multiple apiproxyd instances. It subscribes to pubsub immediately
+// so invalidations from peers start applying before the first request is
+// served.
+func NewClusterCache(dbCache Cache, memoryCacheSize int, ttl time.Duration, locker Locker, pubsub Invalidator) *ClusterCache {
+	c := &ClusterCache{
+		l1:      NewMemoryCache(memoryCacheSize),
+		l2:      dbCache,
+		locker:  locker,
+		pubsub:  pubsub,
+		ttl:     ttl,
+		pending: make(map[string]*leaseWaiter),
+	}
+	c.unsubscribe = pubsub.Subscribe(c.onRemoteInvalidate)
+	return c
+}
+
+// onRemoteInvalidate applies a peer's invalidation to this node's L1 cache.
+func (c *ClusterCache) onRemoteInvalidate(key string) {
+	c.l1.InvalidateRemote(key)
+	c.statsMu.Lock()
+	c.remoteInvalidations++
+	c.statsMu.Unlock()
+}
+
+// Get retrieves a value from L1, falling back to L2 under a distributed
+// lease so that a thundering herd of cache misses for the same key across
+// the cluster results in one fill per node, with the lock keeping those
+// fills from running at the same time.
+func (c *ClusterCache) Get(key string) ([]byte, error) {
+	if value, err := c.l1.Get(key); err == nil {
+		return value, nil
+	}
+
+	return c.GetOrFill(key, func() ([]byte, error) {
+		return c.l2.Get(key)
+	})
+}
+
+// GetContext is like Get but aborts the L2 fill if ctx is canceled before
+// it completes. A fill already shared with other waiters via the
+// singleflight lease keeps running for them even if this caller's ctx is
+// canceled.
+func (c *ClusterCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if value, err := c.l1.Get(key); err == nil {
+		return value, nil
+	}
+
+	return c.GetOrFill(key, func() ([]byte, error) {
+		return c.l2.GetContext(ctx, key)
+	})
+}
+
+// GetOrFill retrieves key via fill, coalescing concurrent callers on this
+// node into a single invocation (singleflight) and holding a short-lived
+// distributed lock for the duration of that invocation so other nodes in
+// the cluster don't run it concurrently too. The lease is renewed every
+// ttl/3 by a background goroutine until fill returns, mirroring the
+// refresh+cancel semantics of minio's RLock/RUnlock helpers; cancel is
+// always invoked on every return path so the renewal goroutine never
+// leaks, regardless of whether fill succeeded or failed.
+func (c *ClusterCache) GetOrFill(key string, fill FillFunc) ([]byte, error) {
+	c.mu.Lock()
+	if w, ok := c.pending[key]; ok {
+		c.mu.Unlock()
+		<-w.done
+		c.statsMu.Lock()
+		c.singleflightHits++
+		c.statsMu.Unlock()
+		return w.value, w.err
+	}
+
+	w := &leaseWaiter{done: make(chan struct{})}
+	c.pending[key] = w
+	c.mu.Unlock()
+
+	value, err := c.fillWithLease(key, fill)
+
+	w.value, w.err = value, err
+	close(w.done)
+
+	c.mu.Lock()
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	return value, err
+}
+
+// fillWithLease acquires the distributed lock for key, keeps it alive with
+// a renewal goroutine, and invokes fill while the lock is held.
+func (c *ClusterCache) fillWithLease(key string, fill FillFunc) ([]byte, error) {
+	const leaseTTL = 10 * time.Second
+
+	lockStart := time.Now()
+	cancel, err := c.locker.AcquireLock(lockKey(key), leaseTTL)
+	if err != nil {
+		return nil, fmt.Errorf("acquire cluster lock for %q: %w", key, err)
+	}
+	defer cancel()
+
+	c.statsMu.Lock()
+	c.lockWait += time.Since(lockStart)
+	c.statsMu.Unlock()
+
+	renewStop := make(chan struct{})
+	renewDone := make(chan struct{})
+	var renewCancels []func()
+	go func() {
+		defer close(renewDone)
+		ticker := time.NewTicker(leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				renewCancel, err := c.locker.AcquireLock(lockKey(key), leaseTTL)
+				if err != nil {
+					return
+				}
+				renewCancels = append(renewCancels, renewCancel)
+			case <-renewStop:
+				return
+			}
+		}
+	}()
+
+	value, err := fill()
+
+	close(renewStop)
+	<-renewDone
+	for _, renewCancel := range renewCancels {
+		renewCancel()
+	}
+
+	if err == nil {
+		c.l1.Set(key, value, c.ttl)
+	}
+
+	return value, err
+}
+
+// lockKey namespaces the distributed lock so it can't collide with an
+// application key used for something other than cache coordination.
+func lockKey(key string) string {
+	return "apiproxyd:cache:lock:" + key
+}
+
+// Set stores a value in L2, then L1, then tells the rest of the cluster to
+// drop their own L1 copy of key so they don't keep serving the old value
+// until it naturally expires.
+func (c *ClusterCache) Set(key string, value []byte) error {
+	return c.SetContext(context.Background(), key, value)
+}
+
+// SetContext is like Set but aborts the L2 write if ctx is canceled first.
+func (c *ClusterCache) SetContext(ctx context.Context, key string, value []byte) error {
+	if err := c.l2.SetContext(ctx, key, value); err != nil {
+		return err
+	}
+
+	if err := c.l1.Set(key, value, c.ttl); err != nil {
+		return err
+	}
+
+	return c.pubsub.Publish(key)
+}
+
+// Delete removes key from both cache layers and broadcasts the
+// invalidation so peers drop it from their own L1 too.
+func (c *ClusterCache) Delete(key string) error {
+	return c.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is like Delete but aborts the L2 delete if ctx is canceled
+// first.
+func (c *ClusterCache) DeleteContext(ctx context.Context, key string) error {
+	c.l1.Delete(key)
+
+	if err := c.l2.DeleteContext(ctx, key); err != nil {
+		return err
+	}
+
+	return c.pubsub.Publish(key)
+}
+
+// Stats returns combined L1/L2 statistics. Use ClusterStatsExt for the
+// distributed-specific counters (lock wait, singleflight hits, remote
+// invalidations).
+func (c *ClusterCache) Stats() (*Stats, error) {
+	ext := c.ClusterStatsExt()
+	return ext.Stats, nil
+}
+
+// ClusterStatsExt returns ClusterCache's L1/L2 statistics plus the
+// distributed-cache counters that plain LayeredCache has no use for.
+func (c *ClusterCache) ClusterStatsExt() *ClusterStats {
+	l1Stats := c.l1.Stats()
+	l2Stats, _ := c.l2.Stats()
+	if l2Stats == nil {
+		l2Stats = &Stats{}
+	}
+
+	totalHits := l1Stats.Hits
+	total := totalHits + l2Stats.Misses
+	hitRate := 0.0
+	if total > 0 {
+		hitRate = float64(totalHits) / float64(total)
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return &ClusterStats{
+		Stats: &Stats{
+			Entries:   l2Stats.Entries,
+			SizeBytes: l2Stats.SizeBytes,
+			HitRate:   hitRate,
+			Hits:      totalHits,
+			Misses:    l2Stats.Misses,
+		},
+		LockWait:            c.lockWait,
+		SingleflightHits:    c.singleflightHits,
+		RemoteInvalidations: c.remoteInvalidations,
+	}
+}
+
+// Close unsubscribes from the invalidation channel and closes the
+// underlying L2 cache.
+func (c *ClusterCache) Close() error {
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+	}
+	return c.l2.Close()
+}
+
+// ClearL1 clears only the L1 cache (useful for testing or cache warming).
+func (c *ClusterCache) ClearL1() {
+	c.l1.Clear()
+}
+
+// GetL1Stats returns L1 (memory) cache statistics.
+func (c *ClusterCache) GetL1Stats() *Stats {
+	return c.l1.Stats()
+}
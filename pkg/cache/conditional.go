@@ -3,61 +3,222 @@ package cache
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // ConditionalCache wraps a cache with conditional request support (ETags, Last-Modified)
 type ConditionalCache struct {
 	cache Cache
+	tags  *TagIndex
 }
 
-// CachedResponse includes metadata for conditional requests
+// CachedResponse includes metadata for conditional requests and RFC 7234
+// freshness calculation.
 type CachedResponse struct {
 	Body         []byte
 	ETag         string
 	LastModified time.Time
 	Headers      map[string]string
 	StatusCode   int
+	Directives   *CacheControlDirectives // Cache-Control directives in effect when this entry was stored
+	Date         time.Time               // upstream Date header, or StoredAt if the origin didn't send one
+	StoredAt     time.Time               // when this entry was written to the cache
+	InitialAge   time.Duration           // upstream Age header value at the time of storage
 }
 
 // NewConditionalCache creates a cache wrapper with conditional request support
 func NewConditionalCache(cache Cache) *ConditionalCache {
 	return &ConditionalCache{
 		cache: cache,
+		tags:  NewTagIndex(),
 	}
 }
 
-// Get retrieves a cached response with conditional request headers
+// TagSet associates key with surrogate-key tags (e.g. from a
+// Surrogate-Key response header), so it can later be purged in bulk via
+// InvalidateByTag without flushing the whole cache.
+func (cc *ConditionalCache) TagSet(key string, tags []string) {
+	cc.tags.TagSet(key, tags)
+}
+
+// InvalidateByTag deletes every cache entry tagged with tag and returns how
+// many were removed.
+func (cc *ConditionalCache) InvalidateByTag(tag string) (int, error) {
+	keys := cc.tags.Keys(tag)
+	n := 0
+	var firstErr error
+	for _, key := range keys {
+		if err := cc.cache.Delete(key); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		cc.tags.Untag(key)
+		n++
+	}
+	return n, firstErr
+}
+
+// Age returns the current age of the cached response per RFC 7234 §4.2.3,
+// combining the upstream Age header (if any) with the time elapsed since it
+// was stored in this cache.
+func (cr *CachedResponse) Age() time.Duration {
+	return cr.InitialAge + time.Since(cr.StoredAt)
+}
+
+// FreshnessLifetime returns how long this response is considered fresh,
+// per RFC 7234 §4.2.1: s-maxage (shared caches) takes precedence over
+// max-age, which takes precedence over Expires/Date.
+func (cr *CachedResponse) FreshnessLifetime() time.Duration {
+	if cr.Directives != nil {
+		if d, ok := cr.Directives.SMaxAgeDuration(); ok {
+			return d
+		}
+		if d, ok := cr.Directives.MaxAgeDuration(); ok {
+			return d
+		}
+	}
+
+	if exp := cr.Headers["Expires"]; exp != "" {
+		if expTime, err := http.ParseTime(exp); err == nil {
+			date := cr.Date
+			if date.IsZero() {
+				date = cr.StoredAt
+			}
+			if lifetime := expTime.Sub(date); lifetime > 0 {
+				return lifetime
+			}
+			return 0
+		}
+	}
+
+	return 0
+}
+
+// IsFresh reports whether the entry is still within its freshness lifetime.
+func (cr *CachedResponse) IsFresh() bool {
+	return cr.Age() < cr.FreshnessLifetime()
+}
+
+// IsUsableStale reports whether a stale entry can still be served under
+// stale-while-revalidate. Callers should trigger a background revalidation
+// when this returns true.
+func (cr *CachedResponse) IsUsableStale() bool {
+	if cr.IsFresh() || cr.Directives == nil {
+		return false
+	}
+	swr, ok := cr.Directives.StaleWhileRevalidateDuration()
+	if !ok {
+		return false
+	}
+	return cr.Age() < cr.FreshnessLifetime()+swr
+}
+
+// IsUsableStaleOnError reports whether a stale entry may be served in place
+// of an upstream error, per stale-if-error.
+func (cr *CachedResponse) IsUsableStaleOnError() bool {
+	if cr.Directives == nil {
+		return false
+	}
+	sie, ok := cr.Directives.StaleIfErrorDuration()
+	if !ok {
+		return false
+	}
+	return cr.Age() < cr.FreshnessLifetime()+sie
+}
+
+// RequiresRevalidation reports whether a stale entry must be revalidated
+// before being served, per must-revalidate/no-cache (RFC 7234 §5.2.2.1/.4).
+func (cr *CachedResponse) RequiresRevalidation() bool {
+	if cr.Directives == nil {
+		return !cr.IsFresh()
+	}
+	if cr.Directives.NoCache {
+		return true
+	}
+	if cr.Directives.MustRevalidate {
+		return !cr.IsFresh()
+	}
+	return !cr.IsFresh() && !cr.IsUsableStale()
+}
+
+// Get retrieves a cached response, including its Cache-Control metadata.
 func (cc *ConditionalCache) Get(key string) (*CachedResponse, error) {
-	// Try to get from underlying cache
 	data, err := cc.cache.Get(key)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate ETag from content
-	etag := generateETag(data)
+	var resp CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode cached response: %w", err)
+	}
 
-	// For now, use cache retrieval time as last modified
-	// In production, this should be stored with the cached entry
-	lastModified := time.Now()
+	if resp.ETag == "" {
+		resp.ETag = generateETag(resp.Body)
+	}
 
-	return &CachedResponse{
-		Body:         data,
-		ETag:         etag,
-		LastModified: lastModified,
-		Headers:      make(map[string]string),
-		StatusCode:   http.StatusOK,
-	}, nil
+	return &resp, nil
 }
 
-// Set stores a response with conditional request metadata
-func (cc *ConditionalCache) Set(key string, resp *CachedResponse) error {
-	// For now, just store the body
-	// In production, serialize the entire CachedResponse
-	return cc.cache.Set(key, resp.Body)
+// Set stores a response with its Cache-Control directives, honoring
+// no-store from either the request or the response according to mode: per
+// RFC 7234 §3 a cache MUST NOT store either side's no-store under
+// CacheModeDefault, but operators can relax or tighten that with
+// CacheModeBypass/CacheModeBypassRequest/CacheModeBypassResponse/CacheModeStrict.
+func (cc *ConditionalCache) Set(key string, resp *CachedResponse, reqDirectives *CacheControlDirectives, mode CacheMode) error {
+	if !shouldStore(mode, resp, reqDirectives) {
+		return nil
+	}
+
+	if resp.ETag == "" {
+		resp.ETag = generateETag(resp.Body)
+	}
+	if resp.StoredAt.IsZero() {
+		resp.StoredAt = time.Now()
+	}
+	if resp.Date.IsZero() {
+		resp.Date = resp.StoredAt
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("encode cached response: %w", err)
+	}
+
+	if err := cc.cache.Set(key, data); err != nil {
+		return err
+	}
+
+	if sk := resp.Headers["Surrogate-Key"]; sk != "" {
+		cc.tags.TagSet(key, strings.Fields(sk))
+	}
+
+	return nil
+}
+
+// Delete removes a key from the cache and the tag index.
+func (cc *ConditionalCache) Delete(key string) error {
+	if err := cc.cache.Delete(key); err != nil {
+		return err
+	}
+	cc.tags.Untag(key)
+	return nil
+}
+
+// ShouldRevalidate reports whether a cached entry must be revalidated
+// against the origin before being served as-is, given the configured
+// CacheMode. Callers should check this before WriteConditionalResponse and
+// fetch a fresh copy instead when it returns true.
+func (cc *ConditionalCache) ShouldRevalidate(mode CacheMode, cached *CachedResponse) bool {
+	return shouldForceRevalidate(mode, cached)
 }
 
 // CheckConditional checks if a request can be served with 304 Not Modified
@@ -89,7 +250,10 @@ func (cc *ConditionalCache) WriteConditionalResponse(w http.ResponseWriter, r *h
 	// Set ETag and Last-Modified headers
 	w.Header().Set("ETag", cached.ETag)
 	w.Header().Set("Last-Modified", cached.LastModified.Format(http.TimeFormat))
-	w.Header().Set("Cache-Control", "private, must-revalidate")
+	w.Header().Set("Age", strconv.Itoa(int(cached.Age().Seconds())))
+	if cached.RequiresRevalidation() {
+		w.Header().Set("Cache-Control", "private, must-revalidate")
+	}
 
 	// Check if we can send 304 Not Modified
 	if cc.CheckConditional(r, cached) {
@@ -140,15 +304,18 @@ func (e *StaleEntry) ShouldRevalidate() bool {
 
 // StaleWhileRevalidateCache implements stale-while-revalidate caching strategy
 type StaleWhileRevalidateCache struct {
-	cache           Cache
-	staleTTL        time.Duration // how long to serve stale content
-	revalidateFunc  func(key string) ([]byte, error)
-	revalidating    map[string]bool
-	revalidateChan  chan string
-	done            chan struct{}
+	cache          Cache
+	staleTTL       time.Duration // fallback stale window when an entry carries no Cache-Control directives
+	revalidateFunc func(key string) ([]byte, error)
+	revalidating   map[string]bool
+	mu             sync.Mutex
+	revalidateChan chan string
+	done           chan struct{}
 }
 
-// NewStaleWhileRevalidateCache creates a new SWR cache
+// NewStaleWhileRevalidateCache creates a new SWR cache. staleTTL is only a
+// fallback: entries with their own stale-while-revalidate directive use
+// that window instead.
 func NewStaleWhileRevalidateCache(cache Cache, staleTTL time.Duration, revalidateFunc func(string) ([]byte, error)) *StaleWhileRevalidateCache {
 	swrc := &StaleWhileRevalidateCache{
 		cache:          cache,
@@ -165,19 +332,37 @@ func NewStaleWhileRevalidateCache(cache Cache, staleTTL time.Duration, revalidat
 	return swrc
 }
 
-// Get retrieves from cache and triggers background revalidation if stale
+// Get retrieves from cache and triggers background revalidation if the
+// entry is stale but within its stale-while-revalidate window.
 func (swrc *StaleWhileRevalidateCache) Get(key string) ([]byte, bool, error) {
-	// Try to get from cache
 	data, err := swrc.cache.Get(key)
 	if err != nil {
 		return nil, false, err
 	}
 
-	// For now, we don't have expiry metadata, so we always return fresh
-	// In production, check if entry is stale and trigger revalidation
-	// This is a simplified implementation
+	var resp CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		// Not a CachedResponse (e.g. a raw value) - treat as always fresh.
+		return data, false, nil
+	}
 
-	return data, false, nil
+	if resp.IsFresh() {
+		return resp.Body, false, nil
+	}
+
+	if resp.IsUsableStale() {
+		swrc.TriggerRevalidation(key)
+		return resp.Body, true, nil
+	}
+
+	// Stale window derived per-entry has elapsed; fall back to the
+	// constructor-wide staleTTL only when the entry carries no directives.
+	if resp.Directives == nil && swrc.staleTTL > 0 && resp.Age() < resp.FreshnessLifetime()+swrc.staleTTL {
+		swrc.TriggerRevalidation(key)
+		return resp.Body, true, nil
+	}
+
+	return nil, false, fmt.Errorf("cache entry expired")
 }
 
 // TriggerRevalidation queues a key for background revalidation
@@ -217,13 +402,15 @@ func (swrc *StaleWhileRevalidateCache) revalidationWorker() {
 
 // isRevalidating checks if a key is currently being revalidated
 func (swrc *StaleWhileRevalidateCache) isRevalidating(key string) bool {
-	// In production, use proper locking
+	swrc.mu.Lock()
+	defer swrc.mu.Unlock()
 	return swrc.revalidating[key]
 }
 
 // setRevalidating sets the revalidating status for a key
 func (swrc *StaleWhileRevalidateCache) setRevalidating(key string, status bool) {
-	// In production, use proper locking
+	swrc.mu.Lock()
+	defer swrc.mu.Unlock()
 	swrc.revalidating[key] = status
 }
 
@@ -233,30 +420,131 @@ func (swrc *StaleWhileRevalidateCache) Close() error {
 	return nil
 }
 
-// CacheControlParser parses Cache-Control headers
+// CacheControlDirectives holds the parsed directives from a Cache-Control header.
 type CacheControlDirectives struct {
-	MaxAge           int
-	SMaxAge          int
+	MaxAge               int
+	SMaxAge              int
 	StaleWhileRevalidate int
-	StaleIfError     int
-	MustRevalidate   bool
-	NoCache          bool
-	NoStore          bool
-	Public           bool
-	Private          bool
+	StaleIfError         int
+	MustRevalidate       bool
+	NoCache              bool
+	NoStore              bool
+	Public               bool
+	Private              bool
+}
+
+// MaxAgeDuration returns the max-age directive as a Duration, if present.
+func (d *CacheControlDirectives) MaxAgeDuration() (time.Duration, bool) {
+	if d.MaxAge < 0 {
+		return 0, false
+	}
+	return time.Duration(d.MaxAge) * time.Second, true
+}
+
+// SMaxAgeDuration returns the s-maxage directive as a Duration, if present.
+func (d *CacheControlDirectives) SMaxAgeDuration() (time.Duration, bool) {
+	if d.SMaxAge < 0 {
+		return 0, false
+	}
+	return time.Duration(d.SMaxAge) * time.Second, true
+}
+
+// StaleWhileRevalidateDuration returns the stale-while-revalidate window, if present.
+func (d *CacheControlDirectives) StaleWhileRevalidateDuration() (time.Duration, bool) {
+	if d.StaleWhileRevalidate < 0 {
+		return 0, false
+	}
+	return time.Duration(d.StaleWhileRevalidate) * time.Second, true
+}
+
+// StaleIfErrorDuration returns the stale-if-error window, if present.
+func (d *CacheControlDirectives) StaleIfErrorDuration() (time.Duration, bool) {
+	if d.StaleIfError < 0 {
+		return 0, false
+	}
+	return time.Duration(d.StaleIfError) * time.Second, true
 }
 
-// ParseCacheControl parses a Cache-Control header value
+// ParseCacheControl parses a Cache-Control header value into directives.
+// The value is tokenized on commas with respect for quoted-string values
+// (e.g. private="X-Foo,X-Bar"), each token is mapped as name[=value], and
+// unknown tokens are treated as no-ops.
 func ParseCacheControl(header string) *CacheControlDirectives {
 	directives := &CacheControlDirectives{
-		MaxAge:  -1,
-		SMaxAge: -1,
+		MaxAge:               -1,
+		SMaxAge:              -1,
 		StaleWhileRevalidate: -1,
-		StaleIfError: -1,
+		StaleIfError:         -1,
 	}
 
-	// Simple parser - in production, use a proper HTTP header parser
-	// This is a placeholder implementation
+	for _, tok := range splitCacheControlTokens(header) {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+
+		name, value := tok, ""
+		if idx := strings.IndexByte(tok, '='); idx >= 0 {
+			name = tok[:idx]
+			value = strings.Trim(strings.TrimSpace(tok[idx+1:]), `"`)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		switch name {
+		case "max-age":
+			if n, err := strconv.Atoi(value); err == nil {
+				directives.MaxAge = n
+			}
+		case "s-maxage":
+			if n, err := strconv.Atoi(value); err == nil {
+				directives.SMaxAge = n
+			}
+		case "stale-while-revalidate":
+			if n, err := strconv.Atoi(value); err == nil {
+				directives.StaleWhileRevalidate = n
+			}
+		case "stale-if-error":
+			if n, err := strconv.Atoi(value); err == nil {
+				directives.StaleIfError = n
+			}
+		case "must-revalidate", "proxy-revalidate":
+			directives.MustRevalidate = true
+		case "no-cache":
+			directives.NoCache = true
+		case "no-store":
+			directives.NoStore = true
+		case "public":
+			directives.Public = true
+		case "private":
+			directives.Private = true
+		}
+	}
 
 	return directives
 }
+
+// splitCacheControlTokens splits a Cache-Control header value on commas,
+// ignoring commas that fall inside a quoted-string value.
+func splitCacheControlTokens(header string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuotes := false
+
+	for _, r := range header {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			cur.WriteRune(r)
+		case r == ',' && !inQuotes:
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+
+	return tokens
+}
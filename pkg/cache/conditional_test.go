@@ -0,0 +1,68 @@
+package cache
+
+import "testing"
+
+func TestParseCacheControlRoundTrip(t *testing.T) {
+	header := `max-age=300, s-maxage=60, stale-while-revalidate=30, stale-if-error=120, must-revalidate, no-cache, no-store, public, private`
+	d := ParseCacheControl(header)
+
+	if d.MaxAge != 300 {
+		t.Errorf("MaxAge = %d, want 300", d.MaxAge)
+	}
+	if d.SMaxAge != 60 {
+		t.Errorf("SMaxAge = %d, want 60", d.SMaxAge)
+	}
+	if d.StaleWhileRevalidate != 30 {
+		t.Errorf("StaleWhileRevalidate = %d, want 30", d.StaleWhileRevalidate)
+	}
+	if d.StaleIfError != 120 {
+		t.Errorf("StaleIfError = %d, want 120", d.StaleIfError)
+	}
+	if !d.MustRevalidate || !d.NoCache || !d.NoStore || !d.Public || !d.Private {
+		t.Errorf("expected all boolean directives set, got %+v", d)
+	}
+
+	if dur, ok := d.MaxAgeDuration(); !ok || dur.Seconds() != 300 {
+		t.Errorf("MaxAgeDuration() = %v, %v, want 300s, true", dur, ok)
+	}
+	if dur, ok := d.SMaxAgeDuration(); !ok || dur.Seconds() != 60 {
+		t.Errorf("SMaxAgeDuration() = %v, %v, want 60s, true", dur, ok)
+	}
+	if dur, ok := d.StaleWhileRevalidateDuration(); !ok || dur.Seconds() != 30 {
+		t.Errorf("StaleWhileRevalidateDuration() = %v, %v, want 30s, true", dur, ok)
+	}
+	if dur, ok := d.StaleIfErrorDuration(); !ok || dur.Seconds() != 120 {
+		t.Errorf("StaleIfErrorDuration() = %v, %v, want 120s, true", dur, ok)
+	}
+}
+
+func TestParseCacheControlAbsentDirectivesReportUnset(t *testing.T) {
+	d := ParseCacheControl("no-cache")
+
+	if _, ok := d.MaxAgeDuration(); ok {
+		t.Error("MaxAgeDuration should report unset when max-age is absent")
+	}
+	if _, ok := d.SMaxAgeDuration(); ok {
+		t.Error("SMaxAgeDuration should report unset when s-maxage is absent")
+	}
+	if _, ok := d.StaleWhileRevalidateDuration(); ok {
+		t.Error("StaleWhileRevalidateDuration should report unset when stale-while-revalidate is absent")
+	}
+	if _, ok := d.StaleIfErrorDuration(); ok {
+		t.Error("StaleIfErrorDuration should report unset when stale-if-error is absent")
+	}
+	if !d.NoCache {
+		t.Error("expected no-cache to be set")
+	}
+}
+
+func TestParseCacheControlQuotedPrivateFields(t *testing.T) {
+	d := ParseCacheControl(`private="X-Foo,X-Bar", max-age=10`)
+
+	if !d.Private {
+		t.Error("expected private to be set")
+	}
+	if d.MaxAge != 10 {
+		t.Errorf("MaxAge = %d, want 10 (comma inside quoted private value shouldn't split tokens)", d.MaxAge)
+	}
+}
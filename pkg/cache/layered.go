@@ -1,32 +1,148 @@
 package cache
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// CommitState describes where a write-back write stands relative to L2.
+type CommitState string
+
+const (
+	// CommitPending means the value is in L1 but hasn't reached L2 yet.
+	CommitPending CommitState = "pending"
+	// CommitCommitted means the value has been durably written to L2.
+	CommitCommitted CommitState = "committed"
+	// CommitFailed means every retry against L2 was exhausted without
+	// success; the value still lives in L1 only.
+	CommitFailed CommitState = "failed"
+)
+
+// defaultWriteBackWorkers, defaultWriteBackQueueSize, defaultWriteBackRetries
+// and defaultWriteBackBackoff are used when a WriteBackOptions field is left
+// at its zero value.
+const (
+	defaultWriteBackWorkers   = 4
+	defaultWriteBackQueueSize = 1024
+	defaultWriteBackRetries   = 3
+	defaultWriteBackBackoff   = 500 * time.Millisecond
+)
+
+// defaultCloseDrainTimeout bounds how long the zero-arg Close waits for
+// in-flight write-back commits to finish before giving up.
+const defaultCloseDrainTimeout = 5 * time.Second
+
+// WriteBackOptions configures LayeredCache's write-back mode, where Set
+// returns as soon as the value lands in L1 and the L2 write happens
+// asynchronously through a bounded worker pool.
+type WriteBackOptions struct {
+	// Enabled turns write-back mode on. When false, Set behaves exactly as
+	// it always has: L2 is written synchronously before L1.
+	Enabled bool
+	// Workers bounds how many goroutines drain the write-back queue
+	// concurrently. Defaults to defaultWriteBackWorkers.
+	Workers int
+	// QueueSize bounds how many pending L2 writes may be buffered before
+	// Set falls back to an inline commit. Defaults to
+	// defaultWriteBackQueueSize.
+	QueueSize int
+	// MaxRetries bounds how many times a failed L2 write is retried before
+	// the key is marked CommitFailed. Defaults to defaultWriteBackRetries.
+	MaxRetries int
+	// Backoff is the base delay between retries, doubled after each
+	// attempt. Defaults to defaultWriteBackBackoff.
+	Backoff time.Duration
+}
+
+// writeJob is one L2 write queued by a write-back Set.
+type writeJob struct {
+	key     string
+	value   []byte
+	attempt int
+}
+
 // LayeredCache implements a two-tier cache system:
 // - L1: Fast in-memory LRU cache (limited size, volatile)
 // - L2: Persistent database cache (larger, durable)
 // This provides optimal performance by keeping hot data in memory
 // while maintaining durability and larger capacity in the database.
+//
+// In write-back mode (see WriteBackOptions), Set only waits on L1; the L2
+// write happens on a background worker and its outcome can be inspected
+// with CommitStatus.
 type LayeredCache struct {
 	l1     *MemoryCache  // Fast memory cache
 	l2     Cache         // Persistent database cache
 	ttl    time.Duration // Default TTL
 	l1Miss int64         // L1 misses that hit L2
 	l2Miss int64         // Complete cache misses
+
+	writeBack  bool
+	queue      chan writeJob
+	maxRetries int
+	backoff    time.Duration
+	wg         sync.WaitGroup
+
+	commitMu    sync.Mutex
+	commitState map[string]CommitState
+	pending     int64 // atomic
+	failed      int64 // atomic
 }
 
-// NewLayeredCache creates a new layered cache
+// NewLayeredCache creates a new layered cache in the default (write-through)
+// mode, where Set only returns once the value has been durably written to
+// L2.
 func NewLayeredCache(dbCache Cache, memoryCacheSize int, ttl time.Duration) *LayeredCache {
-	return &LayeredCache{
-		l1:  NewMemoryCache(memoryCacheSize),
-		l2:  dbCache,
-		ttl: ttl,
+	return NewLayeredCacheWithOptions(dbCache, memoryCacheSize, ttl, WriteBackOptions{})
+}
+
+// NewLayeredCacheWithOptions creates a layered cache with write-back
+// behavior controlled by opts.
+func NewLayeredCacheWithOptions(dbCache Cache, memoryCacheSize int, ttl time.Duration, opts WriteBackOptions) *LayeredCache {
+	c := &LayeredCache{
+		l1:          NewMemoryCache(memoryCacheSize),
+		l2:          dbCache,
+		ttl:         ttl,
+		writeBack:   opts.Enabled,
+		commitState: make(map[string]CommitState),
+	}
+
+	if !opts.Enabled {
+		return c
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultWriteBackWorkers
+	}
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultWriteBackQueueSize
+	}
+	c.maxRetries = opts.MaxRetries
+	if c.maxRetries <= 0 {
+		c.maxRetries = defaultWriteBackRetries
 	}
+	c.backoff = opts.Backoff
+	if c.backoff <= 0 {
+		c.backoff = defaultWriteBackBackoff
+	}
+
+	c.queue = make(chan writeJob, queueSize)
+	for i := 0; i < workers; i++ {
+		c.wg.Add(1)
+		go c.commitWorker()
+	}
+
+	return c
 }
 
-// Get retrieves a value from the cache (L1 -> L2)
+// Get retrieves a value from the cache (L1 -> L2). In write-back mode this
+// doubles as the fast path for a key whose L2 commit is still in flight:
+// the value is already in L1, so it's returned without ever touching L2.
 func (c *LayeredCache) Get(key string) ([]byte, error) {
 	// Try L1 (memory cache) first
 	if value, err := c.l1.Get(key); err == nil {
@@ -47,10 +163,57 @@ func (c *LayeredCache) Get(key string) ([]byte, error) {
 	return value, nil
 }
 
-// Set stores a value in both cache layers
+// GetContext is like Get but aborts the L2 lookup if ctx is canceled
+// before it completes.
+func (c *LayeredCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if value, err := c.l1.Get(key); err == nil {
+		return value, nil
+	}
+
+	value, err := c.l2.GetContext(ctx, key)
+	if err != nil {
+		c.l2Miss++
+		return nil, err
+	}
+
+	c.l1Miss++
+	c.l1.Set(key, value, c.ttl)
+
+	return value, nil
+}
+
+// Set stores a value in L1, then either writes it through to L2
+// synchronously (default mode) or queues it for asynchronous commit
+// (write-back mode, where Set returns as soon as the L1 write completes).
 func (c *LayeredCache) Set(key string, value []byte) error {
+	return c.SetContext(context.Background(), key, value)
+}
+
+// SetContext is like Set but, in write-through mode, aborts the L2 write if
+// ctx is canceled first. In write-back mode ctx has no effect on the queued
+// L2 commit, since that commit runs on a background worker after Set has
+// already returned.
+func (c *LayeredCache) SetContext(ctx context.Context, key string, value []byte) error {
+	if c.writeBack {
+		if err := c.l1.Set(key, value, c.ttl); err != nil {
+			return err
+		}
+		c.setCommitState(key, CommitPending)
+		atomic.AddInt64(&c.pending, 1)
+
+		select {
+		case c.queue <- writeJob{key: key, value: value}:
+		default:
+			// Queue is full: apply backpressure by committing inline
+			// rather than dropping the write or blocking forever on a
+			// queue that a stalled L2 may never drain.
+			c.commit(writeJob{key: key, value: value})
+		}
+		return nil
+	}
+
 	// Store in L2 (persistent) first
-	if err := c.l2.Set(key, value); err != nil {
+	if err := c.l2.SetContext(ctx, key, value); err != nil {
 		return err
 	}
 
@@ -58,13 +221,64 @@ func (c *LayeredCache) Set(key string, value []byte) error {
 	return c.l1.Set(key, value, c.ttl)
 }
 
+// commitWorker drains the write-back queue until it's closed.
+func (c *LayeredCache) commitWorker() {
+	defer c.wg.Done()
+	for job := range c.queue {
+		c.commit(job)
+	}
+}
+
+// commit writes job to L2, retrying with exponential backoff up to
+// maxRetries times before giving up and marking the key CommitFailed.
+func (c *LayeredCache) commit(job writeJob) {
+	err := c.l2.Set(job.key, job.value)
+	if err == nil {
+		c.setCommitState(job.key, CommitCommitted)
+		atomic.AddInt64(&c.pending, -1)
+		return
+	}
+
+	if job.attempt >= c.maxRetries {
+		c.setCommitState(job.key, CommitFailed)
+		atomic.AddInt64(&c.pending, -1)
+		atomic.AddInt64(&c.failed, 1)
+		return
+	}
+
+	time.Sleep(c.backoff * time.Duration(1<<uint(job.attempt)))
+	job.attempt++
+	c.commit(job)
+}
+
+func (c *LayeredCache) setCommitState(key string, state CommitState) {
+	c.commitMu.Lock()
+	c.commitState[key] = state
+	c.commitMu.Unlock()
+}
+
+// CommitStatus reports the write-back commit state of key: "pending",
+// "committed", or "failed". It returns "" for a key that was never written
+// in write-back mode (including every key when write-back is disabled).
+func (c *LayeredCache) CommitStatus(key string) string {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+	return string(c.commitState[key])
+}
+
 // Delete removes a key from both cache layers
 func (c *LayeredCache) Delete(key string) error {
+	return c.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is like Delete but aborts the L2 delete if ctx is canceled
+// first.
+func (c *LayeredCache) DeleteContext(ctx context.Context, key string) error {
 	// Remove from L1
 	c.l1.Delete(key)
 
 	// Remove from L2
-	return c.l2.Delete(key)
+	return c.l2.DeleteContext(ctx, key)
 }
 
 // Stats returns combined statistics from both layers
@@ -85,16 +299,44 @@ func (c *LayeredCache) Stats() (*Stats, error) {
 	}
 
 	return &Stats{
-		Entries:   l2Stats.Entries,        // L2 is the source of truth for total entries
-		SizeBytes: l2Stats.SizeBytes,      // L2 size (L1 size is much smaller)
-		HitRate:   hitRate,                // Combined hit rate
-		Hits:      totalHits,              // L1 hits + L2 hits
-		Misses:    totalMisses,            // Complete misses
+		Entries:       l2Stats.Entries,   // L2 is the source of truth for total entries
+		SizeBytes:     l2Stats.SizeBytes, // L2 size (L1 size is much smaller)
+		HitRate:       hitRate,           // Combined hit rate
+		Hits:          totalHits,         // L1 hits + L2 hits
+		Misses:        totalMisses,       // Complete misses
+		PendingWrites: atomic.LoadInt64(&c.pending),
+		FailedWrites:  atomic.LoadInt64(&c.failed),
 	}, nil
 }
 
-// Close closes the underlying database cache
+// Close closes the underlying database cache, first draining any
+// in-flight write-back commits (bounded by defaultCloseDrainTimeout; see
+// CloseWithTimeout for what happens when the drain times out).
 func (c *LayeredCache) Close() error {
+	return c.CloseWithTimeout(defaultCloseDrainTimeout)
+}
+
+// CloseWithTimeout drains the write-back queue, waiting up to timeout for
+// outstanding commits to finish, before closing L2. If timeout elapses
+// first it returns an error and leaves L2 open; commits still pending are
+// abandoned (their keys remain CommitPending).
+func (c *LayeredCache) CloseWithTimeout(timeout time.Duration) error {
+	if c.writeBack {
+		close(c.queue)
+
+		drained := make(chan struct{})
+		go func() {
+			c.wg.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-time.After(timeout):
+			return fmt.Errorf("layered cache: timed out after %s draining write-back queue", timeout)
+		}
+	}
+
 	return c.l2.Close()
 }
 
@@ -120,3 +362,15 @@ func (c *LayeredCache) GetL1Stats() *Stats {
 func (c *LayeredCache) ClearL1() {
 	c.l1.Clear()
 }
+
+// CompactL1 removes expired entries from the L1 memory cache only, leaving
+// L2 untouched, and reports how many entries were removed and how many
+// bytes that reclaimed - used by daemon.Scheduler's lru_compaction job,
+// kept distinct from CleanupExpired so the two can run on independent
+// schedules.
+func (c *LayeredCache) CompactL1() (removed int, bytesReclaimed int64) {
+	before := c.l1.Stats()
+	removed = c.l1.CleanupExpired()
+	after := c.l1.Stats()
+	return removed, before.SizeBytes - after.SizeBytes
+}
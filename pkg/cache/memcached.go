@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemcachedOptions configures the memcached cache backend, the typed
+// options block behind `cache.backend: memcached` in
+// config.CacheConfig.Memcached.
+type MemcachedOptions struct {
+	// Addr is "host:port". Required.
+	Addr string
+	// DialTimeout bounds the initial connection; zero means 5 seconds.
+	DialTimeout time.Duration
+	// TTL is the default expiry for Set, used when the caller doesn't go
+	// through TTLSetter.SetTTL; zero means 24 hours, matching the other
+	// backends' default.
+	TTL time.Duration
+}
+
+// MemcachedCache is a Cache backed by a single memcached connection,
+// speaking the classic ASCII protocol directly instead of pulling in a
+// client library - the same tradeoff RedisCache makes for Redis.
+type MemcachedCache struct {
+	mu   sync.Mutex
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	ttl  time.Duration
+}
+
+// NewMemcachedCache dials opts.Addr and returns a ready-to-use
+// MemcachedCache.
+func NewMemcachedCache(opts MemcachedOptions) (*MemcachedCache, error) {
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("memcached cache: addr is required")
+	}
+	dialTimeout := opts.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = 5 * time.Second
+	}
+
+	conn, err := net.DialTimeout("tcp", opts.Addr, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("memcached cache: dial %s: %w", opts.Addr, err)
+	}
+
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	return &MemcachedCache{
+		conn: conn,
+		rw:   bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
+		ttl:  ttl,
+	}, nil
+}
+
+func (c *MemcachedCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.rw, "get %s\r\n", key); err != nil {
+		return nil, err
+	}
+	if err := c.rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	header, err := c.rw.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	header = strings.TrimRight(header, "\r\n")
+	if header == "END" {
+		return nil, fmt.Errorf("cache miss")
+	}
+
+	// "VALUE <key> <flags> <bytes>"
+	fields := strings.Fields(header)
+	if len(fields) != 4 || fields[0] != "VALUE" {
+		return nil, fmt.Errorf("memcached cache: unexpected reply %q", header)
+	}
+	n, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("memcached cache: malformed VALUE length: %w", err)
+	}
+
+	buf := make([]byte, n+2) // value + trailing \r\n
+	if _, err := readFullBuf(c.rw.Reader, buf); err != nil {
+		return nil, err
+	}
+	if _, err := c.rw.ReadString('\n'); err != nil { // consume trailing "END\r\n"
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func readFullBuf(r *bufio.Reader, buf []byte) (int, error) {
+	total := 0
+	for total < len(buf) {
+		n, err := r.Read(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (c *MemcachedCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return c.Get(key)
+}
+
+func (c *MemcachedCache) Set(key string, value []byte) error {
+	return c.SetTTL(key, value, c.ttl)
+}
+
+func (c *MemcachedCache) SetContext(ctx context.Context, key string, value []byte) error {
+	return c.Set(key, value)
+}
+
+// SetTTL is like Set but stores the entry under ttl instead of the
+// cache's configured default, implementing cache.TTLSetter.
+func (c *MemcachedCache) SetTTL(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seconds := int(ttl.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	if _, err := fmt.Fprintf(c.rw, "set %s 0 %d %d\r\n%s\r\n", key, seconds, len(value), value); err != nil {
+		return err
+	}
+	if err := c.rw.Flush(); err != nil {
+		return err
+	}
+
+	reply, err := c.rw.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	reply = strings.TrimRight(reply, "\r\n")
+	if reply != "STORED" {
+		return fmt.Errorf("memcached cache: set failed: %s", reply)
+	}
+	return nil
+}
+
+func (c *MemcachedCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.rw, "delete %s\r\n", key); err != nil {
+		return err
+	}
+	if err := c.rw.Flush(); err != nil {
+		return err
+	}
+
+	reply, err := c.rw.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	reply = strings.TrimRight(reply, "\r\n")
+	if reply != "DELETED" && reply != "NOT_FOUND" {
+		return fmt.Errorf("memcached cache: delete failed: %s", reply)
+	}
+	return nil
+}
+
+func (c *MemcachedCache) DeleteContext(ctx context.Context, key string) error {
+	return c.Delete(key)
+}
+
+// Stats is unsupported: parsing memcached's "stats" command output into
+// this package's Stats shape isn't worth it for a cache backend, so every
+// field is left at zero.
+func (c *MemcachedCache) Stats() (*Stats, error) {
+	return &Stats{}, nil
+}
+
+func (c *MemcachedCache) Close() error {
+	return c.conn.Close()
+}
@@ -116,6 +116,21 @@ func (m *MemoryCache) Delete(key string) error {
 	return nil
 }
 
+// InvalidateRemote removes a key from the memory cache on behalf of a peer
+// node's invalidation broadcast rather than a local caller. It is
+// functionally identical to Delete but intentionally does not bump any
+// local hit/miss/eviction counters, since the entry was never actually
+// requested on this node - counting it here would skew this node's own
+// cache-effectiveness stats with another node's activity.
+func (m *MemoryCache) InvalidateRemote(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, exists := m.items[key]; exists {
+		m.removeElement(elem)
+	}
+}
+
 // Clear removes all entries from the cache
 func (m *MemoryCache) Clear() {
 	m.mu.Lock()
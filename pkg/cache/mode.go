@@ -0,0 +1,105 @@
+package cache
+
+import "strings"
+
+// CacheMode controls how aggressively Cache-Control directives from the
+// client and the upstream are honored by ConditionalCache. This lets
+// apiproxyd act as a usable reverse cache in front of upstreams that send
+// overly conservative (or simply wrong) Cache-Control headers.
+type CacheMode string
+
+const (
+	// CacheModeDefault honors RFC 7234 on both the request and response side.
+	CacheModeDefault CacheMode = "default"
+	// CacheModeBypass ignores Cache-Control entirely and caches unconditionally
+	// per the configured TTL, regardless of what either side says.
+	CacheModeBypass CacheMode = "bypass"
+	// CacheModeBypassRequest ignores client-sent directives (no-cache/no-store)
+	// but still honors the origin's response directives.
+	CacheModeBypassRequest CacheMode = "bypass_request"
+	// CacheModeBypassResponse caches unconditionally even if the origin sends
+	// no-store, but still honors client-sent directives.
+	CacheModeBypassResponse CacheMode = "bypass_response"
+	// CacheModeStrict only caches when both the request and the response
+	// explicitly allow it (response must be public/cacheable, request must
+	// not forbid storage).
+	CacheModeStrict CacheMode = "strict"
+)
+
+// CacheModeHeader is the request header operators can use to override the
+// configured cache mode for a single request, gated by an allowlist.
+const CacheModeHeader = "X-Cache-Mode"
+
+// ParseCacheMode parses a cache mode string, falling back to
+// CacheModeDefault for empty or unrecognized values.
+func ParseCacheMode(s string) CacheMode {
+	switch CacheMode(strings.ToLower(strings.TrimSpace(s))) {
+	case CacheModeBypass:
+		return CacheModeBypass
+	case CacheModeBypassRequest:
+		return CacheModeBypassRequest
+	case CacheModeBypassResponse:
+		return CacheModeBypassResponse
+	case CacheModeStrict:
+		return CacheModeStrict
+	default:
+		return CacheModeDefault
+	}
+}
+
+// ResolveCacheMode determines the effective cache mode for a request: a
+// client-supplied X-Cache-Mode header wins over the configured default, but
+// only when its value appears in allowedOverrides. An empty allowedOverrides
+// disables header-based overrides entirely.
+func ResolveCacheMode(configured CacheMode, headerValue string, allowedOverrides []string) CacheMode {
+	headerValue = strings.TrimSpace(headerValue)
+	if headerValue == "" || len(allowedOverrides) == 0 {
+		return configured
+	}
+
+	requested := ParseCacheMode(headerValue)
+	for _, allowed := range allowedOverrides {
+		if ParseCacheMode(allowed) == requested {
+			return requested
+		}
+	}
+
+	return configured
+}
+
+// shouldStore reports whether a response may be written to the cache under
+// the given mode, combining the request's and response's no-store/public
+// signals as described by each mode.
+func shouldStore(mode CacheMode, resp *CachedResponse, reqDirectives *CacheControlDirectives) bool {
+	respNoStore := resp.Directives != nil && resp.Directives.NoStore
+	reqNoStore := reqDirectives != nil && reqDirectives.NoStore
+
+	switch mode {
+	case CacheModeBypass:
+		return true
+	case CacheModeBypassRequest:
+		return !respNoStore
+	case CacheModeBypassResponse:
+		return !reqNoStore
+	case CacheModeStrict:
+		if reqNoStore || respNoStore {
+			return false
+		}
+		return resp.Directives != nil && (resp.Directives.Public || resp.Directives.MaxAge >= 0 || resp.Directives.SMaxAge >= 0)
+	default: // CacheModeDefault
+		return !reqNoStore && !respNoStore
+	}
+}
+
+// shouldForceRevalidate reports whether a fresh-looking entry must still be
+// revalidated before being served, per the configured mode.
+func shouldForceRevalidate(mode CacheMode, resp *CachedResponse) bool {
+	switch mode {
+	case CacheModeBypass, CacheModeBypassResponse:
+		return false
+	case CacheModeStrict:
+		return resp.RequiresRevalidation()
+	default: // CacheModeDefault, CacheModeBypassRequest
+		return resp.Directives != nil && (resp.Directives.NoCache || resp.Directives.MustRevalidate) && !resp.IsFresh()
+	}
+}
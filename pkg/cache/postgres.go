@@ -1,18 +1,55 @@
 package cache
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// adaptiveTTLConfig holds the optional LFU-style promotion settings: a hit
+// extends expires_at by the cache's configured ttl, capped so the entry
+// never lives past created_at+Ceiling. Disabled (Ceiling == 0) by default.
+type adaptiveTTLConfig struct {
+	Ceiling time.Duration
+}
+
 type PostgresCache struct {
 	db  *sql.DB
 	dsn string
 	ttl time.Duration
+
+	adaptiveTTL adaptiveTTLConfig
+
+	// negativeCache controls whether SetEntry stores non-2xx responses and
+	// for how long. Zero value (ResponseCachePositive) caches only 2xx
+	// entries.
+	negativeCache NegativeCacheConfig
+
+	// hits/misses are in-process totals seeded from apiproxy_cache_stats at
+	// construction time and reported verbatim by Stats(). pendingHits/
+	// pendingMisses track the delta since the last flush to that table, so a
+	// restart only loses at most flushInterval worth of history.
+	hits          int64
+	misses        int64
+	pendingHits   int64
+	pendingMisses int64
+
+	statsFlushDone chan struct{}
+	statsFlushWg   sync.WaitGroup
+
+	// stmts caches prepared statements keyed by their query text, so a hot
+	// Set/SetEntry/Delete doesn't make Postgres re-parse and re-plan the
+	// same SQL on every call. Prepared lazily by preparedStmt.
+	stmtMu sync.RWMutex
+	stmts  map[string]*sql.Stmt
 }
 
 func NewPostgres(dsn string) (*PostgresCache, error) {
@@ -27,8 +64,8 @@ func NewPostgres(dsn string) (*PostgresCache, error) {
 
 	// Configure connection pool for PostgreSQL
 	// PostgreSQL handles concurrency well, so we can have more connections
-	db.SetMaxOpenConns(25)       // Max concurrent connections
-	db.SetMaxIdleConns(5)        // Keep connections warm for reuse
+	db.SetMaxOpenConns(25)                 // Max concurrent connections
+	db.SetMaxIdleConns(5)                  // Keep connections warm for reuse
 	db.SetConnMaxLifetime(5 * time.Minute) // Recycle connections periodically
 	db.SetConnMaxIdleTime(1 * time.Minute) // Close idle connections
 
@@ -44,11 +81,18 @@ func NewPostgres(dsn string) (*PostgresCache, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
-	return &PostgresCache{
-		db:  db,
-		dsn: dsn,
-		ttl: 24 * time.Hour,
-	}, nil
+	c := &PostgresCache{
+		db:    db,
+		dsn:   dsn,
+		ttl:   24 * time.Hour,
+		stmts: make(map[string]*sql.Stmt),
+	}
+	if err := c.initStats(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return c, nil
 }
 
 // NewPostgresWithConfig creates a Postgres cache with custom connection pool settings
@@ -80,11 +124,18 @@ func NewPostgresWithConfig(dsn string, maxOpen, maxIdle int, maxLifetime, maxIdl
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
-	return &PostgresCache{
-		db:  db,
-		dsn: dsn,
-		ttl: 24 * time.Hour,
-	}, nil
+	c := &PostgresCache{
+		db:    db,
+		dsn:   dsn,
+		ttl:   24 * time.Hour,
+		stmts: make(map[string]*sql.Stmt),
+	}
+	if err := c.initStats(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return c, nil
 }
 
 func initPostgresSchema(db *sql.DB) error {
@@ -98,29 +149,135 @@ func initPostgresSchema(db *sql.DB) error {
 		status_code INTEGER,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		expires_at TIMESTAMP NOT NULL,
-		metadata JSONB
+		metadata JSONB,
+		access_count BIGINT NOT NULL DEFAULT 0,
+		negative_cache BOOLEAN NOT NULL DEFAULT FALSE
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_apiproxy_cache_expires_at ON apiproxy_cache(expires_at);
 	CREATE INDEX IF NOT EXISTS idx_apiproxy_cache_path ON apiproxy_cache(path);
 	CREATE INDEX IF NOT EXISTS idx_apiproxy_cache_created_at ON apiproxy_cache(created_at);
+
+	ALTER TABLE apiproxy_cache ADD COLUMN IF NOT EXISTS access_count BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE apiproxy_cache ADD COLUMN IF NOT EXISTS negative_cache BOOLEAN NOT NULL DEFAULT FALSE;
+
+	CREATE TABLE IF NOT EXISTS apiproxy_cache_stats (
+		id SMALLINT PRIMARY KEY DEFAULT 1,
+		hits BIGINT NOT NULL DEFAULT 0,
+		misses BIGINT NOT NULL DEFAULT 0,
+		CHECK (id = 1)
+	);
+
+	INSERT INTO apiproxy_cache_stats (id, hits, misses) VALUES (1, 0, 0)
+	ON CONFLICT (id) DO NOTHING;
 	`
 
 	_, err := db.Exec(schema)
 	return err
 }
 
+// initStats seeds the in-process hit/miss totals from the persisted
+// apiproxy_cache_stats row and starts the background flusher that writes
+// new activity back periodically, so a restart only loses unflushed deltas
+// rather than the whole history.
+func (c *PostgresCache) initStats() error {
+	var hits, misses int64
+	err := c.db.QueryRow("SELECT hits, misses FROM apiproxy_cache_stats WHERE id = 1").Scan(&hits, &misses)
+	if err != nil {
+		return fmt.Errorf("failed to load cache stats: %w", err)
+	}
+
+	atomic.StoreInt64(&c.hits, hits)
+	atomic.StoreInt64(&c.misses, misses)
+
+	c.statsFlushDone = make(chan struct{})
+	c.statsFlushWg.Add(1)
+	go c.statsFlusher()
+
+	return nil
+}
+
+// EnableAdaptiveTTL turns on LFU-style TTL promotion: each cache hit
+// extends the entry's expires_at by the cache's configured ttl, capped so
+// it never lives past its original creation time plus ceiling. Frequently
+// read keys stay warm; keys that stop being read still expire on schedule.
+func (c *PostgresCache) EnableAdaptiveTTL(ceiling time.Duration) {
+	c.adaptiveTTL.Ceiling = ceiling
+}
+
+// SetNegativeCacheConfig installs cfg, controlling whether SetEntry stores
+// non-2xx responses and for how long. The zero value caches only
+// successful responses.
+func (c *PostgresCache) SetNegativeCacheConfig(cfg NegativeCacheConfig) {
+	c.negativeCache = cfg
+}
+
+func (c *PostgresCache) statsFlusher() {
+	defer c.statsFlushWg.Done()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flushStats()
+		case <-c.statsFlushDone:
+			c.flushStats()
+			return
+		}
+	}
+}
+
+func (c *PostgresCache) flushStats() {
+	hitsDelta := atomic.SwapInt64(&c.pendingHits, 0)
+	missesDelta := atomic.SwapInt64(&c.pendingMisses, 0)
+	if hitsDelta == 0 && missesDelta == 0 {
+		return
+	}
+
+	_, err := c.db.Exec(`
+		UPDATE apiproxy_cache_stats SET hits = hits + $1, misses = misses + $2 WHERE id = 1
+	`, hitsDelta, missesDelta)
+	if err != nil {
+		// Best-effort persistence: put the delta back so the next flush
+		// (or Close) retries it instead of silently losing the count.
+		atomic.AddInt64(&c.pendingHits, hitsDelta)
+		atomic.AddInt64(&c.pendingMisses, missesDelta)
+	}
+}
+
+func (c *PostgresCache) recordHit() {
+	atomic.AddInt64(&c.hits, 1)
+	atomic.AddInt64(&c.pendingHits, 1)
+}
+
+func (c *PostgresCache) recordMiss() {
+	atomic.AddInt64(&c.misses, 1)
+	atomic.AddInt64(&c.pendingMisses, 1)
+}
+
+// Get is GetContext with context.Background().
 func (c *PostgresCache) Get(key string) ([]byte, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// GetContext is like Get but aborts the query (and any in-flight TTL
+// promotion) if ctx is canceled before it completes, instead of leaking the
+// goroutine and connection until Postgres eventually finishes on its own.
+func (c *PostgresCache) GetContext(ctx context.Context, key string) ([]byte, error) {
 	var value []byte
-	var expiresAt time.Time
+	var expiresAt, createdAt time.Time
+	var negative bool
 
-	err := c.db.QueryRow(`
-		SELECT value, expires_at
+	err := c.db.QueryRowContext(ctx, `
+		SELECT value, expires_at, created_at, negative_cache
 		FROM apiproxy_cache
 		WHERE key = $1
-	`, key).Scan(&value, &expiresAt)
+	`, key).Scan(&value, &expiresAt, &createdAt, &negative)
 
 	if err == sql.ErrNoRows {
+		c.recordMiss()
 		return nil, fmt.Errorf("cache miss")
 	}
 	if err != nil {
@@ -129,38 +286,349 @@ func (c *PostgresCache) Get(key string) ([]byte, error) {
 
 	// Check if expired
 	if time.Now().After(expiresAt) {
-		c.Delete(key)
+		if negative && c.negativeCache.Mode == ResponseCacheBoth && c.negativeCache.ServeStaleOnRefresh {
+			c.recordHit()
+			if c.negativeCache.OnStaleNegative != nil {
+				go c.negativeCache.OnStaleNegative(key)
+			}
+			return value, nil
+		}
+
+		c.DeleteContext(ctx, key)
+		c.recordMiss()
 		return nil, fmt.Errorf("cache expired")
 	}
 
+	c.recordHit()
+	c.bumpAccess(ctx, key, createdAt, expiresAt)
+
 	return value, nil
 }
 
+// bumpAccess increments access_count for key and, when AdaptiveTTL is
+// enabled, extends expires_at by the cache's ttl up to created_at+Ceiling.
+// Best-effort: a failure here doesn't fail the Get that triggered it.
+func (c *PostgresCache) bumpAccess(ctx context.Context, key string, createdAt, expiresAt time.Time) {
+	if c.adaptiveTTL.Ceiling <= 0 {
+		c.db.ExecContext(ctx, "UPDATE apiproxy_cache SET access_count = access_count + 1 WHERE key = $1", key)
+		return
+	}
+
+	newExpiresAt := expiresAt.Add(c.ttl)
+	if ceiling := createdAt.Add(c.adaptiveTTL.Ceiling); newExpiresAt.After(ceiling) {
+		newExpiresAt = ceiling
+	}
+	if newExpiresAt.Before(expiresAt) {
+		newExpiresAt = expiresAt
+	}
+
+	c.db.ExecContext(ctx, `
+		UPDATE apiproxy_cache SET access_count = access_count + 1, expires_at = $2 WHERE key = $1
+	`, key, newExpiresAt)
+}
+
+// preparedStmt returns the cached prepared statement for query, preparing
+// it against c.db on first use. Keying by the query text (rather than, say,
+// a fixed enum of operations) means a new call site just works by passing
+// its own SQL - no separate registration step.
+func (c *PostgresCache) preparedStmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.stmtMu.RLock()
+	stmt, ok := c.stmts[query]
+	c.stmtMu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	c.stmtMu.Lock()
+	defer c.stmtMu.Unlock()
+
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// Set is SetContext with context.Background().
 func (c *PostgresCache) Set(key string, value []byte) error {
-	expiresAt := time.Now().Add(c.ttl)
+	return c.SetContext(context.Background(), key, value)
+}
 
-	_, err := c.db.Exec(`
-		INSERT INTO apiproxy_cache (key, value, method, path, expires_at)
-		VALUES ($1, $2, 'UNKNOWN', 'UNKNOWN', $3)
-		ON CONFLICT (key) DO UPDATE SET
-			value = EXCLUDED.value,
-			expires_at = EXCLUDED.expires_at
-	`, key, value, expiresAt)
+const setQuery = `
+	INSERT INTO apiproxy_cache (key, value, method, path, expires_at)
+	VALUES ($1, $2, 'UNKNOWN', 'UNKNOWN', $3)
+	ON CONFLICT (key) DO UPDATE SET
+		value = EXCLUDED.value,
+		expires_at = EXCLUDED.expires_at
+`
+
+// SetContext is like Set but aborts the write if ctx is canceled first.
+func (c *PostgresCache) SetContext(ctx context.Context, key string, value []byte) error {
+	expiresAt := time.Now().Add(c.ttl)
 
+	stmt, err := c.preparedStmt(ctx, setQuery)
 	if err != nil {
+		return err
+	}
+
+	if _, err := stmt.ExecContext(ctx, key, value, expiresAt); err != nil {
 		return fmt.Errorf("failed to set cache entry: %w", err)
 	}
 
 	return nil
 }
 
+// SetEntry is SetEntryContext with context.Background().
 func (c *PostgresCache) SetEntry(entry *Entry) error {
+	return c.SetEntryContext(context.Background(), entry)
+}
+
+const setEntryQuery = `
+	INSERT INTO apiproxy_cache
+	(key, value, method, path, request_body, status_code, created_at, expires_at, metadata, negative_cache)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (key) DO UPDATE SET
+		value = EXCLUDED.value,
+		method = EXCLUDED.method,
+		path = EXCLUDED.path,
+		request_body = EXCLUDED.request_body,
+		status_code = EXCLUDED.status_code,
+		expires_at = EXCLUDED.expires_at,
+		metadata = EXCLUDED.metadata,
+		negative_cache = EXCLUDED.negative_cache
+`
+
+// SetEntryContext is like SetEntry but aborts the write if ctx is canceled
+// first. If entry.StatusCode is not a 2xx, the negative cache policy
+// installed via SetNegativeCacheConfig decides entry's TTL - or whether it's
+// stored at all, since an unconfigured status code (a 5xx by default) is
+// skipped rather than cached with entry.ExpiresAt.
+func (c *PostgresCache) SetEntryContext(ctx context.Context, entry *Entry) error {
+	expiresAt, negative, skip := c.resolveNegativeCache(entry)
+	if skip {
+		return nil
+	}
+
 	metadata, _ := json.Marshal(entry.Metadata)
 
-	_, err := c.db.Exec(`
-		INSERT INTO apiproxy_cache
-		(key, value, method, path, request_body, status_code, created_at, expires_at, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	stmt, err := c.preparedStmt(ctx, setEntryQuery)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.ExecContext(ctx, entry.Key, entry.Value, entry.Method, entry.Path, entry.RequestBody,
+		entry.StatusCode, entry.CreatedAt, expiresAt, metadata, negative)
+
+	if err != nil {
+		return fmt.Errorf("failed to set cache entry: %w", err)
+	}
+
+	if negative {
+		c.enforceNegativeCacheLimit(ctx)
+	}
+
+	return nil
+}
+
+// resolveNegativeCache decides, for a call to SetEntryContext, what
+// expires_at to store and whether the entry is a negative (non-2xx) one -
+// or whether it should be skipped entirely because the negative cache
+// policy doesn't cover this status code. 2xx responses, and all responses
+// under ResponseCacheOff, pass through with the caller-supplied
+// entry.ExpiresAt unchanged; non-2xx responses under ResponseCachePositive
+// (or an empty Mode) are skipped.
+func (c *PostgresCache) resolveNegativeCache(entry *Entry) (expiresAt time.Time, negative bool, skip bool) {
+	if isSuccessStatus(entry.StatusCode) || c.negativeCache.Mode == ResponseCacheOff {
+		return entry.ExpiresAt, false, false
+	}
+	if c.negativeCache.Mode != ResponseCacheBoth {
+		return time.Time{}, false, true
+	}
+
+	ttl, ok := c.negativeCacheTTL(entry)
+	if !ok {
+		return time.Time{}, false, true
+	}
+
+	return time.Now().Add(ttl), true, false
+}
+
+// negativeCacheTTL returns how long to cache entry based on its status
+// code. A 429 honors a Retry-After value carried in entry.Metadata when
+// present; every other status code falls back to NegativeCacheConfig.
+// StatusTTL. ok is false when the status code isn't configured to be
+// cached at all.
+func (c *PostgresCache) negativeCacheTTL(entry *Entry) (ttl time.Duration, ok bool) {
+	if entry.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, ok := parseRetryAfter(entry.Metadata["Retry-After"]); ok {
+			return retryAfter, true
+		}
+	}
+
+	ttl, ok = c.negativeCache.StatusTTL[entry.StatusCode]
+	return ttl, ok
+}
+
+// enforceNegativeCacheLimit evicts the oldest negative-cache entries beyond
+// NegativeCacheConfig.MaxSize. Best-effort, like bumpAccess: a failure here
+// doesn't fail the SetEntryContext call that triggered it.
+func (c *PostgresCache) enforceNegativeCacheLimit(ctx context.Context) {
+	if c.negativeCache.MaxSize <= 0 {
+		return
+	}
+
+	c.db.ExecContext(ctx, `
+		DELETE FROM apiproxy_cache WHERE key IN (
+			SELECT key FROM apiproxy_cache
+			WHERE negative_cache = true
+			ORDER BY created_at DESC
+			OFFSET $1
+		)
+	`, c.negativeCache.MaxSize)
+}
+
+// isSuccessStatus reports whether code is a 2xx response.
+func isSuccessStatus(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// parseRetryAfter parses an HTTP Retry-After value, which per RFC 9110 is
+// either a number of seconds or an HTTP-date. ok is false for an empty,
+// malformed, or already-past value.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := time.Parse(http.TimeFormat, value); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// SetEntryWithTTL stores entry like SetEntry but with an explicit ttl
+// instead of the cache's configured default, resetting access_count so
+// adaptive TTL promotion starts fresh for the new value.
+func (c *PostgresCache) SetEntryWithTTL(entry *Entry, ttl time.Duration) error {
+	return c.SetEntryWithTTLContext(context.Background(), entry, ttl)
+}
+
+const setEntryWithTTLQuery = `
+	INSERT INTO apiproxy_cache
+	(key, value, method, path, request_body, status_code, created_at, expires_at, metadata, access_count)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 0)
+	ON CONFLICT (key) DO UPDATE SET
+		value = EXCLUDED.value,
+		method = EXCLUDED.method,
+		path = EXCLUDED.path,
+		request_body = EXCLUDED.request_body,
+		status_code = EXCLUDED.status_code,
+		expires_at = EXCLUDED.expires_at,
+		metadata = EXCLUDED.metadata,
+		access_count = 0
+`
+
+// SetEntryWithTTLContext is like SetEntryWithTTL but aborts the write if
+// ctx is canceled first.
+func (c *PostgresCache) SetEntryWithTTLContext(ctx context.Context, entry *Entry, ttl time.Duration) error {
+	metadata, _ := json.Marshal(entry.Metadata)
+	expiresAt := time.Now().Add(ttl)
+
+	stmt, err := c.preparedStmt(ctx, setEntryWithTTLQuery)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.ExecContext(ctx, entry.Key, entry.Value, entry.Method, entry.Path, entry.RequestBody,
+		entry.StatusCode, entry.CreatedAt, expiresAt, metadata)
+
+	if err != nil {
+		return fmt.Errorf("failed to set cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// SetEntryBatch is SetEntryBatchContext with context.Background().
+func (c *PostgresCache) SetEntryBatch(entries []*Entry) error {
+	return c.SetEntryBatchContext(context.Background(), entries)
+}
+
+// SetEntryBatchContext bulk-upserts entries in a single round trip using
+// pq.CopyIn against a temporary staging table, then merges the staging
+// table into apiproxy_cache with one ON CONFLICT DO UPDATE. This is orders
+// of magnitude faster than calling SetEntryContext per entry when warming
+// the cache from a replay log or another backend, since COPY avoids the
+// per-row parse/plan/round-trip cost a series of individual INSERTs pays.
+// CopyIn itself can't express upsert semantics, hence the staging table.
+func (c *PostgresCache) SetEntryBatchContext(ctx context.Context, entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin batch transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const stagingTable = "apiproxy_cache_batch_staging"
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TEMP TABLE %s (
+			key TEXT,
+			value BYTEA,
+			method TEXT,
+			path TEXT,
+			request_body TEXT,
+			status_code INTEGER,
+			created_at TIMESTAMP,
+			expires_at TIMESTAMP,
+			metadata JSONB
+		) ON COMMIT DROP
+	`, stagingTable))
+	if err != nil {
+		return fmt.Errorf("failed to create batch staging table: %w", err)
+	}
+
+	copyStmt, err := tx.PrepareContext(ctx, pq.CopyIn(stagingTable,
+		"key", "value", "method", "path", "request_body", "status_code", "created_at", "expires_at", "metadata"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare COPY: %w", err)
+	}
+
+	for _, entry := range entries {
+		metadata, _ := json.Marshal(entry.Metadata)
+		if _, err := copyStmt.ExecContext(ctx, entry.Key, entry.Value, entry.Method, entry.Path,
+			entry.RequestBody, entry.StatusCode, entry.CreatedAt, entry.ExpiresAt, metadata); err != nil {
+			copyStmt.Close()
+			return fmt.Errorf("failed to copy entry %q into staging table: %w", entry.Key, err)
+		}
+	}
+
+	if _, err := copyStmt.ExecContext(ctx); err != nil {
+		copyStmt.Close()
+		return fmt.Errorf("failed to flush COPY: %w", err)
+	}
+	if err := copyStmt.Close(); err != nil {
+		return fmt.Errorf("failed to close COPY statement: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO apiproxy_cache (key, value, method, path, request_body, status_code, created_at, expires_at, metadata)
+		SELECT key, value, method, path, request_body, status_code, COALESCE(created_at, now()), expires_at, metadata
+		FROM %s
 		ON CONFLICT (key) DO UPDATE SET
 			value = EXCLUDED.value,
 			method = EXCLUDED.method,
@@ -169,18 +637,30 @@ func (c *PostgresCache) SetEntry(entry *Entry) error {
 			status_code = EXCLUDED.status_code,
 			expires_at = EXCLUDED.expires_at,
 			metadata = EXCLUDED.metadata
-	`, entry.Key, entry.Value, entry.Method, entry.Path, entry.RequestBody,
-		entry.StatusCode, entry.CreatedAt, entry.ExpiresAt, metadata)
-
+	`, stagingTable))
 	if err != nil {
-		return fmt.Errorf("failed to set cache entry: %w", err)
+		return fmt.Errorf("failed to merge batch into cache: %w", err)
 	}
 
-	return nil
+	return tx.Commit()
 }
 
+// DB returns the underlying connection pool, for callers that need to run
+// their own queries against the same Postgres instance (e.g.
+// client.NewPostgresLockBackend for distributed singleflight locking).
+func (c *PostgresCache) DB() *sql.DB {
+	return c.db
+}
+
+// Delete is DeleteContext with context.Background().
 func (c *PostgresCache) Delete(key string) error {
-	_, err := c.db.Exec("DELETE FROM apiproxy_cache WHERE key = $1", key)
+	return c.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is like Delete but aborts the query if ctx is canceled
+// first.
+func (c *PostgresCache) DeleteContext(ctx context.Context, key string) error {
+	_, err := c.db.ExecContext(ctx, "DELETE FROM apiproxy_cache WHERE key = $1", key)
 	if err != nil {
 		return fmt.Errorf("failed to delete cache entry: %w", err)
 	}
@@ -204,15 +684,27 @@ func (c *PostgresCache) Stats() (*Stats, error) {
 		return nil, fmt.Errorf("failed to get cache size: %w", err)
 	}
 
-	// TODO: Track hits/misses for hit rate calculation
-	stats.HitRate = 0.0
-	stats.Hits = 0
-	stats.Misses = 0
+	stats.Hits = atomic.LoadInt64(&c.hits)
+	stats.Misses = atomic.LoadInt64(&c.misses)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
 
 	return &stats, nil
 }
 
 func (c *PostgresCache) Close() error {
+	if c.statsFlushDone != nil {
+		close(c.statsFlushDone)
+		c.statsFlushWg.Wait()
+	}
+
+	c.stmtMu.Lock()
+	for _, stmt := range c.stmts {
+		stmt.Close()
+	}
+	c.stmtMu.Unlock()
+
 	return c.db.Close()
 }
 
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// benchPostgresCache returns a PostgresCache backed by the
+// TEST_POSTGRES_DSN environment variable, skipping the benchmark if it
+// isn't set - these benchmarks need a real Postgres instance and aren't
+// run as part of the normal unit test suite.
+func benchPostgresCache(b *testing.B) *PostgresCache {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		b.Skip("TEST_POSTGRES_DSN not set; skipping Postgres benchmark")
+	}
+
+	c, err := NewPostgres(dsn)
+	if err != nil {
+		b.Fatalf("failed to connect to test Postgres: %v", err)
+	}
+	b.Cleanup(func() { c.Close() })
+	return c
+}
+
+func benchEntries(n int) []*Entry {
+	entries := make([]*Entry, n)
+	now := time.Now()
+	for i := range entries {
+		entries[i] = &Entry{
+			Key:        fmt.Sprintf("bench-key-%d", i),
+			Value:      []byte("some cached response body"),
+			Method:     "GET",
+			Path:       "/v1/bench",
+			StatusCode: 200,
+			CreatedAt:  now,
+			ExpiresAt:  now.Add(time.Hour),
+		}
+	}
+	return entries
+}
+
+// BenchmarkPostgresCache_SetEntry measures the existing row-by-row path:
+// one prepared-statement round trip per entry.
+func BenchmarkPostgresCache_SetEntry(b *testing.B) {
+	c := benchPostgresCache(b)
+	entries := benchEntries(b.N)
+
+	b.ResetTimer()
+	for _, entry := range entries {
+		if err := c.SetEntry(entry); err != nil {
+			b.Fatalf("SetEntry failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkPostgresCache_SetEntryBatch measures the COPY-based bulk path
+// for the same workload, in batches of 500 entries.
+func BenchmarkPostgresCache_SetEntryBatch(b *testing.B) {
+	c := benchPostgresCache(b)
+	entries := benchEntries(b.N)
+
+	const batchSize = 500
+
+	b.ResetTimer()
+	for start := 0; start < len(entries); start += batchSize {
+		end := start + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		if err := c.SetEntryBatch(entries[start:end]); err != nil {
+			b.Fatalf("SetEntryBatch failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisOptions configures the redis cache backend, the typed options
+// block behind `cache.backend: redis` in config.CacheConfig.Redis.
+type RedisOptions struct {
+	// Addr is "host:port". Required.
+	Addr string
+	// DB selects the Redis logical database (SELECT), 0 if unset.
+	DB int
+	// Password authenticates with AUTH before SELECT, skipped if empty.
+	Password string
+	// TLS wraps the connection in TLS (e.g. for a managed Redis that only
+	// accepts rediss://), using the system cert pool.
+	TLS bool
+	// DialTimeout bounds the initial connection; zero means 5 seconds.
+	DialTimeout time.Duration
+	// TTL is the default expiry for Set, used when the caller doesn't go
+	// through TTLSetter.SetTTL; zero means 24 hours, matching the other
+	// backends' default.
+	TTL time.Duration
+}
+
+// RedisCache is a Cache backed by a single Redis (or Redis-compatible,
+// e.g. KeyDB/Valkey) connection, speaking RESP directly instead of
+// pulling in a client library, the same tradeoff secrets_aws.go makes for
+// the AWS API: one connection is all a cache backend needs, so the
+// dependency isn't worth it. Concurrent callers share the connection
+// under mu; a busier deployment should put a pooling proxy (e.g.
+// twemproxy) in front rather than expect this to scale unbounded.
+type RedisCache struct {
+	mu   sync.Mutex
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	ttl  time.Duration
+}
+
+// NewRedisCache dials opts.Addr, authenticates and selects opts.DB if
+// set, and returns a ready-to-use RedisCache.
+func NewRedisCache(opts RedisOptions) (*RedisCache, error) {
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("redis cache: addr is required")
+	}
+	dialTimeout := opts.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = 5 * time.Second
+	}
+
+	var conn net.Conn
+	var err error
+	if opts.TLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", opts.Addr, nil)
+	} else {
+		conn, err = net.DialTimeout("tcp", opts.Addr, dialTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis cache: dial %s: %w", opts.Addr, err)
+	}
+
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	c := &RedisCache{
+		conn: conn,
+		rw:   bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
+		ttl:  ttl,
+	}
+
+	if opts.Password != "" {
+		if _, err := c.command("AUTH", opts.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis cache: auth: %w", err)
+		}
+	}
+	if opts.DB != 0 {
+		if _, err := c.command("SELECT", strconv.Itoa(opts.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis cache: select db %d: %w", opts.DB, err)
+		}
+	}
+
+	return c, nil
+}
+
+// command sends args as a RESP array and returns the decoded reply. Must
+// be called with mu held.
+func (c *RedisCache) command(args ...string) (interface{}, error) {
+	var req []byte
+	req = append(req, []byte(fmt.Sprintf("*%d\r\n", len(args)))...)
+	for _, a := range args {
+		req = append(req, []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(a), a))...)
+	}
+	if _, err := c.rw.Write(req); err != nil {
+		return nil, err
+	}
+	if err := c.rw.Flush(); err != nil {
+		return nil, err
+	}
+	return readRESP(c.rw.Reader)
+}
+
+// readRESP decodes one RESP value: simple string (+), error (-), integer
+// (:), bulk string ($, nil on length -1), or array (*).
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = line[:len(line)-2] // strip \r\n
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return n, err
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil // nil bulk string: key miss
+		}
+		buf := make([]byte, n+2) // value + trailing \r\n
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = readRESP(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply prefix %q", line[0])
+	}
+}
+
+// redisHitsKey and redisMissesKey are plain Redis keys (not subject to any
+// apiproxyd key prefixing) that Get increments via INCR, read back by
+// Stats. They show up in DBSIZE like any other key, so Stats' Entries is
+// off by at most two; not worth a separate round trip to correct.
+const (
+	redisHitsKey   = "__apiproxyd_cache_hits__"
+	redisMissesKey = "__apiproxyd_cache_misses__"
+)
+
+func (c *RedisCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reply, err := c.command("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		c.command("INCR", redisMissesKey)
+		return nil, fmt.Errorf("cache miss")
+	}
+	c.command("INCR", redisHitsKey)
+	return value, nil
+}
+
+// readCounter returns the integer value stored at key, or 0 if it's unset
+// or the read fails. Must be called with mu held.
+func (c *RedisCache) readCounter(key string) int64 {
+	reply, err := c.command("GET", key)
+	if err != nil {
+		return 0
+	}
+	v, ok := reply.([]byte)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(string(v), 10, 64)
+	return n
+}
+
+func (c *RedisCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return c.Get(key)
+}
+
+func (c *RedisCache) Set(key string, value []byte) error {
+	return c.SetTTL(key, value, c.ttl)
+}
+
+func (c *RedisCache) SetContext(ctx context.Context, key string, value []byte) error {
+	return c.Set(key, value)
+}
+
+// SetTTL is like Set but stores the entry under ttl instead of the
+// cache's configured default, implementing cache.TTLSetter.
+func (c *RedisCache) SetTTL(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seconds := int64(ttl.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	_, err := c.command("SETEX", key, strconv.FormatInt(seconds, 10), string(value))
+	return err
+}
+
+func (c *RedisCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.command("DEL", key)
+	return err
+}
+
+func (c *RedisCache) DeleteContext(ctx context.Context, key string) error {
+	return c.Delete(key)
+}
+
+// Stats returns DBSIZE as Entries, plus Hits/Misses/HitRate from the
+// counters Get maintains via INCR; Redis doesn't expose total cached value
+// size cheaply, so SizeBytes is left at zero rather than approximated.
+func (c *RedisCache) Stats() (*Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reply, err := c.command("DBSIZE")
+	if err != nil {
+		return nil, err
+	}
+	n, _ := reply.(int64)
+
+	hits := c.readCounter(redisHitsKey)
+	misses := c.readCounter(redisMissesKey)
+
+	stats := &Stats{Entries: n, Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRate = float64(hits) / float64(total)
+	}
+	return stats, nil
+}
+
+func (c *RedisCache) Close() error {
+	return c.conn.Close()
+}
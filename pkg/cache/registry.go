@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory builds a Cache backend from opts, the same options struct New
+// and NewWithOptions accept. A backend registers its Factory under a name
+// via RegisterBackend instead of being wired into a hardcoded switch, so
+// third parties - and Go PluginConfig plugins - can add a backend
+// apiproxyd doesn't ship with.
+type Factory func(opts *CacheOptions) (Cache, error)
+
+// Registry holds the Factory registered for every known cache backend
+// name. DefaultRegistry is populated with the built-in backends at
+// package init; NewRegistry gives a test (or an embedder that wants a
+// restricted set of backends) an isolated one instead.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register adds factory under name, overwriting any existing registration
+// for that name so a plugin can deliberately shadow a built-in backend.
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[name] = factory
+}
+
+// Factory returns the Factory registered under name, or false if none is.
+func (r *Registry) Factory(name string) (Factory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.factories[name]
+	return f, ok
+}
+
+// Names returns every registered backend name, sorted, for error messages
+// and `apiproxy config validate` diagnostics.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the Cache registered under name, or an error naming every
+// registered backend if name has no Factory.
+func (r *Registry) New(name string, opts *CacheOptions) (Cache, error) {
+	if name == "" {
+		name = "sqlite"
+	}
+	factory, ok := r.Factory(name)
+	if !ok {
+		return nil, fmt.Errorf("cache: no backend registered for %q (have: %s)", name, joinNames(r.Names()))
+	}
+	return factory(opts)
+}
+
+func joinNames(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	out := names[0]
+	for _, n := range names[1:] {
+		out += ", " + n
+	}
+	return out
+}
+
+// DefaultRegistry is the Registry consulted by New, NewWithOptions, and
+// config.Set/Validate. RegisterBackend is the usual way to add to it.
+var DefaultRegistry = NewRegistry()
+
+// RegisterBackend registers factory under name in DefaultRegistry. Called
+// from an init function by a backend built into this package, or at
+// startup by a third-party package (or a Go PluginConfig plugin) that
+// wants `cache.backend: <name>` to resolve to its own Cache.
+func RegisterBackend(name string, factory Factory) {
+	DefaultRegistry.Register(name, factory)
+}
+
+func init() {
+	RegisterBackend("sqlite", func(opts *CacheOptions) (Cache, error) {
+		if opts.MaxOpenConns > 0 {
+			return NewSQLiteWithConfig(opts.Path, opts.MaxOpenConns, opts.MaxIdleConns, opts.ConnMaxLifetime, opts.ConnMaxIdleTime)
+		}
+		return NewSQLite(opts.Path)
+	})
+
+	postgresFactory := func(opts *CacheOptions) (Cache, error) {
+		if opts.MaxOpenConns > 0 {
+			return NewPostgresWithConfig(opts.Path, opts.MaxOpenConns, opts.MaxIdleConns, opts.ConnMaxLifetime, opts.ConnMaxIdleTime)
+		}
+		return NewPostgres(opts.Path)
+	}
+	RegisterBackend("postgres", postgresFactory)
+	RegisterBackend("postgresql", postgresFactory)
+
+	RegisterBackend("redis", func(opts *CacheOptions) (Cache, error) {
+		return NewRedisCache(opts.Redis)
+	})
+	RegisterBackend("badger", func(opts *CacheOptions) (Cache, error) {
+		return NewBadgerCache(opts.Badger)
+	})
+	RegisterBackend("memcached", func(opts *CacheOptions) (Cache, error) {
+		return NewMemcachedCache(opts.Memcached)
+	})
+	RegisterBackend("s3", func(opts *CacheOptions) (Cache, error) {
+		return NewS3Cache(opts.S3)
+	})
+}
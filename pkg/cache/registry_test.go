@@ -0,0 +1,46 @@
+package cache
+
+import "testing"
+
+func TestRegistryRegisterAndFactory(t *testing.T) {
+	r := NewRegistry()
+	if _, ok := r.Factory("memory"); ok {
+		t.Fatal("Factory on empty Registry returned ok=true")
+	}
+
+	r.Register("memory", func(opts *CacheOptions) (Cache, error) {
+		return nil, nil
+	})
+	factory, ok := r.Factory("memory")
+	if !ok || factory == nil {
+		t.Fatal("Factory did not return the registered factory")
+	}
+}
+
+func TestRegistryNamesSorted(t *testing.T) {
+	r := NewRegistry()
+	r.Register("zeta", func(opts *CacheOptions) (Cache, error) { return nil, nil })
+	r.Register("alpha", func(opts *CacheOptions) (Cache, error) { return nil, nil })
+
+	names := r.Names()
+	if len(names) != 2 || names[0] != "alpha" || names[1] != "zeta" {
+		t.Errorf("Names() = %v, want [alpha zeta]", names)
+	}
+}
+
+func TestRegistryNewUnknownBackend(t *testing.T) {
+	r := NewRegistry()
+	r.Register("sqlite", func(opts *CacheOptions) (Cache, error) { return nil, nil })
+
+	if _, err := r.New("nonexistent", &CacheOptions{}); err == nil {
+		t.Error("New(\"nonexistent\", ...) = nil error, want an error naming the registered backends")
+	}
+}
+
+func TestDefaultRegistryHasBuiltins(t *testing.T) {
+	for _, name := range []string{"sqlite", "postgres", "postgresql", "redis", "badger", "memcached", "s3"} {
+		if _, ok := DefaultRegistry.Factory(name); !ok {
+			t.Errorf("DefaultRegistry has no factory for built-in backend %q", name)
+		}
+	}
+}
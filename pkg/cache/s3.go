@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options configures the s3 cache backend, the typed options block
+// behind `cache.backend: s3` in config.CacheConfig.S3.
+type S3Options struct {
+	// Bucket is the S3 bucket cache entries are stored in. Required.
+	Bucket string
+	// Prefix is prepended to every cache key, e.g. "apiproxy-cache/", so
+	// the bucket can be shared with other data.
+	Prefix string
+	// Region is the bucket's AWS region, used both to address the
+	// regional endpoint and to sign requests. Required unless Endpoint is
+	// set to an S3-compatible service that ignores it.
+	Region string
+	// Endpoint overrides the AWS <bucket>.s3.<region>.amazonaws.com host,
+	// for an S3-compatible service (e.g. MinIO, R2) instead of real S3. It
+	// is used verbatim: Bucket is not added to it, so it must be a host
+	// that already addresses the bucket.
+	Endpoint string
+	// TTL is the default expiry for Set, used when the caller doesn't go
+	// through TTLSetter.SetTTL; zero means 24 hours, matching the other
+	// backends' default.
+	TTL time.Duration
+}
+
+// S3Cache is a Cache backed by objects in an S3 bucket, calling the S3
+// REST API directly and signing with SigV4 rather than pulling in the
+// aws-sdk-go dependency tree, the same tradeoff secrets_aws.go makes for
+// Secrets Manager. Expiry is tracked with the x-amz-meta-expires-at
+// object metadata header, since S3 doesn't expire an individual PutObject
+// on a TTL the way a normal cache backend would.
+type S3Cache struct {
+	bucket   string
+	prefix   string
+	region   string
+	endpoint string
+	ttl      time.Duration
+	client   *http.Client
+}
+
+// NewS3Cache returns an S3Cache for opts.Bucket. Credentials come from
+// the ambient AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN
+// environment, the same source the AWS CLI and SDKs use.
+func NewS3Cache(opts S3Options) (*S3Cache, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 cache: bucket is required")
+	}
+	if opts.Region == "" && opts.Endpoint == "" {
+		return nil, fmt.Errorf("s3 cache: region is required")
+	}
+
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("%s.s3.%s.amazonaws.com", opts.Bucket, opts.Region)
+	}
+
+	return &S3Cache{
+		bucket:   opts.Bucket,
+		prefix:   opts.Prefix,
+		region:   opts.Region,
+		endpoint: endpoint,
+		ttl:      ttl,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (c *S3Cache) objectKey(key string) string {
+	return c.prefix + key
+}
+
+func (c *S3Cache) Get(key string) ([]byte, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+func (c *S3Cache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	req, err := c.signedRequest(ctx, http.MethodGet, c.objectKey(key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 cache: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("cache miss")
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("s3 cache: unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if expiresAt := resp.Header.Get("x-amz-meta-expires-at"); expiresAt != "" {
+		if unix, err := strconv.ParseInt(expiresAt, 10, 64); err == nil && time.Now().After(time.Unix(unix, 0)) {
+			c.DeleteContext(ctx, key)
+			return nil, fmt.Errorf("cache expired")
+		}
+	}
+
+	return body, nil
+}
+
+func (c *S3Cache) Set(key string, value []byte) error {
+	return c.SetTTL(key, value, c.ttl)
+}
+
+func (c *S3Cache) SetContext(ctx context.Context, key string, value []byte) error {
+	return c.Set(key, value)
+}
+
+// SetTTL is like Set but stores the entry under ttl instead of the
+// cache's configured default, implementing cache.TTLSetter.
+func (c *S3Cache) SetTTL(key string, value []byte, ttl time.Duration) error {
+	req, err := c.signedRequest(context.Background(), http.MethodPut, c.objectKey(key), value)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-amz-meta-expires-at", strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 cache: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("s3 cache: unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (c *S3Cache) Delete(key string) error {
+	return c.DeleteContext(context.Background(), key)
+}
+
+func (c *S3Cache) DeleteContext(ctx context.Context, key string) error {
+	req, err := c.signedRequest(ctx, http.MethodDelete, c.objectKey(key), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 cache: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("s3 cache: unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// Stats is unsupported: S3 has no cheap way to count or size objects
+// under a prefix (ListObjectsV2 is paginated and not free), so every
+// field is left at zero rather than approximated with a slow full scan
+// on every call.
+func (c *S3Cache) Stats() (*Stats, error) {
+	return &Stats{}, nil
+}
+
+// Close is a no-op: S3Cache holds no persistent connection, only an
+// *http.Client.
+func (c *S3Cache) Close() error {
+	return nil
+}
+
+// signedRequest builds a SigV4-signed S3 request for key with body (nil
+// for GET/DELETE).
+func (c *S3Cache) signedRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("s3 cache: AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY are not set")
+	}
+	sessionToken := os.Getenv("AWS_SESSION_TOKEN")
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	path := "/" + url.PathEscape(key)
+	payloadHash := s3SHA256Hex(body)
+
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", c.endpoint, payloadHash, amzDate)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	if sessionToken != "" {
+		canonicalHeaders += fmt.Sprintf("x-amz-security-token:%s\n", sessionToken)
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method, path, "", canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/s3/aws4_request", dateStamp, c.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, credentialScope, s3SHA256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := s3SigningKey(secretKey, dateStamp, c.region)
+	signature := hex.EncodeToString(s3HMACSHA256(signingKey, stringToSign))
+
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, credentialScope, signedHeaders, signature)
+
+	req, err := http.NewRequestWithContext(ctx, method, "https://"+c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Host = c.endpoint
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	req.Header.Set("Authorization", authHeader)
+	return req, nil
+}
+
+func s3SHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func s3HMACSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3SigningKey derives the SigV4 signing key for the "s3" service.
+func s3SigningKey(secretKey, dateStamp, region string) []byte {
+	kDate := s3HMACSHA256([]byte("AWS4"+secretKey), dateStamp)
+	kRegion := s3HMACSHA256(kDate, region)
+	kService := s3HMACSHA256(kRegion, "s3")
+	return s3HMACSHA256(kService, "aws4_request")
+}
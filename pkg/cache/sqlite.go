@@ -1,20 +1,41 @@
 package cache
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// evictionCheckInterval is how often the background goroutine started by
+// NewSQLite checks whether MaxEntries/MaxSizeBytes (see SetEvictionLimits)
+// are exceeded. When neither limit is configured evictLRU returns before
+// running any query, so this runs unconditionally rather than only once a
+// limit is set.
+const evictionCheckInterval = time.Minute
+
 type SQLiteCache struct {
 	db   *sql.DB
 	path string
 	ttl  time.Duration
+
+	// maxEntries and maxSizeBytes bound the cache via the background LRU
+	// eviction goroutine started in NewSQLite; zero (the default) leaves
+	// that dimension unbounded. Set through SetEvictionLimits and read with
+	// atomic loads since the eviction goroutine runs concurrently with any
+	// caller.
+	maxEntries   int64
+	maxSizeBytes int64
+
+	evictDone chan struct{}
+	evictWg   sync.WaitGroup
 }
 
 func NewSQLite(path string) (*SQLiteCache, error) {
@@ -43,11 +64,100 @@ func NewSQLite(path string) (*SQLiteCache, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
-	return &SQLiteCache{
-		db:   db,
-		path: path,
-		ttl:  24 * time.Hour, // Default 24 hour TTL
-	}, nil
+	c := &SQLiteCache{
+		db:        db,
+		path:      path,
+		ttl:       24 * time.Hour, // Default 24 hour TTL
+		evictDone: make(chan struct{}),
+	}
+	c.evictWg.Add(1)
+	go c.evictLoop()
+
+	return c, nil
+}
+
+// NewSQLiteWithConfig creates a SQLite cache with custom connection pool
+// settings, analogous to NewPostgresWithConfig.
+func NewSQLiteWithConfig(path string, maxOpen, maxIdle int, maxLifetime, maxIdleTime time.Duration) (*SQLiteCache, error) {
+	c, err := NewSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxOpen > 0 {
+		c.db.SetMaxOpenConns(maxOpen)
+	}
+	if maxIdle > 0 {
+		c.db.SetMaxIdleConns(maxIdle)
+	}
+	if maxLifetime > 0 {
+		c.db.SetConnMaxLifetime(maxLifetime)
+	}
+	if maxIdleTime > 0 {
+		c.db.SetConnMaxIdleTime(maxIdleTime)
+	}
+
+	return c, nil
+}
+
+// SetEvictionLimits bounds the cache to at most maxEntries rows and
+// maxSizeBytes of total value size, whichever is reached first; the
+// background goroutine started by NewSQLite enforces these by deleting the
+// least-recently-used rows (indexed on last_accessed_at). Either limit may
+// be zero to leave that dimension unbounded.
+func (c *SQLiteCache) SetEvictionLimits(maxEntries, maxSizeBytes int64) {
+	atomic.StoreInt64(&c.maxEntries, maxEntries)
+	atomic.StoreInt64(&c.maxSizeBytes, maxSizeBytes)
+}
+
+func (c *SQLiteCache) evictLoop() {
+	defer c.evictWg.Done()
+
+	ticker := time.NewTicker(evictionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.evictLRU()
+		case <-c.evictDone:
+			return
+		}
+	}
+}
+
+// evictLRU deletes the least-recently-used rows until both MaxEntries and
+// MaxSizeBytes (whichever are configured) are satisfied. It's a no-op if
+// neither limit is set.
+func (c *SQLiteCache) evictLRU() (removed int, bytesReclaimed int64) {
+	maxEntries := atomic.LoadInt64(&c.maxEntries)
+	maxSizeBytes := atomic.LoadInt64(&c.maxSizeBytes)
+	if maxEntries <= 0 && maxSizeBytes <= 0 {
+		return 0, 0
+	}
+
+	var count, totalSize int64
+	if err := c.db.QueryRow("SELECT COUNT(*), COALESCE(SUM(LENGTH(value)), 0) FROM cache_entries").Scan(&count, &totalSize); err != nil {
+		return 0, 0
+	}
+
+	for (maxEntries > 0 && count > maxEntries) || (maxSizeBytes > 0 && totalSize > maxSizeBytes) {
+		var key string
+		var size int64
+		err := c.db.QueryRow("SELECT key, LENGTH(value) FROM cache_entries ORDER BY last_accessed_at ASC LIMIT 1").Scan(&key, &size)
+		if err != nil {
+			break
+		}
+		if _, err := c.db.Exec("DELETE FROM cache_entries WHERE key = ?", key); err != nil {
+			break
+		}
+		count--
+		totalSize -= size
+		removed++
+		bytesReclaimed += size
+	}
+
+	return removed, bytesReclaimed
 }
 
 func initSQLiteSchema(db *sql.DB) error {
@@ -61,29 +171,112 @@ func initSQLiteSchema(db *sql.DB) error {
 		status_code INTEGER,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		expires_at TIMESTAMP NOT NULL,
-		metadata TEXT
+		metadata TEXT,
+		access_count INTEGER NOT NULL DEFAULT 0,
+		last_accessed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_expires_at ON cache_entries(expires_at);
 	CREATE INDEX IF NOT EXISTS idx_path ON cache_entries(path);
 	CREATE INDEX IF NOT EXISTS idx_created_at ON cache_entries(created_at);
+	CREATE INDEX IF NOT EXISTS idx_last_accessed_at ON cache_entries(last_accessed_at);
+
+	CREATE TABLE IF NOT EXISTS cache_stats (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		hits INTEGER NOT NULL DEFAULT 0,
+		misses INTEGER NOT NULL DEFAULT 0
+	);
+
+	INSERT OR IGNORE INTO cache_stats (id, hits, misses) VALUES (1, 0, 0);
 	`
 
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	// cache_entries may already exist from before access_count/
+	// last_accessed_at were added; SQLite has no "ADD COLUMN IF NOT
+	// EXISTS", so check PRAGMA table_info first instead of trying the
+	// ALTER and swallowing a "duplicate column" error.
+	return migrateSQLiteAddColumns(db)
 }
 
+func migrateSQLiteAddColumns(db *sql.DB) error {
+	existing, err := sqliteColumns(db, "cache_entries")
+	if err != nil {
+		return err
+	}
+
+	if !existing["access_count"] {
+		if _, err := db.Exec("ALTER TABLE cache_entries ADD COLUMN access_count INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	if !existing["last_accessed_at"] {
+		if _, err := db.Exec("ALTER TABLE cache_entries ADD COLUMN last_accessed_at TIMESTAMP"); err != nil {
+			return err
+		}
+		if _, err := db.Exec("UPDATE cache_entries SET last_accessed_at = created_at WHERE last_accessed_at IS NULL"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sqliteColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// Get is GetContext with context.Background().
 func (c *SQLiteCache) Get(key string) ([]byte, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// GetContext is like Get but aborts the query if ctx is canceled first. A
+// hit or miss is recorded transactionally against cache_stats, and a hit
+// also bumps access_count/last_accessed_at for LRU eviction, so a crash
+// mid-Get never leaves the counters and the entry's access recency out of
+// sync with each other.
+func (c *SQLiteCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var value []byte
 	var expiresAt time.Time
 
-	err := c.db.QueryRow(`
+	err = tx.QueryRowContext(ctx, `
 		SELECT value, expires_at
 		FROM cache_entries
 		WHERE key = ?
 	`, key).Scan(&value, &expiresAt)
 
 	if err == sql.ErrNoRows {
+		if _, serr := tx.ExecContext(ctx, "UPDATE cache_stats SET misses = misses + 1 WHERE id = 1"); serr != nil {
+			return nil, fmt.Errorf("failed to record cache miss: %w", serr)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit cache stats: %w", err)
+		}
 		return nil, fmt.Errorf("cache miss")
 	}
 	if err != nil {
@@ -92,20 +285,64 @@ func (c *SQLiteCache) Get(key string) ([]byte, error) {
 
 	// Check if expired
 	if time.Now().After(expiresAt) {
-		c.Delete(key)
+		if _, serr := tx.ExecContext(ctx, "UPDATE cache_stats SET misses = misses + 1 WHERE id = 1"); serr != nil {
+			return nil, fmt.Errorf("failed to record cache miss: %w", serr)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit cache stats: %w", err)
+		}
+		c.DeleteContext(ctx, key)
 		return nil, fmt.Errorf("cache expired")
 	}
 
+	if _, serr := tx.ExecContext(ctx, `
+		UPDATE cache_entries SET access_count = access_count + 1, last_accessed_at = ? WHERE key = ?
+	`, time.Now(), key); serr != nil {
+		return nil, fmt.Errorf("failed to bump cache entry access: %w", serr)
+	}
+	if _, serr := tx.ExecContext(ctx, "UPDATE cache_stats SET hits = hits + 1 WHERE id = 1"); serr != nil {
+		return nil, fmt.Errorf("failed to record cache hit: %w", serr)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit cache entry access: %w", err)
+	}
+
 	return value, nil
 }
 
+// Set is SetContext with context.Background().
 func (c *SQLiteCache) Set(key string, value []byte) error {
-	expiresAt := time.Now().Add(c.ttl)
+	return c.SetContext(context.Background(), key, value)
+}
+
+// SetContext is like Set but aborts the write if ctx is canceled first.
+func (c *SQLiteCache) SetContext(ctx context.Context, key string, value []byte) error {
+	now := time.Now()
+	expiresAt := now.Add(c.ttl)
+
+	_, err := c.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO cache_entries (key, value, method, path, expires_at, last_accessed_at)
+		VALUES (?, ?, 'UNKNOWN', 'UNKNOWN', ?, ?)
+	`, key, value, expiresAt, now)
+
+	if err != nil {
+		return fmt.Errorf("failed to set cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// SetTTL is like Set but stores the entry under ttl instead of the cache's
+// configured default, implementing cache.TTLSetter.
+func (c *SQLiteCache) SetTTL(key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
 
 	_, err := c.db.Exec(`
-		INSERT OR REPLACE INTO cache_entries (key, value, method, path, expires_at)
-		VALUES (?, ?, 'UNKNOWN', 'UNKNOWN', ?)
-	`, key, value, expiresAt)
+		INSERT OR REPLACE INTO cache_entries (key, value, method, path, expires_at, last_accessed_at)
+		VALUES (?, ?, 'UNKNOWN', 'UNKNOWN', ?, ?)
+	`, key, value, expiresAt, now)
 
 	if err != nil {
 		return fmt.Errorf("failed to set cache entry: %w", err)
@@ -114,15 +351,22 @@ func (c *SQLiteCache) Set(key string, value []byte) error {
 	return nil
 }
 
+// SetEntry is SetEntryContext with context.Background().
 func (c *SQLiteCache) SetEntry(entry *Entry) error {
+	return c.SetEntryContext(context.Background(), entry)
+}
+
+// SetEntryContext is like SetEntry but aborts the write if ctx is canceled
+// first.
+func (c *SQLiteCache) SetEntryContext(ctx context.Context, entry *Entry) error {
 	metadata, _ := json.Marshal(entry.Metadata)
 
-	_, err := c.db.Exec(`
+	_, err := c.db.ExecContext(ctx, `
 		INSERT OR REPLACE INTO cache_entries
-		(key, value, method, path, request_body, status_code, created_at, expires_at, metadata)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(key, value, method, path, request_body, status_code, created_at, expires_at, metadata, last_accessed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, entry.Key, entry.Value, entry.Method, entry.Path, entry.RequestBody,
-		entry.StatusCode, entry.CreatedAt, entry.ExpiresAt, string(metadata))
+		entry.StatusCode, entry.CreatedAt, entry.ExpiresAt, string(metadata), time.Now())
 
 	if err != nil {
 		return fmt.Errorf("failed to set cache entry: %w", err)
@@ -131,8 +375,15 @@ func (c *SQLiteCache) SetEntry(entry *Entry) error {
 	return nil
 }
 
+// Delete is DeleteContext with context.Background().
 func (c *SQLiteCache) Delete(key string) error {
-	_, err := c.db.Exec("DELETE FROM cache_entries WHERE key = ?", key)
+	return c.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is like Delete but aborts the query if ctx is canceled
+// first.
+func (c *SQLiteCache) DeleteContext(ctx context.Context, key string) error {
+	_, err := c.db.ExecContext(ctx, "DELETE FROM cache_entries WHERE key = ?", key)
 	if err != nil {
 		return fmt.Errorf("failed to delete cache entry: %w", err)
 	}
@@ -156,15 +407,30 @@ func (c *SQLiteCache) Stats() (*Stats, error) {
 		return nil, fmt.Errorf("failed to get cache size: %w", err)
 	}
 
-	// TODO: Track hits/misses for hit rate calculation
-	stats.HitRate = 0.0
-	stats.Hits = 0
-	stats.Misses = 0
+	if err := c.db.QueryRow("SELECT hits, misses FROM cache_stats WHERE id = 1").Scan(&stats.Hits, &stats.Misses); err != nil {
+		return nil, fmt.Errorf("failed to get cache stats: %w", err)
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
 
 	return &stats, nil
 }
 
+// Reset zeroes the persisted hit/miss counters Stats() reports, without
+// touching the cached entries themselves, as distinct from clearing the
+// cache.
+func (c *SQLiteCache) Reset() error {
+	_, err := c.db.Exec("UPDATE cache_stats SET hits = 0, misses = 0 WHERE id = 1")
+	if err != nil {
+		return fmt.Errorf("failed to reset cache stats: %w", err)
+	}
+	return nil
+}
+
 func (c *SQLiteCache) Close() error {
+	close(c.evictDone)
+	c.evictWg.Wait()
 	return c.db.Close()
 }
 
@@ -0,0 +1,32 @@
+package cache
+
+import "bytes"
+
+// StreamWriter buffers bytes written to it and commits them to a Cache
+// with a single Set call on Close. It lets a streamed upstream response be
+// cached without requiring every Cache backend (memory, sqlite, postgres,
+// layered, ...) to support progressive/incremental writes: the response is
+// still relayed to the client chunk-by-chunk as it arrives, but the cache
+// entry itself only materializes once the stream completes.
+type StreamWriter struct {
+	cache Cache
+	key   string
+	buf   bytes.Buffer
+}
+
+// NewStreamWriter returns a StreamWriter that will Set key on cache once
+// closed.
+func NewStreamWriter(cache Cache, key string) *StreamWriter {
+	return &StreamWriter{cache: cache, key: key}
+}
+
+// Write implements io.Writer, buffering p for the eventual Set.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Close commits the buffered bytes to the cache. It does not close the
+// underlying cache itself.
+func (w *StreamWriter) Close() error {
+	return w.cache.Set(w.key, w.buf.Bytes())
+}
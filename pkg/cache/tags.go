@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// TagIndex maintains a tag -> set-of-keys reverse map for surrogate-key
+// (cache-tag) invalidation: a response can be tagged with several keys
+// (e.g. "user-42", "org-7", "product-list") and later purged in bulk by
+// any one of them.
+type TagIndex struct {
+	mu        sync.RWMutex
+	tagToKeys map[string]map[string]struct{}
+	keyToTags map[string][]string
+}
+
+// NewTagIndex creates an empty tag index.
+func NewTagIndex() *TagIndex {
+	return &TagIndex{
+		tagToKeys: make(map[string]map[string]struct{}),
+		keyToTags: make(map[string][]string),
+	}
+}
+
+// TagSet associates key with tags, replacing any tags previously assigned
+// to that key and dropping it from tags it no longer carries.
+func (ti *TagIndex) TagSet(key string, tags []string) {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	for _, old := range ti.keyToTags[key] {
+		if keys, ok := ti.tagToKeys[old]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(ti.tagToKeys, old)
+			}
+		}
+	}
+
+	if len(tags) == 0 {
+		delete(ti.keyToTags, key)
+		return
+	}
+
+	ti.keyToTags[key] = append([]string(nil), tags...)
+	for _, tag := range tags {
+		keys, ok := ti.tagToKeys[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			ti.tagToKeys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// Keys returns the cache keys currently tagged with tag.
+func (ti *TagIndex) Keys(tag string) []string {
+	ti.mu.RLock()
+	defer ti.mu.RUnlock()
+
+	keys := make([]string, 0, len(ti.tagToKeys[tag]))
+	for k := range ti.tagToKeys[tag] {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Untag removes key from the index entirely; callers should do this on
+// eviction so the reverse map doesn't accumulate stale entries.
+func (ti *TagIndex) Untag(key string) {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	for _, tag := range ti.keyToTags[key] {
+		if keys, ok := ti.tagToKeys[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(ti.tagToKeys, tag)
+			}
+		}
+	}
+	delete(ti.keyToTags, key)
+}
+
+// TaggedCache wraps a Cache with a surrogate-key index so responses tagged
+// via a header like `Surrogate-Key: user-42 org-7 product-list` (or a
+// plugin hook) can be purged in bulk without flushing the whole cache. It
+// implements the Cache interface itself so it can be used as a drop-in
+// wrapper around any backend.
+type TaggedCache struct {
+	cache Cache
+	tags  *TagIndex
+}
+
+// NewTaggedCache wraps cache with an empty tag index.
+func NewTaggedCache(cache Cache) *TaggedCache {
+	return &TaggedCache{cache: cache, tags: NewTagIndex()}
+}
+
+// Get retrieves a value from the underlying cache.
+func (tc *TaggedCache) Get(key string) ([]byte, error) {
+	return tc.cache.Get(key)
+}
+
+// GetContext is like Get but aborts the underlying lookup if ctx is
+// canceled before it completes.
+func (tc *TaggedCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return tc.cache.GetContext(ctx, key)
+}
+
+// Set stores a value without touching its tags. Use SetTagged or TagSet to
+// associate surrogate keys.
+func (tc *TaggedCache) Set(key string, value []byte) error {
+	return tc.cache.Set(key, value)
+}
+
+// SetContext is like Set but aborts the underlying write if ctx is
+// canceled first.
+func (tc *TaggedCache) SetContext(ctx context.Context, key string, value []byte) error {
+	return tc.cache.SetContext(ctx, key, value)
+}
+
+// SetTagged stores value and associates it with tags in a single call.
+func (tc *TaggedCache) SetTagged(key string, value []byte, tags []string) error {
+	if err := tc.cache.Set(key, value); err != nil {
+		return err
+	}
+	tc.tags.TagSet(key, tags)
+	return nil
+}
+
+// TagSet associates an already-cached key with tags, merging in new tags
+// and dropping ones the key is no longer tagged with.
+func (tc *TaggedCache) TagSet(key string, tags []string) {
+	tc.tags.TagSet(key, tags)
+}
+
+// InvalidateByTag deletes every cache entry tagged with tag and returns how
+// many entries were removed. It keeps going on a per-key delete error,
+// returning the first one encountered alongside the count of successes.
+func (tc *TaggedCache) InvalidateByTag(tag string) (int, error) {
+	keys := tc.tags.Keys(tag)
+	n := 0
+	var firstErr error
+	for _, key := range keys {
+		if err := tc.cache.Delete(key); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		tc.tags.Untag(key)
+		n++
+	}
+	return n, firstErr
+}
+
+// Delete removes a key from the cache and the tag index.
+func (tc *TaggedCache) Delete(key string) error {
+	if err := tc.cache.Delete(key); err != nil {
+		return err
+	}
+	tc.tags.Untag(key)
+	return nil
+}
+
+// DeleteContext is like Delete but aborts the underlying delete if ctx is
+// canceled first; the tag index is only untagged once the delete succeeds.
+func (tc *TaggedCache) DeleteContext(ctx context.Context, key string) error {
+	if err := tc.cache.DeleteContext(ctx, key); err != nil {
+		return err
+	}
+	tc.tags.Untag(key)
+	return nil
+}
+
+// Stats returns the underlying cache's statistics.
+func (tc *TaggedCache) Stats() (*Stats, error) {
+	return tc.cache.Stats()
+}
+
+// Close closes the underlying cache.
+func (tc *TaggedCache) Close() error {
+	return tc.cache.Close()
+}
+
+// Unwrap returns the cache wrapped by TaggedCache, for callers that need to
+// type-assert against a specific backend (e.g. *LayeredCache).
+func (tc *TaggedCache) Unwrap() Cache {
+	return tc.cache
+}
@@ -4,22 +4,61 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/logger"
+	"github.com/shirou/gopsutil/v3/load"
+	"go.uber.org/zap"
 )
 
 // WarmingConfig defines cache warming configuration
 type WarmingConfig struct {
-	Enabled      bool          `json:"enabled"`
-	ConfigPath   string        `json:"config_path"`
-	OnStartup    bool          `json:"on_startup"`
-	Schedule     string        `json:"schedule"`      // cron-like schedule
-	Concurrency  int           `json:"concurrency"`   // parallel requests
-	Timeout      time.Duration `json:"timeout"`       // per-request timeout
-	RetryCount   int           `json:"retry_count"`
-	RetryDelay   time.Duration `json:"retry_delay"`
+	Enabled     bool          `json:"enabled"`
+	ConfigPath  string        `json:"config_path"`
+	OnStartup   bool          `json:"on_startup"`
+	Schedule    string        `json:"schedule"`    // cron-like schedule
+	Concurrency int           `json:"concurrency"` // parallel requests
+	Timeout     time.Duration `json:"timeout"`     // per-request timeout
+	RetryCount  int           `json:"retry_count"`
+	// RetryDelay is the legacy fixed inter-retry delay, still honored as the
+	// default for RetryBaseDelay when that field is unset.
+	RetryDelay time.Duration `json:"retry_delay"`
+	// RetryBaseDelay and RetryMaxDelay parameterize the exponential backoff
+	// used between retries: the delay before retry n (n = 1, 2, ...) is
+	// min(RetryMaxDelay, RetryBaseDelay*2^(n-1)).
+	RetryBaseDelay time.Duration `json:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `json:"retry_max_delay"`
+	// RetryJitter enables full jitter (the delay is randomized uniformly in
+	// [0, delay)) so concurrent workers retrying the same flapping upstream
+	// don't all wake up in lockstep.
+	RetryJitter bool `json:"retry_jitter"`
+
+	// FaultInjectionEnabled wraps the configured WarmingClient in a
+	// FaultInjector, for exercising retry/backoff behavior in CI or staging
+	// without a genuinely unstable upstream.
+	FaultInjectionEnabled bool    `json:"fault_injection_enabled"`
+	FaultInjectionRate    float64 `json:"fault_injection_rate"` // 0..1 probability per request
+	FaultInjectionTimeout bool    `json:"fault_injection_timeout"`
+
+	// AdaptiveConcurrency replaces the fixed Concurrency semaphore with one
+	// that's resized before every dispatch from the host's 1-minute load
+	// average: target = MaxConcurrency * clamp(1 - load1/numCPU, 0.1, 1.0),
+	// clamped again to [MinConcurrency, MaxConcurrency]. When load exceeds
+	// PauseLoadThreshold, dispatch blocks until load drops back down or
+	// PauseMaxWait elapses. Falls back to a fixed MaxConcurrency (Concurrency
+	// when MaxConcurrency is unset) whenever the host load average can't be
+	// read (e.g. unsupported platform).
+	AdaptiveConcurrency bool `json:"adaptive_concurrency"`
+	MinConcurrency      int  `json:"min_concurrency"`
+	MaxConcurrency      int  `json:"max_concurrency"`
+	// PauseLoadThreshold defaults to numCPU*1.5 when unset.
+	PauseLoadThreshold float64       `json:"pause_load_threshold"`
+	PauseMaxWait       time.Duration `json:"pause_max_wait"`
 }
 
 // WarmingEntry defines a single endpoint to warm
@@ -33,19 +72,62 @@ type WarmingEntry struct {
 
 // WarmingSpec defines the cache warming specification
 type WarmingSpec struct {
-	Version  string          `json:"version"`
-	Updated  time.Time       `json:"updated"`
+	Version   string         `json:"version"`
+	Updated   time.Time      `json:"updated"`
 	Endpoints []WarmingEntry `json:"endpoints"`
 }
 
 // Warmer handles cache warming operations
 type Warmer struct {
-	cache       Cache
-	config      *WarmingConfig
-	spec        *WarmingSpec
-	client      WarmingClient
-	mu          sync.RWMutex
-	stats       *WarmingStats
+	cache     Cache
+	config    *WarmingConfig
+	spec      *WarmingSpec
+	client    WarmingClient
+	mu        sync.RWMutex
+	stats     *WarmingStats
+	runs      int64
+	endpoints map[string]*EndpointWarmingStats
+	observer  WarmingObserver
+}
+
+// WarmingObserver receives a trace event for every warming attempt (success
+// or failure), so operators can diagnose a flapping upstream without
+// enabling debug logging globally.
+type WarmingObserver interface {
+	ObserveAttempt(entry WarmingEntry, attempt int, delay time.Duration, err error)
+}
+
+// WarmingObserverFunc adapts a plain function to a WarmingObserver.
+type WarmingObserverFunc func(entry WarmingEntry, attempt int, delay time.Duration, err error)
+
+// ObserveAttempt calls f.
+func (f WarmingObserverFunc) ObserveAttempt(entry WarmingEntry, attempt int, delay time.Duration, err error) {
+	f(entry, attempt, delay, err)
+}
+
+// EndpointWarmingStats tracks warming results for a single configured
+// endpoint, keyed by "METHOD path", for the
+// apiproxyd_warming_endpoint_success_total /
+// apiproxyd_warming_endpoint_failure_total metrics series.
+type EndpointWarmingStats struct {
+	Method       string
+	Path         string
+	SuccessCount int64
+	FailureCount int64
+	// BytesWarmed and LastDuration describe the most recent successful
+	// attempt only (not a running total), so TransferRate reflects current
+	// throughput rather than an average diluted by history.
+	BytesWarmed  int64
+	LastDuration time.Duration
+}
+
+// TransferRate returns the endpoint's most recent warming throughput in
+// bytes per second, or 0 if it hasn't completed a timed run yet.
+func (e EndpointWarmingStats) TransferRate() float64 {
+	if e.LastDuration <= 0 {
+		return 0
+	}
+	return float64(e.BytesWarmed) / e.LastDuration.Seconds()
 }
 
 // WarmingClient interface for making HTTP requests
@@ -53,23 +135,80 @@ type WarmingClient interface {
 	Request(method, path string, body []byte, headers map[string]string) ([]byte, error)
 }
 
+// FaultInjector wraps a WarmingClient and probabilistically fails requests
+// before they reach it, returning either a synthetic network timeout or a
+// synthetic upstream 5xx error. It lets CI exercise the retry/backoff path
+// against unstable-network conditions without a genuinely flaky upstream.
+type FaultInjector struct {
+	next WarmingClient
+	rate float64 // 0..1 probability of injecting a fault per request
+
+	// Timeout selects the fault shape: a net.Error timeout instead of a
+	// synthetic 5xx error.
+	Timeout bool
+}
+
+// NewFaultInjector wraps next so a fraction (rate, clamped to 0..1) of
+// requests fail synthetically instead of reaching next.
+func NewFaultInjector(next WarmingClient, rate float64) *FaultInjector {
+	if rate < 0 {
+		rate = 0
+	} else if rate > 1 {
+		rate = 1
+	}
+	return &FaultInjector{next: next, rate: rate}
+}
+
+// Request implements WarmingClient.
+func (f *FaultInjector) Request(method, path string, body []byte, headers map[string]string) ([]byte, error) {
+	if f.rate > 0 && rand.Float64() < f.rate {
+		if f.Timeout {
+			return nil, &warmingTimeoutError{op: method + " " + path}
+		}
+		return nil, fmt.Errorf("warming fault injector: synthetic 5xx for %s %s", method, path)
+	}
+	return f.next.Request(method, path, body, headers)
+}
+
+// warmingTimeoutError simulates an upstream timeout as a net.Error, since
+// callers of WarmingClient may special-case Timeout() the way they would
+// for a real http.Client error.
+type warmingTimeoutError struct{ op string }
+
+func (e *warmingTimeoutError) Error() string {
+	return fmt.Sprintf("warming fault injector: synthetic timeout for %s", e.op)
+}
+func (e *warmingTimeoutError) Timeout() bool   { return true }
+func (e *warmingTimeoutError) Temporary() bool { return true }
+
 // WarmingStats tracks cache warming statistics
 type WarmingStats struct {
-	LastRun       time.Time
-	TotalWarmed   int64
-	SuccessCount  int64
-	FailureCount  int64
-	Duration      time.Duration
-	InProgress    bool
+	LastRun      time.Time
+	TotalWarmed  int64
+	SuccessCount int64
+	FailureCount int64
+	Duration     time.Duration
+	InProgress   bool
+	// PauseCount and TotalPauseDuration track AdaptiveConcurrency stalling
+	// dispatch because host load exceeded PauseLoadThreshold.
+	PauseCount         int64
+	TotalPauseDuration time.Duration
 }
 
 // NewWarmer creates a new cache warmer
 func NewWarmer(cache Cache, config *WarmingConfig, client WarmingClient) (*Warmer, error) {
+	if config.FaultInjectionEnabled {
+		fi := NewFaultInjector(client, config.FaultInjectionRate)
+		fi.Timeout = config.FaultInjectionTimeout
+		client = fi
+	}
+
 	w := &Warmer{
-		cache:  cache,
-		config: config,
-		client: client,
-		stats:  &WarmingStats{},
+		cache:     cache,
+		config:    config,
+		client:    client,
+		stats:     &WarmingStats{},
+		endpoints: make(map[string]*EndpointWarmingStats),
 	}
 
 	// Load warming spec if configured
@@ -123,6 +262,7 @@ func (w *Warmer) Warm(ctx context.Context) error {
 	}
 	w.stats.InProgress = true
 	w.stats.LastRun = time.Now()
+	w.runs++
 	w.mu.Unlock()
 
 	defer func() {
@@ -148,7 +288,12 @@ func (w *Warmer) Warm(ctx context.Context) error {
 		concurrency = 5
 	}
 
-	semaphore := make(chan struct{}, concurrency)
+	maxConcurrency := concurrency
+	if w.config.AdaptiveConcurrency && w.config.MaxConcurrency > 0 {
+		maxConcurrency = w.config.MaxConcurrency
+	}
+	gate := newAdaptiveGate(maxConcurrency)
+
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(entries))
 
@@ -159,13 +304,20 @@ func (w *Warmer) Warm(ctx context.Context) error {
 		default:
 		}
 
+		if w.config.AdaptiveConcurrency {
+			if err := w.throttleForLoad(ctx, gate); err != nil {
+				return err
+			}
+		}
+
+		if err := gate.acquire(ctx); err != nil {
+			return err
+		}
+
 		wg.Add(1)
 		go func(e WarmingEntry) {
 			defer wg.Done()
-
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			defer gate.release()
 
 			if err := w.warmEndpoint(ctx, e); err != nil {
 				errChan <- err
@@ -208,23 +360,34 @@ func (w *Warmer) warmEndpoint(ctx context.Context, entry WarmingEntry) error {
 	if retries <= 0 {
 		retries = 2
 	}
-	retryDelay := w.config.RetryDelay
-	if retryDelay <= 0 {
-		retryDelay = 1 * time.Second
+	baseDelay := w.config.RetryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = w.config.RetryDelay
+	}
+	if baseDelay <= 0 {
+		baseDelay = 1 * time.Second
+	}
+	maxDelay := w.config.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
 	}
 
 	var lastErr error
 	for attempt := 0; attempt <= retries; attempt++ {
+		var delay time.Duration
 		if attempt > 0 {
+			delay = backoffDelay(baseDelay, maxDelay, w.config.RetryJitter, attempt)
 			select {
-			case <-time.After(retryDelay):
+			case <-time.After(delay):
 			case <-ctx.Done():
 				return ctx.Err()
 			}
 		}
 
 		// Make request
+		attemptStart := time.Now()
 		resp, err := w.client.Request(entry.Method, entry.Path, []byte(entry.Body), entry.Headers)
+		w.observeAttempt(entry, attempt, delay, err)
 		if err != nil {
 			lastErr = err
 			continue
@@ -238,9 +401,17 @@ func (w *Warmer) warmEndpoint(ctx context.Context, entry WarmingEntry) error {
 		}
 
 		w.incrementTotalWarmed()
+		w.recordEndpointSuccess(entry, int64(len(resp)), time.Since(attemptStart))
 		return nil
 	}
 
+	w.recordEndpointFailure(entry)
+	logger.Component("cache").Warn("cache warming endpoint failed",
+		zap.String("method", entry.Method),
+		zap.String("path", entry.Path),
+		zap.Int("attempts", retries+1),
+		zap.Error(lastErr),
+	)
 	return fmt.Errorf("failed to warm %s %s after %d attempts: %w", entry.Method, entry.Path, retries+1, lastErr)
 }
 
@@ -272,6 +443,220 @@ func (w *Warmer) WarmEndpoints(ctx context.Context, entries []WarmingEntry) erro
 	return w.Warm(ctx)
 }
 
+// backoffDelay computes the delay before a retry attempt (1-indexed: attempt
+// 1 is the first retry after the initial try), growing exponentially from
+// base and capped at maxDelay. When jitter is enabled the result is
+// randomized uniformly in [0, delay) (full jitter) so concurrent workers
+// retrying the same flapping upstream don't wake up in lockstep and cause a
+// thundering herd.
+func backoffDelay(base, maxDelay time.Duration, jitter bool, attempt int) time.Duration {
+	delay := base * time.Duration(int64(1)<<uint(attempt-1))
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	if jitter && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay)))
+	}
+	return delay
+}
+
+// adaptiveGate is a semaphore whose capacity can be resized at runtime, used
+// by AdaptiveConcurrency to scale Warmer's active worker count to host load
+// without tearing down and recreating the worker pool.
+type adaptiveGate struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+}
+
+func newAdaptiveGate(limit int) *adaptiveGate {
+	g := &adaptiveGate{limit: limit}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// setLimit changes the gate's capacity and wakes any goroutine waiting to
+// acquire, in case the new limit now admits it.
+func (g *adaptiveGate) setLimit(limit int) {
+	g.mu.Lock()
+	g.limit = limit
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+// acquire blocks until a slot is free under the current limit or ctx is
+// canceled.
+func (g *adaptiveGate) acquire(ctx context.Context) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			g.cond.Broadcast()
+		case <-stop:
+		}
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.active >= g.limit {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		g.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	g.active++
+	return nil
+}
+
+// release frees a slot acquired via acquire.
+func (g *adaptiveGate) release() {
+	g.mu.Lock()
+	g.active--
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+// loadSample is the host telemetry AdaptiveConcurrency scales against.
+type loadSample struct {
+	Load1  float64
+	NumCPU int
+}
+
+// sampleLoad reads the 1-minute load average via gopsutil. It returns an
+// error on platforms gopsutil doesn't support load reporting on (e.g.
+// Windows), so callers can fall back to fixed concurrency.
+func sampleLoad() (loadSample, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return loadSample{}, err
+	}
+	return loadSample{Load1: avg.Load1, NumCPU: runtime.NumCPU()}, nil
+}
+
+// throttleForLoad samples host load and resizes gate to the target worker
+// count within [MinConcurrency, MaxConcurrency]. If load exceeds
+// PauseLoadThreshold, it blocks further dispatch (via pauseForLoad) until
+// load drops back down or PauseMaxWait elapses. It falls back to a fixed
+// MaxConcurrency (or Concurrency) whenever gopsutil can't read load on this
+// platform, rather than failing the warming run.
+func (w *Warmer) throttleForLoad(ctx context.Context, gate *adaptiveGate) error {
+	minC := w.config.MinConcurrency
+	if minC <= 0 {
+		minC = 1
+	}
+	maxC := w.config.MaxConcurrency
+	if maxC <= 0 {
+		maxC = w.config.Concurrency
+	}
+	if maxC <= 0 {
+		maxC = 5
+	}
+
+	sample, err := sampleLoad()
+	if err != nil {
+		gate.setLimit(maxC)
+		return nil
+	}
+
+	pauseThreshold := w.config.PauseLoadThreshold
+	if pauseThreshold <= 0 {
+		pauseThreshold = float64(sample.NumCPU) * 1.5
+	}
+
+	if sample.Load1 > pauseThreshold {
+		return w.pauseForLoad(ctx, pauseThreshold)
+	}
+
+	gate.setLimit(adaptiveTarget(sample, minC, maxC))
+	return nil
+}
+
+// adaptiveTarget computes the worker count AdaptiveConcurrency targets for
+// the given load sample: maxC scaled down as load1 approaches (or exceeds)
+// numCPU, clamped to [minC, maxC].
+func adaptiveTarget(sample loadSample, minC, maxC int) int {
+	numCPU := sample.NumCPU
+	if numCPU <= 0 {
+		numCPU = 1
+	}
+
+	scale := 1 - sample.Load1/float64(numCPU)
+	if scale < 0.1 {
+		scale = 0.1
+	} else if scale > 1.0 {
+		scale = 1.0
+	}
+
+	target := int(float64(maxC) * scale)
+	if target < minC {
+		target = minC
+	}
+	if target > maxC {
+		target = maxC
+	}
+	return target
+}
+
+// pauseForLoad blocks dispatch, re-sampling load every 500ms, until it drops
+// back to or below threshold or PauseMaxWait elapses, recording the stall
+// in WarmingStats.
+func (w *Warmer) pauseForLoad(ctx context.Context, threshold float64) error {
+	maxWait := w.config.PauseMaxWait
+	if maxWait <= 0 {
+		maxWait = 30 * time.Second
+	}
+
+	start := time.Now()
+	w.mu.Lock()
+	w.stats.PauseCount++
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.stats.TotalPauseDuration += time.Since(start)
+		w.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			sample, err := sampleLoad()
+			if err != nil || sample.Load1 <= threshold || time.Since(start) >= maxWait {
+				return nil
+			}
+		}
+	}
+}
+
+// SetObserver installs a WarmingObserver to receive a trace event for every
+// warming attempt. Pass nil to disable.
+func (w *Warmer) SetObserver(o WarmingObserver) {
+	w.mu.Lock()
+	w.observer = o
+	w.mu.Unlock()
+}
+
+// observeAttempt reports a completed attempt to the installed observer, if
+// any.
+func (w *Warmer) observeAttempt(entry WarmingEntry, attempt int, delay time.Duration, err error) {
+	w.mu.RLock()
+	obs := w.observer
+	w.mu.RUnlock()
+	if obs != nil {
+		obs.ObserveAttempt(entry, attempt, delay, err)
+	}
+}
+
 // Stats returns cache warming statistics
 func (w *Warmer) Stats() WarmingStats {
 	w.mu.RLock()
@@ -279,6 +664,63 @@ func (w *Warmer) Stats() WarmingStats {
 	return *w.stats
 }
 
+// Runs returns the number of times Warm has been started, for the
+// apiproxyd_warming_runs_total counter.
+func (w *Warmer) Runs() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.runs
+}
+
+// EndpointStats returns a snapshot of per-endpoint warming results, for the
+// apiproxyd_warming_endpoint_* metrics series.
+func (w *Warmer) EndpointStats() []EndpointWarmingStats {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	stats := make([]EndpointWarmingStats, 0, len(w.endpoints))
+	for _, s := range w.endpoints {
+		stats = append(stats, *s)
+	}
+	return stats
+}
+
+// endpointKey identifies an endpoint in w.endpoints, e.g. "GET /v1/ip".
+func endpointKey(method, path string) string {
+	return method + " " + path
+}
+
+// recordEndpointSuccess records a successful warming attempt for entry.
+func (w *Warmer) recordEndpointSuccess(entry WarmingEntry, bytes int64, duration time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.endpointFor(entry)
+	s.SuccessCount++
+	s.BytesWarmed = bytes
+	s.LastDuration = duration
+}
+
+// recordEndpointFailure records an exhausted warming attempt for entry.
+func (w *Warmer) recordEndpointFailure(entry WarmingEntry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.endpointFor(entry).FailureCount++
+}
+
+// endpointFor returns entry's EndpointWarmingStats, creating it if this is
+// the first attempt seen for that method/path pair. Caller must hold w.mu.
+func (w *Warmer) endpointFor(entry WarmingEntry) *EndpointWarmingStats {
+	key := endpointKey(entry.Method, entry.Path)
+	s, ok := w.endpoints[key]
+	if !ok {
+		s = &EndpointWarmingStats{Method: entry.Method, Path: entry.Path}
+		w.endpoints[key] = s
+	}
+	return s
+}
+
 // getSpec safely retrieves the warming spec
 func (w *Warmer) getSpec() *WarmingSpec {
 	w.mu.RLock()
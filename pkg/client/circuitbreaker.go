@@ -1,22 +1,165 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/logger"
+	"go.uber.org/zap"
 )
 
 // CircuitBreaker implements the circuit breaker pattern to prevent cascading failures
 // States: Closed (normal) -> Open (failing) -> Half-Open (testing) -> Closed
+//
+// By default a CircuitBreaker opens after a fixed number of consecutive
+// failures (threshold). Setting WindowSize/BucketCount on
+// CircuitBreakerConfig switches it to a rolling time-bucket window instead:
+// the circuit opens once both MinRequests have been observed in the window
+// and the window's failure ratio exceeds FailureRatio, which tolerates a
+// trickle of failures among a much larger stream of successes the way a
+// consecutive-failure counter can't.
 type CircuitBreaker struct {
 	mu              sync.RWMutex
 	state           CircuitState
 	failureCount    int
 	successCount    int
 	lastFailureTime time.Time
-	threshold       int           // failures before opening
+	threshold       int           // failures before opening (legacy mode, i.e. bucketCount == 0)
 	timeout         time.Duration // time to wait before half-open
 	halfOpenMax     int           // max requests in half-open state
+
+	// totalFailures and totalSuccesses count every recordResult call over
+	// the breaker's lifetime, unlike failureCount/successCount above which
+	// reset on transitions. They back the apiproxyd_circuit_failures_total
+	// and apiproxyd_circuit_successes_total metrics (see pkg/metrics),
+	// which need a monotonically increasing counter rather than the
+	// current window's count.
+	totalFailures  int64
+	totalSuccesses int64
+
+	// bucketDuration/buckets/bucketHead implement the rolling window: each
+	// bucket covers bucketDuration and the window covers
+	// len(buckets)*bucketDuration. nil buckets means legacy
+	// consecutive-failure mode. minRequests/failureRatio gate when the
+	// window's contents are enough to open the circuit.
+	bucketDuration time.Duration
+	buckets        []windowBucket
+	bucketHead     int
+	minRequests    int
+	failureRatio   float64
+
+	// isFailure classifies whether an error returned by Call/CallContext
+	// should count as a circuit failure at all - e.g. a 4xx client error
+	// shouldn't trip the breaker for an upstream that's otherwise healthy,
+	// while a 5xx or network timeout should. A nil IsFailure (the default)
+	// treats every non-nil error as a failure, preserving prior behavior.
+	isFailure func(err error) bool
+
+	// OnStateChange, if set, is called whenever the circuit transitions
+	// between states, e.g. so a daemon can publish a "circuit_open" event
+	// for /events subscribers. Called while cb.mu is held, so it must not
+	// call back into the CircuitBreaker.
+	OnStateChange func(from, to CircuitState)
+
+	// store and storeName implement optional persistence: if store is set,
+	// cb rehydrates from it on creation (see attachStore) and saves a
+	// Snapshot to it after every state transition (see persistAsync). Both
+	// are normally wired by CircuitBreakerRegistry, which supplies the
+	// per-host key as storeName.
+	store     StateStore
+	storeName string
+
+	// persistCh feeds persistLoop, the single goroutine that actually
+	// calls store.Save. Routing every snapshot through one goroutine
+	// keeps writes in transition order even when setState fires rapidly
+	// (e.g. Open->HalfOpen->Closed in quick succession); unsynchronized
+	// one-off goroutines per transition could race and leave a stale
+	// snapshot as the last thing persisted. Buffered so persistAsync never
+	// blocks the caller holding cb.mu; full send drops the oldest queued
+	// snapshot in favor of the newest one.
+	persistCh chan Snapshot
+}
+
+// StateStore persists a CircuitBreaker's Snapshot so a daemon restart
+// during an incident rehydrates the breaker's prior state instead of
+// starting closed and immediately hammering a host already known to be
+// failing. Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Load returns the most recently saved Snapshot for name, or
+	// ErrSnapshotNotFound if nothing has been saved under that name yet.
+	Load(name string) (Snapshot, error)
+	// Save persists snapshot under name, overwriting any previous value.
+	Save(name string, snapshot Snapshot) error
+}
+
+// ErrSnapshotNotFound is returned by StateStore.Load when no Snapshot has
+// been saved under the requested name.
+var ErrSnapshotNotFound = errors.New("circuit breaker: no persisted state")
+
+// Snapshot is the durable state of one CircuitBreaker, as saved to and
+// loaded from a StateStore across daemon restarts.
+type Snapshot struct {
+	State           CircuitState           `json:"state"`
+	FailureCount    int                    `json:"failure_count"`
+	SuccessCount    int                    `json:"success_count"`
+	LastFailureTime time.Time              `json:"last_failure_time"`
+	TotalFailures   int64                  `json:"total_failures"`
+	TotalSuccesses  int64                  `json:"total_successes"`
+	Buckets         []WindowBucketSnapshot `json:"buckets,omitempty"`
+	BucketHead      int                    `json:"bucket_head,omitempty"`
+}
+
+// WindowBucketSnapshot is the persisted form of a windowBucket.
+type WindowBucketSnapshot struct {
+	Start     time.Time `json:"start"`
+	Successes int       `json:"successes"`
+	Failures  int       `json:"failures"`
+}
+
+// windowBucket accumulates successes/failures observed during one slice of
+// the rolling window, starting at start.
+type windowBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker or CircuitBreakerRegistry.
+// Threshold/Timeout/HalfOpenMax alone (the zero value of everything else)
+// reproduces the original consecutive-failure breaker. Setting BucketCount
+// > 0 switches to the rolling time-bucket window instead, using
+// WindowSize/BucketCount/MinRequests/FailureRatio and ignoring Threshold.
+type CircuitBreakerConfig struct {
+	Threshold   int
+	Timeout     time.Duration
+	HalfOpenMax int
+
+	// WindowSize is the total duration the sliding window covers, split
+	// evenly across BucketCount buckets (e.g. WindowSize: 10s,
+	// BucketCount: 10 for one-second buckets). Both must be set together.
+	WindowSize  time.Duration
+	BucketCount int
+	// MinRequests is the minimum number of requests (successes + failures)
+	// that must be observed in the window before FailureRatio is even
+	// evaluated, so a handful of early failures on a quiet upstream don't
+	// trip the breaker.
+	MinRequests int
+	// FailureRatio is the fraction (0.0-1.0) of requests in the window
+	// that must have failed for the breaker to open.
+	FailureRatio float64
+
+	// IsFailure classifies whether err should count as a circuit failure.
+	// A nil IsFailure treats every non-nil error as a failure.
+	IsFailure func(err error) bool
+
+	// Store, if set, persists breaker state across daemon restarts. Only
+	// honored when breakers are created through a CircuitBreakerRegistry -
+	// a freestanding CircuitBreaker built via NewCircuitBreakerWithConfig
+	// has no stable name to persist under, so Store is ignored there.
+	Store StateStore
 }
 
 type CircuitState int
@@ -31,14 +174,83 @@ var (
 	ErrCircuitOpen = errors.New("circuit breaker is open")
 )
 
-// NewCircuitBreaker creates a new circuit breaker
+// String renders the state the same way Stats does ("closed", "open",
+// "half-open"), for callers like Daemon's circuit_open event payload.
+func (s CircuitState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalJSON renders CircuitState as its string name rather than the
+// underlying int, so a Snapshot in `apiproxy circuit dump --format=json`
+// output reads naturally.
+func (s CircuitState) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+// MarshalYAML renders CircuitState as its string name, the yaml.v3
+// equivalent of MarshalJSON above.
+func (s CircuitState) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// UnmarshalJSON parses the string name MarshalJSON produces, so a
+// Snapshot persisted by BadgerStateStore round-trips through JSON.
+func (s *CircuitState) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	switch name {
+	case "open":
+		*s = StateOpen
+	case "half-open":
+		*s = StateHalfOpen
+	default:
+		*s = StateClosed
+	}
+	return nil
+}
+
+// NewCircuitBreaker creates a new circuit breaker using the legacy
+// consecutive-failure threshold. Equivalent to
+// NewCircuitBreakerWithConfig(CircuitBreakerConfig{Threshold: threshold,
+// Timeout: timeout, HalfOpenMax: halfOpenMax}).
 func NewCircuitBreaker(threshold int, timeout time.Duration, halfOpenMax int) *CircuitBreaker {
-	return &CircuitBreaker{
-		state:       StateClosed,
-		threshold:   threshold,
-		timeout:     timeout,
-		halfOpenMax: halfOpenMax,
+	return NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		Threshold:   threshold,
+		Timeout:     timeout,
+		HalfOpenMax: halfOpenMax,
+	})
+}
+
+// NewCircuitBreakerWithConfig creates a circuit breaker from cfg. See
+// CircuitBreakerConfig for the legacy-threshold vs. rolling-window choice.
+func NewCircuitBreakerWithConfig(cfg CircuitBreakerConfig) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		state:        StateClosed,
+		threshold:    cfg.Threshold,
+		timeout:      cfg.Timeout,
+		halfOpenMax:  cfg.HalfOpenMax,
+		minRequests:  cfg.MinRequests,
+		failureRatio: cfg.FailureRatio,
+		isFailure:    cfg.IsFailure,
+	}
+	if cfg.BucketCount > 0 {
+		cb.bucketDuration = cfg.WindowSize / time.Duration(cfg.BucketCount)
+		cb.buckets = make([]windowBucket, cfg.BucketCount)
+		now := time.Now()
+		for i := range cb.buckets {
+			cb.buckets[i].start = now
+		}
 	}
+	return cb
 }
 
 // Call executes a function with circuit breaker protection
@@ -48,10 +260,43 @@ func (cb *CircuitBreaker) Call(fn func() error) error {
 	}
 
 	err := fn()
-	cb.recordResult(err == nil)
+	cb.finish(err)
+	return err
+}
+
+// CallContext is like Call, but returns ctx.Err() immediately without
+// invoking fn if ctx is already done, and doesn't invoke fn at all once
+// the circuit is open - callers that want fn itself to be cancellable must
+// have it observe ctx, the same way doRequest's executeRequest does via
+// http.NewRequestWithContext.
+func (cb *CircuitBreaker) CallContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !cb.allowRequest() {
+		return ErrCircuitOpen
+	}
+
+	err := fn()
+	cb.finish(err)
 	return err
 }
 
+// finish classifies err via isFailure and records the outcome, unless
+// isFailure says err shouldn't count as a circuit failure at all (e.g. a
+// 4xx response), in which case neither a success nor a failure is
+// recorded.
+func (cb *CircuitBreaker) finish(err error) {
+	if err == nil {
+		cb.recordResult(true)
+		return
+	}
+	if cb.isFailure != nil && !cb.isFailure(err) {
+		return
+	}
+	cb.recordResult(false)
+}
+
 // allowRequest checks if the request should be allowed
 func (cb *CircuitBreaker) allowRequest() bool {
 	cb.mu.Lock()
@@ -63,7 +308,7 @@ func (cb *CircuitBreaker) allowRequest() bool {
 	case StateOpen:
 		// Check if timeout has elapsed to try half-open
 		if time.Since(cb.lastFailureTime) > cb.timeout {
-			cb.state = StateHalfOpen
+			cb.setState(StateHalfOpen)
 			cb.successCount = 0
 			cb.failureCount = 0
 			return true
@@ -83,19 +328,200 @@ func (cb *CircuitBreaker) recordResult(success bool) {
 
 	if success {
 		cb.successCount++
+		cb.totalSuccesses++
 		cb.failureCount = 0
+	} else {
+		cb.failureCount++
+		cb.totalFailures++
+		cb.lastFailureTime = time.Now()
+	}
 
+	if cb.buckets != nil {
+		cb.recordWindowed(success)
+		return
+	}
+
+	if success {
 		// If in half-open and got enough successes, close the circuit
 		if cb.state == StateHalfOpen && cb.successCount >= cb.halfOpenMax {
-			cb.state = StateClosed
+			cb.setState(StateClosed)
 		}
 	} else {
-		cb.failureCount++
-		cb.lastFailureTime = time.Now()
-
 		// Open circuit if threshold exceeded
-		if cb.failureCount >= cb.threshold {
-			cb.state = StateOpen
+		if cb.failureCount >= cb.threshold && cb.state != StateOpen {
+			cb.setState(StateOpen)
+		}
+	}
+}
+
+// recordWindowed applies the rolling-window open/close decision: it files
+// success into the current bucket (rotating stale buckets out first), then
+// opens the circuit once both MinRequests have been seen in the window and
+// the window's failure ratio exceeds FailureRatio. Half-open -> closed
+// still uses the plain successCount/halfOpenMax probe counters above,
+// independent of window history, the same as legacy mode. Callers must
+// hold cb.mu.
+func (cb *CircuitBreaker) recordWindowed(success bool) {
+	cb.rotateWindow(time.Now())
+	if success {
+		cb.buckets[cb.bucketHead].successes++
+	} else {
+		cb.buckets[cb.bucketHead].failures++
+	}
+
+	if success {
+		if cb.state == StateHalfOpen && cb.successCount >= cb.halfOpenMax {
+			cb.setState(StateClosed)
+		}
+		return
+	}
+
+	if cb.state == StateOpen {
+		return
+	}
+
+	var total, failures int
+	for _, b := range cb.buckets {
+		total += b.successes + b.failures
+		failures += b.failures
+	}
+	if total >= cb.minRequests && float64(failures)/float64(total) > cb.failureRatio {
+		cb.setState(StateOpen)
+	}
+}
+
+// rotateWindow clears out any bucket whose slice of time has fully elapsed
+// since it was last written, so window totals reflect only the last
+// len(buckets)*bucketDuration of activity. Callers must hold cb.mu.
+func (cb *CircuitBreaker) rotateWindow(now time.Time) {
+	elapsed := int(now.Sub(cb.buckets[cb.bucketHead].start) / cb.bucketDuration)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed >= len(cb.buckets) {
+		for i := range cb.buckets {
+			cb.buckets[i] = windowBucket{start: now}
+		}
+		cb.bucketHead = 0
+		return
+	}
+	for i := 1; i <= elapsed; i++ {
+		idx := (cb.bucketHead + i) % len(cb.buckets)
+		cb.buckets[idx] = windowBucket{start: now}
+	}
+	cb.bucketHead = (cb.bucketHead + elapsed) % len(cb.buckets)
+}
+
+// setState transitions to newState and notifies OnStateChange. Callers
+// must hold cb.mu.
+func (cb *CircuitBreaker) setState(newState CircuitState) {
+	oldState := cb.state
+	cb.state = newState
+	if oldState != newState {
+		logger.Component("client").Info("circuit state change",
+			zap.String("from", oldState.String()),
+			zap.String("to", newState.String()),
+		)
+		if cb.OnStateChange != nil {
+			cb.OnStateChange(oldState, newState)
+		}
+		cb.persistAsync()
+	}
+}
+
+// attachStore wires store as cb's StateStore under name and immediately
+// rehydrates any previously persisted Snapshot. Must be called before cb
+// is shared across goroutines, i.e. right after construction.
+func (cb *CircuitBreaker) attachStore(store StateStore, name string) {
+	cb.store = store
+	cb.storeName = name
+	cb.persistCh = make(chan Snapshot, 4)
+	go cb.persistLoop()
+
+	snap, err := store.Load(name)
+	if err != nil {
+		if !errors.Is(err, ErrSnapshotNotFound) {
+			logger.Component("client").Warn("failed to load persisted circuit breaker state",
+				zap.String("name", name), zap.Error(err))
+		}
+		return
+	}
+	cb.restore(snap)
+}
+
+// persistLoop is the sole goroutine that calls cb.store.Save, so snapshots
+// land in the same order their transitions happened in. It exits when
+// persistCh is closed, which currently never happens - a CircuitBreaker
+// with a store lives for the process lifetime.
+func (cb *CircuitBreaker) persistLoop() {
+	for snap := range cb.persistCh {
+		if err := cb.store.Save(cb.storeName, snap); err != nil {
+			logger.Component("client").Warn("failed to persist circuit breaker state",
+				zap.String("name", cb.storeName), zap.Error(err))
+		}
+	}
+}
+
+// restore applies a persisted Snapshot to cb. Only called from
+// attachStore, before cb is shared across goroutines, so unlike setState
+// it doesn't fire OnStateChange or persistAsync - resuming prior state on
+// startup isn't a transition anything should react to.
+func (cb *CircuitBreaker) restore(snap Snapshot) {
+	cb.state = snap.State
+	cb.failureCount = snap.FailureCount
+	cb.successCount = snap.SuccessCount
+	cb.lastFailureTime = snap.LastFailureTime
+	cb.totalFailures = snap.TotalFailures
+	cb.totalSuccesses = snap.TotalSuccesses
+	if cb.buckets != nil && len(snap.Buckets) == len(cb.buckets) {
+		for i, b := range snap.Buckets {
+			cb.buckets[i] = windowBucket{start: b.Start, successes: b.Successes, failures: b.Failures}
+		}
+		cb.bucketHead = snap.BucketHead
+	}
+}
+
+// snapshot captures cb's current state for persistence. Callers must hold
+// cb.mu.
+func (cb *CircuitBreaker) snapshot() Snapshot {
+	buckets := make([]WindowBucketSnapshot, len(cb.buckets))
+	for i, b := range cb.buckets {
+		buckets[i] = WindowBucketSnapshot{Start: b.start, Successes: b.successes, Failures: b.failures}
+	}
+	return Snapshot{
+		State:           cb.state,
+		FailureCount:    cb.failureCount,
+		SuccessCount:    cb.successCount,
+		LastFailureTime: cb.lastFailureTime,
+		TotalFailures:   cb.totalFailures,
+		TotalSuccesses:  cb.totalSuccesses,
+		Buckets:         buckets,
+		BucketHead:      cb.bucketHead,
+	}
+}
+
+// persistAsync hands cb's current snapshot to persistLoop so it's saved to
+// the StateStore without the caller blocking on disk I/O. Callers must
+// hold cb.mu - the snapshot is copied before persistCh is touched, so the
+// actual Save runs unlocked and in whatever order transitions happened
+// in, not whatever order background goroutines happened to finish in.
+func (cb *CircuitBreaker) persistAsync() {
+	if cb.store == nil {
+		return
+	}
+	snap := cb.snapshot()
+	select {
+	case cb.persistCh <- snap:
+	default:
+		// Channel's full; drop the oldest queued snapshot rather than
+		// block the caller, then queue the latest.
+		select {
+		case <-cb.persistCh:
+		default:
+		}
+		select {
+		case cb.persistCh <- snap:
+		default:
 		}
 	}
 }
@@ -114,6 +540,10 @@ func (cb *CircuitBreaker) Reset() {
 	cb.state = StateClosed
 	cb.failureCount = 0
 	cb.successCount = 0
+	now := time.Now()
+	for i := range cb.buckets {
+		cb.buckets[i] = windowBucket{start: now}
+	}
 }
 
 // Stats returns circuit breaker statistics
@@ -121,18 +551,165 @@ func (cb *CircuitBreaker) Stats() map[string]interface{} {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 
-	stateStr := "closed"
-	switch cb.state {
-	case StateOpen:
-		stateStr = "open"
-	case StateHalfOpen:
-		stateStr = "half-open"
+	return map[string]interface{}{
+		"state":           cb.state.String(),
+		"failures":        cb.failureCount,
+		"successes":       cb.successCount,
+		"last_failure":    cb.lastFailureTime,
+		"total_failures":  cb.totalFailures,
+		"total_successes": cb.totalSuccesses,
 	}
+}
 
-	return map[string]interface{}{
-		"state":         stateStr,
-		"failures":      cb.failureCount,
-		"successes":     cb.successCount,
-		"last_failure":  cb.lastFailureTime,
+// CircuitBreakerRegistry shards circuit breakers by a caller-supplied key
+// (typically "method host"), so a single flaky upstream host doesn't trip
+// the breaker for requests to unrelated hosts made through the same
+// Client. Breakers are created lazily on first use and all share the same
+// threshold/timeout/halfOpenMax configuration.
+type CircuitBreakerRegistry struct {
+	mu            sync.Mutex
+	breakers      map[string]*CircuitBreaker
+	cfg           CircuitBreakerConfig
+	onStateChange func(key string, from, to CircuitState)
+}
+
+// NewCircuitBreakerRegistry creates a registry whose breakers all use the
+// given threshold/timeout/halfOpenMax (legacy consecutive-failure mode).
+func NewCircuitBreakerRegistry(threshold int, timeout time.Duration, halfOpenMax int) *CircuitBreakerRegistry {
+	return NewCircuitBreakerRegistryWithConfig(CircuitBreakerConfig{
+		Threshold:   threshold,
+		Timeout:     timeout,
+		HalfOpenMax: halfOpenMax,
+	})
+}
+
+// NewCircuitBreakerRegistryWithConfig creates a registry whose breakers are
+// all constructed from cfg (see CircuitBreakerConfig).
+func NewCircuitBreakerRegistryWithConfig(cfg CircuitBreakerConfig) *CircuitBreakerRegistry {
+	return &CircuitBreakerRegistry{
+		breakers: make(map[string]*CircuitBreaker),
+		cfg:      cfg,
+	}
+}
+
+// get returns the breaker for key, creating it (and wiring OnStateChange,
+// if set) on first use.
+func (r *CircuitBreakerRegistry) get(key string) *CircuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cb, ok := r.breakers[key]; ok {
+		return cb
+	}
+
+	cb := NewCircuitBreakerWithConfig(r.cfg)
+	if r.cfg.Store != nil {
+		cb.attachStore(r.cfg.Store, key)
+	}
+	if r.onStateChange != nil {
+		k := key
+		cb.OnStateChange = func(from, to CircuitState) { r.onStateChange(k, from, to) }
+	}
+	r.breakers[key] = cb
+	return cb
+}
+
+// Call executes fn through the breaker for key.
+func (r *CircuitBreakerRegistry) Call(key string, fn func() error) error {
+	return r.get(key).Call(fn)
+}
+
+// CallContext is like Call, but through CircuitBreaker.CallContext.
+func (r *CircuitBreakerRegistry) CallContext(ctx context.Context, key string, fn func() error) error {
+	return r.get(key).CallContext(ctx, fn)
+}
+
+// Names returns the keys of every breaker the registry has created so far,
+// for callers (e.g. the apiproxy test/admin commands) that want to
+// enumerate breakers rather than look one up by key.
+func (r *CircuitBreakerRegistry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.breakers))
+	for key := range r.breakers {
+		names = append(names, key)
+	}
+	return names
+}
+
+// Get returns the breaker for key and whether it exists, without creating
+// one - unlike Call/CallContext, which create breakers lazily.
+func (r *CircuitBreakerRegistry) Get(key string) (*CircuitBreaker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cb, ok := r.breakers[key]
+	return cb, ok
+}
+
+// Reset resets the breaker for key to closed state, reporting whether a
+// breaker with that key existed at all.
+func (r *CircuitBreakerRegistry) Reset(key string) bool {
+	r.mu.Lock()
+	cb, ok := r.breakers[key]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	cb.Reset()
+	return true
+}
+
+// SetOnStateChange registers fn to be called whenever any breaker in the
+// registry changes state, including breakers created after this call.
+func (r *CircuitBreakerRegistry) SetOnStateChange(fn func(key string, from, to CircuitState)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onStateChange = fn
+	for key, cb := range r.breakers {
+		k := key
+		cb.OnStateChange = func(from, to CircuitState) { fn(k, from, to) }
+	}
+}
+
+// UpdateConfig changes the threshold/timeout/halfOpenMax used for breakers
+// created from now on (e.g. a config hot-reload bumping
+// CircuitBreakerThreshold). Breakers that already exist keep running with
+// whatever configuration they were created with - a host mid-trip isn't
+// retroactively reconfigured out from under its own state machine.
+func (r *CircuitBreakerRegistry) UpdateConfig(threshold int, timeout time.Duration, halfOpenMax int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cfg.Threshold = threshold
+	r.cfg.Timeout = timeout
+	r.cfg.HalfOpenMax = halfOpenMax
+}
+
+// AnyOpen reports whether any breaker in the registry is currently open,
+// for callers (e.g. health checks) that only care whether something is
+// broken, not which host.
+func (r *CircuitBreakerRegistry) AnyOpen() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, cb := range r.breakers {
+		if cb.State() == StateOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns each breaker's Stats(), keyed the same way Call was.
+func (r *CircuitBreakerRegistry) Stats() map[string]map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]map[string]interface{}, len(r.breakers))
+	for key, cb := range r.breakers {
+		stats[key] = cb.Stats()
 	}
+	return stats
 }
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// BadgerStateStore persists CircuitBreaker Snapshots in an embedded
+// BadgerDB, the same engine pkg/cache.BadgerCache uses for its cache
+// backend - so a deployment that already depends on BadgerDB for caching
+// doesn't need a second storage engine just to remember breaker state
+// across restarts.
+type BadgerStateStore struct {
+	db *badger.DB
+}
+
+// NewBadgerStateStore opens (creating if necessary) a BadgerDB at dir
+// dedicated to circuit breaker state. It should be given its own
+// directory, separate from any BadgerDB-backed cache, since both open
+// their directory exclusively.
+func NewBadgerStateStore(dir string) (*BadgerStateStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("circuit breaker state store: dir is required")
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return nil, fmt.Errorf("circuit breaker state store: open %s: %w", dir, err)
+	}
+	return &BadgerStateStore{db: db}, nil
+}
+
+// Load implements StateStore.
+func (s *BadgerStateStore) Load(name string) (Snapshot, error) {
+	var snap Snapshot
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(name))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &snap)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("circuit breaker state store: load %s: %w", name, err)
+	}
+	return snap, nil
+}
+
+// Save implements StateStore.
+func (s *BadgerStateStore) Save(name string, snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("circuit breaker state store: encode %s: %w", name, err)
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(name), data)
+	})
+}
+
+// Dump returns every persisted Snapshot, keyed by breaker name, for the
+// `apiproxy circuit dump` CLI command.
+func (s *BadgerStateStore) Dump() (map[string]Snapshot, error) {
+	out := make(map[string]Snapshot)
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			name := string(item.KeyCopy(nil))
+			var snap Snapshot
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &snap)
+			}); err != nil {
+				return fmt.Errorf("circuit breaker state store: decode %s: %w", name, err)
+			}
+			out[name] = snap
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Close releases the underlying BadgerDB handle.
+func (s *BadgerStateStore) Close() error {
+	return s.db.Close()
+}
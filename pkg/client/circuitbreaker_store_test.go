@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBadgerStateStoreSaveLoadDump(t *testing.T) {
+	store, err := NewBadgerStateStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBadgerStateStore: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.Load("host-a"); err != ErrSnapshotNotFound {
+		t.Errorf("expected ErrSnapshotNotFound before any save, got %v", err)
+	}
+
+	want := Snapshot{
+		State:           StateOpen,
+		FailureCount:    5,
+		LastFailureTime: time.Now().Truncate(time.Second),
+		TotalFailures:   12,
+		TotalSuccesses:  100,
+	}
+	if err := store.Save("host-a", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := store.Load("host-a")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.State != want.State || got.FailureCount != want.FailureCount || !got.LastFailureTime.Equal(want.LastFailureTime) {
+		t.Errorf("round-tripped snapshot = %+v, want %+v", got, want)
+	}
+
+	dump, err := store.Dump()
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if len(dump) != 1 || dump["host-a"].State != StateOpen {
+		t.Errorf("Dump = %+v, want a single open snapshot for host-a", dump)
+	}
+}
@@ -1,7 +1,9 @@
 package client
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 )
@@ -84,6 +86,180 @@ func TestCircuitBreakerStats(t *testing.T) {
 	}
 }
 
+func TestCircuitBreakerWindowed(t *testing.T) {
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		Timeout:      100 * time.Millisecond,
+		HalfOpenMax:  2,
+		WindowSize:   time.Second,
+		BucketCount:  10,
+		MinRequests:  10,
+		FailureRatio: 0.5,
+	})
+
+	// A handful of failures alone shouldn't open the circuit: MinRequests
+	// hasn't been reached yet.
+	for i := 0; i < 3; i++ {
+		cb.Call(func() error { return errors.New("failure") })
+	}
+	if cb.State() != StateClosed {
+		t.Error("circuit should stay closed below MinRequests")
+	}
+
+	// Pad out to MinRequests with successes, keeping the ratio below 0.5.
+	for i := 0; i < 7; i++ {
+		cb.Call(func() error { return nil })
+	}
+	if cb.State() != StateClosed {
+		t.Error("circuit should stay closed while failure ratio is below FailureRatio")
+	}
+
+	// Push the ratio over 0.5 within MinRequests.
+	for i := 0; i < 10; i++ {
+		cb.Call(func() error { return errors.New("failure") })
+	}
+	if cb.State() != StateOpen {
+		t.Error("circuit should open once failure ratio exceeds FailureRatio")
+	}
+}
+
+func TestCircuitBreakerIsFailure(t *testing.T) {
+	clientErr := errors.New("client error")
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		Threshold:   2,
+		Timeout:     time.Second,
+		HalfOpenMax: 1,
+		IsFailure: func(err error) bool {
+			return err != clientErr
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		err := cb.Call(func() error { return clientErr })
+		if err != clientErr {
+			t.Errorf("Call should still return the classified-as-ignored error, got %v", err)
+		}
+	}
+	if cb.State() != StateClosed {
+		t.Error("errors classified as non-failures shouldn't trip the breaker")
+	}
+}
+
+func TestCircuitBreakerCallContext(t *testing.T) {
+	cb := NewCircuitBreaker(3, time.Second, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := cb.CallContext(ctx, func() error {
+		called = true
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("CallContext should return ctx.Err() for an already-canceled context, got %v", err)
+	}
+	if called {
+		t.Error("CallContext should not invoke fn for an already-canceled context")
+	}
+}
+
+func TestCircuitBreakerRegistryEnumerateAndReset(t *testing.T) {
+	r := NewCircuitBreakerRegistry(1, time.Second, 1)
+
+	r.Call("host-a", func() error { return errors.New("failure") })
+	r.Call("host-b", func() error { return nil })
+
+	names := r.Names()
+	if len(names) != 2 {
+		t.Fatalf("expected 2 registered breakers, got %d: %v", len(names), names)
+	}
+
+	if _, ok := r.Get("host-a"); !ok {
+		t.Error("Get should find a breaker created via Call")
+	}
+	if _, ok := r.Get("host-c"); ok {
+		t.Error("Get should not find a breaker that was never created")
+	}
+
+	if cb, _ := r.Get("host-a"); cb.State() != StateOpen {
+		t.Fatal("host-a breaker should have opened after its one configured failure")
+	}
+	if !r.Reset("host-a") {
+		t.Fatal("Reset should report true for an existing breaker")
+	}
+	if cb, _ := r.Get("host-a"); cb.State() != StateClosed {
+		t.Error("host-a breaker should be closed after Reset")
+	}
+	if r.Reset("host-c") {
+		t.Error("Reset should report false for a breaker that doesn't exist")
+	}
+}
+
+// memStateStore is an in-memory StateStore for tests, with a channel
+// signaled after every Save so tests can wait for persistAsync's
+// background goroutine instead of sleeping.
+type memStateStore struct {
+	mu    sync.Mutex
+	snaps map[string]Snapshot
+	saved chan struct{}
+}
+
+func newMemStateStore() *memStateStore {
+	return &memStateStore{snaps: make(map[string]Snapshot), saved: make(chan struct{}, 16)}
+}
+
+func (s *memStateStore) Load(name string) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap, ok := s.snaps[name]
+	if !ok {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	return snap, nil
+}
+
+func (s *memStateStore) Save(name string, snap Snapshot) error {
+	s.mu.Lock()
+	s.snaps[name] = snap
+	s.mu.Unlock()
+	s.saved <- struct{}{}
+	return nil
+}
+
+func TestCircuitBreakerRegistryPersistsAndRehydrates(t *testing.T) {
+	store := newMemStateStore()
+
+	r1 := NewCircuitBreakerRegistryWithConfig(CircuitBreakerConfig{
+		Threshold:   1,
+		Timeout:     time.Hour,
+		HalfOpenMax: 1,
+		Store:       store,
+	})
+	r1.Call("host-a", func() error { return errors.New("failure") })
+
+	select {
+	case <-store.saved:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for breaker state to persist")
+	}
+
+	if _, err := store.Load("host-a"); err != nil {
+		t.Fatalf("expected a persisted snapshot for host-a, got error: %v", err)
+	}
+
+	// A fresh registry backed by the same store should rehydrate host-a as
+	// already open instead of starting closed.
+	r2 := NewCircuitBreakerRegistryWithConfig(CircuitBreakerConfig{
+		Threshold:   1,
+		Timeout:     time.Hour,
+		HalfOpenMax: 1,
+		Store:       store,
+	})
+	if err := r2.Call("host-a", func() error { return nil }); err != ErrCircuitOpen {
+		t.Errorf("rehydrated breaker should start open, got err=%v", err)
+	}
+}
+
 func BenchmarkCircuitBreaker(b *testing.B) {
 	cb := NewCircuitBreaker(100, 1*time.Second, 10)
 
@@ -1,21 +1,49 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+	"github.com/afterdarksys/apiproxyd/pkg/logger"
+	"go.uber.org/zap"
 )
 
 type Client struct {
-	APIKey         string
-	BaseURL        string
-	HTTPClient     *http.Client
-	circuitBreaker *CircuitBreaker
-	singleFlight   *SingleFlight
+	// APIKey is read directly by callers that construct a Client and never
+	// rotate its key afterward. Anything that might race with a rotation
+	// (see SetAPIKey) must go through apiKey()/SetAPIKey instead, which are
+	// guarded by apiKeyMu.
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+
+	apiKeyMu sync.RWMutex
+
+	circuitBreakers     *CircuitBreakerRegistry
+	circuitBreakerKeyFn func(method, host string) string
+	retryBudget         *RetryBudget
+	retry               *RetryPolicy
+	singleFlight        Deduplicator
+
+	// onUpstreamRequest, if set, is called after every upstream request
+	// attempt (including ones retried or short-circuited by an open
+	// circuit breaker), so a caller can back the
+	// apiproxyd_upstream_request_duration_seconds and
+	// apiproxyd_upstream_requests_total metrics without this package
+	// depending on pkg/metrics. See SetUpstreamRequestHook.
+	onUpstreamRequest func(method, path string, statusCode int, duration time.Duration)
 }
 
 type KeyInfo struct {
@@ -43,7 +71,56 @@ type ClientConfig struct {
 	CircuitBreakerThreshold int
 	CircuitBreakerTimeout   time.Duration
 	CircuitBreakerHalfOpen  int
-	DeduplicationEnabled    bool
+	// CircuitBreakerKeyFunc derives the per-breaker key from the request
+	// method and the upstream host. Defaults to "method host", so each host
+	// reached through this Client gets its own breaker and a flaky one
+	// doesn't trip requests to the others.
+	CircuitBreakerKeyFunc func(method, host string) string
+	// CircuitBreakerWindowSize and CircuitBreakerBucketCount switch the
+	// per-host breakers from the legacy consecutive-failure threshold to a
+	// rolling time-bucket window (see CircuitBreakerConfig). Leaving
+	// CircuitBreakerBucketCount at zero (the default) keeps the legacy
+	// CircuitBreakerThreshold behavior.
+	CircuitBreakerWindowSize   time.Duration
+	CircuitBreakerBucketCount  int
+	CircuitBreakerMinRequests  int
+	CircuitBreakerFailureRatio float64
+	// CircuitBreakerIsFailure classifies whether an error should count as
+	// a circuit failure at all, e.g. excluding 4xx responses so only
+	// 5xx/network errors can trip the breaker. Nil counts every non-nil
+	// error as a failure.
+	CircuitBreakerIsFailure func(err error) bool
+	// CircuitBreakerStateStore, if set, persists each per-host breaker's
+	// state so it survives a daemon restart (see StateStore and
+	// BadgerStateStore). Nil keeps breaker state in memory only.
+	CircuitBreakerStateStore StateStore
+	// RetryBudgetRatio and RetryBudgetMinTokens configure the token-bucket
+	// retry budget (see RetryBudget). A zero RetryBudgetRatio disables
+	// retries entirely.
+	RetryBudgetRatio     float64
+	RetryBudgetMinTokens float64
+	// Retry restricts which method/status combinations doRequest's retry
+	// loop will retry at all, and how it backs off. Nil retries every
+	// error regardless of method or status, same as before this field
+	// existed. See RetryPolicy and DefaultRetryPolicy.
+	Retry                *RetryPolicy
+	DeduplicationEnabled bool
+	// DedupBackend selects how DeduplicationEnabled is implemented: "local"
+	// (the default) uses SingleFlight, scoped to this process only. Any
+	// other value is ignored unless DedupLock and DedupCache are also set,
+	// in which case NewWithConfig builds a DistributedSingleFlight instead
+	// so a cluster of daemons shares one set of in-flight calls per key.
+	DedupBackend string
+	// DedupLock and DedupCache back a distributed deduplicator; see
+	// DedupBackend. Daemon.Start wires these from cfg.Cache's configured
+	// backend.
+	DedupLock  LockBackend
+	DedupCache cache.Cache
+	// DialContext overrides the transport's dialer, e.g. with
+	// middleware.SSRFProtection.SafeDialer() so the upstream connection
+	// dials the exact address that was SSRF-validated instead of letting
+	// net/http re-resolve the host itself. Defaults to a plain net.Dialer.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
 // DefaultClientConfig returns sensible defaults for production use
@@ -63,6 +140,9 @@ func DefaultClientConfig() *ClientConfig {
 		CircuitBreakerThreshold: 5,
 		CircuitBreakerTimeout:   60 * time.Second,
 		CircuitBreakerHalfOpen:  3,
+		RetryBudgetRatio:        0.1,
+		RetryBudgetMinTokens:    10,
+		Retry:                   DefaultRetryPolicy(),
 		DeduplicationEnabled:    true,
 	}
 }
@@ -73,12 +153,17 @@ func New(apiKey string) *Client {
 
 // NewWithConfig creates a new client with custom configuration
 func NewWithConfig(apiKey string, cfg *ClientConfig) *Client {
-	// Create custom transport with connection pooling
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
+	dialContext := cfg.DialContext
+	if dialContext == nil {
+		dialContext = (&net.Dialer{
 			Timeout:   cfg.DialTimeout,
 			KeepAlive: cfg.KeepAlive,
-		}).DialContext,
+		}).DialContext
+	}
+
+	// Create custom transport with connection pooling
+	transport := &http.Transport{
+		DialContext:           dialContext,
 		MaxIdleConns:          cfg.MaxIdleConns,
 		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
 		MaxConnsPerHost:       cfg.MaxConnsPerHost,
@@ -112,16 +197,33 @@ func NewWithConfig(apiKey string, cfg *ClientConfig) *Client {
 
 	// Enable circuit breaker if configured
 	if cfg.CircuitBreakerEnabled {
-		client.circuitBreaker = NewCircuitBreaker(
-			cfg.CircuitBreakerThreshold,
-			cfg.CircuitBreakerTimeout,
-			cfg.CircuitBreakerHalfOpen,
-		)
+		client.circuitBreakers = NewCircuitBreakerRegistryWithConfig(CircuitBreakerConfig{
+			Threshold:    cfg.CircuitBreakerThreshold,
+			Timeout:      cfg.CircuitBreakerTimeout,
+			HalfOpenMax:  cfg.CircuitBreakerHalfOpen,
+			WindowSize:   cfg.CircuitBreakerWindowSize,
+			BucketCount:  cfg.CircuitBreakerBucketCount,
+			MinRequests:  cfg.CircuitBreakerMinRequests,
+			FailureRatio: cfg.CircuitBreakerFailureRatio,
+			IsFailure:    cfg.CircuitBreakerIsFailure,
+			Store:        cfg.CircuitBreakerStateStore,
+		})
+		client.circuitBreakerKeyFn = cfg.CircuitBreakerKeyFunc
+	}
+
+	// Enable the retry budget if configured
+	if cfg.RetryBudgetRatio > 0 {
+		client.retryBudget = NewRetryBudget(cfg.RetryBudgetRatio, cfg.RetryBudgetMinTokens)
 	}
+	client.retry = cfg.Retry
 
 	// Enable request deduplication if configured
 	if cfg.DeduplicationEnabled {
-		client.singleFlight = NewSingleFlight()
+		if cfg.DedupBackend != "" && cfg.DedupBackend != "local" && cfg.DedupLock != nil && cfg.DedupCache != nil {
+			client.singleFlight = NewDistributedSingleFlight(cfg.DedupLock, cfg.DedupCache, cfg.RequestTimeout)
+		} else {
+			client.singleFlight = NewSingleFlight()
+		}
 	}
 
 	return client
@@ -134,7 +236,7 @@ func (c *Client) ValidateKey() (*KeyInfo, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("X-API-Key", c.APIKey)
+	req.Header.Set("X-API-Key", c.apiKey())
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -157,6 +259,17 @@ func (c *Client) ValidateKey() (*KeyInfo, error) {
 
 // Request makes an API request through the proxy
 func (c *Client) Request(method, path string, body io.Reader, headers map[string]string) ([]byte, error) {
+	return c.RequestContext(context.Background(), method, path, body, headers)
+}
+
+// RequestContext is like Request, but ctx is threaded through request
+// deduplication, the circuit breaker, and the underlying HTTP request, so a
+// caller (e.g. the upstream HTTP handler whose context is tied to its own
+// client connection) can cancel an in-flight proxied request instead of
+// waiting for it to finish on its own. A second caller deduplicated onto an
+// already in-flight call can abandon its own wait on ctx cancellation
+// without aborting that shared call for whoever else is waiting on it.
+func (c *Client) RequestContext(ctx context.Context, method, path string, body io.Reader, headers map[string]string) ([]byte, error) {
 	// Use request deduplication if enabled
 	if c.singleFlight != nil {
 		// Create a unique key for this request
@@ -164,40 +277,142 @@ func (c *Client) Request(method, path string, body io.Reader, headers map[string
 		// you might want to include a hash of the body in the key
 		key := fmt.Sprintf("%s:%s", method, path)
 
-		return c.singleFlight.Do(key, func() ([]byte, error) {
-			return c.doRequest(method, path, body, headers)
+		return c.singleFlight.DoContext(ctx, key, func(ctx context.Context) ([]byte, error) {
+			return c.doRequest(ctx, method, path, body, headers)
 		})
 	}
 
-	return c.doRequest(method, path, body, headers)
+	return c.doRequest(ctx, method, path, body, headers)
 }
 
-// doRequest performs the actual HTTP request with circuit breaker protection
-func (c *Client) doRequest(method, path string, body io.Reader, headers map[string]string) ([]byte, error) {
-	url := c.BaseURL + path
+// doRequest performs the actual HTTP request with circuit breaker
+// protection and, when a retry budget is configured, bounded retries on
+// failure. The Retry policy decides which method/status combinations are
+// retried at all (by default, idempotent methods on 429/502/503/504) and how long
+// to back off between attempts, honoring any Retry-After the upstream
+// sent. The request body is buffered up front so it can be replayed across
+// attempts.
+func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader, headers map[string]string) ([]byte, error) {
+	reqURL := c.BaseURL + path
+	host := requestHost(reqURL)
+
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	const maxRetryAttempts = 3
+	maxAttempts := maxRetryAttempts
+	if c.retry != nil && c.retry.MaxAttempts > 0 {
+		maxAttempts = c.retry.MaxAttempts
+	}
+
+	var result []byte
+	var err error
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if bodyBytes != nil {
+			bodyReader = bytes.NewReader(bodyBytes)
+		}
 
-	// Use circuit breaker if enabled
-	if c.circuitBreaker != nil {
-		var result []byte
-		err := c.circuitBreaker.Call(func() error {
+		attemptStart := time.Now()
+		err = c.circuitCall(method, host, func() error {
 			var callErr error
-			result, callErr = c.executeRequest(method, url, body, headers)
+			result, callErr = c.executeRequest(ctx, method, reqURL, bodyReader, headers)
 			return callErr
 		})
-		return result, err
+		if c.onUpstreamRequest != nil {
+			c.onUpstreamRequest(method, path, upstreamStatusCode(err), time.Since(attemptStart))
+		}
+
+		if err == nil {
+			if c.retryBudget != nil {
+				c.retryBudget.Deposit()
+			}
+			return result, nil
+		}
+
+		if attempt >= maxAttempts-1 || errors.Is(err, ErrCircuitOpen) || ctx.Err() != nil || !c.retry.allows(method, err) || c.retryBudget == nil || !c.retryBudget.Withdraw() {
+			return nil, err
+		}
+
+		var backoff time.Duration
+		if c.retry != nil {
+			backoff = c.retry.delay(attempt, err)
+		} else {
+			backoff = retryBackoff(attempt)
+		}
+
+		logger.Component("client").Debug("retrying upstream request",
+			zap.String("method", method),
+			zap.String("path", path),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
+}
 
-	return c.executeRequest(method, url, body, headers)
+// circuitCall routes fn through the per-host circuit breaker registry, if
+// enabled, or calls it directly otherwise.
+func (c *Client) circuitCall(method, host string, fn func() error) error {
+	if c.circuitBreakers == nil {
+		return fn()
+	}
+	return c.circuitBreakers.Call(c.circuitBreakerKey(method, host), fn)
+}
+
+// circuitBreakerKey derives the registry key for method/host, using the
+// configured CircuitBreakerKeyFunc if set.
+func (c *Client) circuitBreakerKey(method, host string) string {
+	if c.circuitBreakerKeyFn != nil {
+		return c.circuitBreakerKeyFn(method, host)
+	}
+	return method + " " + host
+}
+
+// requestHost extracts the host portion of rawURL, falling back to
+// rawURL itself if it doesn't parse (keeping the breaker keyed on
+// something stable rather than failing the request over it).
+func requestHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
+// retryBackoff returns the delay before retry attempt n (0-indexed),
+// exponential with full jitter, capped at 2s.
+func retryBackoff(attempt int) time.Duration {
+	const base = 50 * time.Millisecond
+	const max = 2 * time.Second
+
+	d := base << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return time.Duration(rand.Int63n(int64(d)))
 }
 
 // executeRequest performs the raw HTTP request
-func (c *Client) executeRequest(method, url string, body io.Reader, headers map[string]string) ([]byte, error) {
-	req, err := http.NewRequest(method, url, body)
+func (c *Client) executeRequest(ctx context.Context, method, url string, body io.Reader, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("X-API-Key", c.APIKey)
+	req.Header.Set("X-API-Key", c.apiKey())
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept-Encoding", "gzip") // Enable compression
 
@@ -217,20 +432,151 @@ func (c *Client) executeRequest(method, url string, body io.Reader, headers map[
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(data))
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header), Body: data}
 	}
 
 	return data, nil
 }
 
-// GetCircuitBreakerStats returns circuit breaker statistics
+// RequestStream makes an API request like Request, but returns the upstream
+// response body unread instead of buffering it, so a caller proxying a
+// large or long-lived response (NDJSON, SSE, chunked JSON arrays) can relay
+// it to its own client as it arrives. The caller must Close the returned
+// body. It still goes through the circuit breaker, which only gates on
+// whether the connection and headers were received successfully; it does
+// not go through request deduplication, since a streamed body can't be
+// shared between callers.
+func (c *Client) RequestStream(method, path string, body io.Reader, headers map[string]string) (io.ReadCloser, http.Header, int, error) {
+	reqURL := c.BaseURL + path
+	host := requestHost(reqURL)
+
+	var resp *http.Response
+	err := c.circuitCall(method, host, func() error {
+		var err error
+		resp, err = c.executeStreamRequest(method, reqURL, body, headers)
+		return err
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return resp.Body, resp.Header, resp.StatusCode, nil
+}
+
+// executeStreamRequest performs the raw HTTP request and returns the
+// response with its body unread. Unlike executeRequest it does not force
+// Accept-Encoding: gzip, so net/http can negotiate and transparently
+// decompress on its own terms, leaving the streamed body itself as the
+// single source of truth for what reaches the client.
+func (c *Client) executeStreamRequest(method, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("X-API-Key", c.apiKey())
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(data))
+	}
+
+	return resp, nil
+}
+
+// upstreamStatusCode extracts the HTTP status code an upstream request
+// attempt completed with, for the apiproxyd_upstream_request_duration_seconds
+// and apiproxyd_upstream_requests_total labels: 200 on success (doRequest
+// only treats 2xx/3xx as success), the HTTPStatusError's code on a 4xx/5xx
+// response, and 0 for anything that never got a response at all (circuit
+// open, dial failure, context cancellation).
+func upstreamStatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// SetUpstreamRequestHook registers fn to be called after every upstream
+// request attempt this Client makes, e.g. so a caller can wire
+// metrics.PrometheusMetrics.RecordUpstreamRequest.
+func (c *Client) SetUpstreamRequestHook(fn func(method, path string, statusCode int, duration time.Duration)) {
+	c.onUpstreamRequest = fn
+}
+
+// SetCircuitStateChangeHook registers fn to be called whenever any
+// per-host circuit breaker changes state, with key identifying which one
+// (see CircuitBreakerKeyFunc). It's a no-op if the circuit breaker is
+// disabled (CircuitBreakerEnabled: false).
+func (c *Client) SetCircuitStateChangeHook(fn func(key string, from, to CircuitState)) {
+	if c.circuitBreakers != nil {
+		c.circuitBreakers.SetOnStateChange(fn)
+	}
+}
+
+// UpdateCircuitBreakerConfig changes the threshold/timeout/halfOpenMax
+// applied to per-host circuit breakers created from now on (e.g. on a
+// config hot-reload), without rebuilding the Client or its connection
+// pool. It's a no-op if the circuit breaker is disabled.
+func (c *Client) UpdateCircuitBreakerConfig(threshold int, timeout time.Duration, halfOpenMax int) {
+	if c.circuitBreakers != nil {
+		c.circuitBreakers.UpdateConfig(threshold, timeout, halfOpenMax)
+	}
+}
+
+// apiKey returns the key currently attached to every outbound request,
+// synchronized against a concurrent SetAPIKey.
+func (c *Client) apiKey() string {
+	c.apiKeyMu.RLock()
+	defer c.apiKeyMu.RUnlock()
+	return c.APIKey
+}
+
+// SetAPIKey swaps the key attached to every outbound request from now on,
+// e.g. after a config.SecretProvider rotates a leased credential. A
+// request already in flight keeps using whatever key it read at send
+// time; nothing needs to be rebuilt or restarted to pick up the change.
+func (c *Client) SetAPIKey(apiKey string) {
+	c.apiKeyMu.Lock()
+	defer c.apiKeyMu.Unlock()
+	c.APIKey = apiKey
+}
+
+// AnyCircuitOpen reports whether any per-host circuit breaker is
+// currently open, for callers (e.g. health checks) that only care whether
+// something is broken, not which host.
+func (c *Client) AnyCircuitOpen() bool {
+	if c.circuitBreakers == nil {
+		return false
+	}
+	return c.circuitBreakers.AnyOpen()
+}
+
+// GetCircuitBreakerStats returns circuit breaker statistics for every host
+// this Client has talked to, keyed the same way as CircuitBreakerKeyFunc.
 func (c *Client) GetCircuitBreakerStats() map[string]interface{} {
-	if c.circuitBreaker == nil {
+	if c.circuitBreakers == nil {
 		return map[string]interface{}{"enabled": false}
 	}
-	stats := c.circuitBreaker.Stats()
-	stats["enabled"] = true
-	return stats
+	return map[string]interface{}{
+		"enabled": true,
+		"hosts":   c.circuitBreakers.Stats(),
+	}
 }
 
 // GetSingleFlightStats returns request deduplication statistics
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+)
+
+// LockBackend acquires and releases a short-lived, named lock in a shared
+// backend, so that multiple apiproxyd processes can coordinate which one
+// executes a given singleflight call. TryLock must be non-blocking: it
+// either wins the lock immediately or reports ok=false so the caller can
+// fall back to polling or local execution.
+type LockBackend interface {
+	// TryLock attempts to acquire key for ttl, returning a token that
+	// proves ownership for the matching Unlock call. ok is false if
+	// another holder currently has the lock.
+	TryLock(key string, ttl time.Duration) (token string, ok bool, err error)
+
+	// Unlock releases key if token still matches the current holder. It is
+	// not an error to Unlock a key whose TTL has already expired.
+	Unlock(key, token string) error
+}
+
+// DistributedSingleFlight deduplicates concurrent calls for the same key
+// across a cluster of daemons, rather than just within one process (see
+// SingleFlight). The first daemon to win the shared lock executes fn and
+// writes the result to cache; every other daemon asking for the same key
+// polls the cache for that result instead of calling fn itself. If the
+// lock is held elsewhere and no result appears before maxWait elapses
+// (e.g. the lock holder died mid-flight), the caller falls back to
+// executing fn locally rather than blocking indefinitely.
+type DistributedSingleFlight struct {
+	lock  LockBackend
+	cache cache.Cache
+
+	lockTTL      time.Duration
+	pollInterval time.Duration
+	maxWait      time.Duration
+
+	// OnLockWait, if set, is called after every poll loop with how long
+	// this call waited for the lock holder's result before either finding
+	// it or giving up and falling back to local execution. Daemon.Start
+	// wires this to metrics.PrometheusMetrics.RecordDedupLockWait so
+	// lock-wait latency shows up in the existing Prometheus registry.
+	OnLockWait func(waited time.Duration)
+
+	inFlight        int64
+	lockWins        int64
+	lockWaits       int64
+	localFallbacks  int64
+	cacheResultHits int64
+}
+
+// NewDistributedSingleFlight creates a DistributedSingleFlight. lockTTL
+// ties the shared lock's lifetime to the client's RequestTimeout, so a
+// daemon that dies mid-flight doesn't strand the key locked forever; the
+// poll backoff caps its total wait at the same duration before falling
+// back to local execution.
+func NewDistributedSingleFlight(lock LockBackend, c cache.Cache, lockTTL time.Duration) *DistributedSingleFlight {
+	if lockTTL <= 0 {
+		lockTTL = 30 * time.Second
+	}
+	return &DistributedSingleFlight{
+		lock:         lock,
+		cache:        c,
+		lockTTL:      lockTTL,
+		pollInterval: 50 * time.Millisecond,
+		maxWait:      lockTTL,
+	}
+}
+
+// Do executes fn, deduplicating concurrent calls for key across the
+// cluster. The cache entry written by the winner uses key verbatim, so
+// callers should pass the same cache key they'd use to store the final
+// response (e.g. the proxy's own cache key for the request).
+func (d *DistributedSingleFlight) Do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	return d.DoContext(context.Background(), key, func(context.Context) ([]byte, error) {
+		return fn()
+	})
+}
+
+// DoContext is like Do, but a caller waiting on another daemon's lock can
+// give up as soon as ctx is canceled instead of riding out the full poll
+// loop, and the winner's fn is given ctx so its own upstream call can be
+// aborted too.
+func (d *DistributedSingleFlight) DoContext(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	atomic.AddInt64(&d.inFlight, 1)
+	defer atomic.AddInt64(&d.inFlight, -1)
+
+	token, ok, err := d.lock.TryLock(key, d.lockTTL)
+	if err != nil {
+		// The lock backend itself is unhealthy; don't let that take down
+		// request serving, just execute locally.
+		return fn(ctx)
+	}
+
+	if ok {
+		atomic.AddInt64(&d.lockWins, 1)
+		defer d.lock.Unlock(key, token)
+
+		val, err := fn(ctx)
+		if err == nil {
+			// Best-effort: a failed cache write just means losers fall back
+			// to local execution instead of seeing a stale/missing result.
+			d.cache.Set(key, val)
+		}
+		return val, err
+	}
+
+	return d.waitForResult(ctx, key, fn)
+}
+
+// waitForResult polls cache for the winner's result with a bounded,
+// fixed-interval backoff, falling back to local execution of fn if maxWait
+// elapses first or ctx is canceled first.
+func (d *DistributedSingleFlight) waitForResult(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	atomic.AddInt64(&d.lockWaits, 1)
+	deadline := time.Now().Add(d.maxWait)
+	start := time.Now()
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			if d.OnLockWait != nil {
+				d.OnLockWait(time.Since(start))
+			}
+			return nil, ctx.Err()
+		case <-time.After(d.pollInterval):
+		}
+		if val, err := d.cache.Get(key); err == nil && val != nil {
+			atomic.AddInt64(&d.cacheResultHits, 1)
+			if d.OnLockWait != nil {
+				d.OnLockWait(time.Since(start))
+			}
+			return val, nil
+		}
+	}
+
+	atomic.AddInt64(&d.localFallbacks, 1)
+	if d.OnLockWait != nil {
+		d.OnLockWait(time.Since(start))
+	}
+	return fn(ctx)
+}
+
+// Stats returns deduplication statistics in the same shape as
+// SingleFlight.Stats, plus the distributed-specific counters.
+func (d *DistributedSingleFlight) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"in_flight":         atomic.LoadInt64(&d.inFlight),
+		"lock_wins":         atomic.LoadInt64(&d.lockWins),
+		"lock_waits":        atomic.LoadInt64(&d.lockWaits),
+		"cache_result_hits": atomic.LoadInt64(&d.cacheResultHits),
+		"local_fallbacks":   atomic.LoadInt64(&d.localFallbacks),
+	}
+}
+
+// PostgresLockBackend implements LockBackend on a small lock-tracking
+// table rather than session-scoped pg_advisory_lock, since a pooled
+// *sql.DB hands out a different underlying connection per call and
+// session-scoped advisory locks only release when that specific
+// connection closes or calls pg_advisory_unlock itself. A single
+// INSERT ... ON CONFLICT guarded by an expiry column gives the same
+// non-blocking try-lock semantics with an explicit TTL, which is what
+// lets TryLock reclaim a lock stranded by a daemon that died mid-flight.
+// hashtext(key) is still used for the row key, consistent with how an
+// advisory lock would key itself, so key collisions behave the same way.
+type PostgresLockBackend struct {
+	db *sql.DB
+}
+
+// NewPostgresLockBackend wraps db, creating the lock-tracking table if it
+// doesn't already exist.
+func NewPostgresLockBackend(db *sql.DB) (*PostgresLockBackend, error) {
+	const schema = `
+CREATE TABLE IF NOT EXISTS apiproxyd_dedup_locks (
+	key_hash    BIGINT PRIMARY KEY,
+	lock_key    TEXT NOT NULL,
+	token       TEXT NOT NULL,
+	expires_at  TIMESTAMPTZ NOT NULL
+)`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to initialize dedup lock table: %w", err)
+	}
+	return &PostgresLockBackend{db: db}, nil
+}
+
+// TryLock attempts to insert or reclaim the row for key. The WHERE clause
+// on the DO UPDATE lets a caller "win" a row whose previous holder's TTL
+// has already expired, without a separate read-then-write race.
+func (l *PostgresLockBackend) TryLock(key string, ttl time.Duration) (string, bool, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", false, err
+	}
+
+	const query = `
+INSERT INTO apiproxyd_dedup_locks (key_hash, lock_key, token, expires_at)
+VALUES (hashtext($1), $1, $2, now() + $3::interval)
+ON CONFLICT (key_hash) DO UPDATE
+	SET token = EXCLUDED.token, expires_at = EXCLUDED.expires_at, lock_key = EXCLUDED.lock_key
+	WHERE apiproxyd_dedup_locks.expires_at < now()`
+
+	res, err := l.db.Exec(query, key, token, fmt.Sprintf("%d milliseconds", ttl.Milliseconds()))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to try lock: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check lock result: %w", err)
+	}
+	// rows == 0 means the row exists and is still held by someone else.
+	return token, rows > 0, nil
+}
+
+// Unlock deletes the lock row if token still matches the current holder,
+// so a caller whose TTL already expired (and was reclaimed by another
+// daemon) can't accidentally release someone else's lock.
+func (l *PostgresLockBackend) Unlock(key, token string) error {
+	const query = `DELETE FROM apiproxyd_dedup_locks WHERE key_hash = hashtext($1) AND token = $2`
+	_, err := l.db.Exec(query, key, token)
+	return err
+}
+
+// randomToken returns a random hex token identifying a lock holder, used
+// to guard Unlock against releasing a lock some other daemon has since
+// re-acquired after this one's TTL expired.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import "sync"
+
+// RetryBudget implements a gRPC-style token-bucket retry budget: every
+// successful request deposits Ratio tokens, every retry withdraws one, and
+// retries are refused while the balance is at or below MinTokens. Capping
+// retries as a fraction of successful traffic (rather than a fixed count)
+// prevents a partial outage from turning into a retry storm that amplifies
+// load on an already-struggling upstream. The balance starts at MinTokens,
+// so a new budget allows no retry until successful requests have deposited
+// more than MinTokens.
+type RetryBudget struct {
+	mu        sync.Mutex
+	tokens    float64
+	ratio     float64
+	minTokens float64
+	maxTokens float64
+}
+
+// NewRetryBudget creates a budget that grows by ratio tokens per successful
+// request, floored at minTokens and capped at 10x minTokens.
+func NewRetryBudget(ratio, minTokens float64) *RetryBudget {
+	if ratio <= 0 {
+		ratio = 0.1
+	}
+	if minTokens <= 0 {
+		minTokens = 10
+	}
+
+	return &RetryBudget{
+		tokens:    minTokens,
+		ratio:     ratio,
+		minTokens: minTokens,
+		maxTokens: minTokens * 10,
+	}
+}
+
+// Deposit credits the budget for a successful request.
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// Withdraw attempts to consume one token for a retry. It returns false,
+// leaving the balance untouched, once the balance has been drawn down to
+// minTokens - further retries are refused until more requests succeed.
+func (b *RetryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens <= b.minTokens {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Tokens returns the current balance, for stats/metrics.
+func (b *RetryBudget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
@@ -1,9 +1,23 @@
 package client
 
 import (
+	"context"
 	"sync"
 )
 
+// Deduplicator coalesces concurrent calls for the same key into a single
+// execution of fn. SingleFlight implements this within one daemon process;
+// DistributedSingleFlight implements the same contract across a cluster of
+// daemons that share a common cache backend.
+type Deduplicator interface {
+	Do(key string, fn func() ([]byte, error)) ([]byte, error)
+	// DoContext is like Do, but a waiter that isn't the one executing fn
+	// can abandon its wait when ctx is canceled without affecting the
+	// shared call, which keeps running for every other waiter.
+	DoContext(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error)
+	Stats() map[string]interface{}
+}
+
 // SingleFlight prevents duplicate requests for the same key
 // When multiple concurrent requests arrive for the same resource,
 // only one actually executes while others wait for the result.
@@ -13,10 +27,14 @@ type SingleFlight struct {
 	calls map[string]*call
 }
 
+// call represents an in-flight (or just-finished) execution shared by every
+// caller that asked for the same key. done is closed (rather than a
+// sync.WaitGroup) so a waiter can select on it alongside its own ctx.Done()
+// and give up on waiting without touching the shared call itself.
 type call struct {
-	wg  sync.WaitGroup
-	val []byte
-	err error
+	done chan struct{}
+	val  []byte
+	err  error
 }
 
 // NewSingleFlight creates a new single flight instance
@@ -28,30 +46,43 @@ func NewSingleFlight() *SingleFlight {
 
 // Do executes a function, deduplicating concurrent calls with the same key
 func (sf *SingleFlight) Do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	return sf.DoContext(context.Background(), key, func(context.Context) ([]byte, error) {
+		return fn()
+	})
+}
+
+// DoContext is like Do, but if ctx is canceled while this caller is waiting
+// on another goroutine's in-flight call for key, it returns ctx.Err()
+// immediately instead of waiting for that call to finish. The winner (the
+// caller that actually executes fn) always runs fn to completion for the
+// benefit of every other waiter, regardless of its own ctx.
+func (sf *SingleFlight) DoContext(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
 	sf.mu.Lock()
 
 	// Check if there's already a call in flight for this key
 	if c, ok := sf.calls[key]; ok {
 		sf.mu.Unlock()
-		// Wait for the in-flight call to complete
-		c.wg.Wait()
-		return c.val, c.err
+		select {
+		case <-c.done:
+			return c.val, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 
 	// Create new call
-	c := &call{}
-	c.wg.Add(1)
+	c := &call{done: make(chan struct{})}
 	sf.calls[key] = c
 	sf.mu.Unlock()
 
 	// Execute the function
-	c.val, c.err = fn()
+	c.val, c.err = fn(ctx)
 
 	// Mark as done and cleanup
 	sf.mu.Lock()
 	delete(sf.calls, key)
 	sf.mu.Unlock()
-	c.wg.Done()
+	close(c.done)
 
 	return c.val, c.err
 }
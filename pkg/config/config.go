@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,6 +25,17 @@ type ServerConfig struct {
 	TLSKeyFile  string `yaml:"tls_key_file" json:"tls_key_file"`
 	// HTTP/2 support (enabled by default with TLS)
 	EnableHTTP2 bool `yaml:"enable_http2" json:"enable_http2"`
+	// Mutual TLS (mTLS) client authentication: the server requires and
+	// verifies a client certificate signed by ClientCAFile before the TLS
+	// handshake completes. AllowedClientCNs gates every route by default;
+	// MTLSRoutePolicy (route prefix -> allowed CNs) overrides it for
+	// specific routes, e.g. restricting /cache/clear and /metrics to an ops
+	// certificate while leaving /api/ open to any client cert that
+	// verified.
+	MTLSEnabled      bool                `yaml:"mtls_enabled" json:"mtls_enabled"`
+	ClientCAFile     string              `yaml:"client_ca_file,omitempty" json:"client_ca_file,omitempty"`
+	AllowedClientCNs []string            `yaml:"allowed_client_cns,omitempty" json:"allowed_client_cns,omitempty"`
+	MTLSRoutePolicy  map[string][]string `yaml:"mtls_route_policy,omitempty" json:"mtls_route_policy,omitempty"`
 }
 
 type CacheConfig struct {
@@ -34,34 +47,101 @@ type CacheConfig struct {
 	MemoryCacheEnabled bool `yaml:"memory_cache_enabled" json:"memory_cache_enabled"`
 	MemoryCacheSize    int  `yaml:"memory_cache_size" json:"memory_cache_size"` // number of entries
 	// Database connection pooling
-	MaxOpenConns    int `yaml:"max_open_conns" json:"max_open_conns"`       // max open connections
-	MaxIdleConns    int `yaml:"max_idle_conns" json:"max_idle_conns"`       // max idle connections
-	ConnMaxLifetime int `yaml:"conn_max_lifetime" json:"conn_max_lifetime"` // seconds
+	MaxOpenConns    int `yaml:"max_open_conns" json:"max_open_conns"`         // max open connections
+	MaxIdleConns    int `yaml:"max_idle_conns" json:"max_idle_conns"`         // max idle connections
+	ConnMaxLifetime int `yaml:"conn_max_lifetime" json:"conn_max_lifetime"`   // seconds
 	ConnMaxIdleTime int `yaml:"conn_max_idle_time" json:"conn_max_idle_time"` // seconds
 	// Background cleanup
 	CleanupInterval int `yaml:"cleanup_interval" json:"cleanup_interval"` // seconds
+	// MaxSizeBytes caps the cache's total size; the daemon's
+	// cache_size_enforcement scheduler job evicts entries once Stats().
+	// SizeBytes exceeds it. Zero (the default) disables enforcement.
+	MaxSizeBytes int64 `yaml:"max_size_bytes,omitempty" json:"max_size_bytes,omitempty"`
+	// Cache mode: "default" (honor RFC 7234), "bypass", "bypass_request",
+	// "bypass_response", or "strict". See cache.CacheMode.
+	Mode string `yaml:"mode" json:"mode"`
+	// ModeHeaderEnabled allows clients to override Mode per-request via the
+	// X-Cache-Mode header, restricted to AllowedModeOverrides.
+	ModeHeaderEnabled    bool     `yaml:"mode_header_enabled" json:"mode_header_enabled"`
+	AllowedModeOverrides []string `yaml:"allowed_mode_overrides" json:"allowed_mode_overrides"`
+	// Backend-specific option blocks, consulted only when Backend selects
+	// them. Which names are valid at all - "redis", "badger", "memcached",
+	// "s3", or a third party's own - comes from cache.DefaultRegistry
+	// rather than being hardcoded here; see Set and Validate.
+	Redis     *CacheRedisOptions     `yaml:"redis,omitempty" json:"redis,omitempty"`
+	Badger    *CacheBadgerOptions    `yaml:"badger,omitempty" json:"badger,omitempty"`
+	Memcached *CacheMemcachedOptions `yaml:"memcached,omitempty" json:"memcached,omitempty"`
+	S3        *CacheS3Options        `yaml:"s3,omitempty" json:"s3,omitempty"`
+}
+
+// CacheRedisOptions configures `cache.backend: redis`. See
+// cache.RedisOptions, which this is translated into.
+type CacheRedisOptions struct {
+	Addr     string `yaml:"addr" json:"addr"`
+	DB       int    `yaml:"db,omitempty" json:"db,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	TLS      bool   `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
+
+// CacheBadgerOptions configures `cache.backend: badger`. See
+// cache.BadgerOptions, which this is translated into.
+type CacheBadgerOptions struct {
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+// CacheMemcachedOptions configures `cache.backend: memcached`. See
+// cache.MemcachedOptions, which this is translated into.
+type CacheMemcachedOptions struct {
+	Addr string `yaml:"addr" json:"addr"`
+}
+
+// CacheS3Options configures `cache.backend: s3`. See cache.S3Options,
+// which this is translated into.
+type CacheS3Options struct {
+	Bucket   string `yaml:"bucket" json:"bucket"`
+	Prefix   string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Region   string `yaml:"region,omitempty" json:"region,omitempty"`
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+}
+
+// RemoteConfigConfig configures the optional remote_config_poll scheduler
+// job. An empty URL leaves the job unregistered.
+type RemoteConfigConfig struct {
+	URL      string `yaml:"url,omitempty" json:"url,omitempty"`
+	Interval int    `yaml:"interval,omitempty" json:"interval,omitempty"` // seconds
 }
 
 type PluginConfig struct {
-	Enabled bool           `yaml:"enabled" json:"enabled"`
-	Plugins []PluginEntry  `yaml:"plugins" json:"plugins"`
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	Plugins []PluginEntry `yaml:"plugins" json:"plugins"`
 }
 
 type PluginEntry struct {
 	Name    string                 `yaml:"name" json:"name"`
-	Type    string                 `yaml:"type" json:"type"` // "go" or "python"
+	Type    string                 `yaml:"type" json:"type"` // "go", "python", "wasm", or "rpc"
 	Path    string                 `yaml:"path" json:"path"`
 	Enabled bool                   `yaml:"enabled" json:"enabled"`
 	Config  map[string]interface{} `yaml:"config,omitempty" json:"config,omitempty"`
+	// Source and Checksum record where `apiproxy plugin install` downloaded
+	// this artifact from, so `apiproxy plugin update` can re-fetch and
+	// re-verify it. Both are empty for a plugin installed from a local
+	// path, since there's nothing further upstream to pull.
+	Source   string `yaml:"source,omitempty" json:"source,omitempty"`
+	Checksum string `yaml:"checksum,omitempty" json:"checksum,omitempty"`
 }
 
 // SecurityConfig holds security-related settings
 type SecurityConfig struct {
 	// Rate limiting
-	RateLimitEnabled     bool `yaml:"rate_limit_enabled" json:"rate_limit_enabled"`
-	RateLimitPerIP       int  `yaml:"rate_limit_per_ip" json:"rate_limit_per_ip"`           // requests per minute
-	RateLimitPerKey      int  `yaml:"rate_limit_per_key" json:"rate_limit_per_key"`         // requests per minute
-	RateLimitBurst       int  `yaml:"rate_limit_burst" json:"rate_limit_burst"`             // burst size
+	RateLimitEnabled bool `yaml:"rate_limit_enabled" json:"rate_limit_enabled"`
+	RateLimitPerIP   int  `yaml:"rate_limit_per_ip" json:"rate_limit_per_ip"`   // requests per minute
+	RateLimitPerKey  int  `yaml:"rate_limit_per_key" json:"rate_limit_per_key"` // requests per minute
+	// RateLimitPerCN enables a third, per-client-certificate-CN bucket for
+	// deployments with Server.MTLSEnabled, so verified client identity can
+	// be rate limited independently of source IP or API key. Zero disables
+	// it.
+	RateLimitPerCN int `yaml:"rate_limit_per_cn,omitempty" json:"rate_limit_per_cn,omitempty"` // requests per minute
+	RateLimitBurst int `yaml:"rate_limit_burst" json:"rate_limit_burst"`                       // burst size
 	// Request/response size limits
 	MaxRequestBodySize  int64 `yaml:"max_request_body_size" json:"max_request_body_size"`   // bytes
 	MaxResponseBodySize int64 `yaml:"max_response_body_size" json:"max_response_body_size"` // bytes
@@ -69,29 +149,80 @@ type SecurityConfig struct {
 	SSRFProtectionEnabled bool     `yaml:"ssrf_protection_enabled" json:"ssrf_protection_enabled"`
 	AllowedUpstreamHosts  []string `yaml:"allowed_upstream_hosts" json:"allowed_upstream_hosts"`
 	BlockPrivateIPs       bool     `yaml:"block_private_ips" json:"block_private_ips"`
+	// SSRFDenyCIDRs are always rejected regardless of BlockPrivateIPs, e.g.
+	// cloud metadata endpoints. SSRFAllowCIDRs overrides both of these for
+	// addresses that legitimately need to reach internal services.
+	SSRFDenyCIDRs  []string `yaml:"ssrf_deny_cidrs" json:"ssrf_deny_cidrs"`
+	SSRFAllowCIDRs []string `yaml:"ssrf_allow_cidrs" json:"ssrf_allow_cidrs"`
 	// Metrics authentication
 	MetricsAuthEnabled bool   `yaml:"metrics_auth_enabled" json:"metrics_auth_enabled"`
 	MetricsAuthToken   string `yaml:"metrics_auth_token" json:"metrics_auth_token"`
+	// EventsAuthEnabled gates GET /events using the same bearer token
+	// mechanism as MetricsAuthToken, rather than introducing a second token.
+	EventsAuthEnabled bool `yaml:"events_auth_enabled,omitempty" json:"events_auth_enabled,omitempty"`
 }
 
 // ClientConfig holds HTTP client configuration
 type ClientConfig struct {
 	// Timeouts
-	RequestTimeout  int `yaml:"request_timeout" json:"request_timeout"`   // seconds
-	DialTimeout     int `yaml:"dial_timeout" json:"dial_timeout"`         // seconds
-	KeepAlive       int `yaml:"keep_alive" json:"keep_alive"`             // seconds
+	RequestTimeout int `yaml:"request_timeout" json:"request_timeout"` // seconds
+	DialTimeout    int `yaml:"dial_timeout" json:"dial_timeout"`       // seconds
+	KeepAlive      int `yaml:"keep_alive" json:"keep_alive"`           // seconds
 	// Connection pooling
 	MaxIdleConns        int `yaml:"max_idle_conns" json:"max_idle_conns"`
 	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
 	MaxConnsPerHost     int `yaml:"max_conns_per_host" json:"max_conns_per_host"`
 	IdleConnTimeout     int `yaml:"idle_conn_timeout" json:"idle_conn_timeout"` // seconds
 	// Circuit breaker
-	CircuitBreakerEnabled    bool `yaml:"circuit_breaker_enabled" json:"circuit_breaker_enabled"`
-	CircuitBreakerThreshold  int  `yaml:"circuit_breaker_threshold" json:"circuit_breaker_threshold"`   // consecutive failures
-	CircuitBreakerTimeout    int  `yaml:"circuit_breaker_timeout" json:"circuit_breaker_timeout"`       // seconds
-	CircuitBreakerHalfOpen   int  `yaml:"circuit_breaker_half_open" json:"circuit_breaker_half_open"`   // max requests in half-open
+	CircuitBreakerEnabled   bool `yaml:"circuit_breaker_enabled" json:"circuit_breaker_enabled"`
+	CircuitBreakerThreshold int  `yaml:"circuit_breaker_threshold" json:"circuit_breaker_threshold"` // consecutive failures
+	CircuitBreakerTimeout   int  `yaml:"circuit_breaker_timeout" json:"circuit_breaker_timeout"`     // seconds
+	CircuitBreakerHalfOpen  int  `yaml:"circuit_breaker_half_open" json:"circuit_breaker_half_open"` // max requests in half-open
+	// CircuitBreakerWindow and CircuitBreakerBuckets switch the breaker from
+	// the legacy consecutive-failure threshold above to a rolling
+	// time-bucket failure-rate window. CircuitBreakerBuckets defaults to 0,
+	// which keeps the legacy threshold behavior.
+	CircuitBreakerWindow       int     `yaml:"circuit_breaker_window,omitempty" json:"circuit_breaker_window,omitempty"` // seconds
+	CircuitBreakerBuckets      int     `yaml:"circuit_breaker_buckets,omitempty" json:"circuit_breaker_buckets,omitempty"`
+	CircuitBreakerMinRequests  int     `yaml:"circuit_breaker_min_requests,omitempty" json:"circuit_breaker_min_requests,omitempty"`
+	CircuitBreakerFailureRatio float64 `yaml:"circuit_breaker_failure_ratio,omitempty" json:"circuit_breaker_failure_ratio,omitempty"`
+	// CircuitBreakerStatePath, if set, persists per-host circuit breaker
+	// state to a BadgerDB at this directory, so a daemon restart during an
+	// incident rehydrates each breaker's prior state instead of starting
+	// closed and immediately hammering a host already known to be failing.
+	// Empty (the default) keeps breaker state in memory only.
+	CircuitBreakerStatePath string `yaml:"circuit_breaker_state_path,omitempty" json:"circuit_breaker_state_path,omitempty"`
 	// Request deduplication
 	DeduplicationEnabled bool `yaml:"deduplication_enabled" json:"deduplication_enabled"`
+	// DedupBackend selects request deduplication scope: "local" (default)
+	// deduplicates only within this daemon process; "distributed" shares
+	// in-flight calls across every daemon pointed at the same cache
+	// backend (see client.DistributedSingleFlight). Distributed mode is
+	// only available when Cache.Backend is "postgres"/"postgresql".
+	DedupBackend string `yaml:"dedup_backend,omitempty" json:"dedup_backend,omitempty"`
+	// Retry budget: caps retries as a fraction of successful requests so a
+	// partial outage can't turn into a retry storm (see client.RetryBudget).
+	// A zero RetryBudgetRatio disables retries entirely.
+	RetryBudgetRatio     float64 `yaml:"retry_budget_ratio,omitempty" json:"retry_budget_ratio,omitempty"`
+	RetryBudgetMinTokens float64 `yaml:"retry_budget_min_tokens,omitempty" json:"retry_budget_min_tokens,omitempty"`
+	// Retry controls which requests client.Client retries at all (by
+	// method and response status) and how it backs off between attempts,
+	// independent of the RetryBudget above: the budget caps how many
+	// retries the client can issue in aggregate, Retry decides whether a
+	// given failure is retryable in the first place. See client.RetryPolicy.
+	Retry RetryConfig `yaml:"retry,omitempty" json:"retry,omitempty"`
+}
+
+// RetryConfig configures client.Client's request-level retry-with-backoff.
+// A disabled (or zero-value) Retry falls back to client.DefaultRetryPolicy.
+type RetryConfig struct {
+	Enabled        bool     `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	MaxAttempts    int      `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	InitialBackoff int      `yaml:"initial_backoff,omitempty" json:"initial_backoff,omitempty"` // ms
+	MaxBackoff     int      `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`         // ms
+	Multiplier     float64  `yaml:"multiplier,omitempty" json:"multiplier,omitempty"`
+	RetryOn        []int    `yaml:"retry_on,omitempty" json:"retry_on,omitempty"` // status codes
+	RetryOnMethods []string `yaml:"retry_on_methods,omitempty" json:"retry_on_methods,omitempty"`
 }
 
 type Config struct {
@@ -114,11 +245,36 @@ type Config struct {
 	// Client configuration
 	Client ClientConfig `yaml:"client,omitempty" json:"client,omitempty"`
 
+	// Secrets selects a SecretProvider used to resolve APIKey and other
+	// credentials at runtime instead of reading them as plaintext from
+	// this file. Empty Provider leaves those fields as-is.
+	Secrets SecretsConfig `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+
+	// RemoteConfig, if URL is set, has the daemon's scheduler periodically
+	// fetch a JSON-encoded Config from URL and reload it in place - useful
+	// for a fleet pulling its configuration from a central service instead
+	// of a file on each host. See the remote_config_poll scheduler job.
+	RemoteConfig RemoteConfigConfig `yaml:"remote_config,omitempty" json:"remote_config,omitempty"`
+
 	// Offline endpoints - cached indefinitely, work without internet
-	OfflineEndpoints []string `yaml:"offline_endpoints" json:"offline_endpoints"`
+	OfflineEndpoints []EndpointEntry `yaml:"offline_endpoints" json:"offline_endpoints"`
+
+	// Whitelisted endpoints - allowed to be proxied. Entries may carry
+	// per-endpoint cache/rate-limit overrides; see EndpointEntry and
+	// EndpointPolicy.
+	WhitelistedEndpoints []EndpointEntry `yaml:"whitelisted_endpoints" json:"whitelisted_endpoints"`
 
-	// Whitelisted endpoints - allowed to be proxied
-	WhitelistedEndpoints []string `yaml:"whitelisted_endpoints" json:"whitelisted_endpoints"`
+	// Streaming endpoints - proxied with the response body relayed to the
+	// client as it arrives instead of buffered in full first. Useful for
+	// NDJSON/SSE/chunked-array upstreams where buffering would pin large
+	// allocations and delay the first byte to the client.
+	StreamingEndpoints []EndpointEntry `yaml:"streaming_endpoints,omitempty" json:"streaming_endpoints,omitempty"`
+
+	// compileEndpointsOnce guards lazily compiling WhitelistedEndpoints/
+	// OfflineEndpoints/StreamingEndpoints the first time EndpointPolicy is
+	// called on a Config that didn't go through Load/Normalize (e.g. one
+	// built directly in a test).
+	compileEndpointsOnce sync.Once
 
 	// Legacy fields for backward compatibility
 	UserID string `yaml:"user_id,omitempty" json:"user_id,omitempty"`
@@ -159,41 +315,57 @@ func Default() *Config {
 			ConnMaxLifetime:    300,  // 5 minutes
 			ConnMaxIdleTime:    60,   // 1 minute
 			CleanupInterval:    3600, // 1 hour
+			Mode:               "default",
 		},
 		Security: SecurityConfig{
 			RateLimitEnabled:      true,
 			RateLimitPerIP:        60,  // 60 req/min per IP
 			RateLimitPerKey:       300, // 300 req/min per API key
 			RateLimitBurst:        10,
-			MaxRequestBodySize:    10 * 1024 * 1024,  // 10MB
-			MaxResponseBodySize:   50 * 1024 * 1024,  // 50MB
+			MaxRequestBodySize:    10 * 1024 * 1024, // 10MB
+			MaxResponseBodySize:   50 * 1024 * 1024, // 50MB
 			SSRFProtectionEnabled: true,
 			AllowedUpstreamHosts:  []string{"api.apiproxy.app"},
 			BlockPrivateIPs:       true,
-			MetricsAuthEnabled:    false,
+			SSRFDenyCIDRs: []string{
+				"169.254.169.254/32", // cloud metadata (AWS/GCP/Azure)
+				"fd00:ec2::254/128",  // AWS IMDSv2 IPv6
+			},
+			MetricsAuthEnabled: false,
 		},
 		Client: ClientConfig{
-			RequestTimeout:           30,
-			DialTimeout:              10,
-			KeepAlive:                30,
-			MaxIdleConns:             100,
-			MaxIdleConnsPerHost:      10,
-			MaxConnsPerHost:          100,
-			IdleConnTimeout:          90,
-			CircuitBreakerEnabled:    true,
-			CircuitBreakerThreshold:  5,
-			CircuitBreakerTimeout:    60,
-			CircuitBreakerHalfOpen:   3,
-			DeduplicationEnabled:     true,
+			RequestTimeout:          30,
+			DialTimeout:             10,
+			KeepAlive:               30,
+			MaxIdleConns:            100,
+			MaxIdleConnsPerHost:     10,
+			MaxConnsPerHost:         100,
+			IdleConnTimeout:         90,
+			CircuitBreakerEnabled:   true,
+			CircuitBreakerThreshold: 5,
+			CircuitBreakerTimeout:   60,
+			CircuitBreakerHalfOpen:  3,
+			DeduplicationEnabled:    true,
+			RetryBudgetRatio:        0.1,
+			RetryBudgetMinTokens:    10,
+			Retry: RetryConfig{
+				Enabled:        true,
+				MaxAttempts:    3,
+				InitialBackoff: 100,
+				MaxBackoff:     2000,
+				Multiplier:     2.0,
+				RetryOn:        []int{429, 502, 503, 504},
+				RetryOnMethods: []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE"},
+			},
 		},
-		OfflineEndpoints: []string{
-			"/health",
-			"/status",
+		OfflineEndpoints: []EndpointEntry{
+			{Pattern: "/health"},
+			{Pattern: "/status"},
 		},
-		WhitelistedEndpoints: []string{
-			"/v1/darkapi/*",
-			"/v1/nerdapi/*",
-			"/v1/computeapi/*",
+		WhitelistedEndpoints: []EndpointEntry{
+			{Pattern: "/v1/darkapi/*"},
+			{Pattern: "/v1/nerdapi/*"},
+			{Pattern: "/v1/computeapi/*"},
 		},
 	}
 }
@@ -230,6 +402,8 @@ func (c *Config) Normalize() {
 	if c.Server.WriteTimeout == 0 {
 		c.Server.WriteTimeout = 15
 	}
+
+	c.compileEndpointsOnce.Do(c.compileEndpoints)
 }
 
 // Load reads configuration from file (supports both YAML and JSON)
@@ -265,6 +439,31 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// LoadFile reads configuration from an explicit path, e.g. for
+// `apiproxy config validate --file`, rather than Load's usual
+// config.json/config.yml search. Format is chosen by file extension:
+// ".json" parses as JSON, anything else as YAML.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg Config
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	cfg.Normalize()
+	return &cfg, nil
+}
+
 // LoadJSON loads config from config.json specifically
 func LoadJSON() (*Config, error) {
 	path := ConfigJSONPath()
@@ -373,8 +572,8 @@ func (c *Config) Set(key, value string) error {
 		}
 		c.Server.WriteTimeout = timeout
 	case "cache.backend", "cache_backend":
-		if value != "sqlite" && value != "postgres" {
-			return fmt.Errorf("invalid cache backend: %s (must be sqlite or postgres)", value)
+		if _, ok := cache.DefaultRegistry.Factory(value); !ok {
+			return fmt.Errorf("invalid cache backend: %s (must be one of: %s)", value, strings.Join(cache.DefaultRegistry.Names(), ", "))
 		}
 		c.Cache.Backend = value
 	case "cache.path", "cache_path":
@@ -387,51 +586,19 @@ func (c *Config) Set(key, value string) error {
 		c.Cache.TTL = ttl
 	case "cache.postgres_dsn", "postgres.dsn", "postgres_dsn":
 		c.Cache.PostgresDSN = value
+	case "cache.mode":
+		switch value {
+		case "default", "bypass", "bypass_request", "bypass_response", "strict":
+			c.Cache.Mode = value
+		default:
+			return fmt.Errorf("invalid cache mode: %s (must be default, bypass, bypass_request, bypass_response, or strict)", value)
+		}
 	default:
 		return fmt.Errorf("unknown config key: %s", key)
 	}
 	return nil
 }
 
-// IsEndpointWhitelisted checks if an endpoint is whitelisted
-func (c *Config) IsEndpointWhitelisted(endpoint string) bool {
-	for _, pattern := range c.WhitelistedEndpoints {
-		if matchPattern(pattern, endpoint) {
-			return true
-		}
-	}
-	return false
-}
-
-// IsEndpointOffline checks if an endpoint supports offline mode
-func (c *Config) IsEndpointOffline(endpoint string) bool {
-	for _, pattern := range c.OfflineEndpoints {
-		if matchPattern(pattern, endpoint) {
-			return true
-		}
-	}
-	return false
-}
-
-// matchPattern performs simple wildcard matching
-func matchPattern(pattern, str string) bool {
-	if pattern == str {
-		return true
-	}
-
-	if strings.HasSuffix(pattern, "/*") {
-		prefix := strings.TrimSuffix(pattern, "/*")
-		return strings.HasPrefix(str, prefix)
-	}
-
-	if strings.HasSuffix(pattern, "*") {
-		prefix := strings.TrimSuffix(pattern, "*")
-		return strings.HasPrefix(str, prefix)
-	}
-
-	return false
-}
-
 // ToJSON converts config to JSON
 func (c *Config) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(c, "", "  ")
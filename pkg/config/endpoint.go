@@ -0,0 +1,332 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointEntry is one rule in WhitelistedEndpoints, OfflineEndpoints, or
+// StreamingEndpoints. It's usually written as a plain string pattern:
+//
+//	"/v1/darkapi/*"          // glob, matches anything under /v1/darkapi/
+//	"GET:/v1/nerdapi/*"      // method-scoped glob
+//	"regex:^/v1/.*/hash$"    // full regular expression
+//
+// but can also be written as a mapping when it needs to carry per-endpoint
+// overrides:
+//
+//	{method: POST, path: /v1/nerdapi/hash, cache: false}
+//	{path: /v1/darkapi/ip/*, ttl: 604800}
+//
+// See UnmarshalYAML/UnmarshalJSON for how the two forms are told apart, and
+// Config.EndpointPolicy for how a compiled entry is matched against a
+// request.
+type EndpointEntry struct {
+	// Pattern holds the entry verbatim when it was written as a plain
+	// string rather than a mapping; compile() parses it straight into the
+	// entry's matcher and leaves Method and Path empty. Left empty for
+	// mapping-form entries, which set Method/Path directly instead.
+	Pattern string `yaml:"-" json:"-"`
+
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+	Path   string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Cache set to false disables caching for requests matching this
+	// entry; nil (the default) leaves caching behavior unchanged.
+	Cache *bool `yaml:"cache,omitempty" json:"cache,omitempty"`
+	// TTL overrides cache.ttl (seconds) for requests matching this entry.
+	// Zero means no override.
+	TTL int `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	// RateLimit overrides security.rate_limit_per_key (requests per
+	// minute) for requests matching this entry. Zero means no override.
+	RateLimit int `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+
+	matcher endpointMatcher
+}
+
+// UnmarshalYAML accepts either a scalar string ("/v1/darkapi/*") or a
+// mapping ({method: GET, path: /v1/darkapi/*}), so a single endpoint list
+// can mix simple patterns with entries that carry overrides.
+func (e *EndpointEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&e.Pattern)
+	}
+
+	type plain EndpointEntry
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	*e = EndpointEntry(p)
+	return nil
+}
+
+// MarshalYAML round-trips a plain-pattern entry back to a bare string
+// instead of a mapping with every other field empty.
+func (e EndpointEntry) MarshalYAML() (interface{}, error) {
+	if e.Pattern != "" {
+		return e.Pattern, nil
+	}
+	type plain EndpointEntry
+	return plain(e), nil
+}
+
+// UnmarshalJSON mirrors UnmarshalYAML for the config.json format.
+func (e *EndpointEntry) UnmarshalJSON(data []byte) error {
+	var pattern string
+	if err := json.Unmarshal(data, &pattern); err == nil {
+		e.Pattern = pattern
+		return nil
+	}
+
+	type plain EndpointEntry
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*e = EndpointEntry(p)
+	return nil
+}
+
+// MarshalJSON mirrors MarshalYAML for the config.json format.
+func (e EndpointEntry) MarshalJSON() ([]byte, error) {
+	if e.Pattern != "" {
+		return json.Marshal(e.Pattern)
+	}
+	type plain EndpointEntry
+	return json.Marshal(plain(e))
+}
+
+// endpointMatcher is an EndpointEntry's pattern, compiled once so matching a
+// request doesn't recompile a regexp (or reparse a method prefix) on every
+// call.
+type endpointMatcher struct {
+	method string // uppercased; empty means "any method"
+	match  func(path string) bool
+}
+
+// compile builds e.matcher from e.Pattern (plain-string form) or
+// e.Method/e.Path (mapping form).
+func (e *EndpointEntry) compile() error {
+	if e.Pattern != "" {
+		m, err := compileEndpointPattern(e.Pattern)
+		if err != nil {
+			return err
+		}
+		e.matcher = m
+		return nil
+	}
+	if e.Path == "" {
+		return fmt.Errorf("entry must be a pattern string or set path")
+	}
+	re, err := globToRegex(e.Path)
+	if err != nil {
+		return fmt.Errorf("invalid path %q: %w", e.Path, err)
+	}
+	e.matcher = endpointMatcher{method: strings.ToUpper(e.Method), match: re.MatchString}
+	return nil
+}
+
+// matches reports whether method and path satisfy e. An empty method
+// ignores any method restriction on e, for callers (IsEndpointWhitelisted
+// and friends) that don't know the request method; pass the real method
+// through EndpointPolicy to honor method-scoped entries.
+func (e *EndpointEntry) matches(method, path string) bool {
+	if e.matcher.match == nil {
+		return false
+	}
+	if method != "" && e.matcher.method != "" && !strings.EqualFold(e.matcher.method, method) {
+		return false
+	}
+	return e.matcher.match(path)
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true,
+	"PATCH": true, "HEAD": true, "OPTIONS": true,
+}
+
+// compileEndpointPattern parses a plain-string pattern into an
+// endpointMatcher. It understands three forms: a "regex:" prefix taking a
+// full Go regular expression, a leading "METHOD:" scoping the rest of the
+// pattern to one HTTP method (e.g. "GET:/v1/darkapi/*"), and otherwise a
+// glob (see globToRegex).
+func compileEndpointPattern(pattern string) (endpointMatcher, error) {
+	if rest, ok := strings.CutPrefix(pattern, "regex:"); ok {
+		re, err := regexp.Compile(rest)
+		if err != nil {
+			return endpointMatcher{}, fmt.Errorf("invalid regex %q: %w", rest, err)
+		}
+		return endpointMatcher{match: re.MatchString}, nil
+	}
+
+	method, rest := "", pattern
+	if idx := strings.Index(pattern, ":"); idx != -1 {
+		if candidate := strings.ToUpper(pattern[:idx]); httpMethods[candidate] {
+			method, rest = candidate, pattern[idx+1:]
+		}
+	}
+
+	re, err := globToRegex(rest)
+	if err != nil {
+		return endpointMatcher{}, fmt.Errorf("invalid pattern %q: %w", rest, err)
+	}
+	return endpointMatcher{method: method, match: re.MatchString}, nil
+}
+
+// globToRegex compiles a glob pattern to an anchored regexp. It supports
+// "*" (matches within one path segment), "**" (matches across segments,
+// same as a trailing "*" always has - see below), "?" (one character) and
+// "[abc]"/"[!abc]" character classes.
+//
+// A "*" at the very end of the pattern is a special case: the original
+// matchPattern treated a trailing "*" as a full prefix match (it could
+// consume slashes), and every WhitelistedEndpoints entry shipped in
+// Default() ("/v1/darkapi/*" etc.) relies on that to match nested paths
+// like "/v1/darkapi/ip/123". A trailing "*" keeps that behavior instead of
+// being downgraded to a single-segment match.
+func globToRegex(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			switch {
+			case i+1 < len(pattern) && pattern[i+1] == '*':
+				b.WriteString(".*")
+				i++
+			case i == len(pattern)-1:
+				b.WriteString(".*")
+			default:
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end == -1 {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i : i+end+1]
+			if strings.HasPrefix(class, "[!") {
+				class = "[^" + class[2:]
+			}
+			b.WriteString(class)
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// compileEndpoints compiles every entry across WhitelistedEndpoints,
+// OfflineEndpoints, and StreamingEndpoints. It's idempotent, so Normalize
+// (called by every Load path) can run it eagerly while EndpointPolicy also
+// runs it lazily via compileEndpointsOnce, for a Config built directly
+// (e.g. in a test) without going through Load.
+func (c *Config) compileEndpoints() {
+	for i := range c.WhitelistedEndpoints {
+		if err := c.WhitelistedEndpoints[i].compile(); err != nil {
+			c.WhitelistedEndpoints[i].matcher = endpointMatcher{}
+		}
+	}
+	for i := range c.OfflineEndpoints {
+		if err := c.OfflineEndpoints[i].compile(); err != nil {
+			c.OfflineEndpoints[i].matcher = endpointMatcher{}
+		}
+	}
+	for i := range c.StreamingEndpoints {
+		if err := c.StreamingEndpoints[i].compile(); err != nil {
+			c.StreamingEndpoints[i].matcher = endpointMatcher{}
+		}
+	}
+}
+
+// Policy is what Config.EndpointPolicy decided for one request.
+type Policy struct {
+	Whitelisted bool
+	Offline     bool
+	Streaming   bool
+	// CacheDisabled is true when the matching WhitelistedEndpoints entry
+	// set "cache: false".
+	CacheDisabled bool
+	// CacheTTLOverride is the TTL (seconds) the matching WhitelistedEndpoints
+	// entry requested instead of cache.ttl; zero means no override.
+	CacheTTLOverride int
+	// RateLimitOverride is the requests-per-minute limit the matching
+	// WhitelistedEndpoints entry requested instead of
+	// security.rate_limit_per_key; zero means no override.
+	RateLimitOverride int
+}
+
+// EndpointPolicy is the single entry point the request pipeline consults
+// for a given method and path: whether the request is whitelisted at all,
+// whether it's an offline or streaming endpoint, and any per-endpoint
+// cache/rate-limit overrides carried by the matching WhitelistedEndpoints
+// entry. It replaces separately calling
+// IsEndpointWhitelisted/IsEndpointOffline/IsEndpointStreaming. The bool
+// result mirrors Policy.Whitelisted; a caller that only cares whether the
+// request is allowed through can ignore the Policy and just check it.
+func (c *Config) EndpointPolicy(method, path string) (Policy, bool) {
+	c.compileEndpointsOnce.Do(c.compileEndpoints)
+
+	var policy Policy
+	for i := range c.WhitelistedEndpoints {
+		e := &c.WhitelistedEndpoints[i]
+		if e.matches(method, path) {
+			policy.Whitelisted = true
+			if e.TTL > 0 {
+				policy.CacheTTLOverride = e.TTL
+			}
+			if e.Cache != nil && !*e.Cache {
+				policy.CacheDisabled = true
+			}
+			if e.RateLimit > 0 {
+				policy.RateLimitOverride = e.RateLimit
+			}
+			break
+		}
+	}
+	for i := range c.OfflineEndpoints {
+		if c.OfflineEndpoints[i].matches(method, path) {
+			policy.Offline = true
+			break
+		}
+	}
+	for i := range c.StreamingEndpoints {
+		if c.StreamingEndpoints[i].matches(method, path) {
+			policy.Streaming = true
+			break
+		}
+	}
+	return policy, policy.Whitelisted
+}
+
+// IsEndpointWhitelisted checks if an endpoint is whitelisted, ignoring any
+// method restriction on individual entries. Prefer EndpointPolicy when the
+// request method is known.
+func (c *Config) IsEndpointWhitelisted(endpoint string) bool {
+	_, ok := c.EndpointPolicy("", endpoint)
+	return ok
+}
+
+// IsEndpointOffline checks if an endpoint supports offline mode.
+func (c *Config) IsEndpointOffline(endpoint string) bool {
+	policy, _ := c.EndpointPolicy("", endpoint)
+	return policy.Offline
+}
+
+// IsEndpointStreaming checks if an endpoint should be proxied in streaming
+// mode (see StreamingEndpoints).
+func (c *Config) IsEndpointStreaming(endpoint string) bool {
+	policy, _ := c.EndpointPolicy("", endpoint)
+	return policy.Streaming
+}
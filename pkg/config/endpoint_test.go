@@ -0,0 +1,84 @@
+package config
+
+import "testing"
+
+func TestEndpointPolicyGlob(t *testing.T) {
+	cfg := Default()
+	cfg.WhitelistedEndpoints = []EndpointEntry{{Pattern: "/v1/darkapi/*"}}
+
+	if _, ok := cfg.EndpointPolicy("GET", "/v1/darkapi/ip/123"); !ok {
+		t.Error("trailing * should match nested paths, for backward compatibility with the old matchPattern")
+	}
+	if _, ok := cfg.EndpointPolicy("GET", "/v1/other"); ok {
+		t.Error("unrelated path should not match")
+	}
+}
+
+func TestEndpointPolicyRegex(t *testing.T) {
+	cfg := Default()
+	cfg.WhitelistedEndpoints = []EndpointEntry{{Pattern: `regex:^/v1/hash/[0-9]+$`}}
+
+	if _, ok := cfg.EndpointPolicy("GET", "/v1/hash/42"); !ok {
+		t.Error("regex: pattern should match")
+	}
+	if _, ok := cfg.EndpointPolicy("GET", "/v1/hash/abc"); ok {
+		t.Error("regex: pattern should not match non-numeric id")
+	}
+}
+
+func TestEndpointPolicyMethodScoped(t *testing.T) {
+	cfg := Default()
+	cfg.WhitelistedEndpoints = []EndpointEntry{{Pattern: "GET:/v1/darkapi/*"}}
+
+	if _, ok := cfg.EndpointPolicy("GET", "/v1/darkapi/ip"); !ok {
+		t.Error("GET should match a GET-scoped entry")
+	}
+	if _, ok := cfg.EndpointPolicy("POST", "/v1/darkapi/ip"); ok {
+		t.Error("POST should not match a GET-scoped entry")
+	}
+}
+
+func TestEndpointPolicyStructuredEntry(t *testing.T) {
+	cacheDisabled := false
+	cfg := Default()
+	cfg.WhitelistedEndpoints = []EndpointEntry{
+		{Method: "POST", Path: "/v1/nerdapi/hash", Cache: &cacheDisabled},
+		{Path: "/v1/darkapi/ip/*", TTL: 604800, RateLimit: 5},
+	}
+
+	policy, ok := cfg.EndpointPolicy("POST", "/v1/nerdapi/hash")
+	if !ok || !policy.CacheDisabled {
+		t.Errorf("EndpointPolicy() = %+v, ok=%v, want whitelisted with caching disabled", policy, ok)
+	}
+
+	policy, ok = cfg.EndpointPolicy("GET", "/v1/darkapi/ip/1.2.3.4")
+	if !ok || policy.CacheTTLOverride != 604800 || policy.RateLimitOverride != 5 {
+		t.Errorf("EndpointPolicy() = %+v, ok=%v, want ttl=604800 rate_limit=5", policy, ok)
+	}
+}
+
+func TestEndpointPolicyOfflineAndStreaming(t *testing.T) {
+	cfg := Default()
+	cfg.OfflineEndpoints = []EndpointEntry{{Pattern: "/health"}}
+	cfg.StreamingEndpoints = []EndpointEntry{{Pattern: "/v1/darkapi/feed/*"}}
+
+	policy, _ := cfg.EndpointPolicy("GET", "/health")
+	if !policy.Offline {
+		t.Error("/health should be offline")
+	}
+
+	policy, _ = cfg.EndpointPolicy("GET", "/v1/darkapi/feed/live")
+	if !policy.Streaming {
+		t.Error("/v1/darkapi/feed/live should be streaming")
+	}
+}
+
+func TestIsEndpointHelpersMatchEndpointPolicy(t *testing.T) {
+	cfg := Default()
+	if !cfg.IsEndpointWhitelisted("/v1/darkapi/anything") {
+		t.Error("IsEndpointWhitelisted should match a default whitelisted pattern")
+	}
+	if cfg.IsEndpointWhitelisted("/not/whitelisted") {
+		t.Error("IsEndpointWhitelisted should reject an unlisted path")
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Secret is a value resolved from a SecretProvider, along with the lease
+// expiry attached to it (if any). A zero ExpiresAt means the backend
+// doesn't lease this value (e.g. env, file), so nothing ever tries to
+// refresh it early.
+type Secret struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+// SecretProvider resolves a named secret (e.g. "api_key",
+// "metrics_auth_token", "postgres_dsn_password") from an external
+// credential backend instead of reading it as plaintext out of
+// config.yml/config.json. Implementations: envSecretProvider,
+// fileSecretProvider, vaultSecretProvider, awsSecretsManagerProvider.
+type SecretProvider interface {
+	Name() string
+	GetSecret(ctx context.Context, key string) (*Secret, error)
+}
+
+// SecretsConfig selects and configures the backend used to resolve
+// APIKey and other credentials at runtime. An empty Provider leaves those
+// fields as whatever plaintext value is already in Config.
+type SecretsConfig struct {
+	// Provider is "vault", "aws_secrets_manager", "file", or "env".
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// Path is the provider-specific secret location: a Vault KV v2 path
+	// ("secret/data/apiproxy"), an AWS Secrets Manager secret ID/ARN, or a
+	// directory of 0600 files (file provider). Unused by env.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Renew periodically refreshes a leased secret shortly before it
+	// expires instead of only resolving it once at startup.
+	Renew bool `yaml:"renew,omitempty" json:"renew,omitempty"`
+
+	// Vault (AppRole login + KV v2 read)
+	VaultAddr      string `yaml:"vault_addr,omitempty" json:"vault_addr,omitempty"`
+	VaultNamespace string `yaml:"vault_namespace,omitempty" json:"vault_namespace,omitempty"`
+	VaultRoleID    string `yaml:"vault_role_id,omitempty" json:"vault_role_id,omitempty"`
+	VaultSecretID  string `yaml:"vault_secret_id,omitempty" json:"vault_secret_id,omitempty"`
+
+	// AWS Secrets Manager. Credentials come from the ambient
+	// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN
+	// environment, same as the AWS CLI.
+	AWSRegion string `yaml:"aws_region,omitempty" json:"aws_region,omitempty"`
+}
+
+// NewSecretProvider builds the SecretProvider cfg selects, or nil if
+// cfg.Provider is empty (callers should fall back to Config's plaintext
+// fields in that case).
+func NewSecretProvider(cfg SecretsConfig) (SecretProvider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "env":
+		return newEnvSecretProvider(), nil
+	case "file":
+		return newFileSecretProvider(cfg.Path)
+	case "vault":
+		return newVaultSecretProvider(cfg)
+	case "aws", "aws_secrets_manager":
+		return newAWSSecretProvider(cfg)
+	default:
+		return nil, fmt.Errorf("config: unknown secrets provider %q", cfg.Provider)
+	}
+}
+
+// envSecretProvider resolves a secret key to the value of an environment
+// variable named APIPROXY_<KEY> (uppercased). It never leases a value, so
+// SecretsConfig.Renew has no effect on it.
+type envSecretProvider struct{}
+
+func newEnvSecretProvider() *envSecretProvider { return &envSecretProvider{} }
+
+func (p *envSecretProvider) Name() string { return "env" }
+
+func (p *envSecretProvider) GetSecret(_ context.Context, key string) (*Secret, error) {
+	envVar := "APIPROXY_" + strings.ToUpper(key)
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		return nil, fmt.Errorf("env secret provider: %s is not set", envVar)
+	}
+	return &Secret{Value: value}, nil
+}
+
+// fileSecretProvider resolves a secret key by reading <dir>/<key>,
+// refusing to read a file whose permissions are looser than 0600 so a
+// misconfigured deploy can't leave credentials group/world-readable.
+type fileSecretProvider struct {
+	dir string
+}
+
+func newFileSecretProvider(dir string) (*fileSecretProvider, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("file secret provider: secrets.path is required")
+	}
+	return &fileSecretProvider{dir: dir}, nil
+}
+
+func (p *fileSecretProvider) Name() string { return "file" }
+
+func (p *fileSecretProvider) GetSecret(_ context.Context, key string) (*Secret, error) {
+	path := filepath.Join(p.dir, key)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("file secret provider: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("file secret provider: %s must not be readable by group/other (mode %04o)", path, info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file secret provider: %w", err)
+	}
+	return &Secret{Value: strings.TrimSpace(string(data))}, nil
+}
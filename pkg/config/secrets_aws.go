@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// awsSecretsManagerProvider resolves secrets from an AWS Secrets Manager
+// secret, calling the GetSecretValue HTTP API directly and signing with
+// SigV4 rather than pulling in the aws-sdk-go dependency tree for one
+// call. Credentials come from the ambient AWS_ACCESS_KEY_ID /
+// AWS_SECRET_ACCESS_KEY / AWS_SESSION_TOKEN environment, the same source
+// the AWS CLI and SDKs use.
+type awsSecretsManagerProvider struct {
+	secretID string
+	region   string
+	client   *http.Client
+}
+
+func newAWSSecretProvider(cfg SecretsConfig) (*awsSecretsManagerProvider, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("aws secrets manager provider: secrets.path (secret ID/ARN) is required")
+	}
+	region := cfg.AWSRegion
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("aws secrets manager provider: secrets.aws_region (or AWS_REGION) is required")
+	}
+	return &awsSecretsManagerProvider{
+		secretID: cfg.Path,
+		region:   region,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *awsSecretsManagerProvider) Name() string { return "aws_secrets_manager" }
+
+// GetSecret calls GetSecretValue and looks up key in the secret's JSON
+// body. A secret stored as a single plain string rather than a JSON
+// object is returned as-is regardless of key, matching how
+// single-value secrets are typically created in Secrets Manager.
+func (p *awsSecretsManagerProvider) GetSecret(ctx context.Context, key string) (*Secret, error) {
+	reqBody, _ := json.Marshal(map[string]string{"SecretId": p.secretID})
+
+	req, err := p.signedRequest(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("aws secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("aws secrets manager: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		SecretString     string  `json:"SecretString"`
+		NextRotationDate float64 `json:"NextRotationDate"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("aws secrets manager: decode response: %w", err)
+	}
+
+	value := result.SecretString
+	var fields map[string]string
+	if json.Unmarshal([]byte(result.SecretString), &fields) == nil {
+		v, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("aws secrets manager: %s has no field %q", p.secretID, key)
+		}
+		value = v
+	}
+
+	secret := &Secret{Value: value}
+	if result.NextRotationDate > 0 {
+		secret.ExpiresAt = time.Unix(int64(result.NextRotationDate), 0)
+	}
+	return secret, nil
+}
+
+// signedRequest builds a SigV4-signed GetSecretValue POST request.
+func (p *awsSecretsManagerProvider) signedRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("aws secrets manager: AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY are not set")
+	}
+	sessionToken := os.Getenv("AWS_SESSION_TOKEN")
+
+	host := fmt.Sprintf("secretsmanager.%s.amazonaws.com", p.region)
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	canonicalHeaders := fmt.Sprintf("content-type:application/x-amz-json-1.1\nhost:%s\nx-amz-date:%s\n", host, amzDate)
+	signedHeaders := "content-type;host;x-amz-date"
+	if sessionToken != "" {
+		canonicalHeaders += fmt.Sprintf("x-amz-security-token:%s\n", sessionToken)
+		signedHeaders += ";x-amz-security-token"
+	}
+	canonicalHeaders += "x-amz-target:secretsmanager.GetSecretValue\n"
+	signedHeaders += ";x-amz-target"
+
+	canonicalRequest := strings.Join([]string{
+		"POST", "/", "", canonicalHeaders, signedHeaders, sha256Hex(body),
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/secretsmanager/aws4_request", dateStamp, p.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, credentialScope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := sigV4SigningKey(secretKey, dateStamp, p.region, "secretsmanager")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, credentialScope, signedHeaders, signature)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Host = host
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	req.Header.Set("Authorization", authHeader)
+	return req, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sigV4SigningKey derives the SigV4 signing key for the given date,
+// region, and service from the raw AWS secret access key.
+func sigV4SigningKey(secretKey, dateStamp, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretKey), dateStamp)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
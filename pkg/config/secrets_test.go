@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("APIPROXY_API_KEY", "sk-test-123")
+
+	p := newEnvSecretProvider()
+	secret, err := p.GetSecret(context.Background(), "api_key")
+	if err != nil {
+		t.Fatalf("GetSecret: %v", err)
+	}
+	if secret.Value != "sk-test-123" {
+		t.Errorf("Value = %q, want sk-test-123", secret.Value)
+	}
+	if !secret.ExpiresAt.IsZero() {
+		t.Errorf("ExpiresAt = %v, want zero (env secrets don't expire)", secret.ExpiresAt)
+	}
+
+	if _, err := p.GetSecret(context.Background(), "missing_key"); err == nil {
+		t.Error("expected error for unset env var, got nil")
+	}
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api_key")
+	if err := os.WriteFile(path, []byte("sk-test-456\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newFileSecretProvider(dir)
+	if err != nil {
+		t.Fatalf("newFileSecretProvider: %v", err)
+	}
+
+	secret, err := p.GetSecret(context.Background(), "api_key")
+	if err != nil {
+		t.Fatalf("GetSecret: %v", err)
+	}
+	if secret.Value != "sk-test-456" {
+		t.Errorf("Value = %q, want sk-test-456 (trailing whitespace should be trimmed)", secret.Value)
+	}
+}
+
+func TestFileSecretProviderRejectsLoosePermissions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api_key")
+	if err := os.WriteFile(path, []byte("sk-test-789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newFileSecretProvider(dir)
+	if err != nil {
+		t.Fatalf("newFileSecretProvider: %v", err)
+	}
+
+	if _, err := p.GetSecret(context.Background(), "api_key"); err == nil {
+		t.Error("expected error for group/world-readable secret file, got nil")
+	}
+}
+
+func TestNewSecretProviderUnknown(t *testing.T) {
+	if _, err := NewSecretProvider(SecretsConfig{Provider: "bogus"}); err == nil {
+		t.Error("expected error for unknown provider, got nil")
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vaultSecretProvider resolves secrets from a HashiCorp Vault KV v2 mount,
+// authenticating via AppRole and caching the resulting client token until
+// shortly before it expires rather than logging in on every call.
+type vaultSecretProvider struct {
+	addr      string
+	namespace string
+	roleID    string
+	secretID  string
+	kvPath    string
+	client    *http.Client
+
+	mu       sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+func newVaultSecretProvider(cfg SecretsConfig) (*vaultSecretProvider, error) {
+	if cfg.VaultAddr == "" || cfg.VaultRoleID == "" || cfg.VaultSecretID == "" {
+		return nil, fmt.Errorf("vault secret provider: vault_addr, vault_role_id, and vault_secret_id are required")
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("vault secret provider: secrets.path (KV v2 path) is required")
+	}
+	return &vaultSecretProvider{
+		addr:      strings.TrimRight(cfg.VaultAddr, "/"),
+		namespace: cfg.VaultNamespace,
+		roleID:    cfg.VaultRoleID,
+		secretID:  cfg.VaultSecretID,
+		kvPath:    strings.TrimLeft(cfg.Path, "/"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *vaultSecretProvider) Name() string { return "vault" }
+
+// GetSecret logs in via AppRole if the cached token is missing or close to
+// expiry, then reads key out of the KV v2 secret's data map. The returned
+// Secret's ExpiresAt tracks the AppRole token's own lease, since that's
+// what eventually stops reads from succeeding - KV v2 entries themselves
+// don't carry a per-read lease.
+func (p *vaultSecretProvider) GetSecret(ctx context.Context, key string) (*Secret, error) {
+	token, exp, err := p.loginToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/v1/"+p.kvPath, token, nil, &body); err != nil {
+		return nil, fmt.Errorf("vault: read %s: %w", p.kvPath, err)
+	}
+
+	value, ok := body.Data.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("vault: %s has no field %q", p.kvPath, key)
+	}
+	return &Secret{Value: value, ExpiresAt: exp}, nil
+}
+
+// loginToken returns the cached AppRole token, logging in again if it's
+// missing or within a minute of expiring.
+func (p *vaultSecretProvider) loginToken(ctx context.Context) (string, time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Until(p.tokenExp) > time.Minute {
+		return p.token, p.tokenExp, nil
+	}
+
+	reqBody, _ := json.Marshal(map[string]string{"role_id": p.roleID, "secret_id": p.secretID})
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	if err := p.do(ctx, http.MethodPost, "/v1/auth/approle/login", "", reqBody, &resp); err != nil {
+		return "", time.Time{}, fmt.Errorf("vault: approle login: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", time.Time{}, fmt.Errorf("vault: approle login returned no client_token")
+	}
+
+	p.token = resp.Auth.ClientToken
+	p.tokenExp = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	return p.token, p.tokenExp, nil
+}
+
+func (p *vaultSecretProvider) do(ctx context.Context, method, path, token string, reqBody []byte, out interface{}) error {
+	var reader io.Reader
+	if reqBody != nil {
+		reader = bytes.NewReader(reqBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.addr+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(data))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
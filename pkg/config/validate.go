@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+)
+
+// ConfigError describes one invalid field: where it is, what went wrong,
+// and (when there's an obvious fix) how to correct it.
+type ConfigError struct {
+	Path        string `json:"path" yaml:"path"`
+	Message     string `json:"message" yaml:"message"`
+	Remediation string `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+}
+
+func (e ConfigError) Error() string {
+	if e.Remediation != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Path, e.Message, e.Remediation)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ConfigErrors is every problem Validate found, in field order. It
+// implements error so the existing `if err := cfg.Validate().AsError();
+// err != nil` style still works for callers (Watch, Reload) that only
+// care whether the config is valid, not which fields; callers that want
+// the individual problems (the `config validate` CLI command, JSON
+// output) should range over it directly.
+type ConfigErrors []ConfigError
+
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// AsError returns errs as an error, or nil if there were no problems - the
+// nil-slice-is-not-a-nil-interface footgun means `var err error =
+// ConfigErrors(nil)` is non-nil, so callers that just want a plain error
+// should go through this instead of a bare type assertion.
+func (errs ConfigErrors) AsError() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Validate sanity-checks c, catching edits that would produce a
+// nonsensical or dangerous running configuration (e.g. from Watch, before
+// swapping it into a live daemon, or from `apiproxy config validate` in
+// CI) instead of failing confusingly at first use deep inside some
+// subsystem. Unlike a plain error, it collects every problem it finds
+// rather than stopping at the first.
+func (c *Config) Validate() ConfigErrors {
+	var errs ConfigErrors
+
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		errs = append(errs, ConfigError{"server.port", fmt.Sprintf("%d is out of range", c.Server.Port), "use a port between 0 and 65535"})
+	}
+
+	if c.Cache.Backend != "" {
+		if _, ok := cache.DefaultRegistry.Factory(c.Cache.Backend); !ok {
+			errs = append(errs, ConfigError{"cache.backend", fmt.Sprintf("%q is not a supported backend", c.Cache.Backend), fmt.Sprintf("use one of: %s", strings.Join(cache.DefaultRegistry.Names(), ", "))})
+		}
+	}
+	if (c.Cache.Backend == "postgres" || c.Cache.Backend == "postgresql") && c.Cache.PostgresDSN == "" {
+		errs = append(errs, ConfigError{"cache.postgres_dsn", "is required when cache.backend is postgres", "set cache.postgres_dsn to a valid connection string"})
+	}
+	if c.Cache.Backend == "redis" && (c.Cache.Redis == nil || c.Cache.Redis.Addr == "") {
+		errs = append(errs, ConfigError{"cache.redis.addr", "is required when cache.backend is redis", "set cache.redis.addr to host:port"})
+	}
+	if c.Cache.Backend == "badger" && (c.Cache.Badger == nil || c.Cache.Badger.Dir == "") {
+		errs = append(errs, ConfigError{"cache.badger.dir", "is required when cache.backend is badger", "set cache.badger.dir to a writable directory"})
+	}
+	if c.Cache.Backend == "memcached" && (c.Cache.Memcached == nil || c.Cache.Memcached.Addr == "") {
+		errs = append(errs, ConfigError{"cache.memcached.addr", "is required when cache.backend is memcached", "set cache.memcached.addr to host:port"})
+	}
+	if c.Cache.Backend == "s3" && (c.Cache.S3 == nil || c.Cache.S3.Bucket == "") {
+		errs = append(errs, ConfigError{"cache.s3.bucket", "is required when cache.backend is s3", "set cache.s3.bucket to the bucket name"})
+	}
+	if c.Cache.TTL < 0 {
+		errs = append(errs, ConfigError{"cache.ttl", "must not be negative", ""})
+	}
+	switch c.Cache.Mode {
+	case "", "default", "bypass", "bypass_request", "bypass_response", "strict":
+	default:
+		errs = append(errs, ConfigError{"cache.mode", fmt.Sprintf("%q is invalid", c.Cache.Mode), "use default, bypass, bypass_request, bypass_response, or strict"})
+	}
+	if c.Cache.MaxOpenConns > 0 && c.Cache.MaxIdleConns > c.Cache.MaxOpenConns {
+		errs = append(errs, ConfigError{"cache.max_idle_conns", fmt.Sprintf("%d exceeds cache.max_open_conns (%d)", c.Cache.MaxIdleConns, c.Cache.MaxOpenConns), "set cache.max_idle_conns <= cache.max_open_conns"})
+	}
+	if c.Cache.MaxSizeBytes < 0 {
+		errs = append(errs, ConfigError{"cache.max_size_bytes", "must not be negative", ""})
+	}
+
+	if c.RemoteConfig.URL != "" && c.RemoteConfig.Interval < 0 {
+		errs = append(errs, ConfigError{"remote_config.interval", "must not be negative", ""})
+	}
+
+	if c.Security.RateLimitPerIP < 0 || c.Security.RateLimitPerKey < 0 || c.Security.RateLimitPerCN < 0 {
+		errs = append(errs, ConfigError{"security.rate_limit", "rate limits must not be negative", ""})
+	}
+	if c.Security.SSRFProtectionEnabled && len(c.Security.AllowedUpstreamHosts) == 0 {
+		errs = append(errs, ConfigError{"security.allowed_upstream_hosts", "must be non-empty when security.ssrf_protection_enabled is true", "list every upstream host the proxy is allowed to reach"})
+	}
+
+	if c.Client.CircuitBreakerThreshold < 0 {
+		errs = append(errs, ConfigError{"client.circuit_breaker_threshold", "must not be negative", ""})
+	}
+	if c.Client.CircuitBreakerEnabled && c.Client.CircuitBreakerHalfOpen >= c.Client.CircuitBreakerThreshold {
+		errs = append(errs, ConfigError{"client.circuit_breaker_half_open", fmt.Sprintf("%d must be less than client.circuit_breaker_threshold (%d)", c.Client.CircuitBreakerHalfOpen, c.Client.CircuitBreakerThreshold), "lower circuit_breaker_half_open or raise circuit_breaker_threshold"})
+	}
+
+	if c.Server.TLSEnabled {
+		if c.Server.TLSCertFile == "" || c.Server.TLSKeyFile == "" {
+			errs = append(errs, ConfigError{"server.tls_enabled", "requires tls_cert_file and tls_key_file", ""})
+		} else {
+			errs = append(errs, validatePathExists("server.tls_cert_file", c.Server.TLSCertFile)...)
+			errs = append(errs, validatePathExists("server.tls_key_file", c.Server.TLSKeyFile)...)
+		}
+	}
+	if c.Server.MTLSEnabled {
+		if c.Server.ClientCAFile == "" {
+			errs = append(errs, ConfigError{"server.mtls_enabled", "requires client_ca_file", ""})
+		} else {
+			errs = append(errs, validatePathExists("server.client_ca_file", c.Server.ClientCAFile)...)
+		}
+	}
+
+	switch c.Secrets.Provider {
+	case "", "env", "file", "vault", "aws", "aws_secrets_manager":
+	default:
+		errs = append(errs, ConfigError{"secrets.provider", fmt.Sprintf("%q is invalid", c.Secrets.Provider), "use vault, aws_secrets_manager, file, or env"})
+	}
+	if c.Secrets.Provider == "vault" && (c.Secrets.VaultAddr == "" || c.Secrets.VaultRoleID == "" || c.Secrets.VaultSecretID == "") {
+		errs = append(errs, ConfigError{"secrets", "provider vault requires vault_addr, vault_role_id, and vault_secret_id", ""})
+	}
+	if (c.Secrets.Provider == "file" || c.Secrets.Provider == "vault" || c.Secrets.Provider == "aws" || c.Secrets.Provider == "aws_secrets_manager") && c.Secrets.Path == "" {
+		errs = append(errs, ConfigError{"secrets.path", fmt.Sprintf("is required for provider %q", c.Secrets.Provider), ""})
+	}
+
+	errs = append(errs, validateEndpointPatterns("whitelisted_endpoints", c.WhitelistedEndpoints)...)
+	errs = append(errs, validateEndpointPatterns("offline_endpoints", c.OfflineEndpoints)...)
+	errs = append(errs, validateEndpointPatterns("streaming_endpoints", c.StreamingEndpoints)...)
+
+	return errs
+}
+
+// validatePathExists reports path as invalid if it doesn't exist or can't
+// be stat'd, so a typo'd TLS cert path is caught at validate time instead
+// of at the first handshake.
+func validatePathExists(field, path string) ConfigErrors {
+	if _, err := os.Stat(path); err != nil {
+		return ConfigErrors{{field, fmt.Sprintf("%s: %v", path, err), "check the path is correct and readable by the daemon"}}
+	}
+	return nil
+}
+
+// validateEndpointPatterns compiles every entry in entries (see
+// EndpointEntry.compile) and reports the ones that fail: an empty
+// plain-string pattern, a mapping entry missing path, an invalid "regex:"
+// expression, or a glob whose "[...]" character class doesn't compile
+// (e.g. "[z-a]"). An unterminated "[" is matched literally, not reported.
+func validateEndpointPatterns(field string, entries []EndpointEntry) ConfigErrors {
+	var errs ConfigErrors
+	for _, e := range entries {
+		if e.Pattern == "" && e.Path == "" {
+			errs = append(errs, ConfigError{field, "pattern must not be empty", ""})
+			continue
+		}
+		if err := e.compile(); err != nil {
+			errs = append(errs, ConfigError{field, err.Error(), "check the glob/regex syntax"})
+		}
+	}
+	return errs
+}
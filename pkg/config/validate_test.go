@@ -0,0 +1,128 @@
+package config
+
+import "testing"
+
+func TestValidateCatchesMultipleProblems(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 70000
+	cfg.Cache.Mode = "nonsense"
+
+	errs := cfg.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errs), errs)
+	}
+	if errs.AsError() == nil {
+		t.Error("AsError() = nil for a non-empty ConfigErrors")
+	}
+}
+
+func TestValidatePassesOnDefault(t *testing.T) {
+	cfg := Default()
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() on Default() = %v, want none", errs)
+	}
+	if err := cfg.Validate().AsError(); err != nil {
+		t.Errorf("AsError() = %v, want nil", err)
+	}
+}
+
+func TestValidateCrossFieldInvariants(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.MaxOpenConns = 5
+	cfg.Cache.MaxIdleConns = 10
+	cfg.Client.CircuitBreakerEnabled = true
+	cfg.Client.CircuitBreakerThreshold = 3
+	cfg.Client.CircuitBreakerHalfOpen = 3
+
+	errs := cfg.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errs), errs)
+	}
+}
+
+func TestValidateEndpointPatternSyntax(t *testing.T) {
+	cfg := Default()
+	cfg.WhitelistedEndpoints = []EndpointEntry{
+		{Pattern: "/v1/*/sub"},
+		{Pattern: "/v1/ok/*"},
+		{Pattern: "regex:("},
+		{Path: ""},
+	}
+
+	errs := cfg.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errs), errs)
+	}
+}
+
+func TestValidateCacheBackendRegistry(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Backend = "foobar"
+
+	errs := cfg.Validate()
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d errors, want 1: %v", len(errs), errs)
+	}
+}
+
+func TestValidateMemcachedBackendRequiresAddr(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Backend = "memcached"
+
+	errs := cfg.Validate()
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d errors, want 1: %v", len(errs), errs)
+	}
+
+	cfg.Cache.Memcached = &CacheMemcachedOptions{Addr: "localhost:11211"}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() with memcached.addr set = %v, want none", errs)
+	}
+}
+
+func TestValidateRedisBackendRequiresAddr(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Backend = "redis"
+
+	errs := cfg.Validate()
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d errors, want 1: %v", len(errs), errs)
+	}
+
+	cfg.Cache.Redis = &CacheRedisOptions{Addr: "localhost:6379"}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() with redis.addr set = %v, want none", errs)
+	}
+}
+
+func TestValidateCacheMaxSizeBytesRejectsNegative(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.MaxSizeBytes = -1
+
+	errs := cfg.Validate()
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d errors, want 1: %v", len(errs), errs)
+	}
+}
+
+func TestValidateRemoteConfigRejectsNegativeInterval(t *testing.T) {
+	cfg := Default()
+	cfg.RemoteConfig.URL = "https://config.example.com/apiproxy.json"
+	cfg.RemoteConfig.Interval = -1
+
+	errs := cfg.Validate()
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d errors, want 1: %v", len(errs), errs)
+	}
+}
+
+func TestValidateSSRFRequiresAllowedHosts(t *testing.T) {
+	cfg := Default()
+	cfg.Security.SSRFProtectionEnabled = true
+	cfg.Security.AllowedUpstreamHosts = nil
+
+	errs := cfg.Validate()
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d errors, want 1: %v", len(errs), errs)
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch monitors the active config file (config.json if present, else the
+// legacy config.yml) for changes via fsnotify, and also reloads on
+// SIGHUP, calling onChange with the previously active config and the
+// newly loaded one each time the file changes. A file that fails to load
+// or fails Validate is logged and discarded instead of being passed to
+// onChange, so a bad edit can't take down whatever onChange reconfigures.
+//
+// Watch blocks until ctx is canceled, at which point it returns nil. It
+// returns immediately if the initial config can't be loaded or the
+// underlying fsnotify watcher can't be created.
+func Watch(ctx context.Context, onChange func(old, new *Config) error) error {
+	current, err := Load()
+	if err != nil {
+		return fmt.Errorf("config watch: failed to load initial config: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("config watch: failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the containing directory rather than the file itself: editors
+	// commonly replace a config file via rename-into-place, which would
+	// silently stop a watch held on the old file's inode.
+	path := activeConfigPath()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("config watch: failed to watch %s: %w", filepath.Dir(path), err)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	reload := func() {
+		next, err := Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "config watch: failed to load config: %v\n", err)
+			return
+		}
+		if err := next.Validate().AsError(); err != nil {
+			fmt.Fprintf(os.Stderr, "config watch: rejected invalid config: %v\n", err)
+			return
+		}
+
+		old := current
+		current = next
+		if err := onChange(old, next); err != nil {
+			fmt.Fprintf(os.Stderr, "config watch: reload callback failed: %v\n", err)
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "config watch: watcher error: %v\n", err)
+		case <-sighup:
+			reload()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// activeConfigPath returns whichever config file Load would actually
+// read: config.json if present, otherwise the legacy config.yml path.
+func activeConfigPath() string {
+	jsonPath := ConfigJSONPath()
+	if _, err := os.Stat(jsonPath); err == nil {
+		return jsonPath
+	}
+	return ConfigPath()
+}
@@ -19,6 +19,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/afterdarksys/apiproxyd/pkg/analytics"
 	"github.com/afterdarksys/apiproxyd/pkg/cache"
 	"github.com/afterdarksys/apiproxyd/pkg/client"
 	"github.com/afterdarksys/apiproxyd/pkg/config"
@@ -28,19 +29,65 @@ import (
 )
 
 type Daemon struct {
-	host           string
-	port           int
-	server         *http.Server
+	host         string
+	port         int
+	server       *http.Server
+	client       *client.Client
+	metrics      *metrics.PrometheusMetrics
+	analytics    *analytics.Analytics
+	scheduler    *Scheduler
+	gzipPool     sync.Pool
+	singleFlight client.Deduplicator
+	handler      *HandlerPointer
+	events       *EventBus
+	certReloader *certReloader
+
+	// circuitStateStore persists circuit breaker state across restarts
+	// when cfg.Client.CircuitBreakerStatePath is set; nil otherwise.
+	circuitStateStore *client.BadgerStateStore
+
+	// reloadMu guards the fields below, which are rebuilt and swapped
+	// together on a hot reload (SIGHUP, a watched config file change, or
+	// POST /admin/reload) so a single request sees a consistent snapshot
+	// of cache/routing/plugin/security state.
+	reloadMu       sync.RWMutex
+	cfg            *config.Config
 	cache          cache.Cache
-	client         *client.Client
+	pluginManager  *plugin.Manager
+	rateLimiter    *middleware.RateLimiter
+	ssrfProtection *middleware.SSRFProtection
+}
+
+// snapshot is a consistent view of the hot-reloadable daemon state,
+// captured once per request so in-flight requests keep using the state
+// that was active when they started even if a reload happens concurrently.
+type snapshot struct {
 	cfg            *config.Config
+	cache          cache.Cache
 	pluginManager  *plugin.Manager
-	metrics        *metrics.PrometheusMetrics
 	rateLimiter    *middleware.RateLimiter
 	ssrfProtection *middleware.SSRFProtection
-	scheduler      *Scheduler
-	gzipPool       sync.Pool
-	singleFlight   *client.SingleFlight
+}
+
+// current returns a consistent snapshot of the hot-reloadable state.
+func (d *Daemon) current() snapshot {
+	d.reloadMu.RLock()
+	defer d.reloadMu.RUnlock()
+	return snapshot{
+		cfg:            d.cfg,
+		cache:          d.cache,
+		pluginManager:  d.pluginManager,
+		rateLimiter:    d.rateLimiter,
+		ssrfProtection: d.ssrfProtection,
+	}
+}
+
+// activeCache returns the cache backend currently in use, the same way
+// current().cache does, for call sites that only need the cache.
+func (d *Daemon) activeCache() cache.Cache {
+	d.reloadMu.RLock()
+	defer d.reloadMu.RUnlock()
+	return d.cache
 }
 
 func New(host string, port int) *Daemon {
@@ -57,6 +104,20 @@ func (d *Daemon) Start() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	d.cfg = cfg
+	d.events = NewEventBus(256)
+
+	// Resolve credentials (APIKey, MetricsAuthToken) through a configured
+	// secrets backend before anything below reads them off cfg, so the
+	// client and admin auth see the real value instead of whatever
+	// placeholder is in config.yml/config.json. secretsCtx outlives this
+	// function (canceled on shutdown below) since the refresh goroutines
+	// started further down need to keep running for the life of the daemon.
+	secretsCtx, cancelSecrets := context.WithCancel(context.Background())
+	defer cancelSecrets()
+	secretProvider, secretWatches, err := d.resolveSecrets(secretsCtx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve secrets: %w", err)
+	}
 
 	// Override host/port if provided
 	if d.host == "" || d.host == "127.0.0.1" {
@@ -67,140 +128,155 @@ func (d *Daemon) Start() error {
 	}
 
 	// Initialize cache with advanced options
-	cachePath := cfg.Cache.Path
-	if cfg.Cache.Backend == "postgres" {
-		cachePath = cfg.Cache.PostgresDSN
-	}
-
-	cacheOpts := &cache.CacheOptions{
-		Backend:            cfg.Cache.Backend,
-		Path:               cachePath,
-		TTL:                time.Duration(cfg.Cache.TTL) * time.Second,
-		MemoryCacheEnabled: cfg.Cache.MemoryCacheEnabled,
-		MemoryCacheSize:    cfg.Cache.MemoryCacheSize,
-		MaxOpenConns:       cfg.Cache.MaxOpenConns,
-		MaxIdleConns:       cfg.Cache.MaxIdleConns,
-		ConnMaxLifetime:    time.Duration(cfg.Cache.ConnMaxLifetime) * time.Second,
-		ConnMaxIdleTime:    time.Duration(cfg.Cache.ConnMaxIdleTime) * time.Second,
-	}
-
-	cacheStore, err := cache.NewWithOptions(cacheOpts)
+	taggedCache, postgresCache, err := d.buildCache(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize cache: %w", err)
 	}
-	d.cache = cacheStore
+	d.cache = taggedCache
 
-	// Start background cache cleanup scheduler
+	// Start the background job scheduler: expired-entry cleanup, LRU
+	// memory-cache compaction, a periodic circuit breaker snapshot, cache
+	// size enforcement, and (if configured) a remote config poll.
 	if cfg.Cache.CleanupInterval > 0 {
-		d.scheduler = NewScheduler(d.cache, time.Duration(cfg.Cache.CleanupInterval)*time.Second)
-		ctx := context.Background()
-		d.scheduler.Start(ctx)
-		fmt.Printf("Started cache cleanup scheduler (interval: %ds)\n", cfg.Cache.CleanupInterval)
+		interval := time.Duration(cfg.Cache.CleanupInterval) * time.Second
+		d.scheduler = NewScheduler(d.cache)
+		d.scheduler.OnJobComplete = func(name string, stats JobStats, err error) {
+			d.metrics.RecordSchedulerJob(name, stats.Duration, stats.EntriesRemoved, stats.BytesReclaimed, err)
+		}
+		d.scheduler.Register("cleanup", interval, d.cleanupExpiredJob)
+		d.scheduler.Register("lru_compaction", interval, d.lruCompactionJob)
+		d.scheduler.Register("circuit_breaker_snapshot", interval, d.circuitBreakerSnapshotJob)
+		if cfg.Cache.MaxSizeBytes > 0 {
+			d.scheduler.Register("cache_size_enforcement", interval, d.cacheSizeEnforcementJob)
+		}
+		if cfg.RemoteConfig.URL != "" {
+			remoteInterval := interval
+			if cfg.RemoteConfig.Interval > 0 {
+				remoteInterval = time.Duration(cfg.RemoteConfig.Interval) * time.Second
+			}
+			d.scheduler.Register("remote_config_poll", remoteInterval, d.remoteConfigPollJob)
+		}
+		d.scheduler.Start(context.Background())
+		fmt.Printf("Started background job scheduler (cleanup interval: %ds)\n", cfg.Cache.CleanupInterval)
+	}
+
+	// Initialize SSRF protection (before the client, so its SafeDialer can
+	// be wired into the upstream transport)
+	d.ssrfProtection = d.buildSSRFProtection(cfg)
+	if d.ssrfProtection != nil {
+		fmt.Println("SSRF protection enabled")
 	}
 
 	// Initialize client with advanced configuration
 	if cfg.APIKey != "" {
+		if cfg.Client.CircuitBreakerStatePath != "" {
+			store, err := client.NewBadgerStateStore(cfg.Client.CircuitBreakerStatePath)
+			if err != nil {
+				return fmt.Errorf("failed to open circuit breaker state store: %w", err)
+			}
+			d.circuitStateStore = store
+		}
+
 		clientCfg := &client.ClientConfig{
-			RequestTimeout:          time.Duration(cfg.Client.RequestTimeout) * time.Second,
-			DialTimeout:             time.Duration(cfg.Client.DialTimeout) * time.Second,
-			KeepAlive:               time.Duration(cfg.Client.KeepAlive) * time.Second,
-			MaxIdleConns:            cfg.Client.MaxIdleConns,
-			MaxIdleConnsPerHost:     cfg.Client.MaxIdleConnsPerHost,
-			MaxConnsPerHost:         cfg.Client.MaxConnsPerHost,
-			IdleConnTimeout:         time.Duration(cfg.Client.IdleConnTimeout) * time.Second,
-			TLSHandshakeTimeout:     10 * time.Second,
-			ExpectContinueTimeout:   1 * time.Second,
-			ResponseHeaderTimeout:   10 * time.Second,
-			CircuitBreakerEnabled:   cfg.Client.CircuitBreakerEnabled,
-			CircuitBreakerThreshold: cfg.Client.CircuitBreakerThreshold,
-			CircuitBreakerTimeout:   time.Duration(cfg.Client.CircuitBreakerTimeout) * time.Second,
-			CircuitBreakerHalfOpen:  cfg.Client.CircuitBreakerHalfOpen,
-			DeduplicationEnabled:    cfg.Client.DeduplicationEnabled,
+			RequestTimeout:             time.Duration(cfg.Client.RequestTimeout) * time.Second,
+			DialTimeout:                time.Duration(cfg.Client.DialTimeout) * time.Second,
+			KeepAlive:                  time.Duration(cfg.Client.KeepAlive) * time.Second,
+			MaxIdleConns:               cfg.Client.MaxIdleConns,
+			MaxIdleConnsPerHost:        cfg.Client.MaxIdleConnsPerHost,
+			MaxConnsPerHost:            cfg.Client.MaxConnsPerHost,
+			IdleConnTimeout:            time.Duration(cfg.Client.IdleConnTimeout) * time.Second,
+			TLSHandshakeTimeout:        10 * time.Second,
+			ExpectContinueTimeout:      1 * time.Second,
+			ResponseHeaderTimeout:      10 * time.Second,
+			CircuitBreakerEnabled:      cfg.Client.CircuitBreakerEnabled,
+			CircuitBreakerThreshold:    cfg.Client.CircuitBreakerThreshold,
+			CircuitBreakerTimeout:      time.Duration(cfg.Client.CircuitBreakerTimeout) * time.Second,
+			CircuitBreakerHalfOpen:     cfg.Client.CircuitBreakerHalfOpen,
+			CircuitBreakerWindowSize:   time.Duration(cfg.Client.CircuitBreakerWindow) * time.Second,
+			CircuitBreakerBucketCount:  cfg.Client.CircuitBreakerBuckets,
+			CircuitBreakerMinRequests:  cfg.Client.CircuitBreakerMinRequests,
+			CircuitBreakerFailureRatio: cfg.Client.CircuitBreakerFailureRatio,
+			RetryBudgetRatio:           cfg.Client.RetryBudgetRatio,
+			RetryBudgetMinTokens:       cfg.Client.RetryBudgetMinTokens,
+			Retry:                      buildRetryPolicy(cfg.Client.Retry),
+			DeduplicationEnabled:       cfg.Client.DeduplicationEnabled,
+		}
+		if d.circuitStateStore != nil {
+			clientCfg.CircuitBreakerStateStore = d.circuitStateStore
+		}
+		if d.ssrfProtection != nil {
+			clientCfg.DialContext = d.ssrfProtection.SafeDialer()
 		}
 		d.client = client.NewWithConfig(cfg.APIKey, clientCfg)
 		d.client.BaseURL = cfg.EntryPoint
+		d.client.SetCircuitStateChangeHook(func(key string, from, to client.CircuitState) {
+			if to == client.StateOpen {
+				d.events.Publish("circuit_open", fmt.Sprintf(`{"host":%q,"from":%q,"to":%q}`, key, from, to))
+			}
+			d.metrics.RecordCircuitTransition(key, from, to)
+		})
 	}
 
-	// Initialize request deduplication
+	// Initialize request deduplication. Distributed mode needs a shared
+	// lock backend, which today only the Postgres cache backend can
+	// provide; any other configuration falls back to local, per-process
+	// deduplication.
 	if cfg.Client.DeduplicationEnabled {
-		d.singleFlight = client.NewSingleFlight()
+		if cfg.Client.DedupBackend == "distributed" {
+			if postgresCache != nil {
+				lockBackend, err := client.NewPostgresLockBackend(postgresCache.DB())
+				if err != nil {
+					return fmt.Errorf("failed to initialize distributed dedup lock: %w", err)
+				}
+				dsf := client.NewDistributedSingleFlight(lockBackend, d.cache, time.Duration(cfg.Client.RequestTimeout)*time.Second)
+				dsf.OnLockWait = d.metrics.RecordDedupLockWait
+				d.singleFlight = dsf
+				fmt.Println("Distributed request deduplication enabled (Postgres)")
+			} else {
+				fmt.Println("dedup_backend: distributed requires the postgres cache backend; falling back to local")
+				d.singleFlight = client.NewSingleFlight()
+			}
+		} else {
+			d.singleFlight = client.NewSingleFlight()
+		}
 	}
 
 	// Initialize plugin manager
-	pluginCfg := &plugin.Config{
-		Enabled: cfg.Plugins.Enabled,
-		Plugins: make([]plugin.PluginConfig, len(cfg.Plugins.Plugins)),
-	}
-	for i, pe := range cfg.Plugins.Plugins {
-		pluginCfg.Plugins[i] = plugin.PluginConfig{
-			Name:    pe.Name,
-			Type:    pe.Type,
-			Path:    pe.Path,
-			Enabled: pe.Enabled,
-			Config:  pe.Config,
-		}
-	}
-	d.pluginManager = plugin.NewManager(pluginCfg)
-	if err := d.pluginManager.LoadPlugins(); err != nil {
+	pluginManager, err := d.buildPluginManager(cfg)
+	if err != nil {
 		return fmt.Errorf("failed to load plugins: %w", err)
 	}
+	d.pluginManager = pluginManager
 
 	// Initialize metrics
 	d.metrics = metrics.NewPrometheusMetrics()
+	d.analytics = analytics.NewAnalytics()
+	d.metrics.SetAnalytics(d.analytics)
 
 	// Initialize rate limiter
-	if cfg.Security.RateLimitEnabled {
-		d.rateLimiter = middleware.NewRateLimiter(
-			cfg.Security.RateLimitPerIP,
-			cfg.Security.RateLimitPerKey,
-			cfg.Security.RateLimitBurst,
-		)
+	d.rateLimiter = d.buildRateLimiter(cfg)
+	if d.rateLimiter != nil {
 		fmt.Printf("Rate limiting enabled: %d req/min per IP, %d req/min per key\n",
 			cfg.Security.RateLimitPerIP, cfg.Security.RateLimitPerKey)
 	}
-
-	// Initialize SSRF protection
-	if cfg.Security.SSRFProtectionEnabled {
-		d.ssrfProtection = middleware.NewSSRFProtection(
-			cfg.Security.AllowedUpstreamHosts,
-			cfg.Security.BlockPrivateIPs,
-		)
-		fmt.Println("SSRF protection enabled")
-	}
-
-	// Create HTTP server with middleware chain
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", d.handleHealth)
-	mux.HandleFunc("/api/", d.handleProxy)
-	mux.HandleFunc("/cache/stats", d.handleCacheStats)
-	mux.HandleFunc("/cache/clear", d.handleCacheClear)
-	mux.HandleFunc("/metrics", d.handleMetrics)
-
-	// Build middleware chain
-	handler := http.Handler(mux)
-
-	// Add recovery middleware (outermost - catches all panics)
-	handler = middleware.RecoveryMiddleware(handler)
-
-	// Add security headers
-	handler = middleware.SecureHeaders(handler)
-
-	// Add rate limiting
-	if d.rateLimiter != nil {
-		handler = d.rateLimiter.Middleware(handler)
+	d.metrics.SetRateLimiter(d.rateLimiter)
+	d.metrics.SetCacheBackend(cfg.Cache.Backend)
+	if d.client != nil {
+		d.client.SetUpstreamRequestHook(d.metrics.RecordUpstreamRequest)
+		d.metrics.SetCircuitClient(d.client)
 	}
 
-	// Add request body size limiting
-	if cfg.Security.MaxRequestBodySize > 0 {
-		handler = middleware.BodySizeLimiter(cfg.Security.MaxRequestBodySize)(handler)
-	}
+	d.cfg = cfg
 
-	// Add input sanitization
-	handler = middleware.InputSanitizer(handler)
+	// Build the routes/middleware chain behind a HandlerPointer so a
+	// hot-reload can swap it atomically; requests already in flight keep
+	// running against the handler they started with.
+	handler := d.buildHandler(cfg, d.rateLimiter)
+	d.handler = NewHandlerPointer(handler)
 
 	d.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", d.host, d.port),
-		Handler:      handler,
+		Handler:      d.handler,
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
 		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
@@ -214,6 +290,12 @@ func (d *Daemon) Start() error {
 			return fmt.Errorf("TLS enabled but cert/key files not specified")
 		}
 
+		certReloader, err := newCertReloader(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		d.certReloader = certReloader
+
 		d.server.TLSConfig = &tls.Config{
 			MinVersion: tls.VersionTLS12,
 			CipherSuites: []uint16{
@@ -223,12 +305,35 @@ func (d *Daemon) Start() error {
 				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
 			},
 			PreferServerCipherSuites: true,
+			// Serve the certificate through certReloader rather than the
+			// static Certificates list, so Reload can pick up a renewed
+			// keypair (SIGHUP or POST /admin/reload) without restarting
+			// the listener or dropping connections mid-handshake.
+			GetCertificate: certReloader.GetCertificate,
 		}
 
 		// Enable HTTP/2 if configured
 		if cfg.Server.EnableHTTP2 {
 			d.server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
 		}
+
+		// Enable mutual TLS: require and verify a client certificate signed
+		// by the configured CA. Which verified identities are actually
+		// allowed to call which routes is enforced separately by
+		// middleware.ClientCertPolicy, built into the handler chain in
+		// buildHandler.
+		if cfg.Server.MTLSEnabled {
+			if cfg.Server.ClientCAFile == "" {
+				return fmt.Errorf("mTLS enabled but client_ca_file not specified")
+			}
+			clientCAs, err := middleware.LoadClientCAPool(cfg.Server.ClientCAFile)
+			if err != nil {
+				return fmt.Errorf("failed to load client CA pool: %w", err)
+			}
+			d.server.TLSConfig.ClientCAs = clientCAs
+			d.server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			fmt.Println("mTLS client authentication enabled")
+		}
 	}
 
 	// Write PID file with secure permissions
@@ -252,6 +357,9 @@ func (d *Daemon) Start() error {
 		}
 		if cfg.Client.CircuitBreakerEnabled {
 			fmt.Printf("   - Circuit breaker: threshold=%d\n", cfg.Client.CircuitBreakerThreshold)
+			if cfg.Client.CircuitBreakerStatePath != "" {
+				fmt.Printf("   - Circuit breaker state persisted to %s\n", cfg.Client.CircuitBreakerStatePath)
+			}
 		}
 		if cfg.Client.DeduplicationEnabled {
 			fmt.Printf("   - Request deduplication\n")
@@ -265,7 +373,9 @@ func (d *Daemon) Start() error {
 
 		var err error
 		if cfg.Server.TLSEnabled {
-			err = d.server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+			// Cert/key files are already loaded into TLSConfig.GetCertificate
+			// via certReloader, so both arguments are empty.
+			err = d.server.ListenAndServeTLS("", "")
 		} else {
 			err = d.server.ListenAndServe()
 		}
@@ -275,6 +385,28 @@ func (d *Daemon) Start() error {
 		}
 	}()
 
+	// Reload config/routes/plugins on SIGHUP or a watched config file
+	// change, without dropping connections. config.Watch validates before
+	// calling back, so a bad edit is logged and discarded instead of
+	// reaching ReloadWithConfig.
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	go func() {
+		err := config.Watch(watchCtx, func(old, new *config.Config) error {
+			_, err := d.ReloadWithConfig(new)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "config watch failed: %v\n", err)
+		}
+	}()
+
+	// Refresh leased secrets (if cfg.Secrets.Renew) shortly before they
+	// expire, without restarting or dropping connections.
+	if secretProvider != nil {
+		d.runSecretWatches(secretsCtx, secretProvider, secretWatches)
+	}
+
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -293,6 +425,10 @@ func (d *Daemon) Start() error {
 	// Cleanup resources
 	d.cache.Close()
 
+	if d.circuitStateStore != nil {
+		d.circuitStateStore.Close()
+	}
+
 	if d.scheduler != nil {
 		d.scheduler.Stop()
 	}
@@ -393,7 +529,7 @@ func (d *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
 	dbStatus := "ok"
 
 	// Try a simple cache operation to verify DB is accessible
-	if _, err := d.cache.Stats(); err != nil {
+	if _, err := d.activeCache().Stats(); err != nil {
 		healthy = false
 		dbStatus = fmt.Sprintf("error: %v", err)
 	}
@@ -405,8 +541,8 @@ func (d *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := map[string]interface{}{
-		"status":  status,
-		"version": "0.2.0",
+		"status":   status,
+		"version":  "0.2.0",
 		"database": dbStatus,
 	}
 
@@ -414,28 +550,145 @@ func (d *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
 	components := make(map[string]interface{})
 	if d.client != nil {
 		components["upstream_client"] = "ok"
-		if d.client.GetCircuitBreakerStats()["state"] == "open" {
+		if d.client.AnyCircuitOpen() {
 			components["upstream_client"] = "circuit_open"
 			status = "degraded"
 		}
 	}
-	if d.rateLimiter != nil {
+	rt := d.current()
+	if rt.rateLimiter != nil {
 		components["rate_limiter"] = "ok"
 	}
+	if rt.pluginManager != nil {
+		if pluginHealth := rt.pluginManager.Health(); len(pluginHealth) > 0 {
+			components["plugins"] = pluginHealth
+			for _, h := range pluginHealth {
+				if !h.Healthy {
+					status = "degraded"
+				}
+			}
+		}
+	}
 	response["components"] = components
 
 	json.NewEncoder(w).Encode(response)
 }
 
+// routedByPlugin returns the "plugin" metrics label for a request, derived
+// from the metadata convention plugins use to mark a request as handled
+// (e.g. CustomRouterPlugin setting Metadata["routed"]="true"). It returns
+// "" when no plugin claimed the request.
+func routedByPlugin(req *plugin.Request) string {
+	if req != nil && req.Metadata != nil && req.Metadata["routed"] == "true" {
+		return "router"
+	}
+	return ""
+}
+
+// recordRequest feeds a request's outcome to both the labeled
+// PrometheusMetrics counters/histograms and the legacy analytics.Analytics
+// tracker, which estimates cache-serving savings independently by endpoint
+// path (see writeAnalyticsMetrics in pkg/metrics).
+func (d *Daemon) recordRequest(labels metrics.RequestLabels, duration time.Duration, bytes int64) {
+	d.metrics.RecordRequest(labels, duration, bytes)
+	d.analytics.RecordRequest(labels.Route, labels.Cached, duration, bytes)
+}
+
+// recordPluginUpstreamLatency reports a plugin's upstream call latency to
+// apiproxyd_plugin_upstream_call_duration_seconds when the plugin
+// annotated the request per the routed_pattern/routed_latency_seconds
+// metadata convention (see CustomRouterPlugin).
+func (d *Daemon) recordPluginUpstreamLatency(req *plugin.Request) {
+	if req == nil || req.Metadata == nil {
+		return
+	}
+	pattern := req.Metadata["routed_pattern"]
+	latencyStr := req.Metadata["routed_latency_seconds"]
+	if pattern == "" || latencyStr == "" {
+		return
+	}
+	seconds, err := strconv.ParseFloat(latencyStr, 64)
+	if err != nil {
+		return
+	}
+	d.metrics.RecordPluginUpstreamLatency(pattern, time.Duration(seconds*float64(time.Second)))
+}
+
+// writeShortCircuitResponse delivers a response for a request a plugin's
+// OnRequest stopped (cont=false), instead of the bare 200 OK the daemon
+// used to write regardless of what the plugin set. It honors the
+// custom_status/custom_response metadata convention (see
+// CustomRouterPlugin), running the result back through the plugin
+// OnResponse hooks first so a plugin like CustomRouterPlugin can still
+// shape the final body/headers exactly as it would for a normal upstream
+// call. A plugin that stops the chain without setting custom_response
+// (e.g. one that already wrote its own response, or intends the default
+// empty 200) gets that same bare 200 OK as before.
+func (d *Daemon) writeShortCircuitResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, req *plugin.Request, pluginManager *plugin.Manager, startTime time.Time, endpoint string) {
+	status := http.StatusOK
+	if req != nil && req.Metadata != nil {
+		if s := req.Metadata["custom_status"]; s != "" {
+			if n, err := strconv.Atoi(s); err == nil {
+				status = n
+			}
+		}
+	}
+
+	pluginResp := &plugin.Response{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	}
+	if req != nil && req.Metadata != nil {
+		pluginResp.Body = []byte(req.Metadata["custom_response"])
+	}
+
+	if pluginManager != nil {
+		if modifiedResp, err := pluginManager.OnResponse(ctx, req, pluginResp); err == nil {
+			pluginResp = modifiedResp
+		}
+	}
+
+	for k, v := range pluginResp.Headers {
+		w.Header().Set(k, v)
+	}
+	if req != nil {
+		for k, v := range req.Metadata {
+			if name := strings.TrimPrefix(k, customHeaderMetadataPrefix); name != k {
+				w.Header().Set(name, v)
+			}
+		}
+	}
+	w.WriteHeader(pluginResp.StatusCode)
+	w.Write(pluginResp.Body)
+	d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: pluginResp.StatusCode, Route: endpoint, Plugin: routedByPlugin(req)}, time.Since(startTime), int64(len(pluginResp.Body)))
+}
+
+// customHeaderMetadataPrefix marks Metadata entries a plugin sets to add an
+// extra response header when it stops the request chain (see
+// writeShortCircuitResponse), e.g. Metadata["custom_header_Retry-After"] =
+// "30". custom_status/custom_response cover status code and body; there
+// was no equivalent for headers until the ratelimit example plugin needed
+// one for Retry-After/X-RateLimit-Remaining.
+const customHeaderMetadataPrefix = "custom_header_"
+
 func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 	ctx := r.Context()
 
+	// Snapshot the hot-reloadable state once so this request is handled
+	// consistently even if a reload swaps it out concurrently.
+	rt := d.current()
+	cfg := rt.cfg
+	pluginManager := rt.pluginManager
+	ssrfProtection := rt.ssrfProtection
+	cacheStore := rt.cache
+
 	// Extract endpoint path (remove /api prefix)
 	endpoint := strings.TrimPrefix(r.URL.Path, "/api")
 
-	// Check if endpoint is whitelisted
-	if !d.cfg.IsEndpointWhitelisted(endpoint) {
+	// Check if endpoint is whitelisted, before anything (including a
+	// plugin) gets a chance to touch the request.
+	if _, ok := cfg.EndpointPolicy(r.Method, endpoint); !ok {
 		http.Error(w, fmt.Sprintf("Endpoint not whitelisted: %s", endpoint), http.StatusForbidden)
 		return
 	}
@@ -443,8 +696,8 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	// Read body with size limit
 	var body []byte
 	var err error
-	if d.cfg.Security.MaxRequestBodySize > 0 {
-		limitedReader := middleware.LimitReader(r.Body, d.cfg.Security.MaxRequestBodySize)
+	if cfg.Security.MaxRequestBodySize > 0 {
+		limitedReader := middleware.LimitReader(r.Body, cfg.Security.MaxRequestBodySize)
 		body, err = io.ReadAll(limitedReader)
 		if err != nil {
 			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
@@ -464,18 +717,18 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	pluginReq.Endpoint = endpoint
 
 	// Call plugin OnRequest hooks
-	if d.pluginManager != nil {
-		modifiedReq, cont, err := d.pluginManager.OnRequest(ctx, pluginReq)
+	if pluginManager != nil {
+		modifiedReq, cont, err := pluginManager.OnRequest(ctx, pluginReq)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Plugin error: %v", err), http.StatusInternalServerError)
 			return
 		}
 		if !cont {
-			// Plugin stopped the request, return early
-			w.WriteHeader(http.StatusOK)
+			d.writeShortCircuitResponse(ctx, w, r, modifiedReq, pluginManager, startTime, endpoint)
 			return
 		}
 		pluginReq = modifiedReq
+		d.recordPluginUpstreamLatency(pluginReq)
 		// Update endpoint and body in case plugins modified them
 		endpoint = pluginReq.Endpoint
 		body = pluginReq.Body
@@ -484,11 +737,18 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	// Generate cache key
 	cacheKey := cache.GenerateKey(pluginReq.Method, endpoint, string(body))
 
-	// Check if this is an offline endpoint
-	isOffline := d.cfg.IsEndpointOffline(endpoint)
+	// Re-resolve policy against the post-plugin method/path: a plugin may
+	// have rewritten the endpoint, and offline/streaming status and any
+	// cache/rate-limit overrides apply to the endpoint actually proxied.
+	policy, _ := cfg.EndpointPolicy(pluginReq.Method, endpoint)
+	isOffline := policy.Offline
 
-	// Try cache first
-	if cached, err := d.cache.Get(cacheKey); err == nil {
+	// Try cache first, unless this endpoint opted out of caching entirely.
+	cached, cacheErr := []byte(nil), fmt.Errorf("cache disabled for this endpoint")
+	if !policy.CacheDisabled {
+		cached, cacheErr = cacheStore.Get(cacheKey)
+	}
+	if cacheErr == nil {
 		pluginResp := &plugin.Response{
 			StatusCode: http.StatusOK,
 			Headers:    map[string]string{"Content-Type": "application/json"},
@@ -497,8 +757,8 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Call plugin OnCacheHit hooks
-		if d.pluginManager != nil {
-			modifiedResp, err := d.pluginManager.OnCacheHit(ctx, pluginReq, pluginResp)
+		if pluginManager != nil {
+			modifiedResp, err := pluginManager.OnCacheHit(ctx, pluginReq, pluginResp)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Plugin error: %v", err), http.StatusInternalServerError)
 				return
@@ -515,7 +775,7 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set(k, v)
 		}
 		d.writeResponse(w, r, pluginResp.Body, startTime, true)
-		d.metrics.RecordRequest(r.Method, http.StatusOK, time.Since(startTime), true, int64(len(pluginResp.Body)))
+		d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: http.StatusOK, Route: endpoint, Plugin: routedByPlugin(pluginReq), Cached: true}, time.Since(startTime), int64(len(pluginResp.Body)))
 		return
 	}
 
@@ -532,15 +792,22 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate upstream URL if SSRF protection is enabled
-	if d.ssrfProtection != nil {
+	if ssrfProtection != nil {
 		upstreamURL := d.client.BaseURL + endpoint
-		if err := d.ssrfProtection.ValidateURL(upstreamURL); err != nil {
+		if err := ssrfProtection.ValidateURL(upstreamURL); err != nil {
 			http.Error(w, "Invalid upstream URL", http.StatusForbidden)
-			d.metrics.RecordRequest(r.Method, http.StatusForbidden, time.Since(startTime), false, 0)
+			d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: http.StatusForbidden, Route: endpoint}, time.Since(startTime), 0)
 			return
 		}
 	}
 
+	// Streaming endpoints relay the upstream body to the client as it
+	// arrives instead of buffering the whole response first.
+	if policy.Streaming {
+		d.handleProxyStream(w, r, pluginReq, endpoint, cacheKey, startTime)
+		return
+	}
+
 	// Make request to API with deduplication
 	headers := make(map[string]string)
 	for k, v := range pluginReq.Headers {
@@ -561,7 +828,7 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Return safe error message (don't leak internal details)
 		http.Error(w, "Upstream service unavailable", http.StatusBadGateway)
-		d.metrics.RecordRequest(r.Method, http.StatusBadGateway, time.Since(startTime), false, 0)
+		d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: http.StatusBadGateway, Route: endpoint}, time.Since(startTime), 0)
 		return
 	}
 
@@ -574,8 +841,8 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call plugin OnResponse hooks
-	if d.pluginManager != nil {
-		modifiedResp, err := d.pluginManager.OnResponse(ctx, pluginReq, pluginResp)
+	if pluginManager != nil {
+		modifiedResp, err := pluginManager.OnResponse(ctx, pluginReq, pluginResp)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Plugin error: %v", err), http.StatusInternalServerError)
 			return
@@ -583,8 +850,21 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 		pluginResp = modifiedResp
 	}
 
-	// Cache response (with longer TTL for offline endpoints)
-	d.cache.Set(cacheKey, pluginResp.Body)
+	// Cache response, unless this endpoint's WhitelistedEndpoints entry set
+	// "cache: false". A per-endpoint TTL override is applied when the backend
+	// under the tag index implements cache.TTLSetter; otherwise (e.g. a
+	// layered memory+disk cache) the entry is stored with the default TTL.
+	if !policy.CacheDisabled {
+		if policy.CacheTTLOverride > 0 {
+			if ttlCache, ok := unwrapCache(cacheStore).(cache.TTLSetter); ok {
+				ttlCache.SetTTL(cacheKey, pluginResp.Body, time.Duration(policy.CacheTTLOverride)*time.Second)
+			} else {
+				cacheStore.Set(cacheKey, pluginResp.Body)
+			}
+		} else {
+			cacheStore.Set(cacheKey, pluginResp.Body)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Cache", "MISS")
@@ -592,13 +872,97 @@ func (d *Daemon) handleProxy(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(k, v)
 	}
 	d.writeResponse(w, r, pluginResp.Body, startTime, false)
-	d.metrics.RecordRequest(r.Method, http.StatusOK, time.Since(startTime), false, int64(len(pluginResp.Body)))
+	d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: http.StatusOK, Route: endpoint, Plugin: routedByPlugin(pluginReq), Cached: false}, time.Since(startTime), int64(len(pluginResp.Body)))
+}
+
+// handleProxyStream proxies endpoint in streaming mode: the upstream body
+// is relayed to the client chunk-by-chunk, flushing after each one, rather
+// than read into memory and handed to writeResponse in one go. The chunks
+// are also teed into a cache.StreamWriter so the response is still cached
+// once fully received. Since it bypasses writeResponse entirely, streamed
+// responses are never re-gzipped here; whatever content-encoding the
+// upstream sent (or didn't) passes straight through.
+//
+// Plugin OnResponse hooks are skipped for streamed responses, since they
+// operate on a fully buffered plugin.Response body, which is exactly what
+// streaming mode exists to avoid.
+func (d *Daemon) handleProxyStream(w http.ResponseWriter, r *http.Request, pluginReq *plugin.Request, endpoint, cacheKey string, startTime time.Time) {
+	headers := make(map[string]string)
+	for k, v := range pluginReq.Headers {
+		headers[k] = v
+	}
+
+	upstreamBody, upstreamHeaders, status, err := d.client.RequestStream(pluginReq.Method, endpoint, bytes.NewReader(pluginReq.Body), headers)
+	if err != nil {
+		http.Error(w, "Upstream service unavailable", http.StatusBadGateway)
+		d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: http.StatusBadGateway, Route: endpoint}, time.Since(startTime), 0)
+		return
+	}
+	defer upstreamBody.Close()
+
+	for k, vals := range upstreamHeaders {
+		if k == "Content-Length" {
+			// The streamed byte count isn't known up front.
+			continue
+		}
+		for _, v := range vals {
+			w.Header().Add(k, v)
+		}
+	}
+	w.Header().Set("X-Cache", "MISS")
+	w.Header().Set("X-Stream", "true")
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	streamWriter := cache.NewStreamWriter(d.activeCache(), cacheKey)
+	written, err := copyStreamed(w, upstreamBody, streamWriter, flusher)
+	if err != nil {
+		// The client or upstream connection broke mid-stream; don't cache a
+		// partial response.
+		d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: status, Route: endpoint}, time.Since(startTime), written)
+		return
+	}
+
+	if err := streamWriter.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "stream cache commit failed for %s: %v\n", endpoint, err)
+	}
+	d.recordRequest(metrics.RequestLabels{Method: r.Method, Status: status, Route: endpoint, Plugin: routedByPlugin(pluginReq), Cached: false}, time.Since(startTime), written)
+}
+
+// copyStreamed copies src to dst in chunks, flushing dst (if it supports
+// flushing) after each one so the client starts receiving bytes immediately
+// instead of waiting for the whole body, while also teeing every chunk into
+// cacheWriter. It returns the number of bytes copied; io.EOF from src is
+// treated as success, not an error.
+func copyStreamed(dst io.Writer, src io.Reader, cacheWriter io.Writer, flusher http.Flusher) (int64, error) {
+	buf := make([]byte, 32*1024)
+	var written int64
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return written, werr
+			}
+			cacheWriter.Write(buf[:n])
+			written += int64(n)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if rerr != nil {
+			if rerr == io.EOF {
+				return written, nil
+			}
+			return written, rerr
+		}
+	}
 }
 
 // writeResponse writes response with optional gzip compression
 func (d *Daemon) writeResponse(w http.ResponseWriter, r *http.Request, data []byte, startTime time.Time, cached bool) {
 	// Check response size limit
-	if d.cfg.Security.MaxResponseBodySize > 0 && int64(len(data)) > d.cfg.Security.MaxResponseBodySize {
+	cfg := d.current().cfg
+	if cfg.Security.MaxResponseBodySize > 0 && int64(len(data)) > cfg.Security.MaxResponseBodySize {
 		http.Error(w, "Response too large", http.StatusInternalServerError)
 		return
 	}
@@ -629,7 +993,7 @@ func (d *Daemon) writeResponse(w http.ResponseWriter, r *http.Request, data []by
 }
 
 func (d *Daemon) handleCacheStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := d.cache.Stats()
+	stats, err := d.activeCache().Stats()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -641,25 +1005,91 @@ func (d *Daemon) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 
 func (d *Daemon) handleCacheClear(w http.ResponseWriter, r *http.Request) {
 	// Clear L1 cache if layered
-	if layered, ok := d.cache.(*cache.LayeredCache); ok {
+	underlying := d.activeCache()
+	if tagged, ok := underlying.(*cache.TaggedCache); ok {
+		underlying = tagged.Unwrap()
+	}
+	if layered, ok := underlying.(*cache.LayeredCache); ok {
 		layered.ClearL1()
 	}
 
 	// Trigger immediate cleanup of expired entries
 	if d.scheduler != nil {
-		d.scheduler.RunNow()
+		d.scheduler.RunNow("cleanup")
 	}
 
+	d.events.Publish("cache_invalidate", `{"scope":"all"}`)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status": "cleared",
 	})
 }
 
+// handleCachePurge invalidates cache entries by surrogate key (tag) or by
+// exact key, e.g. POST /admin/cache/purge {"tags": ["user-42"]}.
+func (d *Daemon) handleCachePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tagged, ok := d.activeCache().(*cache.TaggedCache)
+	if !ok {
+		http.Error(w, "cache backend does not support tag-based purge", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Tags []string `json:"tags"`
+		Keys []string `json:"keys"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	purged := 0
+	for _, tag := range req.Tags {
+		n, err := tagged.InvalidateByTag(tag)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("purge failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		purged += n
+	}
+	for _, key := range req.Keys {
+		if err := tagged.Delete(key); err == nil {
+			purged++
+		}
+	}
+
+	d.events.Publish("cache_invalidate", fmt.Sprintf(`{"tags":%s,"keys":%s,"count":%d}`, jsonStrings(req.Tags), jsonStrings(req.Keys), purged))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "purged",
+		"count":  purged,
+	})
+}
+
+// jsonStrings renders ss as a JSON string array, for hand-built SSE event
+// payloads where pulling in encoding/json for one field would be overkill.
+func jsonStrings(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		b, _ := json.Marshal(s)
+		quoted[i] = string(b)
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
 // handleMetrics serves Prometheus metrics with optional authentication
 func (d *Daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	rt := d.current()
+
 	// Check authentication if enabled
-	if d.cfg.Security.MetricsAuthEnabled {
+	if rt.cfg.Security.MetricsAuthEnabled {
 		token := r.Header.Get("Authorization")
 		if token == "" {
 			token = r.URL.Query().Get("token")
@@ -669,16 +1099,75 @@ func (d *Daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		token = strings.TrimPrefix(token, "Bearer ")
 
 		// Use constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(token), []byte(d.cfg.Security.MetricsAuthToken)) != 1 {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(rt.cfg.Security.MetricsAuthToken)) != 1 {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 	}
 
 	// Serve metrics
+	d.metrics.SetCacheStats(d.activeCache())
 	d.metrics.ServeHTTP(w, r)
 }
 
+// handleEvents serves GET /events: a long-lived Server-Sent Events stream
+// of cache_invalidate, circuit_open, circuit_snapshot, plugin_reload,
+// rate_limit_trip, and secret_rotated frames, so clients and sidecars can react in real time instead of
+// polling /cache/stats and /health.
+func (d *Daemon) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rt := d.current()
+
+	if rt.cfg.Security.EventsAuthEnabled {
+		token := r.Header.Get("Authorization")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		token = strings.TrimPrefix(token, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(rt.cfg.Security.MetricsAuthToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		if id, err := strconv.ParseInt(lastID, 10, 64); err == nil {
+			for _, ev := range d.events.Since(id) {
+				writeSSEFrame(w, ev)
+			}
+			flusher.Flush()
+		}
+	}
+
+	ch, unsubscribe := d.events.Subscribe("")
+	defer unsubscribe()
+
+	for {
+		select {
+		case ev := <-ch:
+			writeSSEFrame(w, ev)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSEFrame writes ev in SSE wire format (id/event/data fields).
+func writeSSEFrame(w io.Writer, ev Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Topic, ev.Data)
+}
+
 func (d *Daemon) pidFilePath() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".apiproxy", "daemon.pid")
@@ -0,0 +1,109 @@
+package daemon
+
+import "sync"
+
+// Event is a single SSE frame published on an EventBus.
+type Event struct {
+	ID    int64
+	Topic string
+	Data  string
+}
+
+type subscriber struct {
+	ch    chan Event
+	topic string // "" subscribes to every topic
+}
+
+// EventBus fans published events out to subscribers by topic, for the
+// GET /events SSE endpoint: publishers (cache invalidation, circuit
+// breaker transitions, plugin reloads) don't know or care whether anyone
+// is listening. A slow subscriber can only ever fall behind its own
+// buffered channel — Publish drops that subscriber's oldest queued event
+// to make room rather than blocking, so one stalled client can't back up
+// delivery to everyone else.
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers map[int64]*subscriber
+	nextSubID   int64
+	ring        []Event
+	ringSize    int
+	nextEventID int64
+}
+
+// NewEventBus creates an EventBus that retains the last ringSize events
+// for Last-Event-ID based resume.
+func NewEventBus(ringSize int) *EventBus {
+	return &EventBus{
+		subscribers: make(map[int64]*subscriber),
+		ringSize:    ringSize,
+	}
+}
+
+// Publish fans data out to every subscriber of topic ("" subscribers get
+// every topic) and appends it to the replay ring.
+func (b *EventBus) Publish(topic, data string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextEventID++
+	ev := Event{ID: b.nextEventID, Topic: topic, Data: data}
+
+	b.ring = append(b.ring, ev)
+	if len(b.ring) > b.ringSize {
+		b.ring = b.ring[len(b.ring)-b.ringSize:]
+	}
+
+	for _, sub := range b.subscribers {
+		if sub.topic != "" && sub.topic != topic {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			// Buffered channel is full: drop the oldest queued event and
+			// retry once, so the subscriber trades staleness for falling
+			// further behind instead of stalling Publish.
+			select {
+			case <-sub.ch:
+			default:
+			}
+			select {
+			case sub.ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// Subscribe registers a new subscriber for topic ("" for every topic) and
+// returns its channel plus an unsubscribe function the caller must call
+// when done listening.
+func (b *EventBus) Subscribe(topic string) (<-chan Event, func()) {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	sub := &subscriber{ch: make(chan Event, 32), topic: topic}
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, id)
+		b.mu.Unlock()
+	}
+}
+
+// Since returns every ring-buffered event with an ID greater than
+// lastEventID, oldest first, for resuming from a client's Last-Event-ID.
+func (b *EventBus) Since(lastEventID int64) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out []Event
+	for _, ev := range b.ring {
+		if ev.ID > lastEventID {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
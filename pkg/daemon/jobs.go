@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+)
+
+// cleanupExpiredJob removes expired entries from the active cache backend
+// (and, for a layered cache, its L2 tier), reporting how many entries and
+// bytes Stats() shows were reclaimed. It's a no-op if the backend doesn't
+// implement CleanupExpired.
+func (d *Daemon) cleanupExpiredJob(ctx context.Context) (JobStats, error) {
+	c := d.scheduler.Cache()
+	cleaner, ok := unwrapCache(c).(interface{ CleanupExpired() error })
+	if !ok {
+		return JobStats{}, nil
+	}
+
+	before, _ := c.Stats()
+	if err := cleaner.CleanupExpired(); err != nil {
+		return JobStats{}, err
+	}
+	after, _ := c.Stats()
+
+	stats := JobStats{}
+	if before != nil && after != nil {
+		stats.EntriesRemoved = before.Entries - after.Entries
+		stats.BytesReclaimed = before.SizeBytes - after.SizeBytes
+	}
+	return stats, nil
+}
+
+// lruCompactionJob removes expired entries from the L1 memory tier of a
+// layered cache only, independent of cleanupExpiredJob's L2 pass. It's a
+// no-op if the active backend isn't layered.
+func (d *Daemon) lruCompactionJob(ctx context.Context) (JobStats, error) {
+	layered, ok := unwrapCache(d.scheduler.Cache()).(*cache.LayeredCache)
+	if !ok {
+		return JobStats{}, nil
+	}
+	removed, bytesReclaimed := layered.CompactL1()
+	return JobStats{EntriesRemoved: int64(removed), BytesReclaimed: bytesReclaimed}, nil
+}
+
+// circuitBreakerSnapshotJob publishes the current per-host circuit breaker
+// state as a "circuit_snapshot" event, so a subscriber (see handleEvents)
+// gets a periodic full picture instead of only the open/close transitions
+// client.Client.SetCircuitStateChangeHook already reports.
+func (d *Daemon) circuitBreakerSnapshotJob(ctx context.Context) (JobStats, error) {
+	if d.client == nil {
+		return JobStats{}, nil
+	}
+	data, err := json.Marshal(d.client.GetCircuitBreakerStats())
+	if err != nil {
+		return JobStats{}, err
+	}
+	if d.events != nil {
+		d.events.Publish("circuit_snapshot", string(data))
+	}
+	return JobStats{}, nil
+}
+
+// cacheSizeEnforcementJob evicts cache entries once the active backend's
+// reported size exceeds cfg.Cache.MaxSizeBytes. Only a layered cache's L1
+// tier can be evicted by this job today; a non-layered backend over its
+// configured limit is reported as an error instead of silently ignored, so
+// an operator notices instead of assuming enforcement is happening.
+func (d *Daemon) cacheSizeEnforcementJob(ctx context.Context) (JobStats, error) {
+	cfg := d.current().cfg
+	if cfg.Cache.MaxSizeBytes <= 0 {
+		return JobStats{}, nil
+	}
+
+	c := d.scheduler.Cache()
+	stats, err := c.Stats()
+	if err != nil {
+		return JobStats{}, err
+	}
+	if stats.SizeBytes <= cfg.Cache.MaxSizeBytes {
+		return JobStats{}, nil
+	}
+
+	layered, ok := unwrapCache(c).(*cache.LayeredCache)
+	if !ok {
+		return JobStats{}, fmt.Errorf("cache size %d exceeds cache.max_size_bytes %d but backend does not support eviction", stats.SizeBytes, cfg.Cache.MaxSizeBytes)
+	}
+
+	before := stats.SizeBytes
+	layered.ClearL1()
+	after, err := c.Stats()
+	if err != nil {
+		return JobStats{}, err
+	}
+	return JobStats{BytesReclaimed: before - after.SizeBytes}, nil
+}
+
+// remoteConfigPollJob fetches a JSON-encoded config.Config from
+// cfg.RemoteConfig.URL and, if it validates, reloads the daemon with it -
+// the same path POST /admin/reload and SIGHUP use. It's a no-op if no URL
+// is configured.
+func (d *Daemon) remoteConfigPollJob(ctx context.Context) (JobStats, error) {
+	cfg := d.current().cfg
+	if cfg.RemoteConfig.URL == "" {
+		return JobStats{}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.RemoteConfig.URL, nil)
+	if err != nil {
+		return JobStats{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return JobStats{}, fmt.Errorf("remote config poll: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return JobStats{}, fmt.Errorf("remote config poll: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return JobStats{}, fmt.Errorf("remote config poll: %w", err)
+	}
+
+	var newCfg config.Config
+	if err := json.Unmarshal(data, &newCfg); err != nil {
+		return JobStats{}, fmt.Errorf("remote config poll: invalid config: %w", err)
+	}
+	newCfg.Normalize()
+	if err := newCfg.Validate().AsError(); err != nil {
+		return JobStats{}, fmt.Errorf("remote config poll: %w", err)
+	}
+
+	if _, err := d.ReloadWithConfig(&newCfg); err != nil {
+		return JobStats{}, fmt.Errorf("remote config poll: reload failed: %w", err)
+	}
+	return JobStats{}, nil
+}
+
+// unwrapCache strips a TaggedCache wrapper (every cache in this daemon is
+// one) so callers can type-assert against the backend underneath it, e.g.
+// *cache.LayeredCache.
+func unwrapCache(c cache.Cache) cache.Cache {
+	if tagged, ok := c.(*cache.TaggedCache); ok {
+		return tagged.Unwrap()
+	}
+	return c
+}
+
+// handleSchedulerDebug serves GET /debug/scheduler: every registered job's
+// interval, last run/error, next run, and last run's stats.
+func (d *Daemon) handleSchedulerDebug(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if d.scheduler == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jobs": []JobStatus{}})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jobs": d.scheduler.Status()})
+}
+
+// handleRunJob serves POST /admin/scheduler/run?job=<name>, triggering that
+// job immediately out of band with its regular ticker - the endpoint
+// `apiproxy daemon run-job <name>` calls.
+func (d *Daemon) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if d.scheduler == nil {
+		http.Error(w, "scheduler is not running (cache.cleanup_interval is 0)", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := r.URL.Query().Get("job")
+	if name == "" {
+		http.Error(w, "missing job query parameter", http.StatusBadRequest)
+		return
+	}
+	if err := d.scheduler.RunNow(name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ran", "job": name})
+}
@@ -0,0 +1,418 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+	"github.com/afterdarksys/apiproxyd/pkg/client"
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+	"github.com/afterdarksys/apiproxyd/pkg/middleware"
+	"github.com/afterdarksys/apiproxyd/pkg/plugin"
+)
+
+// HandlerPointer holds an atomically-swappable http.Handler. A hot-reload
+// builds a brand new handler off to the side and swaps the pointer in one
+// atomic Store; requests already dispatched to the old handler run to
+// completion unaffected.
+type HandlerPointer struct {
+	ptr atomic.Pointer[http.Handler]
+}
+
+// NewHandlerPointer creates a HandlerPointer initialized to h.
+func NewHandlerPointer(h http.Handler) *HandlerPointer {
+	hp := &HandlerPointer{}
+	hp.Store(h)
+	return hp
+}
+
+// Store atomically swaps in a new handler for subsequent requests.
+func (hp *HandlerPointer) Store(h http.Handler) {
+	hp.ptr.Store(&h)
+}
+
+// ServeHTTP dispatches to whichever handler is currently active.
+func (hp *HandlerPointer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*hp.ptr.Load()).ServeHTTP(w, r)
+}
+
+// pluginManagerConfig translates cfg.Plugins into the plugin package's own
+// config shape, shared by a fresh load (buildPluginManager) and a diffed
+// reload (Manager.ReloadFrom).
+func pluginManagerConfig(cfg *config.Config) *plugin.Config {
+	pluginCfg := &plugin.Config{
+		Enabled: cfg.Plugins.Enabled,
+		Plugins: make([]plugin.PluginConfig, len(cfg.Plugins.Plugins)),
+	}
+	for i, pe := range cfg.Plugins.Plugins {
+		pluginCfg.Plugins[i] = plugin.PluginConfig{
+			Name:    pe.Name,
+			Type:    pe.Type,
+			Path:    pe.Path,
+			Enabled: pe.Enabled,
+			Config:  pe.Config,
+		}
+	}
+	return pluginCfg
+}
+
+// buildPluginManager constructs and loads a plugin manager from cfg.
+func (d *Daemon) buildPluginManager(cfg *config.Config) (*plugin.Manager, error) {
+	pm := plugin.NewManager(pluginManagerConfig(cfg))
+	if err := pm.LoadPlugins(); err != nil {
+		return nil, err
+	}
+	return pm, nil
+}
+
+// buildRateLimiter constructs a rate limiter from cfg, or nil if disabled.
+func (d *Daemon) buildRateLimiter(cfg *config.Config) *middleware.RateLimiter {
+	if !cfg.Security.RateLimitEnabled {
+		return nil
+	}
+	rl := middleware.NewRateLimiterWithOptions(
+		cfg.Security.RateLimitPerIP,
+		cfg.Security.RateLimitPerKey,
+		cfg.Security.RateLimitBurst,
+		middleware.RateLimiterOptions{CNRate: cfg.Security.RateLimitPerCN},
+	)
+	if d.events != nil {
+		rl.OnLimitExceeded = func(scope, identifier string) {
+			d.events.Publish("rate_limit_trip", fmt.Sprintf(`{"scope":%q,"identifier":%q}`, scope, identifier))
+		}
+	}
+	return rl
+}
+
+// buildCache constructs the cache backend (wrapped in a TaggedCache) from
+// cfg, along with the raw *cache.PostgresCache underneath it when that's
+// the backend in use, for callers (distributed dedup) that need the raw
+// *sql.DB. Shared by Start and Reload so both build the cache the same way.
+func (d *Daemon) buildCache(cfg *config.Config) (*cache.TaggedCache, *cache.PostgresCache, error) {
+	cachePath := cfg.Cache.Path
+	if cfg.Cache.Backend == "postgres" {
+		cachePath = cfg.Cache.PostgresDSN
+	}
+
+	cacheOpts := &cache.CacheOptions{
+		Backend:            cfg.Cache.Backend,
+		Path:               cachePath,
+		TTL:                time.Duration(cfg.Cache.TTL) * time.Second,
+		MemoryCacheEnabled: cfg.Cache.MemoryCacheEnabled,
+		MemoryCacheSize:    cfg.Cache.MemoryCacheSize,
+		MaxOpenConns:       cfg.Cache.MaxOpenConns,
+		MaxIdleConns:       cfg.Cache.MaxIdleConns,
+		ConnMaxLifetime:    time.Duration(cfg.Cache.ConnMaxLifetime) * time.Second,
+		ConnMaxIdleTime:    time.Duration(cfg.Cache.ConnMaxIdleTime) * time.Second,
+	}
+	if r := cfg.Cache.Redis; r != nil {
+		cacheOpts.Redis = cache.RedisOptions{Addr: r.Addr, DB: r.DB, Password: r.Password, TLS: r.TLS, TTL: cacheOpts.TTL}
+	}
+	if b := cfg.Cache.Badger; b != nil {
+		cacheOpts.Badger = cache.BadgerOptions{Dir: b.Dir, TTL: cacheOpts.TTL}
+	}
+	if m := cfg.Cache.Memcached; m != nil {
+		cacheOpts.Memcached = cache.MemcachedOptions{Addr: m.Addr, TTL: cacheOpts.TTL}
+	}
+	if s := cfg.Cache.S3; s != nil {
+		cacheOpts.S3 = cache.S3Options{Bucket: s.Bucket, Prefix: s.Prefix, Region: s.Region, Endpoint: s.Endpoint, TTL: cacheOpts.TTL}
+	}
+
+	cacheStore, err := cache.NewWithOptions(cacheOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	postgresCache, _ := cacheStore.(*cache.PostgresCache)
+	return cache.NewTaggedCache(cacheStore), postgresCache, nil
+}
+
+// cacheNeedsRecreate reports whether old and new Cache config differ in a
+// way that requires tearing down and rebuilding the backend (e.g. a
+// changed path or connection pool size). A TTL change alone is not one of
+// them: it doesn't trigger a rebuild, and the running backend keeps the TTL
+// it was built with until the cache is next rebuilt.
+func cacheNeedsRecreate(old, new config.CacheConfig) bool {
+	return old.Backend != new.Backend ||
+		old.Path != new.Path ||
+		old.PostgresDSN != new.PostgresDSN ||
+		old.MemoryCacheEnabled != new.MemoryCacheEnabled ||
+		old.MemoryCacheSize != new.MemoryCacheSize ||
+		old.MaxOpenConns != new.MaxOpenConns ||
+		old.MaxIdleConns != new.MaxIdleConns ||
+		old.ConnMaxLifetime != new.ConnMaxLifetime ||
+		old.ConnMaxIdleTime != new.ConnMaxIdleTime ||
+		!reflect.DeepEqual(old.Redis, new.Redis) ||
+		!reflect.DeepEqual(old.Badger, new.Badger) ||
+		!reflect.DeepEqual(old.Memcached, new.Memcached) ||
+		!reflect.DeepEqual(old.S3, new.S3)
+}
+
+// buildSSRFProtection constructs the SSRF allowlist from cfg, or nil if disabled.
+func (d *Daemon) buildSSRFProtection(cfg *config.Config) *middleware.SSRFProtection {
+	if !cfg.Security.SSRFProtectionEnabled {
+		return nil
+	}
+	return middleware.NewSSRFProtection(
+		cfg.Security.AllowedUpstreamHosts,
+		cfg.Security.BlockPrivateIPs,
+		cfg.Security.SSRFDenyCIDRs,
+		cfg.Security.SSRFAllowCIDRs,
+	)
+}
+
+// buildRetryPolicy translates cfg.Client.Retry into a client.RetryPolicy,
+// or client.DefaultRetryPolicy() if it isn't enabled in config.
+func buildRetryPolicy(cfg config.RetryConfig) *client.RetryPolicy {
+	if !cfg.Enabled {
+		return client.DefaultRetryPolicy()
+	}
+
+	statuses := make(map[int]bool, len(cfg.RetryOn))
+	for _, code := range cfg.RetryOn {
+		statuses[code] = true
+	}
+	methods := make(map[string]bool, len(cfg.RetryOnMethods))
+	for _, m := range cfg.RetryOnMethods {
+		methods[strings.ToUpper(m)] = true
+	}
+
+	return &client.RetryPolicy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoff) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.MaxBackoff) * time.Millisecond,
+		Multiplier:     cfg.Multiplier,
+		RetryOnStatus:  statuses,
+		RetryOnMethods: methods,
+	}
+}
+
+// buildHandler assembles the mux and middleware chain from cfg and the
+// given rate limiter. This is called both at startup and on every
+// hot-reload so routing always reflects the rebuilt state.
+func (d *Daemon) buildHandler(cfg *config.Config, rateLimiter *middleware.RateLimiter) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", d.handleHealth)
+	mux.HandleFunc("/api/", d.handleProxy)
+	mux.HandleFunc("/cache/stats", d.handleCacheStats)
+	mux.HandleFunc("/cache/clear", d.handleCacheClear)
+	mux.HandleFunc("/admin/cache/purge", d.handleCachePurge)
+	mux.HandleFunc("/admin/reload", d.handleReload)
+	mux.HandleFunc("/admin/scheduler/run", d.handleRunJob)
+	mux.HandleFunc("/debug/scheduler", d.handleSchedulerDebug)
+	mux.HandleFunc("/metrics", d.handleMetrics)
+	mux.HandleFunc("/events", d.handleEvents)
+
+	handler := http.Handler(mux)
+
+	// Add recovery middleware (outermost - catches all panics)
+	handler = middleware.RecoveryMiddleware(handler)
+
+	// Add security headers
+	handler = middleware.SecureHeaders(handler)
+
+	// Restrict routes to specific client-certificate identities when mTLS
+	// is enabled. Placed ahead of rate limiting so an unauthorized client
+	// cert is rejected before it can consume a rate-limit token.
+	if cfg.Server.MTLSEnabled {
+		policy := middleware.NewClientCertPolicy(cfg.Server.AllowedClientCNs, cfg.Server.MTLSRoutePolicy)
+		handler = policy.Middleware(handler)
+	}
+
+	// Add rate limiting
+	if rateLimiter != nil {
+		handler = rateLimiter.Middleware(handler)
+	}
+
+	// Add request body size limiting
+	if cfg.Security.MaxRequestBodySize > 0 {
+		handler = middleware.BodySizeLimiter(cfg.Security.MaxRequestBodySize)(handler)
+	}
+
+	// Add input sanitization
+	handler = middleware.InputSanitizer(handler)
+
+	return handler
+}
+
+// ReloadReport enumerates what a call to Reload actually changed, returned
+// over POST /admin/reload so an operator (or a CI smoke test) can tell a
+// no-op reload from one that replaced plugins, rebuilt the rate limiter,
+// or failed partway through.
+type ReloadReport struct {
+	ConfigChanged           bool     `json:"config_changed"`
+	PluginsAdded            []string `json:"plugins_added,omitempty"`
+	PluginsRemoved          []string `json:"plugins_removed,omitempty"`
+	PluginsUnchanged        []string `json:"plugins_unchanged,omitempty"`
+	RateLimiterRebuilt      bool     `json:"rate_limiter_rebuilt"`
+	CacheRecreated          bool     `json:"cache_recreated"`
+	SchedulerIntervalUpdate bool     `json:"scheduler_interval_updated"`
+	CircuitBreakerUpdated   bool     `json:"circuit_breaker_updated"`
+	TLSReloaded             bool     `json:"tls_reloaded"`
+	Errors                  []string `json:"errors,omitempty"`
+}
+
+// Reload re-reads and validates configuration from disk and applies it via
+// ReloadWithConfig. It's the entry point for POST /admin/reload; SIGHUP and
+// config file changes go through config.Watch instead, which calls
+// ReloadWithConfig directly with the config it already loaded and validated.
+func (d *Daemon) Reload() (*ReloadReport, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return &ReloadReport{}, fmt.Errorf("reload: failed to load config: %w", err)
+	}
+	if err := cfg.Validate().AsError(); err != nil {
+		return &ReloadReport{}, fmt.Errorf("reload: invalid config: %w", err)
+	}
+	return d.ReloadWithConfig(cfg)
+}
+
+// ReloadWithConfig applies cfg as the daemon's new active configuration.
+// cfg is assumed already validated (see Reload and config.Watch) - a bad
+// edit never reaches here, so it can't take down the running daemon.
+//
+// It diffs the plugin set against what's currently loaded (reusing
+// unchanged plugins rather than re-initializing them), rebuilds the rate
+// limiter buckets while migrating current token counts, recreates the
+// cache backend only if a structural setting changed (path, backend,
+// pool size - not a plain TTL bump), updates the scheduler's cleanup
+// interval and the client's circuit breaker thresholds in place, reloads
+// the TLS keypair, and atomically swaps in a new routes/middleware chain.
+// Requests already in flight keep running against the handler and cache
+// they started with; only new requests see the reloaded state. It always
+// returns a ReloadReport, even on error, so a caller can see how far the
+// reload got.
+func (d *Daemon) ReloadWithConfig(cfg *config.Config) (*ReloadReport, error) {
+	report := &ReloadReport{}
+
+	d.reloadMu.RLock()
+	oldCfg := d.cfg
+	oldCache := d.cache
+	oldPluginManager := d.pluginManager
+	oldRateLimiter := d.rateLimiter
+	d.reloadMu.RUnlock()
+
+	report.ConfigChanged = oldCfg == nil || !reflect.DeepEqual(oldCfg, cfg)
+
+	newPluginManager := plugin.NewManager(pluginManagerConfig(cfg))
+	pluginDiff, err := newPluginManager.ReloadFrom(oldPluginManager)
+	if pluginDiff != nil {
+		report.PluginsAdded = pluginDiff.Added
+		report.PluginsRemoved = pluginDiff.Removed
+		report.PluginsUnchanged = pluginDiff.Unchanged
+	}
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("plugins: %v", err))
+		return report, fmt.Errorf("reload: failed to reload plugins: %w", err)
+	}
+
+	newRateLimiter := d.buildRateLimiter(cfg)
+	if newRateLimiter != nil && oldRateLimiter != nil {
+		newRateLimiter.MigrateFrom(oldRateLimiter)
+	}
+	report.RateLimiterRebuilt = newRateLimiter != oldRateLimiter
+
+	newSSRFProtection := d.buildSSRFProtection(cfg)
+	newHandler := d.buildHandler(cfg, newRateLimiter)
+
+	// Cache: only torn down and rebuilt if a structural setting changed
+	// (backend, path, pool size). A TTL-only change doesn't rebuild it, so
+	// the kept backend goes on writing entries with the TTL it was built with.
+	newCache := oldCache
+	if oldCfg == nil || cacheNeedsRecreate(oldCfg.Cache, cfg.Cache) {
+		rebuilt, _, err := d.buildCache(cfg)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("cache: %v", err))
+		} else {
+			newCache = rebuilt
+			report.CacheRecreated = true
+			if d.scheduler != nil {
+				d.scheduler.SetCache(newCache)
+			}
+		}
+	}
+	if d.scheduler != nil && oldCfg != nil && oldCfg.Cache.CleanupInterval != cfg.Cache.CleanupInterval {
+		interval := time.Duration(cfg.Cache.CleanupInterval) * time.Second
+		d.scheduler.SetJobInterval("cleanup", interval)
+		d.scheduler.SetJobInterval("lru_compaction", interval)
+		d.scheduler.SetJobInterval("circuit_breaker_snapshot", interval)
+		d.scheduler.SetJobInterval("cache_size_enforcement", interval)
+		report.SchedulerIntervalUpdate = true
+	}
+
+	// Circuit breaker: new thresholds apply to breakers created from now
+	// on; a host already mid-trip keeps running with its existing breaker.
+	if d.client != nil && (oldCfg == nil ||
+		oldCfg.Client.CircuitBreakerThreshold != cfg.Client.CircuitBreakerThreshold ||
+		oldCfg.Client.CircuitBreakerTimeout != cfg.Client.CircuitBreakerTimeout ||
+		oldCfg.Client.CircuitBreakerHalfOpen != cfg.Client.CircuitBreakerHalfOpen) {
+		d.client.UpdateCircuitBreakerConfig(
+			cfg.Client.CircuitBreakerThreshold,
+			time.Duration(cfg.Client.CircuitBreakerTimeout)*time.Second,
+			cfg.Client.CircuitBreakerHalfOpen,
+		)
+		report.CircuitBreakerUpdated = true
+	}
+
+	if d.certReloader != nil && cfg.Server.TLSEnabled {
+		if err := d.certReloader.reload(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("tls: %v", err))
+		} else {
+			report.TLSReloaded = true
+		}
+	}
+
+	d.reloadMu.Lock()
+	d.cfg = cfg
+	d.cache = newCache
+	d.pluginManager = newPluginManager
+	d.rateLimiter = newRateLimiter
+	d.ssrfProtection = newSSRFProtection
+	d.reloadMu.Unlock()
+	d.metrics.SetRateLimiter(newRateLimiter)
+
+	// Swap the handler only after the fields above are visible, so a
+	// request dispatched to the new handler never observes stale state.
+	d.handler.Store(newHandler)
+
+	// Shut down replaced resources in the background so this call doesn't
+	// block on them. Replaced plugins were already handed off to
+	// ReloadFrom, which shuts down only the ones that didn't survive the
+	// diff.
+	if oldRateLimiter != nil && oldRateLimiter != newRateLimiter {
+		go oldRateLimiter.Close()
+	}
+	if report.CacheRecreated && oldCache != nil {
+		go oldCache.Close()
+	}
+
+	if d.events != nil {
+		d.events.Publish("plugin_reload", fmt.Sprintf(`{"added":%d,"removed":%d,"unchanged":%d}`,
+			len(report.PluginsAdded), len(report.PluginsRemoved), len(report.PluginsUnchanged)))
+	}
+
+	fmt.Println("✅ Reloaded configuration, routes, and plugins")
+	return report, nil
+}
+
+// handleReload triggers a hot reload via POST /admin/reload and returns
+// the resulting ReloadReport as JSON, with a 500 status if the reload
+// failed partway through.
+func (d *Daemon) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := d.Reload()
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -3,83 +3,292 @@ package daemon
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/afterdarksys/apiproxyd/pkg/cache"
 )
 
-// Scheduler handles background tasks like cache cleanup
+// Job is a named, periodically-run background task. It reports what it did
+// as JobStats and, if it failed, a non-nil error; Scheduler records both
+// regardless of outcome and, if OnJobComplete is set, forwards them there.
+type Job func(ctx context.Context) (JobStats, error)
+
+// JobStats summarizes what a single Job run did. Not every job fills in
+// every field - e.g. circuit_breaker_snapshot leaves both zero - since not
+// every job's work is naturally expressed as entries/bytes.
+type JobStats struct {
+	EntriesRemoved int64
+	BytesReclaimed int64
+	Duration       time.Duration
+}
+
+// JobStatus is a point-in-time snapshot of one registered job, returned by
+// Scheduler.Status for GET /debug/scheduler.
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Interval  string    `json:"interval"`
+	Running   bool      `json:"running"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	NextRun   time.Time `json:"next_run,omitempty"`
+	LastStats JobStats  `json:"last_stats"`
+}
+
+// job is the Scheduler's bookkeeping for one registered Job.
+type job struct {
+	name string
+	fn   Job
+
+	mu        sync.Mutex
+	interval  time.Duration
+	running   bool
+	lastRun   time.Time
+	lastErr   error
+	lastStats JobStats
+	nextRun   time.Time
+}
+
+// Scheduler runs a registry of named background jobs, each on its own
+// timer with a little jitter mixed into every interval so jobs registered
+// with the same period don't all wake up in lockstep. A job still running
+// when its next tick arrives is skipped rather than overlapped with itself
+// (single-flight protection); a job that panics is recovered and recorded
+// as a failed run instead of taking the whole scheduler down.
 type Scheduler struct {
-	cache    cache.Cache
-	interval time.Duration
-	ticker   *time.Ticker
-	done     chan struct{}
+	// cacheMu guards cache, which SetCache can swap out from under a
+	// running scheduler after a config hot-reload recreates the backend.
+	cacheMu sync.RWMutex
+	cache   cache.Cache
+
+	mu   sync.RWMutex
+	jobs map[string]*job
+
+	// OnJobComplete, if set, is called after every job run (success or
+	// failure) so a caller (daemon.Start wires this to
+	// metrics.RecordSchedulerJob) can record run stats without Scheduler
+	// importing the metrics package.
+	OnJobComplete func(name string, stats JobStats, err error)
+
+	started bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a new background scheduler over c. Jobs are added
+// with Register and only start running once Start is called.
+func NewScheduler(c cache.Cache) *Scheduler {
+	return &Scheduler{
+		cache: c,
+		jobs:  make(map[string]*job),
+		done:  make(chan struct{}),
+	}
 }
 
-// NewScheduler creates a new background scheduler
-func NewScheduler(c cache.Cache, interval time.Duration) *Scheduler {
+// Register adds fn as a named job run every interval (plus jitter). It may
+// be called before or after Start; a job registered after Start begins
+// ticking immediately. Registering a name a second time replaces the
+// existing job definition; if the scheduler is already running, a new
+// goroutine starts ticking the replacement while the goroutine already
+// ticking the old definition keeps running it too.
+func (s *Scheduler) Register(name string, interval time.Duration, fn Job) {
 	if interval <= 0 {
-		interval = 1 * time.Hour // default to hourly cleanup
+		interval = time.Hour
 	}
+	j := &job{name: name, fn: fn, interval: interval, nextRun: time.Now().Add(jitter(interval))}
 
-	return &Scheduler{
-		cache:    c,
-		interval: interval,
-		done:     make(chan struct{}),
+	s.mu.Lock()
+	s.jobs[name] = j
+	started := s.started
+	s.mu.Unlock()
+
+	if started {
+		s.wg.Add(1)
+		go s.runJobLoop(context.Background(), j)
 	}
 }
 
-// Start begins the background scheduler
+// Start begins ticking every job registered so far. Jobs registered after
+// Start start ticking as soon as they're registered.
 func (s *Scheduler) Start(ctx context.Context) {
-	s.ticker = time.NewTicker(s.interval)
-
-	go func() {
-		// Run initial cleanup
-		s.runCleanup()
-
-		for {
-			select {
-			case <-s.ticker.C:
-				s.runCleanup()
-			case <-s.done:
-				return
-			case <-ctx.Done():
-				return
-			}
+	s.mu.Lock()
+	s.started = true
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		s.wg.Add(1)
+		go s.runJobLoop(ctx, j)
+	}
+}
+
+// runJobLoop ticks j every interval (re-jittered each cycle) until ctx is
+// canceled or Stop is called.
+func (s *Scheduler) runJobLoop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+
+	j.mu.Lock()
+	timer := time.NewTimer(jitter(j.interval))
+	j.mu.Unlock()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			s.runJob(ctx, j)
+			j.mu.Lock()
+			timer.Reset(jitter(j.interval))
+			j.mu.Unlock()
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
 		}
+	}
+}
+
+// runJob runs j.fn once, recovering a panic into an error and recording
+// the outcome regardless of how it finished. It's a no-op if j is already
+// running (single-flight protection against RunNow racing the ticker).
+func (s *Scheduler) runJob(ctx context.Context, j *job) {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	start := time.Now()
+	var stats JobStats
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("scheduler: job %q panicked: %v", j.name, r)
+			}
+		}()
+		stats, err = j.fn(ctx)
 	}()
+	stats.Duration = time.Since(start)
+
+	j.mu.Lock()
+	j.running = false
+	j.lastRun = start
+	j.lastErr = err
+	j.lastStats = stats
+	j.nextRun = time.Now().Add(jitter(j.interval))
+	j.mu.Unlock()
+
+	if err != nil {
+		fmt.Printf("Scheduler job %q failed: %v\n", j.name, err)
+	}
+	if s.OnJobComplete != nil {
+		s.OnJobComplete(j.name, stats, err)
+	}
 }
 
-// Stop stops the background scheduler
-func (s *Scheduler) Stop() {
-	if s.ticker != nil {
-		s.ticker.Stop()
+// RunNow runs the named job immediately, out of band with its regular
+// ticker, the way `apiproxy daemon run-job <name>` does. It's a no-op (not
+// an error) if the job is already running. Returns an error if no job is
+// registered under name.
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.RLock()
+	j, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("scheduler: no job registered as %q", name)
 	}
-	close(s.done)
+	s.runJob(context.Background(), j)
+	return nil
 }
 
-// runCleanup performs cache cleanup
-func (s *Scheduler) runCleanup() {
-	start := time.Now()
+// SetJobInterval changes the period of the named job, taking effect on its
+// next tick rather than resetting its timer immediately. A zero or
+// negative interval, or an unknown name, is ignored.
+func (s *Scheduler) SetJobInterval(name string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.mu.RLock()
+	j, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	j.mu.Lock()
+	j.interval = interval
+	j.mu.Unlock()
+}
 
-	// Clean up expired entries in database cache
-	if cleaner, ok := s.cache.(interface{ CleanupExpired() error }); ok {
-		if err := cleaner.CleanupExpired(); err != nil {
-			fmt.Printf("Cache cleanup error: %v\n", err)
-			return
-		}
+// SetCache swaps the cache jobs run against, e.g. after a config
+// hot-reload recreates the cache backend.
+func (s *Scheduler) SetCache(c cache.Cache) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.cache = c
+}
+
+// Cache returns the cache backend jobs should currently run against.
+func (s *Scheduler) Cache() cache.Cache {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return s.cache
+}
+
+// Status returns a snapshot of every registered job, sorted by name, for
+// GET /debug/scheduler.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
 	}
+	jobs := s.jobs
+	s.mu.RUnlock()
+	sort.Strings(names)
 
-	// Clean up expired entries in memory cache (if layered)
-	if layered, ok := s.cache.(*cache.LayeredCache); ok {
-		layered.CleanupExpired()
+	statuses := make([]JobStatus, 0, len(names))
+	for _, name := range names {
+		j := jobs[name]
+		j.mu.Lock()
+		status := JobStatus{
+			Name:      j.name,
+			Interval:  j.interval.String(),
+			Running:   j.running,
+			LastRun:   j.lastRun,
+			NextRun:   j.nextRun,
+			LastStats: j.lastStats,
+		}
+		if j.lastErr != nil {
+			status.LastError = j.lastErr.Error()
+		}
+		j.mu.Unlock()
+		statuses = append(statuses, status)
 	}
+	return statuses
+}
 
-	duration := time.Since(start)
-	fmt.Printf("Cache cleanup completed in %v\n", duration)
+// Stop stops every job's ticker and waits for any in-flight run to finish.
+func (s *Scheduler) Stop() {
+	close(s.done)
+	s.wg.Wait()
 }
 
-// RunNow triggers an immediate cleanup
-func (s *Scheduler) RunNow() {
-	s.runCleanup()
+// jitter returns interval adjusted by up to +/-5%, so jobs sharing the same
+// configured interval don't all fire on the same tick.
+func jitter(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return time.Hour
+	}
+	spread := interval / 10
+	if spread <= 0 {
+		return interval
+	}
+	return interval - spread/2 + time.Duration(rand.Int63n(int64(spread)))
 }
@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+)
+
+// Secret keys resolveSecrets knows how to fetch from a configured
+// config.SecretProvider and apply without a restart.
+const (
+	secretKeyAPIKey           = "api_key"
+	secretKeyMetricsAuthToken = "metrics_auth_token"
+)
+
+// secretWatch is one credential resolveSecrets pulled from a provider,
+// plus how to apply a refreshed value in place once runSecretWatches
+// renews it.
+type secretWatch struct {
+	key    string
+	secret *config.Secret
+	apply  func(value string)
+}
+
+// resolveSecrets fills in cfg's plaintext credential fields from
+// cfg.Secrets's provider, if one is configured, and returns the provider
+// plus the watches a background refresh loop should maintain for any
+// secret carrying a lease. It returns a nil provider (and no error) if
+// cfg.Secrets.Provider is empty, so the caller just keeps using cfg's
+// existing plaintext values.
+func (d *Daemon) resolveSecrets(ctx context.Context, cfg *config.Config) (config.SecretProvider, []secretWatch, error) {
+	provider, err := config.NewSecretProvider(cfg.Secrets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("secrets: %w", err)
+	}
+	if provider == nil {
+		return nil, nil, nil
+	}
+
+	secret, err := provider.GetSecret(ctx, secretKeyAPIKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("secrets: resolve %s: %w", secretKeyAPIKey, err)
+	}
+	cfg.APIKey = secret.Value
+	watches := []secretWatch{{
+		key:    secretKeyAPIKey,
+		secret: secret,
+		apply: func(value string) {
+			cfg.APIKey = value
+			if d.client != nil {
+				d.client.SetAPIKey(value)
+			}
+		},
+	}}
+
+	// MetricsAuthToken is optional: a provider without that field just
+	// means the plaintext config.yml/config.json value (if any) is used.
+	if secret, err := provider.GetSecret(ctx, secretKeyMetricsAuthToken); err == nil {
+		cfg.Security.MetricsAuthToken = secret.Value
+		watches = append(watches, secretWatch{
+			key:    secretKeyMetricsAuthToken,
+			secret: secret,
+			apply: func(value string) {
+				d.reloadMu.Lock()
+				d.cfg.Security.MetricsAuthToken = value
+				d.reloadMu.Unlock()
+			},
+		})
+	}
+
+	if !cfg.Secrets.Renew {
+		return provider, nil, nil
+	}
+	return provider, watches, nil
+}
+
+// runSecretWatches starts one goroutine per watch that re-fetches its
+// secret from provider shortly before the currently held lease expires,
+// applies the new value, and publishes a secret_rotated event over the
+// GET /events stream. A watch whose secret never expires (ExpiresAt is
+// zero, e.g. the env/file providers) is skipped - there's nothing to
+// renew. Every goroutine exits when ctx is canceled.
+func (d *Daemon) runSecretWatches(ctx context.Context, provider config.SecretProvider, watches []secretWatch) {
+	for _, w := range watches {
+		if w.secret.ExpiresAt.IsZero() {
+			continue
+		}
+		go d.watchSecret(ctx, provider, w)
+	}
+}
+
+// minSecretRefreshLead is how far ahead of a lease's expiry watchSecret
+// tries to renew it, so a slow provider call or a missed wakeup still
+// leaves margin before the old value actually stops working.
+const minSecretRefreshLead = 30 * time.Second
+
+func (d *Daemon) watchSecret(ctx context.Context, provider config.SecretProvider, w secretWatch) {
+	secret := w.secret
+	for {
+		wait := time.Until(secret.ExpiresAt) - minSecretRefreshLead
+		if wait < 0 {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		next, err := provider.GetSecret(ctx, w.key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "secrets: refresh %s failed, retrying in 1m: %v\n", w.key, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Minute):
+			}
+			continue
+		}
+
+		w.apply(next.Value)
+		if d.events != nil {
+			d.events.Publish("secret_rotated", fmt.Sprintf(`{"key":%q}`, w.key))
+		}
+
+		if next.ExpiresAt.IsZero() {
+			return
+		}
+		secret = next
+	}
+}
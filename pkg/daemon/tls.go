@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"sync/atomic"
+)
+
+// certReloader serves the daemon's TLS certificate/key pair through
+// tls.Config.GetCertificate instead of tls.Config.Certificates, so Reload
+// can swap in a renewed keypair by storing a new *tls.Certificate;
+// connections already established keep the certificate they were
+// handshaked with, and new handshakes pick up the reloaded one.
+type certReloader struct {
+	certFile, keyFile string
+	cert              atomic.Pointer[tls.Certificate]
+}
+
+// newCertReloader loads certFile/keyFile and returns a certReloader ready
+// to be installed as a tls.Config's GetCertificate callback.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := cr.reload(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// reload re-reads certFile/keyFile and atomically swaps in the new
+// keypair. It updates the stored paths too, so a reload that also
+// changes Server.TLSCertFile/TLSKeyFile takes effect.
+func (cr *certReloader) reload(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	cr.certFile, cr.keyFile = certFile, keyFile
+	cr.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate implements the tls.Config.GetCertificate signature.
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return cr.cert.Load(), nil
+}
@@ -0,0 +1,307 @@
+package diagnostics
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+	"github.com/afterdarksys/apiproxyd/pkg/client"
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func init() {
+	RegisterCheck(configCheck{})
+	RegisterCheck(authCheck{})
+	RegisterCheck(cacheCheck{})
+	RegisterCheck(daemonCheck{})
+	RegisterCheck(upstreamCheck{})
+	RegisterCheck(diskSpaceCheck{})
+	RegisterCheck(circuitBreakerCheck{})
+}
+
+// configCheck verifies the config file loads and has an upstream endpoint
+// and cache backend configured.
+type configCheck struct{}
+
+func (configCheck) Name() string     { return "config" }
+func (configCheck) Category() string { return "setup" }
+
+func (configCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	if cfg == nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("no configuration loaded")}
+	}
+	if cfg.EntryPoint == "" || cfg.Cache.Backend == "" {
+		return Result{Status: StatusFail, Err: fmt.Errorf("entry_point and cache.backend must both be set")}
+	}
+	return Result{
+		Status: StatusPass,
+		Details: map[string]interface{}{
+			"entry_point":   cfg.EntryPoint,
+			"cache_backend": cfg.Cache.Backend,
+		},
+	}
+}
+
+// authCheck validates the configured API key against the upstream.
+type authCheck struct{}
+
+func (authCheck) Name() string     { return "auth" }
+func (authCheck) Category() string { return "connectivity" }
+
+func (authCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	if cfg == nil || cfg.APIKey == "" {
+		return Result{Status: StatusSkip, Details: map[string]interface{}{"reason": "not authenticated"}}
+	}
+
+	c := client.New(cfg.APIKey)
+	info, err := c.ValidateKey()
+	if err != nil {
+		return Result{Status: StatusFail, Err: err}
+	}
+	return Result{
+		Status: StatusPass,
+		Details: map[string]interface{}{
+			"email": info.Email,
+			"tier":  info.Tier,
+		},
+	}
+}
+
+// cacheCheck exercises a write/read/delete round trip against the
+// configured cache backend.
+type cacheCheck struct{}
+
+func (cacheCheck) Name() string     { return "cache" }
+func (cacheCheck) Category() string { return "setup" }
+
+func (cacheCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	if cfg == nil {
+		return Result{Status: StatusSkip, Details: map[string]interface{}{"reason": "no config"}}
+	}
+
+	store, err := cache.New(cfg.Cache.Backend, cfg.Cache.Path)
+	if err != nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("opening cache: %w", err)}
+	}
+	defer store.Close()
+
+	key := fmt.Sprintf("diagnostics:%d", time.Now().UnixNano())
+	value := []byte(`{"diagnostics":"probe"}`)
+
+	if err := store.Set(key, value); err != nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("write: %w", err)}
+	}
+	defer store.Delete(key)
+
+	got, err := store.Get(key)
+	if err != nil || string(got) != string(value) {
+		return Result{Status: StatusFail, Err: fmt.Errorf("read back mismatch: %w", err)}
+	}
+
+	stats, _ := store.Stats()
+	details := map[string]interface{}{"backend": cfg.Cache.Backend}
+	if stats != nil {
+		details["entries"] = stats.Entries
+	}
+	return Result{Status: StatusPass, Details: details}
+}
+
+// daemonCheck dials the locally configured daemon's /health endpoint.
+type daemonCheck struct{}
+
+func (daemonCheck) Name() string     { return "daemon" }
+func (daemonCheck) Category() string { return "connectivity" }
+
+func (daemonCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	host, port := "127.0.0.1", 9002
+	if cfg != nil {
+		if cfg.Server.Host != "" {
+			host = cfg.Server.Host
+		}
+		if cfg.Server.Port != 0 {
+			port = cfg.Server.Port
+		}
+	}
+
+	endpoint := fmt.Sprintf("http://%s:%d/health", host, port)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Result{Status: StatusFail, Err: err}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("daemon not reachable at %s: %w", endpoint, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{Status: StatusFail, Err: fmt.Errorf("daemon returned %s", resp.Status)}
+	}
+	return Result{Status: StatusPass, Details: map[string]interface{}{"endpoint": endpoint}}
+}
+
+// upstreamCheck confirms the configured upstream is reachable over TLS and
+// warns if its certificate is close to expiring.
+type upstreamCheck struct{}
+
+func (upstreamCheck) Name() string     { return "upstream" }
+func (upstreamCheck) Category() string { return "connectivity" }
+
+// certExpiryWarning is how close to expiry a certificate triggers a warn
+// rather than a pass.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+func (upstreamCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	if cfg == nil || cfg.EntryPoint == "" {
+		return Result{Status: StatusSkip, Details: map[string]interface{}{"reason": "no entry point configured"}}
+	}
+
+	u, err := url.Parse(cfg.EntryPoint)
+	if err != nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("invalid entry point: %w", err)}
+	}
+	if u.Scheme != "https" {
+		return Result{Status: StatusSkip, Details: map[string]interface{}{"reason": "entry point is not https"}}
+	}
+
+	host := u.Hostname()
+	addr := net.JoinHostPort(host, portOrDefault(u.Port(), "443"))
+
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{}, Config: &tls.Config{ServerName: host}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("TLS dial %s: %w", addr, err)}
+	}
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok || len(tlsConn.ConnectionState().PeerCertificates) == 0 {
+		return Result{Status: StatusFail, Err: fmt.Errorf("no peer certificate presented by %s", addr)}
+	}
+
+	cert := tlsConn.ConnectionState().PeerCertificates[0]
+	remaining := time.Until(cert.NotAfter)
+	details := map[string]interface{}{
+		"host":       host,
+		"not_after":  cert.NotAfter.Format(time.RFC3339),
+		"expires_in": remaining.String(),
+	}
+
+	if remaining <= 0 {
+		return Result{Status: StatusFail, Err: fmt.Errorf("certificate for %s expired %s ago", host, -remaining), Details: details}
+	}
+	if remaining < certExpiryWarning {
+		return Result{Status: StatusWarn, Details: details}
+	}
+	return Result{Status: StatusPass, Details: details}
+}
+
+func portOrDefault(port, def string) string {
+	if port == "" {
+		return def
+	}
+	return port
+}
+
+// diskSpaceCheck warns when the filesystem backing the cache path is
+// running low on free space.
+type diskSpaceCheck struct{}
+
+func (diskSpaceCheck) Name() string     { return "disk_space" }
+func (diskSpaceCheck) Category() string { return "setup" }
+
+// diskSpaceWarnPercent is the free-space floor (as a percentage used)
+// above which this check warns instead of passing.
+const diskSpaceWarnPercent = 90.0
+
+func (diskSpaceCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	path := "."
+	if cfg != nil && cfg.Cache.Path != "" {
+		path = cfg.Cache.Path
+	}
+
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return Result{Status: StatusFail, Err: fmt.Errorf("reading disk usage for %s: %w", path, err)}
+	}
+
+	details := map[string]interface{}{
+		"path":         path,
+		"used_percent": usage.UsedPercent,
+		"free_bytes":   usage.Free,
+	}
+	if usage.UsedPercent >= diskSpaceWarnPercent {
+		return Result{Status: StatusWarn, Details: details}
+	}
+	return Result{Status: StatusPass, Details: details}
+}
+
+// circuitBreakerCheck scrapes the running daemon's /metrics for
+// apiproxyd_circuit_state and reports any host whose circuit is currently
+// open or half-open.
+type circuitBreakerCheck struct{}
+
+func (circuitBreakerCheck) Name() string     { return "circuit_breaker" }
+func (circuitBreakerCheck) Category() string { return "connectivity" }
+
+func (circuitBreakerCheck) Run(ctx context.Context, cfg *config.Config) Result {
+	host, port := "127.0.0.1", 9002
+	if cfg != nil {
+		if cfg.Server.Host != "" {
+			host = cfg.Server.Host
+		}
+		if cfg.Server.Port != 0 {
+			port = cfg.Server.Port
+		}
+	}
+
+	endpoint := fmt.Sprintf("http://%s:%d/metrics", host, port)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Result{Status: StatusFail, Err: err}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Result{Status: StatusSkip, Details: map[string]interface{}{"reason": fmt.Sprintf("daemon not reachable: %v", err)}}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{Status: StatusFail, Err: fmt.Errorf("scrape failed: %s", resp.Status)}
+	}
+
+	open := map[string]float64{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "apiproxyd_circuit_state{") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || value == 0 {
+			continue
+		}
+		name := fields[0][len("apiproxyd_circuit_state"):]
+		open[name] = value
+	}
+
+	if len(open) > 0 {
+		return Result{Status: StatusWarn, Details: map[string]interface{}{"open_or_half_open": open}}
+	}
+	return Result{Status: StatusPass, Details: map[string]interface{}{"endpoint": endpoint}}
+}
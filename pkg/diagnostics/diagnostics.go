@@ -0,0 +1,190 @@
+// Package diagnostics implements the checks behind `apiproxy test`: small,
+// independent probes of the local setup (config, auth, cache, daemon,
+// upstream reachability, disk space, circuit breakers) that can be run
+// individually or all together and reported in a machine-readable format
+// for CI as well as a human-friendly one for interactive use.
+package diagnostics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+)
+
+// Status is the outcome of a single Check.Run call.
+type Status int
+
+const (
+	StatusPass Status = iota
+	StatusWarn
+	StatusFail
+	StatusSkip
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "pass"
+	case StatusWarn:
+		return "warn"
+	case StatusFail:
+		return "fail"
+	case StatusSkip:
+		return "skip"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON renders Status as its lowercase name rather than an int, so
+// `apiproxy test --format=json` output reads naturally.
+func (s Status) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+// MarshalYAML renders Status as its lowercase name, the yaml.v3 equivalent
+// of MarshalJSON above.
+func (s Status) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// Result is what a Check reports after running.
+type Result struct {
+	Name     string                 `json:"name" yaml:"name"`
+	Category string                 `json:"category" yaml:"category"`
+	Status   Status                 `json:"status" yaml:"status"`
+	Duration time.Duration          `json:"duration" yaml:"duration"`
+	Details  map[string]interface{} `json:"details,omitempty" yaml:"details,omitempty"`
+	Err      error                  `json:"-" yaml:"-"`
+	// ErrMessage mirrors Err.Error() so it survives JSON/YAML marshaling,
+	// which can't encode the error interface directly.
+	ErrMessage string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Check is one diagnostic probe. Implementations should be safe to run
+// with a nil or partially-populated cfg and report StatusSkip rather than
+// panicking when a prerequisite (e.g. an API key) is missing.
+type Check interface {
+	// Name uniquely identifies the check, e.g. "cache". Matched against
+	// --only/--skip.
+	Name() string
+	// Category groups related checks for display, e.g. "connectivity".
+	Category() string
+	Run(ctx context.Context, cfg *config.Config) Result
+}
+
+// Registry holds the set of Checks `apiproxy test` runs. DefaultRegistry is
+// populated by this package's checks at init; NewRegistry gives tests (or
+// an embedder that wants a restricted set) an isolated one instead.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register adds check under its own Name(), overwriting any existing
+// registration for that name.
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[check.Name()] = check
+}
+
+// Names returns every registered check name, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Checks returns the registered Checks whose name appears in only (if
+// non-empty) and doesn't appear in skip, ordered by name for stable
+// output.
+func (r *Registry) Checks(only, skip []string) []Check {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var onlySet, skipSet map[string]bool
+	if len(only) > 0 {
+		onlySet = make(map[string]bool, len(only))
+		for _, name := range only {
+			onlySet[name] = true
+		}
+	}
+	if len(skip) > 0 {
+		skipSet = make(map[string]bool, len(skip))
+		for _, name := range skip {
+			skipSet[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		if onlySet != nil && !onlySet[name] {
+			continue
+		}
+		if skipSet[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]Check, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, r.checks[name])
+	}
+	return checks
+}
+
+// DefaultRegistry is the Registry `apiproxy test` runs against. RegisterCheck
+// is the usual way to add to it.
+var DefaultRegistry = NewRegistry()
+
+// RegisterCheck registers check in DefaultRegistry.
+func RegisterCheck(check Check) {
+	DefaultRegistry.Register(check)
+}
+
+// Run executes every check in checks against cfg, applying perCheckTimeout
+// (if positive) to each one independently, and returns one Result per
+// check in the same order.
+func Run(ctx context.Context, cfg *config.Config, checks []Check, perCheckTimeout time.Duration) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		checkCtx := ctx
+		var cancel context.CancelFunc
+		if perCheckTimeout > 0 {
+			checkCtx, cancel = context.WithTimeout(ctx, perCheckTimeout)
+		}
+
+		start := time.Now()
+		result := check.Run(checkCtx, cfg)
+		result.Name = check.Name()
+		result.Category = check.Category()
+		if result.Duration == 0 {
+			result.Duration = time.Since(start)
+		}
+		if result.Err != nil {
+			result.ErrMessage = result.Err.Error()
+		}
+
+		if cancel != nil {
+			cancel()
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,84 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/config"
+)
+
+var errBoom = errors.New("boom")
+
+type fakeCheck struct {
+	name     string
+	category string
+	result   Result
+}
+
+func (f fakeCheck) Name() string     { return f.name }
+func (f fakeCheck) Category() string { return f.category }
+func (f fakeCheck) Run(ctx context.Context, cfg *config.Config) Result { return f.result }
+
+func TestRegistryChecksOnlyAndSkip(t *testing.T) {
+	r := NewRegistry()
+	r.Register(fakeCheck{name: "a", category: "setup"})
+	r.Register(fakeCheck{name: "b", category: "setup"})
+	r.Register(fakeCheck{name: "c", category: "connectivity"})
+
+	all := r.Checks(nil, nil)
+	if len(all) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(all))
+	}
+	if all[0].Name() != "a" || all[1].Name() != "b" || all[2].Name() != "c" {
+		t.Errorf("expected checks sorted by name, got %v", names(all))
+	}
+
+	only := r.Checks([]string{"a", "c"}, nil)
+	if got := names(only); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Errorf("only filter: got %v", got)
+	}
+
+	skip := r.Checks(nil, []string{"b"})
+	if got := names(skip); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Errorf("skip filter: got %v", got)
+	}
+}
+
+func names(checks []Check) []string {
+	out := make([]string, len(checks))
+	for i, c := range checks {
+		out[i] = c.Name()
+	}
+	return out
+}
+
+func TestRunPopulatesNameCategoryAndDuration(t *testing.T) {
+	check := fakeCheck{name: "slow", category: "setup", result: Result{Status: StatusPass}}
+
+	results := Run(context.Background(), nil, []Check{check}, time.Second)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	r := results[0]
+	if r.Name != "slow" || r.Category != "setup" {
+		t.Errorf("Run did not stamp name/category: %+v", r)
+	}
+	if r.Duration <= 0 {
+		t.Error("expected a non-zero duration to be recorded")
+	}
+}
+
+func TestRunCopiesErrToErrMessage(t *testing.T) {
+	check := fakeCheck{name: "failing", category: "setup", result: Result{
+		Status: StatusFail,
+		Err:    errBoom,
+	}}
+
+	results := Run(context.Background(), nil, []Check{check}, 0)
+	if results[0].ErrMessage != errBoom.Error() {
+		t.Errorf("expected ErrMessage %q, got %q", errBoom.Error(), results[0].ErrMessage)
+	}
+}
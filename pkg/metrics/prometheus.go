@@ -2,36 +2,241 @@ package metrics
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/afterdarksys/apiproxyd/pkg/analytics"
+	"github.com/afterdarksys/apiproxyd/pkg/audit"
+	"github.com/afterdarksys/apiproxyd/pkg/cache"
+	"github.com/afterdarksys/apiproxyd/pkg/client"
+	"github.com/afterdarksys/apiproxyd/pkg/middleware"
 )
 
+// DefaultLatencyBuckets are Prometheus's own standard histogram
+// boundaries (seconds), reused here because they already cover the
+// sub-10ms-to-10s range most HTTP proxy latencies fall into.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// RequestLabels identifies the dimensions a single proxied request is
+// recorded under.
+type RequestLabels struct {
+	Method string
+	Status int
+	// Route is the whitelisted endpoint pattern the request matched, or
+	// the raw path if no pattern-based routing applies.
+	Route string
+	// Plugin is the name of the plugin that ultimately served the
+	// request (e.g. a router plugin that forwarded it to a third-party
+	// upstream instead of apiproxy.app), or "" if none claimed it.
+	Plugin string
+	Cached bool
+}
+
+// labelSet turns RequestLabels into the stable, pre-sorted key used both
+// to look up a family's per-combination counters and to render the
+// exposition line, so the same combination always emits identically
+// ordered labels.
+func (l RequestLabels) labelSet() string {
+	return formatLabels([]label{
+		{"method", l.Method},
+		{"status", strconv.Itoa(l.Status)},
+		{"plugin", l.Plugin},
+		{"cached", strconv.FormatBool(l.Cached)},
+		{"route", l.Route},
+	})
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// formatLabels renders labels in the given (already-deliberate) order,
+// escaping values per the text exposition format.
+func formatLabels(labels []label) string {
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, l.name, escapeLabelValue(l.value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return v
+}
+
+// histogram is a Prometheus-style cumulative histogram: counts[i] holds
+// the number of observations <= buckets[i], so the exposed `_bucket`
+// series are already cumulative without any summing at render time.
+type histogram struct {
+	buckets []float64
+	counts  []int64
+	sum     float64
+	count   int64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]int64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(seconds float64) {
+	h.sum += seconds
+	h.count++
+	for i, le := range h.buckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+}
+
+// summary is a minimal latency summary exposing only sum/count (no
+// quantiles), used for the CustomRouterPlugin upstream-call latency
+// series where per-route histograms would be overkill.
+type summary struct {
+	sum   float64
+	count int64
+}
+
+func (s *summary) observe(seconds float64) {
+	s.sum += seconds
+	s.count++
+}
+
 // PrometheusMetrics handles Prometheus metrics export
 type PrometheusMetrics struct {
-	mu                sync.RWMutex
-	requestsTotal     int64
-	requestsDuration  float64
-	cacheHits         int64
-	cacheMisses       int64
-	bytesTransferred  int64
-	errorCount        int64
-	requestsByMethod  map[string]int64
-	requestsByStatus  map[int]int64
-	enabled           bool
-}
-
-// NewPrometheusMetrics creates a new metrics collector
+	mu sync.RWMutex
+
+	requestsTotal    map[string]int64
+	requestDurations map[string]*histogram
+	cacheHits        int64
+	cacheMisses      int64
+	bytesTransferred int64
+	errorCount       int64
+
+	buckets []float64
+
+	// pluginUpstreamLatency tracks per-pattern upstream call latency
+	// reported by plugins such as CustomRouterPlugin.
+	pluginUpstreamLatency map[string]*summary
+
+	cacheStats cache.Cache
+
+	// dedupLockWait tracks how long DistributedSingleFlight.Do spent
+	// waiting on another daemon's in-flight result before it either found
+	// one or fell back to local execution (see client.DistributedSingleFlight).
+	dedupLockWait *histogram
+
+	// schedulerJobDuration, schedulerJobEntriesRemoved, and
+	// schedulerJobBytesReclaimed are keyed by job name and back the
+	// apiproxyd_scheduler_job_* series, fed by RecordSchedulerJob.
+	schedulerJobDuration       map[string]*histogram
+	schedulerJobEntriesRemoved map[string]int64
+	schedulerJobBytesReclaimed map[string]int64
+	schedulerJobFailures       map[string]int64
+
+	// analyticsSrc, warmer, and rateLimiter back the apiproxyd_bytes_saved_total,
+	// apiproxyd_cost_savings_usd, and apiproxyd_request_latency_seconds
+	// (legacy analytics.Analytics tracker), apiproxyd_warming_*, and
+	// apiproxyd_rate_limit_* series respectively. Each is read fresh on
+	// every scrape rather than mirrored into PrometheusMetrics, the same
+	// way cacheStats already works.
+	analyticsSrc *analytics.Analytics
+	warmer       *cache.Warmer
+	rateLimiter  *middleware.RateLimiter
+	auditLogger  *audit.Logger
+
+	// circuitClient backs the apiproxyd_circuit_state,
+	// apiproxyd_circuit_failures_total, and apiproxyd_circuit_successes_total
+	// series, read fresh from GetCircuitBreakerStats() on every scrape the
+	// same way cacheStats is. circuitTransitions is instead accumulated as
+	// transitions happen (see RecordCircuitTransition), since a scrape-time
+	// snapshot can't recover counts of transitions that already reverted.
+	circuitClient      *client.Client
+	circuitTransitions map[string]int64
+
+	// upstreamDuration and upstreamRequests back
+	// apiproxyd_upstream_request_duration_seconds and
+	// apiproxyd_upstream_requests_total, keyed by the same
+	// {method,endpoint,code} label set, fed by RecordUpstreamRequest.
+	upstreamDuration map[string]*histogram
+	upstreamRequests map[string]int64
+
+	// cacheBackend labels the apiproxyd_cache_hits_by_backend_total,
+	// apiproxyd_cache_misses_by_backend_total, and apiproxyd_cache_entries_by_backend
+	// series read from cacheStats, since a deployment only ever runs one
+	// cache backend at a time but operators comparing dashboards across
+	// deployments want the backend in the label rather than implied by
+	// which dashboard they're looking at.
+	cacheBackend string
+
+	enabled bool
+}
+
+// NewPrometheusMetrics creates a new metrics collector using the standard
+// Prometheus latency bucket boundaries.
 func NewPrometheusMetrics() *PrometheusMetrics {
+	return NewPrometheusMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewPrometheusMetricsWithBuckets creates a new metrics collector with
+// custom histogram bucket boundaries (seconds), for deployments whose
+// latency SLOs don't match Prometheus's defaults.
+func NewPrometheusMetricsWithBuckets(buckets []float64) *PrometheusMetrics {
 	return &PrometheusMetrics{
-		requestsByMethod: make(map[string]int64),
-		requestsByStatus: make(map[int]int64),
-		enabled:          true,
+		requestsTotal:              make(map[string]int64),
+		requestDurations:           make(map[string]*histogram),
+		pluginUpstreamLatency:      make(map[string]*summary),
+		dedupLockWait:              newHistogram(buckets),
+		schedulerJobDuration:       make(map[string]*histogram),
+		schedulerJobEntriesRemoved: make(map[string]int64),
+		schedulerJobBytesReclaimed: make(map[string]int64),
+		schedulerJobFailures:       make(map[string]int64),
+		buckets:                    buckets,
+		circuitTransitions:         make(map[string]int64),
+		upstreamDuration:           make(map[string]*histogram),
+		upstreamRequests:           make(map[string]int64),
+		enabled:                    true,
+	}
+}
+
+// RecordUpstreamRequest records one upstream request attempt made through
+// client.Client, wired via client.Client.SetUpstreamRequestHook. endpoint is
+// the request path (not including host) and code is the HTTP status code,
+// or 0 if the attempt never got a response at all (see
+// client.upstreamStatusCode).
+func (p *PrometheusMetrics) RecordUpstreamRequest(method, endpoint string, code int, duration time.Duration) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := formatLabels([]label{{"method", method}, {"endpoint", endpoint}, {"code", strconv.Itoa(code)}})
+	p.upstreamRequests[key]++
+
+	hist, ok := p.upstreamDuration[key]
+	if !ok {
+		hist = newHistogram(p.buckets)
+		p.upstreamDuration[key] = hist
 	}
+	hist.observe(duration.Seconds())
 }
 
-// RecordRequest records a request metric
-func (p *PrometheusMetrics) RecordRequest(method string, statusCode int, duration time.Duration, cached bool, bytes int64) {
+// RecordRequest records a request's outcome under the given label
+// combination.
+func (p *PrometheusMetrics) RecordRequest(labels RequestLabels, duration time.Duration, bytes int64) {
 	if !p.enabled {
 		return
 	}
@@ -39,38 +244,180 @@ func (p *PrometheusMetrics) RecordRequest(method string, statusCode int, duratio
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.requestsTotal++
-	p.requestsDuration += duration.Seconds()
+	key := labels.labelSet()
+	p.requestsTotal[key]++
+
+	hist, ok := p.requestDurations[key]
+	if !ok {
+		hist = newHistogram(p.buckets)
+		p.requestDurations[key] = hist
+	}
+	hist.observe(duration.Seconds())
+
 	p.bytesTransferred += bytes
 
-	if cached {
+	if labels.Cached {
 		p.cacheHits++
 	} else {
 		p.cacheMisses++
 	}
 
-	if statusCode >= 400 {
+	if labels.Status >= 400 {
 		p.errorCount++
 	}
+}
 
-	p.requestsByMethod[method]++
-	p.requestsByStatus[statusCode]++
+// RecordPluginUpstreamLatency records how long a plugin-initiated upstream
+// call for route pattern took, e.g. CustomRouterPlugin's call to its
+// configured target URL.
+func (p *PrometheusMetrics) RecordPluginUpstreamLatency(pattern string, duration time.Duration) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.pluginUpstreamLatency[pattern]
+	if !ok {
+		s = &summary{}
+		p.pluginUpstreamLatency[pattern] = s
+	}
+	s.observe(duration.Seconds())
+}
+
+// RecordDedupLockWait records how long a DistributedSingleFlight.Do call
+// waited for another daemon's in-flight result, wired via
+// client.DistributedSingleFlight.OnLockWait.
+func (p *PrometheusMetrics) RecordDedupLockWait(waited time.Duration) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dedupLockWait.observe(waited.Seconds())
+}
+
+// RecordSchedulerJob records one run of a daemon.Scheduler job, wired via
+// daemon.Scheduler.OnJobComplete. entriesRemoved and bytesReclaimed are
+// whatever the job itself reported in its daemon.JobStats; a job that
+// doesn't track either (e.g. a circuit breaker snapshot) just reports zero.
+func (p *PrometheusMetrics) RecordSchedulerJob(name string, duration time.Duration, entriesRemoved, bytesReclaimed int64, err error) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hist, ok := p.schedulerJobDuration[name]
+	if !ok {
+		hist = newHistogram(p.buckets)
+		p.schedulerJobDuration[name] = hist
+	}
+	hist.observe(duration.Seconds())
+
+	p.schedulerJobEntriesRemoved[name] += entriesRemoved
+	p.schedulerJobBytesReclaimed[name] += bytesReclaimed
+	if err != nil {
+		p.schedulerJobFailures[name]++
+	}
+}
+
+// SetCacheStats wires up the cache whose Stats() should back the
+// apiproxyd_cache_entries and apiproxyd_cache_size_bytes gauges (and the
+// apiproxyd_cache_*_by_backend series once SetCacheBackend is set). It is
+// cheap to call on every scrape: it only stores c, and ServeHTTP reads
+// Stats() when it renders.
+func (p *PrometheusMetrics) SetCacheStats(c cache.Cache) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cacheStats = c
+}
+
+// SetAnalytics wires up the analytics.Analytics tracker whose GetSummary
+// should back the apiproxyd_request_latency_seconds,
+// apiproxyd_bytes_saved_total, and apiproxyd_cost_savings_usd series.
+func (p *PrometheusMetrics) SetAnalytics(a *analytics.Analytics) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.analyticsSrc = a
+}
+
+// SetWarmer wires up the cache.Warmer whose Stats/Runs/EndpointStats should
+// back the apiproxyd_warming_* series.
+func (p *PrometheusMetrics) SetWarmer(w *cache.Warmer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.warmer = w
+}
+
+// SetRateLimiter wires up the middleware.RateLimiter whose
+// Rejections/ActiveBuckets/CredentialErrors should back the
+// apiproxyd_rate_limit_* series. Since a hot reload can replace the active
+// rate limiter, callers should call this again after every rebuild.
+func (p *PrometheusMetrics) SetRateLimiter(rl *middleware.RateLimiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rateLimiter = rl
 }
 
-// ServeHTTP exports metrics in Prometheus format
+// SetAuditLogger wires up the audit.Logger whose SinkDropCounts should back
+// the apiproxyd_audit_sink_drops_total series.
+func (p *PrometheusMetrics) SetAuditLogger(l *audit.Logger) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.auditLogger = l
+}
+
+// SetCircuitClient wires up the client.Client whose per-host circuit
+// breakers should back the apiproxyd_circuit_state,
+// apiproxyd_circuit_failures_total, and apiproxyd_circuit_successes_total
+// series. Call RecordCircuitTransition from the same
+// client.Client.SetCircuitStateChangeHook callback the daemon already
+// installs for circuit_open events, to also back
+// apiproxyd_circuit_transitions_total.
+func (p *PrometheusMetrics) SetCircuitClient(c *client.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.circuitClient = c
+}
+
+// RecordCircuitTransition increments apiproxyd_circuit_transitions_total
+// for a name's from->to transition. name matches whatever key
+// GetCircuitBreakerStats() reports the breaker under.
+func (p *PrometheusMetrics) RecordCircuitTransition(name string, from, to client.CircuitState) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := formatLabels([]label{{"name", name}, {"from", from.String()}, {"to", to.String()}})
+	p.circuitTransitions[key]++
+}
+
+// SetCacheBackend records the configured cache.Backend name (e.g. "redis",
+// "sqlite") used to label the apiproxyd_cache_hits_by_backend_total,
+// apiproxyd_cache_misses_by_backend_total, and apiproxyd_cache_entries_by_backend
+// series.
+func (p *PrometheusMetrics) SetCacheBackend(backend string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cacheBackend = backend
+}
+
+// ServeHTTP exports metrics in Prometheus text exposition format.
 func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
 
-	fmt.Fprintf(w, "# HELP apiproxyd_requests_total Total number of requests\n")
-	fmt.Fprintf(w, "# TYPE apiproxyd_requests_total counter\n")
-	fmt.Fprintf(w, "apiproxyd_requests_total %d\n\n", p.requestsTotal)
+	writeCounterFamily(w, "apiproxyd_requests_total", "Total number of requests", p.requestsTotal)
 
-	fmt.Fprintf(w, "# HELP apiproxyd_requests_duration_seconds Total duration of all requests\n")
-	fmt.Fprintf(w, "# TYPE apiproxyd_requests_duration_seconds counter\n")
-	fmt.Fprintf(w, "apiproxyd_requests_duration_seconds %.2f\n\n", p.requestsDuration)
+	writeHistogramFamily(w, "apiproxyd_request_duration_seconds", "Duration of proxied requests", p.buckets, p.requestDurations)
 
 	fmt.Fprintf(w, "# HELP apiproxyd_cache_hits_total Total number of cache hits\n")
 	fmt.Fprintf(w, "# TYPE apiproxyd_cache_hits_total counter\n")
@@ -88,16 +435,374 @@ func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "# TYPE apiproxyd_errors_total counter\n")
 	fmt.Fprintf(w, "apiproxyd_errors_total %d\n\n", p.errorCount)
 
-	fmt.Fprintf(w, "# HELP apiproxyd_requests_by_method Requests by HTTP method\n")
-	fmt.Fprintf(w, "# TYPE apiproxyd_requests_by_method counter\n")
-	for method, count := range p.requestsByMethod {
-		fmt.Fprintf(w, "apiproxyd_requests_by_method{method=\"%s\"} %d\n", method, count)
+	writeSummaryFamily(w, "apiproxyd_plugin_upstream_call_duration_seconds", "Duration of plugin-initiated upstream calls, labeled by route pattern", p.pluginUpstreamLatency)
+
+	writeHistogramFamily(w, "apiproxyd_dedup_lock_wait_seconds", "Time spent waiting for another daemon's in-flight distributed singleflight result", p.buckets, map[string]*histogram{"": p.dedupLockWait})
+
+	writeSchedulerMetrics(w, p.buckets, p.schedulerJobDuration, p.schedulerJobEntriesRemoved, p.schedulerJobBytesReclaimed, p.schedulerJobFailures)
+
+	writeCacheGauges(w, p.cacheStats)
+	writeCacheBackendMetrics(w, p.cacheStats, p.cacheBackend)
+
+	writeAnalyticsMetrics(w, p.analyticsSrc)
+	writeWarmingMetrics(w, p.warmer)
+	writeRateLimiterMetrics(w, p.rateLimiter)
+	writeAuditMetrics(w, p.auditLogger)
+	writeCircuitMetrics(w, p.circuitClient, p.circuitTransitions)
+
+	writeCounterFamily(w, "apiproxyd_upstream_requests_total", "Total upstream request attempts made through client.Client, by method/endpoint/code", p.upstreamRequests)
+	writeHistogramFamily(w, "apiproxyd_upstream_request_duration_seconds", "Duration of upstream request attempts made through client.Client, by method/endpoint/code", p.buckets, p.upstreamDuration)
+}
+
+// writeCircuitMetrics exports the apiproxyd_circuit_state,
+// apiproxyd_circuit_failures_total, apiproxyd_circuit_successes_total, and
+// apiproxyd_circuit_transitions_total series. c may be nil if none was
+// wired up via SetCircuitClient (or the client has circuit breakers
+// disabled), in which case only the zero-valued transitions map, if any,
+// is emitted.
+func writeCircuitMetrics(w io.Writer, c *client.Client, transitions map[string]int64) {
+	hosts := map[string]map[string]interface{}{}
+	if c != nil {
+		if stats, ok := c.GetCircuitBreakerStats()["hosts"].(map[string]map[string]interface{}); ok {
+			hosts = stats
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP apiproxyd_circuit_state Current circuit breaker state per name (0=closed, 1=half-open, 2=open)\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_circuit_state gauge\n")
+	for _, name := range sortedKeys(hosts) {
+		fmt.Fprintf(w, "apiproxyd_circuit_state%s %d\n", formatLabels([]label{{"name", name}}), circuitStateValue(hosts[name]["state"]))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_circuit_failures_total Total failed calls recorded by a circuit breaker, by name\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_circuit_failures_total counter\n")
+	for _, name := range sortedKeys(hosts) {
+		fmt.Fprintf(w, "apiproxyd_circuit_failures_total%s %v\n", formatLabels([]label{{"name", name}}), hosts[name]["total_failures"])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_circuit_successes_total Total successful calls recorded by a circuit breaker, by name\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_circuit_successes_total counter\n")
+	for _, name := range sortedKeys(hosts) {
+		fmt.Fprintf(w, "apiproxyd_circuit_successes_total%s %v\n", formatLabels([]label{{"name", name}}), hosts[name]["total_successes"])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_circuit_transitions_total Total state transitions, by name/from/to\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_circuit_transitions_total counter\n")
+	for _, key := range sortedKeys(transitions) {
+		fmt.Fprintf(w, "apiproxyd_circuit_transitions_total%s %d\n", key, transitions[key])
+	}
+	fmt.Fprintln(w)
+}
+
+// circuitStateValue maps a CircuitBreaker.Stats() "state" string to the
+// numeric value apiproxyd_circuit_state exposes. This differs from
+// client.CircuitState's own iota ordering (closed=0, open=1, half-open=2
+// internally, renumbered here to closed=0/half-open=1/open=2 so severity
+// increases monotonically with the gauge value).
+func circuitStateValue(state interface{}) int {
+	switch state {
+	case "half-open":
+		return 1
+	case "open":
+		return 2
+	default:
+		return 0
+	}
+}
+
+// writeCacheBackendMetrics exports apiproxyd_cache_hits_by_backend_total,
+// apiproxyd_cache_misses_by_backend_total, and
+// apiproxyd_cache_entries_by_backend, each labeled by backend, sourced from
+// a cache.Cache's Stats(). Named apart from the unlabeled
+// apiproxyd_cache_hits_total/misses_total series above (which come from
+// RecordRequest's own bookkeeping, not the backend's Stats()) and the
+// unlabeled apiproxyd_cache_entries gauge: a deployment only ever runs one backend, but dashboards that
+// aggregate across deployments want the backend in the label. backend is
+// "" if no cache.Config backend name was wired up via SetCacheBackend, in
+// which case these series are omitted.
+func writeCacheBackendMetrics(w io.Writer, c cache.Cache, backend string) {
+	if backend == "" {
+		return
+	}
+
+	var hits, misses, entries int64
+	if c != nil {
+		if stats, err := c.Stats(); err == nil && stats != nil {
+			hits, misses, entries = stats.Hits, stats.Misses, stats.Entries
+		}
+	}
+
+	labels := formatLabels([]label{{"backend", backend}})
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_hits_by_backend_total Total cache hits reported by the active backend's own Stats(), labeled by backend\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_hits_by_backend_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_cache_hits_by_backend_total%s %d\n\n", labels, hits)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_misses_by_backend_total Total cache misses reported by the active backend's own Stats(), labeled by backend\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_misses_by_backend_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_cache_misses_by_backend_total%s %d\n\n", labels, misses)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_entries_by_backend Number of entries currently in the cache, labeled by backend\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_entries_by_backend gauge\n")
+	fmt.Fprintf(w, "apiproxyd_cache_entries_by_backend%s %d\n", labels, entries)
+}
+
+// writeAuditMetrics exports the apiproxyd_audit_sink_drops_total series,
+// labeled by sink name, sourced from an audit.Logger. l may be nil if none
+// was wired up via SetAuditLogger, in which case nothing is emitted.
+func writeAuditMetrics(w io.Writer, l *audit.Logger) {
+	var drops map[string]int64
+	if l != nil {
+		drops = l.SinkDropCounts()
+	}
+
+	fmt.Fprintf(w, "# HELP apiproxyd_audit_sink_drops_total Total audit events dropped by a sink, by sink name, either from a full queue or exhausted delivery retries\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_audit_sink_drops_total counter\n")
+	for _, name := range sortedKeys(drops) {
+		fmt.Fprintf(w, "apiproxyd_audit_sink_drops_total%s %d\n", formatLabels([]label{{"sink", name}}), drops[name])
+	}
+	fmt.Fprintln(w)
+}
+
+// writeAnalyticsMetrics exports the apiproxyd_bytes_saved_total,
+// apiproxyd_cost_savings_usd, and apiproxyd_request_latency_seconds series
+// sourced from the legacy analytics.Analytics tracker, which estimates
+// cache-serving savings independently of PrometheusMetrics' own
+// RecordRequest-based counters above. a may be nil if no tracker was wired
+// up via SetAnalytics, in which case zero values are reported.
+func writeAnalyticsMetrics(w io.Writer, a *analytics.Analytics) {
+	var savedBytes int64
+	var costSavings, latencySum float64
+	var requestCount int64
+	if a != nil {
+		summary := a.GetSummary(0)
+		savedBytes = summary.SavedBytes
+		costSavings = summary.CostSavings
+		requestCount = summary.TotalRequests
+		latencySum = a.TotalLatencySeconds()
+	}
+
+	fmt.Fprintf(w, "# HELP apiproxyd_bytes_saved_total Total bytes served from cache instead of upstream, as estimated by analytics.Analytics\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_bytes_saved_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_bytes_saved_total %d\n\n", savedBytes)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cost_savings_usd Estimated USD cost savings from serving cached responses, as tracked by analytics.Analytics\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cost_savings_usd gauge\n")
+	fmt.Fprintf(w, "apiproxyd_cost_savings_usd %.6f\n\n", costSavings)
+
+	// Named apart from apiproxyd_request_duration_seconds above: that one is
+	// labeled per method/status/route/plugin/cached combination, this one is
+	// the analytics tracker's single aggregate sum/count.
+	fmt.Fprintf(w, "# HELP apiproxyd_request_latency_seconds Aggregate request latency as tracked by analytics.Analytics\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_request_latency_seconds summary\n")
+	fmt.Fprintf(w, "apiproxyd_request_latency_seconds_sum %.6f\n", latencySum)
+	fmt.Fprintf(w, "apiproxyd_request_latency_seconds_count %d\n\n", requestCount)
+}
+
+// writeWarmingMetrics exports the apiproxyd_warming_* series sourced from a
+// cache.Warmer. warmer may be nil if none was wired up via SetWarmer, in
+// which case zero values are reported.
+func writeWarmingMetrics(w io.Writer, warmer *cache.Warmer) {
+	var runs int64
+	var stats cache.WarmingStats
+	var endpoints []cache.EndpointWarmingStats
+	if warmer != nil {
+		runs = warmer.Runs()
+		stats = warmer.Stats()
+		endpoints = warmer.EndpointStats()
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+
+	fmt.Fprintf(w, "# HELP apiproxyd_warming_runs_total Total number of cache warming runs started\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_warming_runs_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_warming_runs_total %d\n\n", runs)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_warming_duration_seconds Duration of the most recently completed (or currently running) cache warming run\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_warming_duration_seconds gauge\n")
+	fmt.Fprintf(w, "apiproxyd_warming_duration_seconds %.6f\n\n", stats.Duration.Seconds())
+
+	fmt.Fprintf(w, "# HELP apiproxyd_warming_in_progress Whether a cache warming run is currently executing\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_warming_in_progress gauge\n")
+	fmt.Fprintf(w, "apiproxyd_warming_in_progress %s\n\n", boolToGaugeValue(stats.InProgress))
+
+	fmt.Fprintf(w, "# HELP apiproxyd_warming_endpoint_success_total Total successful warming attempts, per endpoint\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_warming_endpoint_success_total counter\n")
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "apiproxyd_warming_endpoint_success_total%s %d\n", endpointLabels(e), e.SuccessCount)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_warming_endpoint_failure_total Total failed warming attempts (after retries), per endpoint\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_warming_endpoint_failure_total counter\n")
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "apiproxyd_warming_endpoint_failure_total%s %d\n", endpointLabels(e), e.FailureCount)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_warming_endpoint_transfer_rate_bytes_per_second Throughput of the most recent successful warming attempt, per endpoint\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_warming_endpoint_transfer_rate_bytes_per_second gauge\n")
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "apiproxyd_warming_endpoint_transfer_rate_bytes_per_second%s %.2f\n", endpointLabels(e), e.TransferRate())
+	}
+	fmt.Fprintln(w)
+}
+
+func endpointLabels(e cache.EndpointWarmingStats) string {
+	return formatLabels([]label{{"path", e.Path}, {"method", e.Method}})
+}
+
+// writeRateLimiterMetrics exports the apiproxyd_rate_limit_* series sourced
+// from a middleware.RateLimiter. rl may be nil if none was wired up via
+// SetRateLimiter (or rate limiting is disabled), in which case zero values
+// are reported.
+func writeRateLimiterMetrics(w io.Writer, rl *middleware.RateLimiter) {
+	var rejections map[string]int64
+	var activeBuckets map[string]int
+	var credentialErrors int64
+	if rl != nil {
+		rejections = rl.Rejections()
+		activeBuckets = rl.ActiveBuckets()
+		credentialErrors = rl.CredentialErrors()
+	}
+
+	fmt.Fprintf(w, "# HELP apiproxyd_rate_limit_rejections_total Total requests rejected by the rate limiter, by scope\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_rate_limit_rejections_total counter\n")
+	for _, scope := range []string{"ip", "key", "cn"} {
+		fmt.Fprintf(w, "apiproxyd_rate_limit_rejections_total%s %d\n", formatLabels([]label{{"scope", scope}}), rejections[scope])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_rate_limit_active_buckets Number of distinct identities currently tracked by the rate limiter, by scope\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_rate_limit_active_buckets gauge\n")
+	for _, scope := range []string{"ip", "key", "cn"} {
+		fmt.Fprintf(w, "apiproxyd_rate_limit_active_buckets%s %d\n", formatLabels([]label{{"scope", scope}}), activeBuckets[scope])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_rate_limit_credential_errors_total Total requests that hit the per-CN rate limit dimension with no verified client certificate, including counts carried over across reloads\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_rate_limit_credential_errors_total counter\n")
+	fmt.Fprintf(w, "apiproxyd_rate_limit_credential_errors_total %d\n\n", credentialErrors)
+}
+
+// writeSchedulerMetrics exports the apiproxyd_scheduler_job_* series, each
+// labeled by job name, sourced from PrometheusMetrics.RecordSchedulerJob.
+func writeSchedulerMetrics(w io.Writer, buckets []float64, duration map[string]*histogram, entriesRemoved, bytesReclaimed, failures map[string]int64) {
+	labeled := make(map[string]*histogram, len(duration))
+	for name, hist := range duration {
+		labeled[formatLabels([]label{{"job", name}})] = hist
+	}
+	writeHistogramFamily(w, "apiproxyd_scheduler_job_duration_seconds", "Duration of daemon.Scheduler job runs, by job name", buckets, labeled)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_scheduler_job_entries_removed_total Total entries removed, summed across runs, by job name\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_scheduler_job_entries_removed_total counter\n")
+	for _, name := range sortedKeys(entriesRemoved) {
+		fmt.Fprintf(w, "apiproxyd_scheduler_job_entries_removed_total%s %d\n", formatLabels([]label{{"job", name}}), entriesRemoved[name])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_scheduler_job_bytes_reclaimed_total Total bytes reclaimed, summed across runs, by job name\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_scheduler_job_bytes_reclaimed_total counter\n")
+	for _, name := range sortedKeys(bytesReclaimed) {
+		fmt.Fprintf(w, "apiproxyd_scheduler_job_bytes_reclaimed_total%s %d\n", formatLabels([]label{{"job", name}}), bytesReclaimed[name])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_scheduler_job_failures_total Total failed runs, by job name\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_scheduler_job_failures_total counter\n")
+	for _, name := range sortedKeys(failures) {
+		fmt.Fprintf(w, "apiproxyd_scheduler_job_failures_total%s %d\n", formatLabels([]label{{"job", name}}), failures[name])
+	}
+	fmt.Fprintln(w)
+}
+
+func boolToGaugeValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func writeCounterFamily(w io.Writer, name, help string, series map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, key := range sortedKeys(series) {
+		fmt.Fprintf(w, "%s%s %d\n", name, key, series[key])
+	}
+	fmt.Fprintln(w)
+}
+
+func writeHistogramFamily(w io.Writer, name, help string, buckets []float64, series map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, key := range sortedKeys(series) {
+		hist := series[key]
+		base := strings.TrimSuffix(key, "}")
+		prefix := base
+		if prefix != "" {
+			prefix += ","
+		} else {
+			prefix = "{"
+		}
+		for i, le := range buckets {
+			fmt.Fprintf(w, "%s_bucket%sle=\"%s\"} %d\n", name, prefix, formatBucketBound(le), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%sle=\"+Inf\"} %d\n", name, prefix, hist.count)
+		fmt.Fprintf(w, "%s_sum%s %.6f\n", name, key, hist.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", name, key, hist.count)
 	}
 	fmt.Fprintln(w)
+}
+
+func writeSummaryFamily(w io.Writer, name, help string, series map[string]*summary) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s summary\n", name)
+	for _, pattern := range sortedKeys(series) {
+		s := series[pattern]
+		labels := formatLabels([]label{{"route", pattern}})
+		fmt.Fprintf(w, "%s_sum%s %.6f\n", name, labels, s.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", name, labels, s.count)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeCacheGauges(w io.Writer, c cache.Cache) {
+	var entries, sizeBytes int64
+	if c != nil {
+		if stats, err := c.Stats(); err == nil && stats != nil {
+			entries = stats.Entries
+			sizeBytes = stats.SizeBytes
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_entries Number of entries currently in the cache\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_entries gauge\n")
+	fmt.Fprintf(w, "apiproxyd_cache_entries %d\n\n", entries)
+
+	fmt.Fprintf(w, "# HELP apiproxyd_cache_size_bytes Total size in bytes of entries currently in the cache\n")
+	fmt.Fprintf(w, "# TYPE apiproxyd_cache_size_bytes gauge\n")
+	fmt.Fprintf(w, "apiproxyd_cache_size_bytes %d\n", sizeBytes)
+}
+
+// formatBucketBound renders a bucket boundary the way Prometheus client
+// libraries do (trailing zeros trimmed, but always a valid float literal).
+func formatBucketBound(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
 
-	fmt.Fprintf(w, "# HELP apiproxyd_requests_by_status Requests by status code\n")
-	fmt.Fprintf(w, "# TYPE apiproxyd_requests_by_status counter\n")
-	for status, count := range p.requestsByStatus {
-		fmt.Fprintf(w, "apiproxyd_requests_by_status{status=\"%d\"} %d\n", status, count)
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
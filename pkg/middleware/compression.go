@@ -1,13 +1,48 @@
 package middleware
 
 import (
+	"bytes"
 	"compress/gzip"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoding identifies a supported Content-Encoding.
+type encoding string
+
+const (
+	encodingBrotli encoding = "br"
+	encodingZstd   encoding = "zstd"
+	encodingGzip   encoding = "gzip"
 )
 
+// defaultPreference is used when CompressionOptions.Preference is empty:
+// brotli compresses best, zstd is a fast middle ground, gzip is the
+// universal fallback.
+var defaultPreference = []encoding{encodingBrotli, encodingZstd, encodingGzip}
+
+// defaultSkipContentTypes lists MIME type prefixes that are already
+// compressed (or otherwise not worth recompressing); CompressionMiddleware
+// passes these through untouched by default.
+var defaultSkipContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"font/",
+}
+
 // gzipWriterPool is a pool of gzip writers to reduce GC pressure
 // Gzip compression is CPU-intensive, and creating new writers allocates memory.
 // Pooling allows reuse of allocated buffers, significantly improving performance.
@@ -19,80 +54,327 @@ var gzipWriterPool = sync.Pool{
 	},
 }
 
-// getGzipWriter gets a gzip writer from the pool
 func getGzipWriter(w io.Writer) *gzip.Writer {
 	gz := gzipWriterPool.Get().(*gzip.Writer)
 	gz.Reset(w)
 	return gz
 }
 
-// putGzipWriter returns a gzip writer to the pool
 func putGzipWriter(gz *gzip.Writer) {
 	gz.Close()
 	gzipWriterPool.Put(gz)
 }
 
-// gzipResponseWriter wraps http.ResponseWriter to support gzip compression
-type gzipResponseWriter struct {
-	io.Writer
+// brotliWriterPool mirrors gzipWriterPool for brotli.
+var brotliWriterPool = sync.Pool{
+	New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	},
+}
+
+func getBrotliWriter(w io.Writer) *brotli.Writer {
+	bw := brotliWriterPool.Get().(*brotli.Writer)
+	bw.Reset(w)
+	return bw
+}
+
+func putBrotliWriter(bw *brotli.Writer) {
+	bw.Close()
+	brotliWriterPool.Put(bw)
+}
+
+// zstdWriterPool mirrors gzipWriterPool for zstd.
+var zstdWriterPool = sync.Pool{
+	New: func() interface{} {
+		zw, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return zw
+	},
+}
+
+func getZstdWriter(w io.Writer) *zstd.Encoder {
+	zw := zstdWriterPool.Get().(*zstd.Encoder)
+	zw.Reset(w)
+	return zw
+}
+
+func putZstdWriter(zw *zstd.Encoder) {
+	zw.Close()
+	zstdWriterPool.Put(zw)
+}
+
+// CompressionOptions configures CompressionMiddleware.
+type CompressionOptions struct {
+	// MinSize is the minimum response size, in bytes, worth compressing.
+	// Responses smaller than this are written uncompressed to avoid
+	// overhead that outweighs the savings. Defaults to 1024.
+	MinSize int
+	// Preference orders which encoding wins when a client's Accept-Encoding
+	// allows more than one. Defaults to defaultPreference (br, zstd, gzip).
+	Preference []encoding
+	// SkipContentTypes lists Content-Type prefixes to never compress, e.g.
+	// already-compressed media. Defaults to defaultSkipContentTypes.
+	SkipContentTypes []string
+}
+
+func (o *CompressionOptions) minSize() int {
+	if o.MinSize <= 0 {
+		return 1024
+	}
+	return o.MinSize
+}
+
+func (o *CompressionOptions) preference() []encoding {
+	if len(o.Preference) == 0 {
+		return defaultPreference
+	}
+	return o.Preference
+}
+
+func (o *CompressionOptions) skipContentTypes() []string {
+	if len(o.SkipContentTypes) == 0 {
+		return defaultSkipContentTypes
+	}
+	return o.SkipContentTypes
+}
+
+// acceptedEncoding is one comma-separated term of an Accept-Encoding
+// header, e.g. "br;q=0.8".
+type acceptedEncoding struct {
+	name string
+	q    float64
+}
+
+// parseAcceptEncoding parses an Accept-Encoding header into its terms,
+// honoring q-values (a q of 0 means "not acceptable"). Terms without an
+// explicit q default to 1.0, per RFC 7231 section 5.3.1.
+func parseAcceptEncoding(header string) []acceptedEncoding {
+	var accepted []acceptedEncoding
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name := part
+		q := 1.0
+		if idx := strings.Index(part, ";"); idx != -1 {
+			name = strings.TrimSpace(part[:idx])
+			for _, param := range strings.Split(part[idx+1:], ";") {
+				param = strings.TrimSpace(param)
+				if v, ok := strings.CutPrefix(param, "q="); ok {
+					if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+		}
+
+		accepted = append(accepted, acceptedEncoding{name: strings.ToLower(name), q: q})
+	}
+
+	return accepted
+}
+
+// negotiateEncoding picks the best encoding in preference order that the
+// client's Accept-Encoding header allows (q > 0), returning "" if none of
+// the preferred encodings are acceptable.
+func negotiateEncoding(acceptEncodingHeader string, preference []encoding) encoding {
+	accepted := parseAcceptEncoding(acceptEncodingHeader)
+	if len(accepted) == 0 {
+		return ""
+	}
+
+	allowed := make(map[string]float64, len(accepted))
+	wildcardQ := -1.0
+	for _, a := range accepted {
+		allowed[a.name] = a.q
+		if a.name == "*" {
+			wildcardQ = a.q
+		}
+	}
+
+	for _, enc := range preference {
+		if q, ok := allowed[string(enc)]; ok {
+			if q > 0 {
+				return enc
+			}
+			continue
+		}
+		if wildcardQ > 0 {
+			return enc
+		}
+	}
+
+	return ""
+}
+
+// hasContentTypePrefix reports whether contentType (as set on the
+// response, which may carry a "; charset=..." suffix) starts with any
+// prefix in skip.
+func hasContentTypePrefix(contentType string, skip []string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range skip {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressionResponseWriter buffers the start of a response until it can
+// decide whether to compress: either MinSize bytes have been seen, or the
+// handler finished writing (via Close) with less than that. This lets
+// MinSize apply to the real response size instead of guessing from the
+// first Write call alone.
+type compressionResponseWriter struct {
 	http.ResponseWriter
+	req  *http.Request
+	opts *CompressionOptions
+
+	status      int
 	wroteHeader bool
+
+	buf      bytes.Buffer
+	decided  bool
+	passthru bool
+
+	target io.Writer
+	closer func() error
 }
 
-func (w *gzipResponseWriter) WriteHeader(status int) {
+func (w *compressionResponseWriter) WriteHeader(status int) {
+	w.status = status
 	w.wroteHeader = true
-	w.ResponseWriter.WriteHeader(status)
 }
 
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+func (w *compressionResponseWriter) Write(b []byte) (int, error) {
 	if !w.wroteHeader {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.Writer.Write(b)
+
+	if w.decided {
+		return w.target.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() >= w.opts.minSize() {
+		w.decide()
+		return len(b), nil
+	}
+
+	return len(b), nil
 }
 
-// GzipMiddleware provides gzip compression for responses
-// It only compresses responses larger than 1KB to avoid overhead for small responses
-func GzipMiddleware(minSize int) func(http.Handler) http.Handler {
-	if minSize <= 0 {
-		minSize = 1024 // default 1KB minimum
+// decide chooses whether and how to compress, flushes the buffered
+// prefix through whatever writer it picked, and switches Write into
+// passthrough mode for subsequent calls.
+func (w *compressionResponseWriter) decide() {
+	w.decided = true
+
+	contentType := w.ResponseWriter.Header().Get("Content-Type")
+	alreadyEncoded := w.ResponseWriter.Header().Get("Content-Encoding") != ""
+	skip := alreadyEncoded || hasContentTypePrefix(contentType, w.opts.skipContentTypes())
+
+	var enc encoding
+	if !skip {
+		enc = negotiateEncoding(w.req.Header.Get("Accept-Encoding"), w.opts.preference())
+	}
+
+	if enc == "" {
+		w.passthru = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.target = w.ResponseWriter
+		w.buf.WriteTo(w.target)
+		return
+	}
+
+	w.ResponseWriter.Header().Set("Content-Encoding", string(enc))
+	w.ResponseWriter.Header().Set("Vary", "Accept-Encoding")
+	w.ResponseWriter.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	switch enc {
+	case encodingBrotli:
+		bw := getBrotliWriter(w.ResponseWriter)
+		w.target = bw
+		w.closer = func() error { putBrotliWriter(bw); return nil }
+	case encodingZstd:
+		zw := getZstdWriter(w.ResponseWriter)
+		w.target = zw
+		w.closer = func() error { putZstdWriter(zw); return nil }
+	case encodingGzip:
+		gz := getGzipWriter(w.ResponseWriter)
+		w.target = gz
+		w.closer = func() error { putGzipWriter(gz); return nil }
+	}
+
+	w.buf.WriteTo(w.target)
+}
+
+// finish is called once the wrapped handler returns. If the response never
+// reached MinSize, it's flushed uncompressed here; if it did, the
+// compressor (if any) is closed to flush its trailer/footer.
+func (w *compressionResponseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.passthru = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.target = w.ResponseWriter
+		w.buf.WriteTo(w.target)
+		w.decided = true
+	}
+	if w.closer != nil {
+		w.closer()
+	}
+}
+
+// CompressionMiddleware negotiates Content-Encoding with the client,
+// preferring brotli, then zstd, then gzip (configurable via
+// opts.Preference), and compresses responses larger than opts.MinSize
+// that aren't already compressed.
+func CompressionMiddleware(opts *CompressionOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &CompressionOptions{}
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Check if client accepts gzip
-			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			// Don't compress if already compressed
-			if w.Header().Get("Content-Encoding") != "" {
+			if parseAcceptEncoding(r.Header.Get("Accept-Encoding")) == nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Get gzip writer from pool
-			gz := getGzipWriter(w)
-			defer putGzipWriter(gz)
-
-			// Set headers
-			w.Header().Set("Content-Encoding", "gzip")
-			w.Header().Set("Vary", "Accept-Encoding")
-			w.Header().Del("Content-Length") // Length will change after compression
-
-			// Wrap response writer
-			gzw := &gzipResponseWriter{
-				Writer:         gz,
+			cw := &compressionResponseWriter{
 				ResponseWriter: w,
+				req:            r,
+				opts:           opts,
 			}
+			defer cw.finish()
 
-			next.ServeHTTP(gzw, r)
+			next.ServeHTTP(cw, r)
 		})
 	}
 }
 
+// GzipMiddleware provides gzip compression for responses
+// It only compresses responses larger than minSize bytes to avoid overhead for small responses
+//
+// Deprecated: use CompressionMiddleware, which also negotiates brotli and
+// zstd. GzipMiddleware is kept as a thin wrapper that pins the preference
+// to gzip only.
+func GzipMiddleware(minSize int) func(http.Handler) http.Handler {
+	return CompressionMiddleware(&CompressionOptions{
+		MinSize:    minSize,
+		Preference: []encoding{encodingGzip},
+	})
+}
+
 // GzipHandler wraps a handler with gzip compression
+//
+// Deprecated: use CompressionMiddleware.
 func GzipHandler(h http.Handler) http.Handler {
 	return GzipMiddleware(1024)(h)
 }
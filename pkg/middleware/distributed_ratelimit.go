@@ -0,0 +1,560 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm selects which strategy owns an identifier's bucket.
+type RateLimitAlgorithm string
+
+const (
+	TokenBucketAlgorithm RateLimitAlgorithm = "token_bucket"
+	LeakyBucketAlgorithm RateLimitAlgorithm = "leaky_bucket"
+)
+
+// RateLimitRequest asks whichever node owns identifier to spend hits
+// against its bucket under algorithm.
+type RateLimitRequest struct {
+	Identifier string             `json:"identifier"`
+	Algorithm  RateLimitAlgorithm `json:"algorithm"`
+	Limit      int                `json:"limit"`
+	Duration   time.Duration      `json:"duration"`
+	Hits       int                `json:"hits"`
+}
+
+// RateLimitDecision is the owner's answer for one RateLimitRequest.
+type RateLimitDecision struct {
+	Remaining int       `json:"remaining"`
+	Limit     int       `json:"limit"`
+	ResetTime time.Time `json:"reset_time"`
+	OverLimit bool      `json:"over_limit"`
+}
+
+// Peer is one other apiproxyd instance in the cluster. GetRateLimit is the
+// forwarding path a non-owner node uses to ask the owner to decide on a
+// batch of identifiers in one round trip; HTTPPeer is the transport this
+// package ships (plain HTTP rather than gRPC, the same call the RPC
+// plugin transport made: a protobuf/gRPC dependency isn't justified by
+// anything a JSON-over-HTTP request/response can't already do here).
+type Peer interface {
+	// ID uniquely identifies this peer's position on the hash ring.
+	ID() string
+	// GetRateLimit asks this peer, as owner, to decide on every request in
+	// reqs and return the matching decisions in the same order.
+	GetRateLimit(reqs []RateLimitRequest) ([]RateLimitDecision, error)
+	// Gossip pushes a snapshot of decisions this peer owns so receivers can
+	// answer read-heavy lookups locally instead of forwarding every one.
+	// Only called when DistributedRateLimiter.EnableGossip is on.
+	Gossip(snapshot map[string]RateLimitDecision) error
+}
+
+// strategy computes a single identifier's rate-limit decision. It is only
+// ever invoked on the node that owns that identifier.
+type strategy interface {
+	decide(identifier string, limit int, duration time.Duration, hits int) RateLimitDecision
+}
+
+// tokenBucketStrategy is the distributed-owner equivalent of the local
+// tokenBucket: each identifier refills continuously up to limit and a
+// request is allowed if at least hits tokens are available.
+type tokenBucketStrategy struct {
+	mu      sync.Mutex
+	buckets map[string]*ownedBucket
+}
+
+type ownedBucket struct {
+	tokens     float64
+	updatedAt  time.Time
+	limit      int
+	refillRate float64 // tokens per second
+}
+
+func newTokenBucketStrategy() *tokenBucketStrategy {
+	return &tokenBucketStrategy{buckets: make(map[string]*ownedBucket)}
+}
+
+func (s *tokenBucketStrategy) decide(identifier string, limit int, duration time.Duration, hits int) RateLimitDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[identifier]
+	if !ok {
+		b = &ownedBucket{tokens: float64(limit), updatedAt: now}
+		s.buckets[identifier] = b
+	}
+
+	b.refillRate = float64(limit) / duration.Seconds()
+	b.limit = limit
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens += elapsed * b.refillRate
+	if b.tokens > float64(limit) {
+		b.tokens = float64(limit)
+	}
+	b.updatedAt = now
+
+	over := b.tokens < float64(hits)
+	if !over {
+		b.tokens -= float64(hits)
+	}
+
+	return RateLimitDecision{
+		Remaining: int(b.tokens),
+		Limit:     limit,
+		ResetTime: now.Add(duration),
+		OverLimit: over,
+	}
+}
+
+// leakyBucketStrategy tracks {remaining, limit, updated_at, duration} per
+// identifier. On each request it leaks tokens back in proportion to
+// elapsed time (elapsed_ms * limit / duration_ms), refills up to limit,
+// and only then spends hits.
+type leakyBucketStrategy struct {
+	mu     sync.Mutex
+	states map[string]*leakyState
+}
+
+type leakyState struct {
+	remaining int
+	limit     int
+	updatedAt time.Time
+	duration  time.Duration
+}
+
+func newLeakyBucketStrategy() *leakyBucketStrategy {
+	return &leakyBucketStrategy{states: make(map[string]*leakyState)}
+}
+
+func (s *leakyBucketStrategy) decide(identifier string, limit int, duration time.Duration, hits int) RateLimitDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	st, ok := s.states[identifier]
+	if !ok {
+		st = &leakyState{remaining: limit, limit: limit, updatedAt: now, duration: duration}
+		s.states[identifier] = st
+	}
+
+	elapsedMs := now.Sub(st.updatedAt).Milliseconds()
+	durationMs := st.duration.Milliseconds()
+	if durationMs <= 0 {
+		durationMs = 1
+	}
+	leaked := int(elapsedMs * int64(st.limit) / durationMs)
+	st.remaining += leaked
+	if st.remaining > limit {
+		st.remaining = limit
+	}
+	st.updatedAt = now
+	st.limit = limit
+	st.duration = duration
+
+	over := st.remaining < hits
+	if !over {
+		st.remaining -= hits
+	}
+
+	return RateLimitDecision{
+		Remaining: st.remaining,
+		Limit:     limit,
+		ResetTime: now.Add(duration),
+		OverLimit: over,
+	}
+}
+
+// ring is a consistent-hashing ring mapping a rate-limit identifier to the
+// peer responsible for owning its bucket, so adding or removing a node
+// only reshuffles roughly 1/N of the keyspace instead of all of it.
+type ring struct {
+	mu       sync.RWMutex
+	replicas int
+	points   []uint32
+	owners   map[uint32]string
+	peers    map[string]Peer
+}
+
+func newRing(replicas int) *ring {
+	if replicas <= 0 {
+		replicas = 100
+	}
+	return &ring{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		peers:    make(map[string]Peer),
+	}
+}
+
+// set replaces the ring's membership with peers.
+func (r *ring) set(peers ...Peer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.points = r.points[:0]
+	r.owners = make(map[uint32]string)
+	r.peers = make(map[string]Peer, len(peers))
+
+	for _, p := range peers {
+		r.peers[p.ID()] = p
+		for i := 0; i < r.replicas; i++ {
+			h := ringHash(fmt.Sprintf("%s#%d", p.ID(), i))
+			r.points = append(r.points, h)
+			r.owners[h] = p.ID()
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+// owner returns the peer responsible for key, or false if the ring has no
+// members.
+func (r *ring) owner(key string) (Peer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 {
+		return nil, false
+	}
+
+	h := ringHash(key)
+	idx := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if idx == len(r.points) {
+		idx = 0
+	}
+	return r.peers[r.owners[r.points[idx]]], true
+}
+
+func ringHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// pendingRequest is a caller blocked on a batched owner round trip.
+type pendingRequest struct {
+	req  RateLimitRequest
+	done chan RateLimitDecision
+}
+
+// DistributedRateLimiter shares one logical bucket per (identifier,
+// algorithm) pair across a cluster of apiproxyd instances rather than
+// letting each replica enforce its own independent limit. Decide routes
+// each lookup to the owning peer (per the consistent-hashing ring) and
+// coalesces concurrent lookups for the same owner into a single batched
+// GetRateLimit call. If the owner can't be reached, it falls back to
+// enforcing the limit against this node's own local strategy instead of
+// failing the request open or closed.
+type DistributedRateLimiter struct {
+	self  Peer
+	ring  *ring
+	local map[RateLimitAlgorithm]strategy
+
+	batchWindow time.Duration
+	batchMu     sync.Mutex
+	batches     map[string][]*pendingRequest // peer ID -> pending batch
+
+	// EnableGossip, when true, makes StartGossip periodically push this
+	// node's owned decisions to every peer so they can answer hot keys
+	// without a round trip. Received snapshots are merged into gossipCache
+	// and consulted before falling back to a forwarded lookup.
+	EnableGossip bool
+	gossipMu     sync.RWMutex
+	gossipCache  map[string]RateLimitDecision
+	gossipStop   chan struct{}
+}
+
+// NewDistributedRateLimiter creates a DistributedRateLimiter whose own
+// identity on the ring is self, coordinating with the given initial peer
+// set (which may be updated later via SetPeers).
+func NewDistributedRateLimiter(self Peer, peers ...Peer) *DistributedRateLimiter {
+	d := &DistributedRateLimiter{
+		self: self,
+		ring: newRing(100),
+		local: map[RateLimitAlgorithm]strategy{
+			TokenBucketAlgorithm: newTokenBucketStrategy(),
+			LeakyBucketAlgorithm: newLeakyBucketStrategy(),
+		},
+		batchWindow: 5 * time.Millisecond,
+		batches:     make(map[string][]*pendingRequest),
+		gossipCache: make(map[string]RateLimitDecision),
+	}
+	d.SetPeers(peers...)
+	return d
+}
+
+// SetPeers replaces the ring's membership (self plus the given peers),
+// e.g. after a cluster membership change.
+func (d *DistributedRateLimiter) SetPeers(peers ...Peer) {
+	d.ring.set(append([]Peer{d.self}, peers...)...)
+}
+
+// Decide asks whichever node owns identifier to spend hits against its
+// algorithm bucket and returns the resulting decision.
+func (d *DistributedRateLimiter) Decide(req RateLimitRequest) RateLimitDecision {
+	if d.EnableGossip {
+		d.gossipMu.RLock()
+		cached, ok := d.gossipCache[gossipKey(req.Identifier, req.Algorithm)]
+		d.gossipMu.RUnlock()
+		if ok && !cached.OverLimit && cached.Remaining > req.Hits && time.Now().Before(cached.ResetTime) {
+			// A fresh, under-limit gossiped snapshot is safe to answer
+			// locally; anything closer to the limit still goes to the
+			// owner so two nodes can't both allow the last few hits.
+			return cached
+		}
+	}
+
+	owner, ok := d.ring.owner(req.Identifier)
+	if !ok || owner.ID() == d.self.ID() {
+		return d.local[req.Algorithm].decide(req.Identifier, req.Limit, req.Duration, req.Hits)
+	}
+
+	decisions, err := d.forward(owner, req)
+	if err != nil {
+		// The owner is unreachable: degrade to enforcing the limit against
+		// this node's own local strategy rather than failing the request.
+		return d.local[req.Algorithm].decide(req.Identifier, req.Limit, req.Duration, req.Hits)
+	}
+	return decisions[0]
+}
+
+// forward coalesces req into the in-flight batch bound for owner. The
+// first request of a new batch starts a batchWindow timer; when it fires,
+// the whole batch is flushed as a single GetRateLimit call.
+func (d *DistributedRateLimiter) forward(owner Peer, req RateLimitRequest) ([]RateLimitDecision, error) {
+	p := &pendingRequest{req: req, done: make(chan RateLimitDecision, 1)}
+
+	d.batchMu.Lock()
+	batch, inFlight := d.batches[owner.ID()]
+	d.batches[owner.ID()] = append(batch, p)
+	if !inFlight {
+		time.AfterFunc(d.batchWindow, func() { d.flush(owner) })
+	}
+	d.batchMu.Unlock()
+
+	return []RateLimitDecision{<-p.done}, nil
+}
+
+// flush sends every request queued for owner as one batched GetRateLimit
+// call and delivers each result back to its waiter.
+func (d *DistributedRateLimiter) flush(owner Peer) {
+	d.batchMu.Lock()
+	batch := d.batches[owner.ID()]
+	delete(d.batches, owner.ID())
+	d.batchMu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	reqs := make([]RateLimitRequest, len(batch))
+	for i, p := range batch {
+		reqs[i] = p.req
+	}
+
+	decisions, err := owner.GetRateLimit(reqs)
+	if err != nil {
+		// Owner unreachable: each waiter falls back to local enforcement.
+		for _, p := range batch {
+			p.done <- d.local[p.req.Algorithm].decide(p.req.Identifier, p.req.Limit, p.req.Duration, p.req.Hits)
+		}
+		return
+	}
+
+	for i, p := range batch {
+		p.done <- decisions[i]
+	}
+}
+
+// gossipKey namespaces the gossip cache by algorithm so the same
+// identifier under token-bucket and leaky-bucket accounting never collide.
+func gossipKey(identifier string, algo RateLimitAlgorithm) string {
+	return string(algo) + ":" + identifier
+}
+
+// StartGossip begins periodically broadcasting this node's owned
+// decisions to every peer, so read-heavy limits can be answered from
+// gossipCache without an owner round trip. Call Stop to end it.
+func (d *DistributedRateLimiter) StartGossip(interval time.Duration) {
+	d.gossipStop = make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.broadcastOwned()
+			case <-d.gossipStop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the gossip broadcaster started by StartGossip. It is a no-op
+// if gossip was never started.
+func (d *DistributedRateLimiter) Stop() {
+	if d.gossipStop != nil {
+		close(d.gossipStop)
+	}
+}
+
+// broadcastOwned snapshots every identifier this node currently owns (for
+// both strategies) and pushes it to each peer on the ring.
+func (d *DistributedRateLimiter) broadcastOwned() {
+	snapshot := make(map[string]RateLimitDecision)
+	for algo, strat := range d.local {
+		switch s := strat.(type) {
+		case *tokenBucketStrategy:
+			s.mu.Lock()
+			for id, b := range s.buckets {
+				snapshot[gossipKey(id, algo)] = RateLimitDecision{
+					Remaining: int(b.tokens),
+					Limit:     b.limit,
+					ResetTime: b.updatedAt.Add(time.Duration(float64(time.Second) * float64(b.limit) / maxFloat(b.refillRate, 1))),
+				}
+			}
+			s.mu.Unlock()
+		case *leakyBucketStrategy:
+			s.mu.Lock()
+			for id, st := range s.states {
+				snapshot[gossipKey(id, algo)] = RateLimitDecision{
+					Remaining: st.remaining,
+					Limit:     st.limit,
+					ResetTime: st.updatedAt.Add(st.duration),
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+
+	if len(snapshot) == 0 {
+		return
+	}
+
+	d.ring.mu.RLock()
+	peers := make([]Peer, 0, len(d.ring.peers))
+	for _, p := range d.ring.peers {
+		if p.ID() != d.self.ID() {
+			peers = append(peers, p)
+		}
+	}
+	d.ring.mu.RUnlock()
+
+	for _, p := range peers {
+		go p.Gossip(snapshot)
+	}
+}
+
+// ReceiveGossip merges a peer's broadcast into this node's gossip cache,
+// called by the HTTP handler backing a peer's Gossip call.
+func (d *DistributedRateLimiter) ReceiveGossip(snapshot map[string]RateLimitDecision) {
+	d.gossipMu.Lock()
+	defer d.gossipMu.Unlock()
+	for k, v := range snapshot {
+		d.gossipCache[k] = v
+	}
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// HTTPPeer is the Peer transport this package ships: GetRateLimit and
+// Gossip are each a single JSON POST to the peer's apiproxyd instance.
+type HTTPPeer struct {
+	id      string
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPPeer creates an HTTPPeer identified by id, reachable at baseURL
+// (e.g. "https://apiproxyd-2.internal:9443").
+func NewHTTPPeer(id, baseURL string, client *http.Client) *HTTPPeer {
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Second}
+	}
+	return &HTTPPeer{id: id, baseURL: baseURL, client: client}
+}
+
+func (p *HTTPPeer) ID() string { return p.id }
+
+func (p *HTTPPeer) GetRateLimit(reqs []RateLimitRequest) ([]RateLimitDecision, error) {
+	var decisions []RateLimitDecision
+	if err := p.post("/internal/ratelimit", reqs, &decisions); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+func (p *HTTPPeer) Gossip(snapshot map[string]RateLimitDecision) error {
+	return p.post("/internal/ratelimit/gossip", snapshot, nil)
+}
+
+func (p *HTTPPeer) post(path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request to peer %s: %w", p.id, err)
+	}
+
+	resp, err := p.client.Post(p.baseURL+path, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("call peer %s: %w", p.id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("peer %s returned status %d", p.id, resp.StatusCode)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ServeRateLimitHTTP handles the owner side of HTTPPeer.GetRateLimit: it
+// decodes a batch of RateLimitRequest, decides each one against d's local
+// strategies (this node is assumed to be the owner whenever it receives
+// this call), and writes back the matching decisions in order.
+func ServeRateLimitHTTP(d *DistributedRateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var reqs []RateLimitRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		decisions := make([]RateLimitDecision, len(reqs))
+		for i, req := range reqs {
+			decisions[i] = d.local[req.Algorithm].decide(req.Identifier, req.Limit, req.Duration, req.Hits)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(decisions)
+	}
+}
+
+// ServeRateLimitGossipHTTP handles the receiving side of HTTPPeer.Gossip.
+func ServeRateLimitGossipHTTP(d *DistributedRateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var snapshot map[string]RateLimitDecision
+		if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		d.ReceiveGossip(snapshot)
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+type fakePeer struct {
+	id string
+}
+
+func (p *fakePeer) ID() string { return p.id }
+func (p *fakePeer) GetRateLimit(reqs []RateLimitRequest) ([]RateLimitDecision, error) {
+	return nil, nil
+}
+func (p *fakePeer) Gossip(map[string]RateLimitDecision) error { return nil }
+
+func TestLeakyBucketStrategy(t *testing.T) {
+	s := newLeakyBucketStrategy()
+
+	d := s.decide("user-1", 5, time.Second, 5)
+	if d.OverLimit {
+		t.Fatal("first request should not be over limit")
+	}
+	if d.Remaining != 0 {
+		t.Errorf("expected 0 remaining after spending the whole bucket, got %d", d.Remaining)
+	}
+
+	d = s.decide("user-1", 5, time.Second, 1)
+	if !d.OverLimit {
+		t.Error("request against an empty bucket should be over limit")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	d = s.decide("user-1", 5, time.Second, 1)
+	if d.OverLimit {
+		t.Error("request after leaking for 250ms of a 1s/5-token bucket should be allowed")
+	}
+}
+
+func TestTokenBucketStrategyBurstThenRefill(t *testing.T) {
+	s := newTokenBucketStrategy()
+
+	for i := 0; i < 5; i++ {
+		if d := s.decide("ip-1", 5, time.Second, 1); d.OverLimit {
+			t.Fatalf("token %d within burst should be allowed", i)
+		}
+	}
+	if d := s.decide("ip-1", 5, time.Second, 1); !d.OverLimit {
+		t.Error("request beyond burst should be over limit")
+	}
+}
+
+func TestRingOwnerStable(t *testing.T) {
+	r := newRing(50)
+	r.set(&fakePeer{id: "a"}, &fakePeer{id: "b"}, &fakePeer{id: "c"})
+
+	owner, ok := r.owner("some-identifier")
+	if !ok {
+		t.Fatal("expected a ring owner with peers present")
+	}
+
+	for i := 0; i < 10; i++ {
+		again, _ := r.owner("some-identifier")
+		if again.ID() != owner.ID() {
+			t.Errorf("ring owner for a fixed key should be stable, got %q then %q", owner.ID(), again.ID())
+		}
+	}
+}
+
+func TestRingNoPeers(t *testing.T) {
+	r := newRing(50)
+	if _, ok := r.owner("anything"); ok {
+		t.Error("empty ring should report no owner")
+	}
+}
+
+func TestDistributedRateLimiterFallsBackToLocal(t *testing.T) {
+	self := &fakePeer{id: "self"}
+	d := NewDistributedRateLimiter(self)
+
+	decision := d.Decide(RateLimitRequest{
+		Identifier: "ip-1",
+		Algorithm:  TokenBucketAlgorithm,
+		Limit:      1,
+		Duration:   time.Second,
+		Hits:       1,
+	})
+	if decision.OverLimit {
+		t.Error("first request against a single-node ring should be allowed locally")
+	}
+}
@@ -7,36 +7,75 @@ import (
 	"time"
 )
 
-// RateLimiter implements token bucket rate limiting for per-IP and per-key limits
+// RateLimiter implements token bucket rate limiting for per-IP, per-key,
+// and (optionally) per-client-certificate-CN limits.
 type RateLimiter struct {
 	mu            sync.RWMutex
 	ipLimiters    map[string]*tokenBucket
 	keyLimiters   map[string]*tokenBucket
-	ipRate        int           // requests per minute
-	keyRate       int           // requests per minute
-	burst         int           // burst size
+	cnLimiters    map[string]*tokenBucket
+	ipRate        int // requests per minute
+	keyRate       int // requests per minute
+	cnRate        int // requests per minute; 0 disables the per-CN dimension
+	burst         int // burst size
 	cleanupTicker *time.Ticker
 	done          chan struct{}
+
+	// rejections counts requests turned away, keyed by dimension ("ip",
+	// "key", or "cn"), for the apiproxyd_rate_limit_rejections_total
+	// metrics series.
+	rejections map[string]int64
+
+	// credentialErrors counts requests that arrived with the per-CN
+	// dimension enabled but no verified client certificate to key off, so
+	// operators can tell an mTLS misconfiguration apart from a quiet client
+	// simply not presenting a cert.
+	credentialErrors int64
+
+	// OnLimitExceeded, if set, is called whenever a request is rejected,
+	// with the dimension ("ip", "key", or "cn") and the offending
+	// identifier, e.g. so a daemon can publish a "rate_limit_trip" event.
+	OnLimitExceeded func(scope, identifier string)
+}
+
+// RateLimiterOptions configures additional, optional rate-limiting
+// dimensions beyond the standard per-IP/per-key limits.
+type RateLimiterOptions struct {
+	// CNRate enables a third bucket keyed by the verified client
+	// certificate CN (see Server.MTLSEnabled), for deployments that want to
+	// rate limit by client identity independently of source IP or API key.
+	// Zero disables it.
+	CNRate int
 }
 
 // tokenBucket implements the token bucket algorithm
 type tokenBucket struct {
-	tokens       float64
-	capacity     float64
-	refillRate   float64 // tokens per second
-	lastRefill   time.Time
-	mu           sync.Mutex
+	tokens     float64
+	capacity   float64
+	refillRate float64 // tokens per second
+	lastRefill time.Time
+	mu         sync.Mutex
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a new rate limiter with just the per-IP and
+// per-key dimensions.
 func NewRateLimiter(ipRate, keyRate, burst int) *RateLimiter {
+	return NewRateLimiterWithOptions(ipRate, keyRate, burst, RateLimiterOptions{})
+}
+
+// NewRateLimiterWithOptions creates a rate limiter, additionally enabling
+// the dimensions requested in opts.
+func NewRateLimiterWithOptions(ipRate, keyRate, burst int, opts RateLimiterOptions) *RateLimiter {
 	rl := &RateLimiter{
 		ipLimiters:  make(map[string]*tokenBucket),
 		keyLimiters: make(map[string]*tokenBucket),
+		cnLimiters:  make(map[string]*tokenBucket),
 		ipRate:      ipRate,
 		keyRate:     keyRate,
+		cnRate:      opts.CNRate,
 		burst:       burst,
 		done:        make(chan struct{}),
+		rejections:  make(map[string]int64),
 	}
 
 	// Start cleanup goroutine to remove stale limiters
@@ -54,6 +93,7 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 
 		// Check IP-based rate limit
 		if !rl.allowIP(ip) {
+			rl.trip("ip", ip)
 			http.Error(w, "Rate limit exceeded for IP", http.StatusTooManyRequests)
 			return
 		}
@@ -61,14 +101,48 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		// Check API key-based rate limit if present
 		apiKey := r.Header.Get("X-API-Key")
 		if apiKey != "" && !rl.allowKey(apiKey) {
+			rl.trip("key", apiKey)
 			http.Error(w, "Rate limit exceeded for API key", http.StatusTooManyRequests)
 			return
 		}
 
+		// Check client-certificate-CN rate limit, if mTLS is in use and this
+		// dimension is enabled
+		if rl.cnRate > 0 {
+			cn := clientCertCN(r)
+			if cn == "" {
+				rl.recordCredentialError()
+			} else if !rl.allowCN(cn) {
+				rl.trip("cn", cn)
+				http.Error(w, "Rate limit exceeded for client certificate", http.StatusTooManyRequests)
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// trip records the rejection under scope and notifies OnLimitExceeded, if
+// set, that identifier was rejected.
+func (rl *RateLimiter) trip(scope, identifier string) {
+	rl.mu.Lock()
+	rl.rejections[scope]++
+	rl.mu.Unlock()
+
+	if rl.OnLimitExceeded != nil {
+		rl.OnLimitExceeded(scope, identifier)
+	}
+}
+
+// recordCredentialError counts a request that hit the per-CN dimension
+// with no verified client certificate to rate limit by.
+func (rl *RateLimiter) recordCredentialError() {
+	rl.mu.Lock()
+	rl.credentialErrors++
+	rl.mu.Unlock()
+}
+
 // allowIP checks if a request from the given IP should be allowed
 func (rl *RateLimiter) allowIP(ip string) bool {
 	rl.mu.Lock()
@@ -95,6 +169,84 @@ func (rl *RateLimiter) allowKey(key string) bool {
 	return bucket.allow()
 }
 
+// allowCN checks if a request bearing the given client certificate CN
+// should be allowed
+func (rl *RateLimiter) allowCN(cn string) bool {
+	rl.mu.Lock()
+	bucket, exists := rl.cnLimiters[cn]
+	if !exists {
+		bucket = newTokenBucket(rl.cnRate, rl.burst)
+		rl.cnLimiters[cn] = bucket
+	}
+	rl.mu.Unlock()
+
+	return bucket.allow()
+}
+
+// MigrateFrom carries current token counts over from old into rl for
+// every identity old has seen (per-CN buckets only when rl has a CN rate),
+// so a hot reload that rebuilds buckets at new rate/burst settings doesn't
+// hand every existing client a full fresh burst. It also adds old's
+// rejection and credential-error counts to rl's. Identities old hasn't
+// seen are created lazily, at the new settings, on their next request.
+func (rl *RateLimiter) MigrateFrom(old *RateLimiter) {
+	if old == nil {
+		return
+	}
+
+	old.mu.RLock()
+	ipTokens := snapshotTokens(old.ipLimiters)
+	keyTokens := snapshotTokens(old.keyLimiters)
+	cnTokens := snapshotTokens(old.cnLimiters)
+	oldRejections := old.rejections
+	oldCredentialErrors := old.credentialErrors
+	old.mu.RUnlock()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for scope, count := range oldRejections {
+		rl.rejections[scope] += count
+	}
+	rl.credentialErrors += oldCredentialErrors
+
+	for id, tokens := range ipTokens {
+		rl.ipLimiters[id] = migratedBucket(rl.ipRate, rl.burst, tokens)
+	}
+	for id, tokens := range keyTokens {
+		rl.keyLimiters[id] = migratedBucket(rl.keyRate, rl.burst, tokens)
+	}
+	if rl.cnRate > 0 {
+		for id, tokens := range cnTokens {
+			rl.cnLimiters[id] = migratedBucket(rl.cnRate, rl.burst, tokens)
+		}
+	}
+}
+
+// snapshotTokens reads the current token count out of every bucket in
+// limiters. Caller must hold the owning RateLimiter's mu.
+func snapshotTokens(limiters map[string]*tokenBucket) map[string]float64 {
+	tokens := make(map[string]float64, len(limiters))
+	for id, bucket := range limiters {
+		bucket.mu.Lock()
+		tokens[id] = bucket.tokens
+		bucket.mu.Unlock()
+	}
+	return tokens
+}
+
+// migratedBucket creates a bucket at the given rate/burst, carrying over
+// tokens from the old bucket but clamped to the new capacity so a lowered
+// burst setting takes effect immediately rather than grandfathering in a
+// higher count.
+func migratedBucket(ratePerMinute, burst int, tokens float64) *tokenBucket {
+	bucket := newTokenBucket(ratePerMinute, burst)
+	if tokens < bucket.capacity {
+		bucket.tokens = tokens
+	}
+	return bucket
+}
+
 // cleanup removes stale rate limiters
 func (rl *RateLimiter) cleanup() {
 	for {
@@ -121,6 +273,15 @@ func (rl *RateLimiter) cleanup() {
 				bucket.mu.Unlock()
 			}
 
+			// Remove CN limiters inactive for > 10 minutes
+			for cn, bucket := range rl.cnLimiters {
+				bucket.mu.Lock()
+				if now.Sub(bucket.lastRefill) > 10*time.Minute {
+					delete(rl.cnLimiters, cn)
+				}
+				bucket.mu.Unlock()
+			}
+
 			rl.mu.Unlock()
 		case <-rl.done:
 			return
@@ -140,14 +301,58 @@ func (rl *RateLimiter) Stats() map[string]interface{} {
 	defer rl.mu.RUnlock()
 
 	return map[string]interface{}{
-		"ip_limiters":  len(rl.ipLimiters),
-		"key_limiters": len(rl.keyLimiters),
-		"ip_rate":      rl.ipRate,
-		"key_rate":     rl.keyRate,
-		"burst":        rl.burst,
+		"ip_limiters":       len(rl.ipLimiters),
+		"key_limiters":      len(rl.keyLimiters),
+		"cn_limiters":       len(rl.cnLimiters),
+		"ip_rate":           rl.ipRate,
+		"key_rate":          rl.keyRate,
+		"cn_rate":           rl.cnRate,
+		"burst":             rl.burst,
+		"ip_rejections":     rl.rejections["ip"],
+		"key_rejections":    rl.rejections["key"],
+		"cn_rejections":     rl.rejections["cn"],
+		"credential_errors": rl.credentialErrors,
 	}
 }
 
+// ActiveBuckets returns the number of tracked identities per dimension
+// ("ip", "key", "cn"), for the apiproxyd_rate_limit_active_buckets gauge.
+func (rl *RateLimiter) ActiveBuckets() map[string]int {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	return map[string]int{
+		"ip":  len(rl.ipLimiters),
+		"key": len(rl.keyLimiters),
+		"cn":  len(rl.cnLimiters),
+	}
+}
+
+// Rejections returns the number of rejected requests per dimension ("ip",
+// "key", "cn") since the rate limiter was created, plus any counts
+// MigrateFrom carried over from the limiter it replaced, for the
+// apiproxyd_rate_limit_rejections_total counter series.
+func (rl *RateLimiter) Rejections() map[string]int64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	return map[string]int64{
+		"ip":  rl.rejections["ip"],
+		"key": rl.rejections["key"],
+		"cn":  rl.rejections["cn"],
+	}
+}
+
+// CredentialErrors returns the number of requests that hit the per-CN
+// dimension with no verified client certificate to key off, since the rate
+// limiter was created, plus any count MigrateFrom carried over from the
+// limiter it replaced.
+func (rl *RateLimiter) CredentialErrors() int64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.credentialErrors
+}
+
 // newTokenBucket creates a new token bucket
 func newTokenBucket(ratePerMinute, burst int) *tokenBucket {
 	return &tokenBucket{
@@ -38,6 +38,45 @@ func TestRateLimiter(t *testing.T) {
 	}
 }
 
+func TestRateLimiterRejectionStats(t *testing.T) {
+	rl := NewRateLimiter(60, 300, 1) // burst of 1 so the second request always trips
+	defer rl.Close()
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := rl.Rejections()["ip"]; got != 1 {
+		t.Errorf("expected 1 ip rejection, got %d", got)
+	}
+	if got := rl.CredentialErrors(); got != 0 {
+		t.Errorf("expected 0 credential errors without a CN dimension, got %d", got)
+	}
+}
+
+func TestRateLimiterCredentialErrors(t *testing.T) {
+	rl := NewRateLimiterWithOptions(6000, 30000, 100, RateLimiterOptions{CNRate: 60})
+	defer rl.Close()
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := rl.CredentialErrors(); got != 1 {
+		t.Errorf("expected 1 credential error for a request with no client cert, got %d", got)
+	}
+}
+
 func TestTokenBucket(t *testing.T) {
 	bucket := newTokenBucket(60, 10) // 60/min = 1/sec, burst 10
 
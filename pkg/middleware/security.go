@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // SecurityConfig holds security middleware configuration
@@ -36,12 +38,18 @@ func BodySizeLimiter(maxSize int64) func(http.Handler) http.Handler {
 
 // SSRFProtection prevents Server-Side Request Forgery attacks
 type SSRFProtection struct {
-	allowedHosts  map[string]bool
-	blockPrivate  bool
+	allowedHosts map[string]bool
+	blockPrivate bool
+	denyNets     []*net.IPNet
+	allowNets    []*net.IPNet
 }
 
-// NewSSRFProtection creates a new SSRF protection middleware
-func NewSSRFProtection(allowedHosts []string, blockPrivate bool) *SSRFProtection {
+// NewSSRFProtection creates a new SSRF protection middleware. denyCIDRs are
+// always rejected regardless of blockPrivate (e.g. cloud metadata endpoints);
+// allowCIDRs override both denyCIDRs and the private-IP check for addresses
+// that need to reach internal services on purpose. Malformed CIDRs are
+// skipped.
+func NewSSRFProtection(allowedHosts []string, blockPrivate bool, denyCIDRs, allowCIDRs []string) *SSRFProtection {
 	allowed := make(map[string]bool)
 	for _, host := range allowedHosts {
 		allowed[strings.ToLower(host)] = true
@@ -50,9 +58,22 @@ func NewSSRFProtection(allowedHosts []string, blockPrivate bool) *SSRFProtection
 	return &SSRFProtection{
 		allowedHosts: allowed,
 		blockPrivate: blockPrivate,
+		denyNets:     parseCIDRs(denyCIDRs),
+		allowNets:    parseCIDRs(allowCIDRs),
 	}
 }
 
+// parseCIDRs parses cidrs into IPNets, silently skipping malformed entries.
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, network)
+		}
+	}
+	return nets
+}
+
 // ValidateURL checks if a URL is safe to request
 func (s *SSRFProtection) ValidateURL(rawURL string) error {
 	u, err := url.Parse(rawURL)
@@ -83,15 +104,18 @@ func (s *SSRFProtection) ValidateURL(rawURL string) error {
 	return nil
 }
 
-// checkPrivateIP checks if a hostname resolves to a private IP
+// checkPrivateIP checks if a hostname resolves to a private or denied IP.
+//
+// This check and the dial that eventually follows it use two separate DNS
+// lookups, so a hostname that resolves differently between the two (DNS
+// rebinding) can slip past it. SafeDialer closes that gap by resolving once
+// and dialing the exact address it validated; use it for the transport that
+// actually performs the upstream request.
 func (s *SSRFProtection) checkPrivateIP(hostname string) error {
 	// Parse as IP first
 	ip := net.ParseIP(hostname)
 	if ip != nil {
-		if isPrivateIP(ip) {
-			return fmt.Errorf("private IP addresses are not allowed: %s", hostname)
-		}
-		return nil
+		return s.checkIP(ip)
 	}
 
 	// Resolve hostname
@@ -102,14 +126,85 @@ func (s *SSRFProtection) checkPrivateIP(hostname string) error {
 
 	// Check all resolved IPs
 	for _, ip := range ips {
-		if isPrivateIP(ip) {
-			return fmt.Errorf("hostname resolves to private IP: %s -> %s", hostname, ip)
+		if err := s.checkIP(ip); err != nil {
+			return fmt.Errorf("hostname resolves to disallowed IP: %s -> %w", hostname, err)
 		}
 	}
 
 	return nil
 }
 
+// checkIP validates a single resolved address against allowNets, denyNets,
+// and (if blockPrivate is set) isPrivateIP, in that priority order so an
+// operator-configured allow-CIDR can carve out an exception to either.
+func (s *SSRFProtection) checkIP(ip net.IP) error {
+	for _, allow := range s.allowNets {
+		if allow.Contains(ip) {
+			return nil
+		}
+	}
+
+	for _, deny := range s.denyNets {
+		if deny.Contains(ip) {
+			return fmt.Errorf("IP address is in a denied range: %s", ip)
+		}
+	}
+
+	if s.blockPrivate && isPrivateIP(ip) {
+		return fmt.Errorf("private IP addresses are not allowed: %s", ip)
+	}
+
+	return nil
+}
+
+// SafeDialer returns a DialContext function that resolves the hostname
+// itself, validates every candidate address with checkIP, and dials the one
+// chosen address directly. Wire this into the http.Transport used to reach
+// upstream hosts: net/http otherwise resolves a second time at dial time,
+// and nothing stops that second lookup from returning a different (private)
+// address than the one ValidateURL checked — a DNS-rebinding bypass.
+func (s *SSRFProtection) SafeDialer() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+
+		ip, err := s.resolveAllowed(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+}
+
+// resolveAllowed resolves host and returns the first address that passes
+// checkIP, so the caller can dial that exact address instead of letting the
+// transport re-resolve (and potentially land on a different one) later.
+func (s *SSRFProtection) resolveAllowed(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if err := s.checkIP(ip); err != nil {
+			return nil, err
+		}
+		return ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve hostname: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if err := s.checkIP(addr.IP); err == nil {
+			return addr.IP, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no allowed address found for hostname: %s", host)
+}
+
 // isPrivateIP checks if an IP is private/internal
 func isPrivateIP(ip net.IP) bool {
 	// Check for loopback
@@ -122,6 +217,11 @@ func isPrivateIP(ip net.IP) bool {
 		return true
 	}
 
+	// Check for multicast (IPv4 224.0.0.0/4 and IPv6 ff00::/8)
+	if ip.IsMulticast() {
+		return true
+	}
+
 	// Check for private ranges
 	privateRanges := []string{
 		"10.0.0.0/8",
@@ -129,9 +229,14 @@ func isPrivateIP(ip net.IP) bool {
 		"192.168.0.0/16",
 		"169.254.0.0/16", // link-local
 		"127.0.0.0/8",    // loopback
+		"0.0.0.0/8",      // "this" network
+		"100.64.0.0/10",  // CGNAT (RFC 6598)
 		"::1/128",        // IPv6 loopback
 		"fe80::/10",      // IPv6 link-local
 		"fc00::/7",       // IPv6 unique local
+		// IPv4-mapped IPv6 addresses need no range of their own:
+		// IPNet.Contains compares them in their IPv4 form against the
+		// IPv4 ranges above.
 	}
 
 	for _, cidr := range privateRanges {
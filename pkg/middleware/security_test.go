@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestSafeDialerDialsValidatedAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	s := NewSSRFProtection(nil, false, nil, nil)
+	dial := s.SafeDialer()
+
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("expected dial to succeed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestSafeDialerRejectsMetadataIP(t *testing.T) {
+	s := NewSSRFProtection(nil, true, nil, nil)
+	dial := s.SafeDialer()
+
+	// 169.254.169.254 is the cloud metadata endpoint; blockPrivate treats
+	// the whole 169.254.0.0/16 link-local range as disallowed.
+	_, err := dial(context.Background(), "tcp", "169.254.169.254:80")
+	if err == nil {
+		t.Fatal("expected dial to the metadata IP to be rejected")
+	}
+}
+
+func TestResolveAllowedRejectsDeniedCIDR(t *testing.T) {
+	s := NewSSRFProtection(nil, false, []string{"203.0.113.0/24"}, nil)
+
+	if _, err := s.resolveAllowed(context.Background(), "203.0.113.5"); err == nil {
+		t.Fatal("expected address in denied CIDR to be rejected")
+	}
+	if _, err := s.resolveAllowed(context.Background(), "203.0.114.5"); err != nil {
+		t.Fatalf("expected address outside denied CIDR to be allowed, got %v", err)
+	}
+}
@@ -59,6 +59,12 @@ func (g *GoPlugin) Init(config map[string]interface{}) error {
 	return g.impl.Init(config)
 }
 
+func (g *GoPlugin) ConfigSchema() []byte {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.impl.ConfigSchema()
+}
+
 func (g *GoPlugin) OnRequest(ctx context.Context, req *Request) (*Request, bool, error) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
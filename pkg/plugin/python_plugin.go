@@ -8,8 +8,13 @@ import (
 	"io"
 	"os/exec"
 	"sync"
+	"time"
 )
 
+// defaultRPCTimeout bounds a call when neither the caller's context nor the
+// plugin config supplies a deadline.
+const defaultRPCTimeout = 30 * time.Second
+
 // PythonPlugin wraps a Python plugin executed as a subprocess
 type PythonPlugin struct {
 	name    string
@@ -19,9 +24,14 @@ type PythonPlugin struct {
 	stdin   io.WriteCloser
 	stdout  io.ReadCloser
 	stderr  io.ReadCloser
-	scanner *bufio.Scanner
-	mu      sync.Mutex
+
+	rpcTimeout time.Duration
+
+	writeMu sync.Mutex // serializes writes to stdin only
+
+	mu      sync.Mutex // guards reqID and pending
 	reqID   int
+	pending map[int]chan RPCResponse
 }
 
 // RPCRequest represents a JSON-RPC request
@@ -46,7 +56,21 @@ type RPCError struct {
 	Message string `json:"message"`
 }
 
-// LoadPythonPlugin loads a Python plugin from the specified path
+// ErrPluginTimeout indicates an RPC call did not complete before its
+// deadline. It is a distinct type (rather than a plain error or a sentinel)
+// so callers can decide, e.g. via errors.As, whether to bypass this plugin
+// for the current request or restart it entirely.
+type ErrPluginTimeout struct {
+	Method string
+}
+
+func (e *ErrPluginTimeout) Error() string {
+	return fmt.Sprintf("plugin call %q timed out", e.Method)
+}
+
+// LoadPythonPlugin loads a Python plugin from the specified path. config may
+// set "rpc_timeout_seconds" to override defaultRPCTimeout for calls that
+// don't otherwise carry a context deadline.
 func LoadPythonPlugin(path string, config map[string]interface{}) (Plugin, error) {
 	cmd := exec.Command("python3", path)
 
@@ -77,18 +101,28 @@ func LoadPythonPlugin(path string, config map[string]interface{}) (Plugin, error
 		}
 	}()
 
+	rpcTimeout := defaultRPCTimeout
+	if v, ok := config["rpc_timeout_seconds"].(float64); ok && v > 0 {
+		rpcTimeout = time.Duration(v * float64(time.Second))
+	}
+
 	p := &PythonPlugin{
-		path:    path,
-		cmd:     cmd,
-		stdin:   stdin,
-		stdout:  stdout,
-		stderr:  stderr,
-		scanner: bufio.NewScanner(stdout),
-		reqID:   0,
+		path:       path,
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     stdout,
+		stderr:     stderr,
+		rpcTimeout: rpcTimeout,
+		pending:    make(map[int]chan RPCResponse),
 	}
 
+	// Dispatch responses to their caller as they arrive, so one goroutine's
+	// read doesn't block another's write or a third's still-pending read —
+	// responses may come back out of order.
+	go p.readLoop()
+
 	// Get plugin info
-	info, err := p.call("get_info", nil)
+	info, err := p.call(context.Background(), "get_info", nil)
 	if err != nil {
 		p.Shutdown()
 		return nil, fmt.Errorf("failed to get plugin info: %w", err)
@@ -115,17 +149,28 @@ func (p *PythonPlugin) Version() string {
 }
 
 func (p *PythonPlugin) Init(config map[string]interface{}) error {
-	_, err := p.call("init", []interface{}{config})
+	_, err := p.call(context.Background(), "init", []interface{}{config})
 	return err
 }
 
+// ConfigSchema asks the subprocess for its JSON Schema via a best-effort
+// "config_schema" RPC call. Older plugins that don't implement that method
+// simply fail the call, which is treated the same as declaring no schema.
+func (p *PythonPlugin) ConfigSchema() []byte {
+	result, err := p.call(context.Background(), "config_schema", nil)
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
 func (p *PythonPlugin) OnRequest(ctx context.Context, req *Request) (*Request, bool, error) {
 	reqJSON, err := req.ToJSON()
 	if err != nil {
 		return req, false, err
 	}
 
-	result, err := p.call("on_request", []interface{}{string(reqJSON)})
+	result, err := p.call(ctx, "on_request", []interface{}{string(reqJSON)})
 	if err != nil {
 		return req, false, err
 	}
@@ -158,7 +203,7 @@ func (p *PythonPlugin) OnResponse(ctx context.Context, req *Request, resp *Respo
 		return resp, err
 	}
 
-	result, err := p.call("on_response", []interface{}{string(reqJSON), string(respJSON)})
+	result, err := p.call(ctx, "on_response", []interface{}{string(reqJSON), string(respJSON)})
 	if err != nil {
 		return resp, err
 	}
@@ -182,7 +227,7 @@ func (p *PythonPlugin) OnCacheHit(ctx context.Context, req *Request, resp *Respo
 		return resp, err
 	}
 
-	result, err := p.call("on_cache_hit", []interface{}{string(reqJSON), string(respJSON)})
+	result, err := p.call(ctx, "on_cache_hit", []interface{}{string(reqJSON), string(respJSON)})
 	if err != nil {
 		return resp, err
 	}
@@ -196,13 +241,12 @@ func (p *PythonPlugin) OnCacheHit(ctx context.Context, req *Request, resp *Respo
 }
 
 func (p *PythonPlugin) Shutdown() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	if p.stdin != nil {
-		// Send shutdown command
-		p.call("shutdown", nil)
+		// Send shutdown command; call takes writeMu itself.
+		p.call(context.Background(), "shutdown", nil)
+		p.writeMu.Lock()
 		p.stdin.Close()
+		p.writeMu.Unlock()
 	}
 
 	if p.cmd != nil && p.cmd.Process != nil {
@@ -213,11 +257,57 @@ func (p *PythonPlugin) Shutdown() error {
 	return nil
 }
 
-// call makes a JSON-RPC call to the Python plugin
-func (p *PythonPlugin) call(method string, params []interface{}) (json.RawMessage, error) {
+// readLoop continuously scans stdout and routes each response to the
+// pending channel matching its id, so calls complete as soon as their own
+// response arrives rather than waiting behind whichever call started the
+// scan. It exits (and wakes any still-pending callers) once the subprocess
+// closes stdout.
+func (p *PythonPlugin) readLoop() {
+	scanner := bufio.NewScanner(p.stdout)
+	for scanner.Scan() {
+		var resp RPCResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.mu.Unlock()
+
+		if ok {
+			ch <- resp
+		}
+	}
+
+	p.mu.Lock()
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+	p.mu.Unlock()
+}
+
+// call makes a JSON-RPC call to the Python plugin and waits for its
+// response, bounded by ctx's deadline (falling back to p.rpcTimeout if ctx
+// carries none). Borrowing the deadline pattern from gonet's Conn, a
+// time.AfterFunc timer keyed off that deadline fires if the response hasn't
+// arrived in time; rather than closing the subprocess's pipes (which would
+// kill every call still in flight on this plugin), it sends a best-effort
+// JSON-RPC "cancel" notification carrying the outstanding request id so the
+// plugin can drop the work, and returns *ErrPluginTimeout.
+func (p *PythonPlugin) call(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && p.rpcTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.rpcTimeout)
+		defer cancel()
+	}
+
 	p.mu.Lock()
 	p.reqID++
 	id := p.reqID
+	respCh := make(chan RPCResponse, 1)
+	p.pending[id] = respCh
 	p.mu.Unlock()
 
 	req := RPCRequest{
@@ -229,37 +319,76 @@ func (p *PythonPlugin) call(method string, params []interface{}) (json.RawMessag
 
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
+		p.removePending(id)
 		return nil, err
 	}
 
-	// Send request
-	p.mu.Lock()
+	p.writeMu.Lock()
 	_, err = p.stdin.Write(append(reqJSON, '\n'))
-	p.mu.Unlock()
+	p.writeMu.Unlock()
 	if err != nil {
+		p.removePending(id)
 		return nil, fmt.Errorf("failed to write request: %w", err)
 	}
 
-	// Read response
-	p.mu.Lock()
-	if !p.scanner.Scan() {
-		p.mu.Unlock()
-		if err := p.scanner.Err(); err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
+	timedOut := make(chan struct{})
+	var timer *time.Timer
+	if deadline, ok := ctx.Deadline(); ok {
+		timer = time.AfterFunc(time.Until(deadline), func() {
+			if p.removePending(id) {
+				p.sendCancel(id)
+				close(timedOut)
+			}
+		})
+	}
+	if timer != nil {
+		defer timer.Stop()
+	}
+
+	select {
+	case resp, ok := <-respCh:
+		if !ok {
+			return nil, fmt.Errorf("plugin closed connection")
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("plugin error: %s (code %d)", resp.Error.Message, resp.Error.Code)
 		}
-		return nil, fmt.Errorf("plugin closed connection")
+		return resp.Result, nil
+	case <-timedOut:
+		return nil, &ErrPluginTimeout{Method: method}
 	}
-	line := p.scanner.Text()
-	p.mu.Unlock()
+}
+
+// removePending deletes id from the pending map and reports whether it was
+// still there, so a timer firing after the real response already arrived is
+// a no-op instead of clobbering it.
+func (p *PythonPlugin) removePending(id int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pending[id]; !ok {
+		return false
+	}
+	delete(p.pending, id)
+	return true
+}
 
-	var resp RPCResponse
-	if err := json.Unmarshal([]byte(line), &resp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+// sendCancel sends a best-effort JSON-RPC "cancel" notification so the
+// plugin can drop work for a request the host has given up waiting on. Any
+// error is ignored: the host is about to return an error to its own caller
+// regardless.
+func (p *PythonPlugin) sendCancel(id int) {
+	notice := RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "cancel",
+		Params:  []interface{}{id},
 	}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("plugin error: %s (code %d)", resp.Error.Message, resp.Error.Code)
+	data, err := json.Marshal(notice)
+	if err != nil {
+		return
 	}
 
-	return resp.Result, nil
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	p.stdin.Write(append(data, '\n'))
 }
@@ -0,0 +1,524 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rpcProtocolVersion is the handshake version this host speaks. A plugin
+// subprocess replies with the highest version it supports; LoadRPCPlugin
+// fails closed if the two can't agree, rather than guessing at a wire
+// format the plugin might not implement.
+const rpcProtocolVersion = 1
+
+// defaultHealthCheckInterval bounds how often a health-check ping is sent
+// to an idle RPCPlugin, and defaultHealthCheckTimeout bounds how long the
+// health-check loop waits on that ping before marking the plugin
+// unhealthy; Manager skips a plugin that reports itself unhealthy.
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+	defaultMaxRestarts         = 3
+)
+
+// RPCPluginOptions configures process isolation for a loaded RPCPlugin.
+type RPCPluginOptions struct {
+	// Args are extra arguments passed to the plugin binary.
+	Args []string
+	// MaxRestarts bounds how many times the manager will respawn the
+	// subprocess after it exits unexpectedly before giving up on it for
+	// good. Defaults to defaultMaxRestarts. A crash loop beyond this limit
+	// leaves the plugin permanently unhealthy rather than restarting
+	// forever.
+	MaxRestarts int
+	// HealthCheckInterval sets how often an idle plugin is pinged.
+	// Defaults to defaultHealthCheckInterval.
+	HealthCheckInterval time.Duration
+	// RPCTimeout bounds a call when neither the caller's context nor this
+	// field supplies a deadline. Defaults to defaultRPCTimeout.
+	RPCTimeout time.Duration
+}
+
+// RPCPlugin wraps a plugin running as an isolated subprocess, speaking a
+// length-prefixed JSON-RPC protocol over stdin/stdout. Isolating the
+// plugin in its own process means a panic or infinite loop in untrusted
+// plugin code can't take the daemon down with it; RPCPlugin instead
+// detects the crash, respawns the subprocess up to MaxRestarts times, and
+// reports itself unhealthy in between so Manager can skip it rather than
+// block.
+type RPCPlugin struct {
+	path string
+	opts RPCPluginOptions
+
+	name    string
+	version string
+
+	protocolVersion int
+
+	mu         sync.Mutex // guards process, stdin/stdout/stderr, pending, reqID
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     io.ReadCloser
+	stderr     io.ReadCloser
+	reqID      int
+	pending    map[int]chan RPCResponse
+	lastConfig map[string]interface{}
+	restarts   int
+
+	healthy      atomic.Bool
+	shutdown     atomic.Bool
+	lastExitCode atomic.Int32  // -1 until the subprocess has exited at least once
+	doneCh       chan struct{} // closed once the health-check loop exits
+}
+
+// LoadRPCPlugin spawns path as a subprocess, performs a version handshake,
+// and starts a background health-check loop. opts may be nil to accept all
+// defaults.
+func LoadRPCPlugin(path string, opts *RPCPluginOptions) (Plugin, error) {
+	if opts == nil {
+		opts = &RPCPluginOptions{}
+	}
+	if opts.MaxRestarts <= 0 {
+		opts.MaxRestarts = defaultMaxRestarts
+	}
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if opts.RPCTimeout <= 0 {
+		opts.RPCTimeout = defaultRPCTimeout
+	}
+
+	p := &RPCPlugin{
+		path:    path,
+		opts:    *opts,
+		pending: make(map[int]chan RPCResponse),
+		doneCh:  make(chan struct{}),
+	}
+	p.lastExitCode.Store(-1)
+
+	if err := p.spawn(); err != nil {
+		p.Shutdown()
+		return nil, err
+	}
+
+	info, err := p.call(context.Background(), "get_info", nil)
+	if err != nil {
+		p.Shutdown()
+		return nil, fmt.Errorf("failed to get plugin info: %w", err)
+	}
+
+	var infoMap map[string]string
+	if err := json.Unmarshal(info, &infoMap); err != nil {
+		p.Shutdown()
+		return nil, fmt.Errorf("failed to parse plugin info: %w", err)
+	}
+	p.name = infoMap["name"]
+	p.version = infoMap["version"]
+
+	p.healthy.Store(true)
+	go p.healthCheckLoop()
+
+	return p, nil
+}
+
+// spawn starts the subprocess, wires up its pipes, performs the version
+// handshake, and starts the response dispatch loop. It's called once from
+// LoadRPCPlugin and again from restart after an unexpected exit.
+func (p *RPCPlugin) spawn() error {
+	cmd := exec.Command(p.path, p.opts.Args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin process: %w", err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			fmt.Printf("[Plugin %s stderr] %s\n", p.path, scanner.Text())
+		}
+	}()
+
+	p.mu.Lock()
+	p.cmd = cmd
+	p.stdin = stdin
+	p.stdout = stdout
+	p.stderr = stderr
+	p.pending = make(map[int]chan RPCResponse)
+	p.reqID = 0
+	p.mu.Unlock()
+
+	go p.readLoop(stdout)
+	go p.watchProcess(cmd)
+
+	version, err := p.handshake()
+	if err != nil {
+		return err
+	}
+	p.protocolVersion = version
+
+	if p.lastConfig != nil {
+		if _, err := p.call(context.Background(), "init", []interface{}{p.lastConfig}); err != nil {
+			return fmt.Errorf("failed to re-init plugin after restart: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// handshake negotiates a protocol version with the freshly spawned
+// subprocess: the host offers rpcProtocolVersion and the plugin replies
+// with the highest version it supports, which must not exceed the offer.
+func (p *RPCPlugin) handshake() (int, error) {
+	result, err := p.call(context.Background(), "handshake", []interface{}{rpcProtocolVersion})
+	if err != nil {
+		return 0, fmt.Errorf("handshake failed: %w", err)
+	}
+
+	var resp struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(result, &resp); err != nil {
+		return 0, fmt.Errorf("malformed handshake response: %w", err)
+	}
+	if resp.Version <= 0 || resp.Version > rpcProtocolVersion {
+		return 0, fmt.Errorf("plugin offered unsupported protocol version %d (host supports up to %d)", resp.Version, rpcProtocolVersion)
+	}
+
+	return resp.Version, nil
+}
+
+// watchProcess waits for the subprocess to exit and, unless the plugin is
+// shutting down deliberately, attempts a bounded restart so a crashing
+// plugin doesn't quietly stop processing requests forever.
+func (p *RPCPlugin) watchProcess(cmd *exec.Cmd) {
+	cmd.Wait()
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	p.lastExitCode.Store(int32(exitCode))
+
+	if p.shutdown.Load() {
+		return
+	}
+
+	p.healthy.Store(false)
+
+	p.mu.Lock()
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+	p.restarts++
+	restarts := p.restarts
+	p.mu.Unlock()
+
+	if restarts > p.opts.MaxRestarts {
+		fmt.Printf("[Plugin %s] exited and exceeded max restarts (%d); giving up\n", p.path, p.opts.MaxRestarts)
+		return
+	}
+
+	fmt.Printf("[Plugin %s] exited unexpectedly, restarting (attempt %d/%d)\n", p.path, restarts, p.opts.MaxRestarts)
+	if err := p.spawn(); err != nil {
+		fmt.Printf("[Plugin %s] restart failed: %v\n", p.path, err)
+		return
+	}
+	p.healthy.Store(true)
+}
+
+// healthCheckLoop pings the subprocess at opts.HealthCheckInterval and
+// records whether it answered within defaultHealthCheckTimeout, so
+// Manager.OnRequest can skip an unresponsive plugin instead of blocking
+// the proxy hot path on it.
+func (p *RPCPlugin) healthCheckLoop() {
+	ticker := time.NewTicker(p.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	defer close(p.doneCh)
+
+	for range ticker.C {
+		if p.shutdown.Load() {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultHealthCheckTimeout)
+		_, err := p.call(ctx, "ping", nil)
+		cancel()
+
+		p.healthy.Store(err == nil)
+	}
+}
+
+// Healthy reports whether the plugin answered its last health-check ping
+// (or hasn't missed one yet). Manager checks this via the unexported
+// healthChecker interface before dispatching a hook call, so one wedged
+// plugin can't stall every request through the proxy.
+func (p *RPCPlugin) Healthy() bool {
+	return p.healthy.Load()
+}
+
+// Stats reports this plugin's supervisor state: whether it answered its
+// last health-check ping, how many times it's been restarted after an
+// unexpected exit, and the exit code of its most recent process (-1 if it
+// hasn't exited yet). Manager.Health aggregates this across every
+// supervised plugin for the daemon's /health endpoint.
+func (p *RPCPlugin) Stats() PluginHealth {
+	p.mu.Lock()
+	restarts := p.restarts
+	p.mu.Unlock()
+
+	return PluginHealth{
+		Name:         p.name,
+		Healthy:      p.healthy.Load(),
+		Restarts:     restarts,
+		LastExitCode: int(p.lastExitCode.Load()),
+	}
+}
+
+func (p *RPCPlugin) Name() string {
+	return p.name
+}
+
+func (p *RPCPlugin) Version() string {
+	return p.version
+}
+
+func (p *RPCPlugin) Init(config map[string]interface{}) error {
+	p.mu.Lock()
+	p.lastConfig = config
+	p.mu.Unlock()
+	_, err := p.call(context.Background(), "init", []interface{}{config})
+	return err
+}
+
+// ConfigSchema asks the subprocess for its JSON Schema via a best-effort
+// "config_schema" RPC call; a plugin that doesn't implement it is treated
+// the same as one declaring no schema.
+func (p *RPCPlugin) ConfigSchema() []byte {
+	result, err := p.call(context.Background(), "config_schema", nil)
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+func (p *RPCPlugin) OnRequest(ctx context.Context, req *Request) (*Request, bool, error) {
+	reqJSON, err := req.ToJSON()
+	if err != nil {
+		return req, false, err
+	}
+
+	result, err := p.call(ctx, "on_request", []interface{}{string(reqJSON)})
+	if err != nil {
+		return req, false, err
+	}
+
+	var response struct {
+		Request  json.RawMessage `json:"request"`
+		Continue bool            `json:"continue"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return req, false, err
+	}
+
+	var modifiedReq Request
+	if err := modifiedReq.FromJSON(response.Request); err != nil {
+		return req, false, err
+	}
+
+	return &modifiedReq, response.Continue, nil
+}
+
+func (p *RPCPlugin) OnResponse(ctx context.Context, req *Request, resp *Response) (*Response, error) {
+	reqJSON, err := req.ToJSON()
+	if err != nil {
+		return resp, err
+	}
+	respJSON, err := resp.ToJSON()
+	if err != nil {
+		return resp, err
+	}
+
+	result, err := p.call(ctx, "on_response", []interface{}{string(reqJSON), string(respJSON)})
+	if err != nil {
+		return resp, err
+	}
+
+	var modifiedResp Response
+	if err := modifiedResp.FromJSON(result); err != nil {
+		return resp, err
+	}
+	return &modifiedResp, nil
+}
+
+func (p *RPCPlugin) OnCacheHit(ctx context.Context, req *Request, resp *Response) (*Response, error) {
+	reqJSON, err := req.ToJSON()
+	if err != nil {
+		return resp, err
+	}
+	respJSON, err := resp.ToJSON()
+	if err != nil {
+		return resp, err
+	}
+
+	result, err := p.call(ctx, "on_cache_hit", []interface{}{string(reqJSON), string(respJSON)})
+	if err != nil {
+		return resp, err
+	}
+
+	var modifiedResp Response
+	if err := modifiedResp.FromJSON(result); err != nil {
+		return resp, err
+	}
+	return &modifiedResp, nil
+}
+
+func (p *RPCPlugin) Shutdown() error {
+	p.shutdown.Store(true)
+
+	p.mu.Lock()
+	stdin := p.stdin
+	cmd := p.cmd
+	p.mu.Unlock()
+
+	if stdin != nil {
+		p.call(context.Background(), "shutdown", nil)
+		stdin.Close()
+	}
+
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+
+	return nil
+}
+
+// readLoop reads length-prefixed JSON-RPC frames from stdout (a 4-byte
+// big-endian length followed by that many bytes of JSON, rather than
+// newline-delimited like PythonPlugin) and routes each response to the
+// pending channel matching its id. It exits once the subprocess closes
+// stdout, e.g. because it crashed; watchProcess is responsible for
+// restarting and re-populating p.pending for the new process.
+func (p *RPCPlugin) readLoop(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	for {
+		var lengthBuf [4]byte
+		if _, err := io.ReadFull(reader, lengthBuf[:]); err != nil {
+			return
+		}
+		length := binary.BigEndian.Uint32(lengthBuf[:])
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return
+		}
+
+		var resp RPCResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.mu.Unlock()
+
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// call makes a length-prefixed JSON-RPC call and waits for its response,
+// bounded by ctx's deadline (falling back to opts.RPCTimeout if ctx
+// carries none).
+func (p *RPCPlugin) call(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && p.opts.RPCTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.opts.RPCTimeout)
+		defer cancel()
+	}
+
+	p.mu.Lock()
+	p.reqID++
+	id := p.reqID
+	respCh := make(chan RPCResponse, 1)
+	p.pending[id] = respCh
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	req := RPCRequest{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      id,
+	}
+
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		p.removePending(id)
+		return nil, err
+	}
+
+	frame := make([]byte, 4+len(reqJSON))
+	binary.BigEndian.PutUint32(frame, uint32(len(reqJSON)))
+	copy(frame[4:], reqJSON)
+
+	if stdin == nil {
+		p.removePending(id)
+		return nil, fmt.Errorf("plugin process is not running")
+	}
+	if _, err := stdin.Write(frame); err != nil {
+		p.removePending(id)
+		return nil, fmt.Errorf("failed to write request: %w", err)
+	}
+
+	select {
+	case resp, ok := <-respCh:
+		if !ok {
+			return nil, fmt.Errorf("plugin closed connection")
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("plugin error: %s (code %d)", resp.Error.Message, resp.Error.Code)
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		p.removePending(id)
+		return nil, &ErrPluginTimeout{Method: method}
+	}
+}
+
+// removePending deletes id from the pending map and reports whether it was
+// still there, so a timeout firing after the real response already
+// arrived is a no-op instead of clobbering it.
+func (p *RPCPlugin) removePending(id int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pending[id]; !ok {
+		return false
+	}
+	delete(p.pending, id)
+	return true
+}
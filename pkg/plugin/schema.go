@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ValidateConfig checks config against a plugin-supplied JSON Schema (as
+// returned by Plugin.ConfigSchema), returning an error naming the
+// offending key and constraint if it doesn't conform. A nil or empty
+// schema is treated as "no constraints" rather than an error, since most
+// plugins don't bother declaring one.
+func ValidateConfig(schema []byte, config map[string]interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("config.json", bytes.NewReader(schema)); err != nil {
+		return fmt.Errorf("invalid config schema: %w", err)
+	}
+	compiled, err := compiler.Compile("config.json")
+	if err != nil {
+		return fmt.Errorf("invalid config schema: %w", err)
+	}
+
+	// Round-trip through JSON so map[string]interface{} values (e.g.
+	// numbers decoded as float64 vs int) match what the schema library
+	// expects from a JSON document.
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config for validation: %w", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("failed to decode config for validation: %w", err)
+	}
+
+	if err := compiled.Validate(doc); err != nil {
+		return fmt.Errorf("config does not match schema: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,352 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASMPlugin runs a plugin compiled to WebAssembly, using wazero so
+// apiproxyd stays CGO-free and cross-platform. Unlike GoPlugin (which wraps
+// plugin.Open and can never be unloaded), a WASMPlugin can be closed and
+// replaced on a hot reload.
+//
+// ABI: the guest module exports "alloc"/"dealloc" for host-managed linear
+// memory, "name"/"version" (no args, return a packed ptr<<32|len uint64),
+// "init"/"on_request"/"on_response"/"on_cache_hit" (ptr/len of an input JSON
+// buffer in, packed ptr<<32|len of an output JSON buffer out), and
+// "shutdown" (no args, no return). A guest may additionally export
+// "config_schema" (no args, packed ptr<<32|len of a JSON Schema buffer
+// out) to have its Config validated before Init is called; it's optional
+// and skipped if absent. The host imports a "log" function into module
+// "env" so the guest can emit diagnostics.
+type WASMPlugin struct {
+	name    string
+	version string
+
+	runtime wazero.Runtime
+	module  api.Module
+
+	callTimeout time.Duration
+	mu          sync.Mutex
+}
+
+// WASMPluginOptions configures resource limits for a loaded WASM plugin.
+type WASMPluginOptions struct {
+	// CallTimeout bounds each Init/OnRequest/OnResponse/OnCacheHit call.
+	// Defaults to 5 seconds.
+	CallTimeout time.Duration
+	// MemoryLimitPages caps the guest's linear memory, in 64KiB pages.
+	// Defaults to 256 (16MB). Zero means use the default.
+	MemoryLimitPages uint32
+}
+
+// LoadWASMPlugin reads the WASM module at path and instantiates it via
+// LoadWASMPluginBytes; each call runs under a fresh wall-clock timeout so a
+// misbehaving guest can't hang the daemon.
+func LoadWASMPlugin(path string, opts *WASMPluginOptions) (Plugin, error) {
+	wasmBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WASM plugin %s: %w", path, err)
+	}
+	return LoadWASMPluginBytes(path, wasmBytes, opts)
+}
+
+// LoadWASMPluginBytes compiles and instantiates an already-loaded WASM
+// module (e.g. fetched remotely and checksum-verified by
+// FetchWASMModule), returning a Plugin that can be called repeatedly.
+// name is used only to label the module for diagnostics; it does not need
+// to match the plugin's own Name().
+func LoadWASMPluginBytes(name string, wasmBytes []byte, opts *WASMPluginOptions) (Plugin, error) {
+	if opts == nil {
+		opts = &WASMPluginOptions{}
+	}
+	callTimeout := opts.CallTimeout
+	if callTimeout <= 0 {
+		callTimeout = 5 * time.Second
+	}
+	memoryLimitPages := opts.MemoryLimitPages
+	if memoryLimitPages == 0 {
+		memoryLimitPages = 256 // 16MB
+	}
+	if name == "" {
+		name = "wasm-plugin"
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitPages).
+		WithCloseOnContextDone(true))
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI for plugin %s: %w", name, err)
+	}
+
+	if _, err := runtime.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(hostLog).
+		Export("log").
+		Instantiate(ctx); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to register host imports for plugin %s: %w", name, err)
+	}
+
+	module, err := runtime.InstantiateWithConfig(ctx, wasmBytes,
+		wazero.NewModuleConfig().WithName(name))
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate plugin %s: %w", name, err)
+	}
+
+	for _, fn := range []string{"alloc", "name", "version", "init", "on_request", "on_response", "on_cache_hit", "shutdown"} {
+		if module.ExportedFunction(fn) == nil {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("plugin %s does not export required function %q", name, fn)
+		}
+	}
+
+	p := &WASMPlugin{
+		runtime:     runtime,
+		module:      module,
+		callTimeout: callTimeout,
+	}
+
+	pluginName, err := p.callString(ctx, "name")
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to read plugin name: %w", err)
+	}
+	p.name = pluginName
+
+	version, err := p.callString(ctx, "version")
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to read plugin version: %w", err)
+	}
+	p.version = version
+
+	return p, nil
+}
+
+// hostLog is the "env.log" import; guests pass a pointer/length into their
+// own memory and the host reads and prints it.
+func hostLog(ctx context.Context, m api.Module, ptr, length uint32) {
+	data, ok := m.Memory().Read(ptr, length)
+	if !ok {
+		return
+	}
+	fmt.Printf("[WASM plugin %s] %s\n", m.Name(), string(data))
+}
+
+func (w *WASMPlugin) Name() string {
+	return w.name
+}
+
+func (w *WASMPlugin) Version() string {
+	return w.version
+}
+
+func (w *WASMPlugin) Init(config map[string]interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.callTimeout)
+	defer cancel()
+
+	_, err = w.callJSON(ctx, "init", payload)
+	return err
+}
+
+// ConfigSchema calls the guest's optional "config_schema" export (absent
+// from the required-export list checked at load time), returning nil if
+// the guest doesn't define it.
+func (w *WASMPlugin) ConfigSchema() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fn := w.module.ExportedFunction("config_schema")
+	if fn == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.callTimeout)
+	defer cancel()
+
+	results, err := fn.Call(ctx)
+	if err != nil {
+		return nil
+	}
+
+	ptr, length := unpackPtrLen(results[0])
+	data, ok := w.module.Memory().Read(ptr, length)
+	if !ok {
+		return nil
+	}
+
+	out := make([]byte, len(data))
+	copy(out, data)
+	return out
+}
+
+func (w *WASMPlugin) OnRequest(ctx context.Context, req *Request) (*Request, bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	reqJSON, err := req.ToJSON()
+	if err != nil {
+		return req, false, err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, w.callTimeout)
+	defer cancel()
+
+	result, err := w.callJSON(callCtx, "on_request", reqJSON)
+	if err != nil {
+		return req, false, err
+	}
+
+	var out struct {
+		Request  json.RawMessage `json:"request"`
+		Continue bool            `json:"continue"`
+	}
+	if err := json.Unmarshal(result, &out); err != nil {
+		return req, false, fmt.Errorf("failed to parse on_request result: %w", err)
+	}
+
+	var modifiedReq Request
+	if err := modifiedReq.FromJSON(out.Request); err != nil {
+		return req, false, err
+	}
+
+	return &modifiedReq, out.Continue, nil
+}
+
+func (w *WASMPlugin) OnResponse(ctx context.Context, req *Request, resp *Response) (*Response, error) {
+	return w.callRequestResponse(ctx, "on_response", req, resp)
+}
+
+func (w *WASMPlugin) OnCacheHit(ctx context.Context, req *Request, resp *Response) (*Response, error) {
+	return w.callRequestResponse(ctx, "on_cache_hit", req, resp)
+}
+
+func (w *WASMPlugin) callRequestResponse(ctx context.Context, fn string, req *Request, resp *Response) (*Response, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	payload, err := json.Marshal(struct {
+		Request  *Request  `json:"request"`
+		Response *Response `json:"response"`
+	}{req, resp})
+	if err != nil {
+		return resp, err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, w.callTimeout)
+	defer cancel()
+
+	result, err := w.callJSON(callCtx, fn, payload)
+	if err != nil {
+		return resp, err
+	}
+
+	var modifiedResp Response
+	if err := modifiedResp.FromJSON(result); err != nil {
+		return resp, fmt.Errorf("failed to parse %s result: %w", fn, err)
+	}
+
+	return &modifiedResp, nil
+}
+
+func (w *WASMPlugin) Shutdown() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.callTimeout)
+	defer cancel()
+
+	if fn := w.module.ExportedFunction("shutdown"); fn != nil {
+		fn.Call(ctx)
+	}
+
+	return w.runtime.Close(ctx)
+}
+
+// callJSON writes payload into guest memory (allocated via the guest's own
+// "alloc" export), calls fn with (ptr, len), and reads back the JSON buffer
+// the guest returns as a packed ptr<<32|len uint64.
+func (w *WASMPlugin) callJSON(ctx context.Context, fn string, payload []byte) (json.RawMessage, error) {
+	ptr, err := w.writeBytes(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := w.module.ExportedFunction(fn).Call(ctx, uint64(ptr), uint64(len(payload)))
+	if err != nil {
+		return nil, fmt.Errorf("plugin call %s failed: %w", fn, err)
+	}
+
+	outPtr, outLen := unpackPtrLen(results[0])
+	data, ok := w.module.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("plugin call %s returned an out-of-range buffer", fn)
+	}
+
+	// Copy out of guest memory before returning, since it may be reused or
+	// freed by a subsequent call.
+	out := make([]byte, len(data))
+	copy(out, data)
+	return out, nil
+}
+
+// callString calls a zero-argument guest function that returns a packed
+// ptr<<32|len uint64 pointing at a UTF-8 string, e.g. "name" or "version".
+func (w *WASMPlugin) callString(ctx context.Context, fn string) (string, error) {
+	results, err := w.module.ExportedFunction(fn).Call(ctx)
+	if err != nil {
+		return "", fmt.Errorf("plugin call %s failed: %w", fn, err)
+	}
+
+	ptr, length := unpackPtrLen(results[0])
+	data, ok := w.module.Memory().Read(ptr, length)
+	if !ok {
+		return "", fmt.Errorf("plugin call %s returned an out-of-range buffer", fn)
+	}
+
+	return string(data), nil
+}
+
+// writeBytes asks the guest to allocate len(data) bytes via its own "alloc"
+// export, then writes data into that buffer so the guest owns the memory
+// (and can free it itself once done processing).
+func (w *WASMPlugin) writeBytes(ctx context.Context, data []byte) (uint32, error) {
+	results, err := w.module.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("plugin alloc failed: %w", err)
+	}
+
+	ptr := uint32(results[0])
+	if !w.module.Memory().Write(ptr, data) {
+		return 0, fmt.Errorf("failed to write %d bytes into guest memory at %d", len(data), ptr)
+	}
+
+	return ptr, nil
+}
+
+// unpackPtrLen splits a packed ptr<<32|len uint64 as returned by guest
+// functions that hand back a buffer.
+func unpackPtrLen(packed uint64) (ptr uint32, length uint32) {
+	return uint32(packed >> 32), uint32(packed)
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// fetchTimeout bounds how long FetchWASMModule will wait on a remote
+// module download, so a slow or stalled upstream can't hang daemon
+// startup indefinitely.
+const fetchTimeout = 30 * time.Second
+
+// FetchWASMModule resolves the bytes of a "wasm" PluginConfig: from pc.URL
+// over HTTP(S) if set, otherwise from pc.Path on the local filesystem.
+// pc.Checksum, the module's sha256 (hex, optionally prefixed "sha256:"), is
+// required with pc.URL and optional with pc.Path; when set it must match or
+// the module is rejected before it ever reaches the wazero runtime.
+func FetchWASMModule(pc PluginConfig) ([]byte, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case pc.URL != "":
+		if pc.Checksum == "" {
+			return nil, fmt.Errorf("wasm plugin %q: checksum is required when url is set", pc.Name)
+		}
+		data, err = fetchWASMFromURL(pc.URL)
+	case pc.Path != "":
+		data, err = os.ReadFile(pc.Path)
+	default:
+		return nil, fmt.Errorf("wasm plugin %q has neither url nor path set", pc.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if pc.Checksum != "" {
+		if err := verifyChecksum(data, pc.Checksum); err != nil {
+			return nil, fmt.Errorf("wasm plugin %q: %w", pc.Name, err)
+		}
+	}
+
+	return data, nil
+}
+
+func fetchWASMFromURL(url string) ([]byte, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch WASM module from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch WASM module from %s: status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WASM module from %s: %w", url, err)
+	}
+
+	return data, nil
+}
+
+func verifyChecksum(data []byte, want string) error {
+	want = strings.TrimPrefix(strings.ToLower(want), "sha256:")
+
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+
+	if got != want {
+		return fmt.Errorf("checksum mismatch: expected sha256:%s, got sha256:%s", want, got)
+	}
+	return nil
+}